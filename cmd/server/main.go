@@ -11,13 +11,17 @@ import (
 	"time"
 
 	"github.com/owner/go-cms/internal/adapters/external/email"
+	"github.com/owner/go-cms/internal/adapters/external/oidc"
 	"github.com/owner/go-cms/internal/adapters/repositories"
 	"github.com/owner/go-cms/internal/adapters/repositories/postgres"
 	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/ports"
 	"github.com/owner/go-cms/internal/core/usecases"
+	"github.com/owner/go-cms/internal/core/usecases/apitoken"
 	"github.com/owner/go-cms/internal/core/usecases/audit"
 	"github.com/owner/go-cms/internal/core/usecases/auth"
 	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/core/usecases/customer"
 	"github.com/owner/go-cms/internal/core/usecases/document"
 	"github.com/owner/go-cms/internal/core/usecases/page_builder"
 	"github.com/owner/go-cms/internal/core/usecases/user"
@@ -29,6 +33,8 @@ import (
 	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/internal/infrastructure/database"
 	storage "github.com/owner/go-cms/internal/infrastructure/filestorage"
+	"github.com/owner/go-cms/internal/infrastructure/jwtkeys"
+	"github.com/owner/go-cms/internal/infrastructure/preview"
 	"github.com/owner/go-cms/internal/infrastructure/websocket"
 	"github.com/owner/go-cms/pkg/logger"
 	"go.uber.org/zap"
@@ -124,6 +130,12 @@ func main() {
 	userRepo := postgres.NewUserRepository(db)
 	otpRepo := postgres.NewOTPRepository(db)
 	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
+	sessionRepo := postgres.NewUserSessionRepository(db)
+	recoveryCodeRepo := postgres.NewRecoveryCodeRepository(db)
+	webAuthnRepo := postgres.NewWebAuthnCredentialRepository(db)
+	identityRepo := postgres.NewExternalIdentityRepository(db)
+	signingKeyRepo := postgres.NewSigningKeyRepository(db)
+	apiTokenRepo := postgres.NewAPITokenRepository(db)
 
 	// Initialize authorization repositories
 	moduleRepo := postgres.NewModuleRepository(db)
@@ -131,8 +143,12 @@ func main() {
 	serviceRepo := postgres.NewServiceRepository(db)
 	scopeRepo := postgres.NewScopeRepository(db)
 	roleRepo := postgres.NewRoleRepository(db)
-	permissionRepo := postgres.NewPermissionRepository(db)
+	permissionRepo := postgres.NewEnhancedPermissionRepository(db)
+	policyRepo := postgres.NewPolicyRepository(db)
+	modelRepo := postgres.NewAuthorizationModelRepository(db)
+	accessRequestRepo := postgres.NewAccessRequestRepository(db)
 	documentRepo := postgres.NewDocumentRepository(db)
+	customerRepo := postgres.NewCustomerRepository(db)
 	categoryRepo := postgres.NewCategoryRepository(db)
 	// Initialize notification repository
 	notificationRepo := repositories.NewNotificationRepository(db)
@@ -140,26 +156,49 @@ func main() {
 	// Initialize audit log repository
 	auditLogRepo := postgres.NewAuditLogRepository(db)
 
+	// Give departments created by migrations and seeding their materialized paths
+	if err := departmentRepo.RebuildPaths(context.Background()); err != nil {
+		logger.Fatal("Failed to rebuild department paths", zap.Error(err))
+	}
+
+	// Initialize token signing keys
+	keyring := jwtkeys.NewKeyring(&cfg.JWT, signingKeyRepo)
+	if err := keyring.Init(context.Background()); err != nil {
+		logger.Fatal("Failed to initialize JWT signing keys", zap.Error(err))
+	}
+
+	// Initialize single sign-on providers
+	var identityProviders []ports.IdentityProvider
+	if cfg.OIDC.Enabled {
+		provider, err := oidc.NewProvider(context.Background(), &cfg.OIDC)
+		if err != nil {
+			logger.Error("Failed to initialize OIDC provider, SSO disabled", zap.Error(err))
+		} else {
+			identityProviders = append(identityProviders, provider)
+		}
+	}
+
+	// Initialize permission checker
+	permissionChecker := middleware.NewPermissionChecker(db)
+
 	// Initialize use cases
-	authUseCase := auth.NewUseCase(userRepo, otpRepo, refreshTokenRepo, cfg, emailService)
+	authUseCase := auth.NewUseCase(userRepo, otpRepo, refreshTokenRepo, sessionRepo, recoveryCodeRepo, webAuthnRepo, identityRepo, roleRepo, permissionChecker, cfg, emailService, keyring, identityProviders)
 	userUseCase := user.NewUserUseCase(userRepo, roleRepo, departmentRepo)
+	apiTokenUseCase := apitoken.NewUseCase(apiTokenRepo, userRepo, permissionChecker, &cfg.APITokens)
 
 	// Initialize authorization use cases
 	moduleUseCase := authorization.NewModuleUseCase(moduleRepo)
-	departmentUseCase := authorization.NewDepartmentUseCase(departmentRepo, moduleRepo)
+	departmentUseCase := authorization.NewDepartmentUseCase(departmentRepo, moduleRepo, userRepo)
 	serviceUseCase := authorization.NewServiceUseCase(serviceRepo, departmentRepo)
 	scopeUseCase := authorization.NewScopeUseCase(scopeRepo)
-	roleUseCase := authorization.NewRoleUseCase(roleRepo, permissionRepo)
-	permissionUseCase := authorization.NewPermissionUseCase(permissionRepo)
+	roleUseCase := authorization.NewRoleUseCase(roleRepo, permissionRepo, userRepo)
+	permissionUseCase := authorization.NewPermissionUseCase(permissionRepo, moduleRepo, departmentRepo, serviceRepo, scopeRepo)
+	policyUseCase := authorization.NewPolicyUseCase(policyRepo, userRepo, permissionChecker)
+	permissionChecker.SetPolicyEvaluator(policyUseCase)
+	modelUseCase := authorization.NewModelUseCase(modelRepo)
 
-	// Initialize document use cases
-	documentUseCase := document.NewDocumentUsecase(documentRepo, storage)
-	pageUseCase := page_builder.NewPageUseCase(pageRepo, pageVersionRepo)
-	blockUseCase := page_builder.NewBlockUseCase(blockRepo)
-	pageBlockUseCase := page_builder.NewPageBlockUseCase(pageBlockRepo, blockRepo)
-	pageVersionUseCase := page_builder.NewPageVersionUseCase(pageVersionRepo, pageRepo, pageBlockRepo)
-	themeSettingUseCase := page_builder.NewThemeSettingUseCase(themeSettingRepo)
-	categoryUseCase := usecases.NewCategoryUseCase(categoryRepo)
+	// Initialize audit log use case
+	auditLogUseCase := audit.NewUseCase(auditLogRepo)
 
 	// Initialize WebSocket Hub
 	wsHub := websocket.NewHub(log)
@@ -168,13 +207,34 @@ func main() {
 
 	// Initialize notification use case
 	notificationUseCase := usecases.NewNotificationUseCase(notificationRepo, wsHub, log)
+	grantUseCase := authorization.NewGrantUseCase(permissionRepo, roleRepo, userRepo, notificationUseCase, cfg.Grants.ExpiryInterval)
+	accessRequestUseCase := authorization.NewAccessRequestUseCase(accessRequestRepo, roleRepo, permissionRepo, userRepo, departmentRepo, grantUseCase, permissionChecker, notificationUseCase, auditLogUseCase, cfg.Grants)
 
-	// Initialize audit log use case
-	auditLogUseCase := audit.NewUseCase(auditLogRepo)
+	// Initialize document use cases
+	documentUseCase := document.NewDocumentUsecase(documentRepo, storage, auditLogUseCase, notificationUseCase, permissionChecker, permissionChecker, preview.NewGenerator(), cfg.Documents)
+	customerUseCase := customer.NewUseCase(customerRepo, userRepo, documentUseCase, permissionChecker)
+	pageUseCase := page_builder.NewPageUseCase(pageRepo, pageVersionRepo)
+	blockUseCase := page_builder.NewBlockUseCase(blockRepo)
+	pageBlockUseCase := page_builder.NewPageBlockUseCase(pageBlockRepo, blockRepo)
+	pageVersionUseCase := page_builder.NewPageVersionUseCase(pageVersionRepo, pageRepo, pageBlockRepo)
+	themeSettingUseCase := page_builder.NewThemeSettingUseCase(themeSettingRepo)
+	categoryUseCase := usecases.NewCategoryUseCase(categoryRepo)
+
+	// Start background document preview generation, retention purging and grant expiry
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	documentUseCase.StartPreviewWorkers(backgroundCtx)
+	documentUseCase.StartRetentionJob(backgroundCtx)
+	keyring.StartRotationJob(backgroundCtx)
+	grantUseCase.StartExpiryJob(backgroundCtx)
+
+	// Drop in-process permissions and policies when any instance changes them
+	cache.SubscribePermissionEvents(backgroundCtx, permissionChecker.HandlePermissionEvent, policyUseCase.HandlePermissionEvent)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authUseCase)
 	userHandler := handlers.NewUserHandler(userUseCase)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenUseCase)
 
 	// Initialize authorization handlers
 	moduleHandler := authHandlers.NewModuleHandler(moduleUseCase)
@@ -183,9 +243,14 @@ func main() {
 	scopeHandler := authHandlers.NewScopeHandler(scopeUseCase)
 	roleHandler := handlers.NewRoleHandler(roleUseCase)
 	permissionHandler := handlers.NewPermissionHandler(permissionUseCase)
+	grantHandler := handlers.NewGrantHandler(grantUseCase)
+	policyHandler := handlers.NewPolicyHandler(policyUseCase)
+	modelHandler := handlers.NewModelHandler(modelUseCase)
+	accessRequestHandler := handlers.NewAccessRequestHandler(accessRequestUseCase)
 
 	// Initialize document handler
 	documentHandler := handlers.NewDocumentHandler(documentUseCase)
+	customerHandler := handlers.NewCustomerHandler(customerUseCase)
 	pageHandler := pageBuilderHandlers.NewPageHandler(pageUseCase)
 	blockHandler := pageBuilderHandlers.NewBlockHandler(blockUseCase)
 	pageBlockHandler := pageBuilderHandlers.NewPageBlockHandler(pageBlockUseCase)
@@ -201,24 +266,30 @@ func main() {
 	// Initialize category handler
 	categoryHandler := handlers.NewCategoryHandler(categoryUseCase)
 
-	// Initialize permission checker
-	permissionChecker := middleware.NewPermissionChecker(db)
-
 	r := router.NewRouter(
 		cfg,
+		keyring,
+		apiTokenUseCase,
 		permissionChecker,
+		departmentUseCase,
 		authHandler,
 		userHandler,
+		apiTokenHandler,
 		moduleHandler,
 		departmentHandler,
 		serviceHandler,
 		scopeHandler,
 		roleHandler,
 		permissionHandler,
+		grantHandler,
+		policyHandler,
+		modelHandler,
+		accessRequestHandler,
 		notificationHandler,
 		websocketHandler,
 		auditLogHandler,
 		documentHandler,
+		customerHandler,
 		auditLogUseCase,
 		categoryHandler,
 		// Page Builder handlers
@@ -256,6 +327,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopBackground()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
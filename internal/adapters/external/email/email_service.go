@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"net/smtp"
+	"time"
 
 	"github.com/owner/go-cms/internal/config"
 	"github.com/owner/go-cms/pkg/logger"
@@ -127,6 +128,7 @@ func (s *Service) getTemplate(name string) (*template.Template, error) {
 		"verify_email":   verifyEmailTemplate,
 		"reset_password": resetPasswordTemplate,
 		"otp":            otpTemplate,
+		"account_locked": accountLockedTemplate,
 	}
 
 	tmplStr, ok := templates[name]
@@ -231,6 +233,26 @@ const otpTemplate = `
 </html>
 `
 
+const accountLockedTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your Account Was Locked</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #FF5722;">Your Account Was Locked</h1>
+        <p>Hi {{.Name}},</p>
+        <p>We locked your account after several failed sign-in attempts{{if .IPAddress}} from {{.IPAddress}}{{end}}.</p>
+        <p>You can sign in again after <strong>{{.LockedUntil}}</strong>, or ask an administrator to unlock your account sooner.</p>
+        <p>If these attempts were not yours, we recommend resetting your password once the lock expires.</p>
+        <p>Best regards,<br>The GO CMS Team</p>
+    </div>
+</body>
+</html>
+`
+
 // Helper functions for common email scenarios
 
 // SendOTPEmail sends an OTP verification email
@@ -275,3 +297,14 @@ func (s *Service) SendWelcomeEmail(to, name string) error {
 
 	return s.SendTemplateEmail(to, "Welcome to GO CMS", "welcome", data)
 }
+
+// SendAccountLockedEmail tells a user their account was locked after failed logins
+func (s *Service) SendAccountLockedEmail(to, name string, lockedUntil time.Time, ipAddress string) error {
+	data := map[string]interface{}{
+		"Name":        name,
+		"LockedUntil": lockedUntil.UTC().Format("2006-01-02 15:04 MST"),
+		"IPAddress":   ipAddress,
+	}
+
+	return s.SendTemplateEmail(to, "Your Account Was Locked", "account_locked", data)
+}
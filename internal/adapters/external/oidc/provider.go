@@ -0,0 +1,175 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/ports"
+	"golang.org/x/oauth2"
+)
+
+// Provider logs users in with an OpenID Connect identity provider
+type Provider struct {
+	name        string
+	oauth2      oauth2.Config
+	verifier    *gooidc.IDTokenVerifier
+	groupsClaim string
+	policy      ports.SSOPolicy
+}
+
+// NewProvider discovers the provider's endpoints from its issuer. ID tokens are
+// verified against the signing keys published at the discovered JWKS URL.
+func NewProvider(ctx context.Context, cfg *config.OIDCConfig) (*Provider, error) {
+	provider, err := gooidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if !contains(scopes, gooidc.ScopeOpenID) {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+
+	return &Provider{
+		name: cfg.Name,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:    provider.Verifier(&gooidc.Config{ClientID: cfg.ClientID}),
+		groupsClaim: cfg.GroupsClaim,
+		policy: ports.SSOPolicy{
+			AllowedDomains:  cfg.AllowedDomains,
+			AutoCreateUsers: cfg.AutoCreateUsers,
+			GroupRoles:      parseGroupRoles(cfg.GroupRoles),
+		},
+	}, nil
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Policy returns the login and role mapping rules
+func (p *Provider) Policy() ports.SSOPolicy {
+	return p.policy
+}
+
+// AuthCodeURL returns the provider's authorization URL with an S256 PKCE challenge
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	return p.oauth2.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier))
+}
+
+// Exchange redeems the code and verifies the ID token's signature, issuer, audience,
+// expiry and nonce
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ports.ExternalClaims, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("ID token nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse ID token claims: %w", err)
+	}
+
+	external := &ports.ExternalClaims{
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(stringClaim(claims, "email")),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		FirstName:     stringClaim(claims, "given_name"),
+		LastName:      stringClaim(claims, "family_name"),
+		Groups:        stringsClaim(claims, p.groupsClaim),
+	}
+
+	// Some providers only send the full name
+	if external.FirstName == "" && external.LastName == "" {
+		name := strings.TrimSpace(stringClaim(claims, "name"))
+		external.FirstName, external.LastName, _ = strings.Cut(name, " ")
+	}
+
+	return external, nil
+}
+
+// parseGroupRoles parses group:role pairs. Group names may contain colons, so the
+// role is taken after the last one.
+func parseGroupRoles(pairs []string) map[string]string {
+	groupRoles := make(map[string]string)
+	for _, pair := range pairs {
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 || i == len(pair)-1 {
+			continue
+		}
+		groupRoles[strings.TrimSpace(pair[:i])] = strings.TrimSpace(pair[i+1:])
+	}
+	return groupRoles
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim, which some providers send as a string
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	default:
+		return false
+	}
+}
+
+// stringsClaim reads a claim holding a list of strings or a single string
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	switch value := claims[name].(type) {
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"github.com/owner/go-cms/pkg/pagination"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type accessRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewAccessRequestRepository creates a new access request repository
+func NewAccessRequestRepository(db *gorm.DB) repositories.AccessRequestRepository {
+	return &accessRequestRepository{db: db}
+}
+
+func (r *accessRequestRepository) Create(ctx context.Context, request *domain.AccessRequest) error {
+	if err := r.db.WithContext(ctx).Omit("Requester", "Approver", "Role", "Permission").Create(request).Error; err != nil {
+		logger.Error("Failed to create access request", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create access request", 500)
+	}
+	return nil
+}
+
+func (r *accessRequestRepository) GetByID(ctx context.Context, id uint) (*domain.AccessRequest, error) {
+	var request domain.AccessRequest
+	if err := r.preload(r.db.WithContext(ctx)).First(&request, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "access request not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get access request", 500)
+	}
+	return &request, nil
+}
+
+func (r *accessRequestRepository) List(ctx context.Context, filter repositories.AccessRequestFilter, page *pagination.OffsetPagination) ([]*domain.AccessRequest, int64, error) {
+	var requests []*domain.AccessRequest
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.AccessRequest{})
+	if filter.RequesterID != nil {
+		query = query.Where("requester_id = ?", *filter.RequesterID)
+	}
+	if filter.ApproverID != nil {
+		query = query.Where("approver_id = ?", *filter.ApproverID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to count access requests", 500)
+	}
+
+	offset := (page.Page - 1) * page.Limit
+	if err := r.preload(query).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(page.Limit).
+		Find(&requests).Error; err != nil {
+		return nil, 0, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list access requests", 500)
+	}
+	return requests, total, nil
+}
+
+func (r *accessRequestRepository) FindPending(ctx context.Context, requesterID uuid.UUID, roleID, permissionID *uint) (*domain.AccessRequest, error) {
+	query := r.db.WithContext(ctx).Where("requester_id = ? AND status = ?", requesterID, domain.AccessRequestStatusPending)
+	if roleID != nil {
+		query = query.Where("role_id = ?", *roleID)
+	} else {
+		query = query.Where("role_id IS NULL")
+	}
+	if permissionID != nil {
+		query = query.Where("permission_id = ?", *permissionID)
+	} else {
+		query = query.Where("permission_id IS NULL")
+	}
+
+	var requests []domain.AccessRequest
+	if err := query.Limit(1).Find(&requests).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to find pending access request", 500)
+	}
+	if len(requests) == 0 {
+		return nil, nil
+	}
+	return &requests[0], nil
+}
+
+func (r *accessRequestRepository) Decide(ctx context.Context, request *domain.AccessRequest) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.AccessRequest{}).
+		Where("id = ? AND status = ?", request.ID, domain.AccessRequestStatusPending).
+		Updates(map[string]interface{}{
+			"status":           request.Status,
+			"decided_by":       request.DecidedBy,
+			"decided_at":       request.DecidedAt,
+			"decision_note":    request.DecisionNote,
+			"grant_expires_at": request.GrantExpiresAt,
+		})
+	if result.Error != nil {
+		logger.Error("Failed to record access request decision", zap.Uint("id", request.ID), zap.Error(result.Error))
+		return false, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to record access request decision", 500)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *accessRequestRepository) Reopen(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Model(&domain.AccessRequest{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":           domain.AccessRequestStatusPending,
+			"decided_by":       nil,
+			"decided_at":       nil,
+			"decision_note":    "",
+			"grant_expires_at": nil,
+		}).Error; err != nil {
+		logger.Error("Failed to reopen access request", zap.Uint("id", id), zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to reopen access request", 500)
+	}
+	return nil
+}
+
+func (r *accessRequestRepository) preload(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Requester").
+		Preload("Approver").
+		Preload("Role").
+		Preload("Permission")
+}
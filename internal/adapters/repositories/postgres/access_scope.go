@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"github.com/owner/go-cms/internal/core/domain"
+	"gorm.io/gorm"
+)
+
+// Users in the same department as the scope's user
+const teamMembersSQL = `SELECT id FROM users
+	WHERE department_id = (SELECT department_id FROM users WHERE id = ?)`
+
+// A department and every department below it, by materialized path
+const departmentSubtreeSQL = `SELECT d.id FROM departments d
+	INNER JOIN departments root ON root.path <> '' AND d.path LIKE root.path || '%'
+	WHERE root.id = ?`
+
+// Users in the scope's user's department or any department below it
+const departmentMembersSQL = `SELECT id FROM users WHERE department_id IN (
+	SELECT d.id FROM departments d
+	INNER JOIN departments own ON own.path <> '' AND d.path LIKE own.path || '%'
+	WHERE own.id = (SELECT department_id FROM users WHERE id = ?)
+)`
+
+// applyAccessScope limits a query to records owned by users within the scope.
+// ownerColumn is the SQL expression holding the owning user's UUID.
+func applyAccessScope(query *gorm.DB, scope *domain.AccessScope, ownerColumn string) *gorm.DB {
+	if scope.Unrestricted() {
+		return query
+	}
+
+	switch scope.Level {
+	case domain.ScopeLevelTeam:
+		return query.Where(ownerColumn+" = ? OR "+ownerColumn+" IN ("+teamMembersSQL+")", scope.UserID, scope.UserID)
+	case domain.ScopeLevelDepartment:
+		return query.Where(ownerColumn+" = ? OR "+ownerColumn+" IN ("+departmentMembersSQL+")", scope.UserID, scope.UserID)
+	default:
+		return query.Where(ownerColumn+" = ?", scope.UserID)
+	}
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// apiTokenRepository implements the APITokenRepository interface
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAPITokenRepository creates a new API token repository
+func NewAPITokenRepository(db *gorm.DB) repositories.APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+// Create stores a new token
+func (r *apiTokenRepository) Create(ctx context.Context, token *domain.APIToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		logger.Error("Failed to create API token", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create API token", 500)
+	}
+	return nil
+}
+
+// GetByID gets a token by ID
+func (r *apiTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.APIToken, error) {
+	var token domain.APIToken
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get API token", 500)
+	}
+	return &token, nil
+}
+
+// GetByHash gets a token by the hash of its value, along with its owner
+func (r *apiTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) {
+	var token domain.APIToken
+	if err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("token_hash = ?", tokenHash).
+		First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get API token", 500)
+	}
+	return &token, nil
+}
+
+// ListByUserID lists a user's tokens of one type, newest first
+func (r *apiTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID, tokenType domain.APITokenType) ([]*domain.APIToken, error) {
+	var tokens []*domain.APIToken
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND type = ?", userID, tokenType).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list API tokens", 500)
+	}
+	return tokens, nil
+}
+
+// Revoke revokes a token. Revoked tokens are kept so audit logs can refer to them.
+func (r *apiTokenRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&domain.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to revoke API token", 500)
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// RecordUse stores when and from where a token was last used
+func (r *apiTokenRepository) RecordUse(ctx context.Context, id uuid.UUID, ip string) error {
+	if err := r.db.WithContext(ctx).Model(&domain.APIToken{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": ip,
+		}).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to record API token use", 500)
+	}
+	return nil
+}
@@ -141,6 +141,13 @@ func (r *auditLogRepository) applyFilters(query *gorm.DB, filter repositories.Au
 		query = query.Where("user_id = ?", *filter.UserID)
 	}
 
+	if filter.APITokenID != nil {
+		query = query.Where("api_token_id = ?", *filter.APITokenID)
+	}
+	if filter.ImpersonatorID != nil {
+		query = query.Where("impersonator_id = ?", *filter.ImpersonatorID)
+	}
+
 	if filter.Action != "" {
 		query = query.Where("action = ?", filter.Action)
 	}
@@ -151,6 +151,41 @@ func (r *refreshTokenRepository) RevokeAllByUserID(ctx context.Context, userID u
 	return nil
 }
 
+// RevokeBySessionID revokes every refresh token of a session
+func (r *refreshTokenRepository) RevokeBySessionID(ctx context.Context, sessionID uuid.UUID) error {
+	updates := map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": gorm.Expr("NOW()"),
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("session_id = ? AND revoked = ?", sessionID, false).
+		Updates(updates).Error; err != nil {
+		logger.Error("Failed to revoke session refresh tokens", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to revoke session refresh tokens", 500)
+	}
+
+	return nil
+}
+
+// Rotate marks a refresh token as used. It returns false if the token was already
+// used or revoked, so only one of several concurrent refreshes can succeed.
+func (r *refreshTokenRepository) Rotate(ctx context.Context, token string) (bool, error) {
+	updates := map[string]interface{}{
+		"revoked":    true,
+		"revoked_at": gorm.Expr("NOW()"),
+	}
+
+	result := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("token = ? AND revoked = ?", token, false).
+		Updates(updates)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to rotate refresh token", 500)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
 // DeleteExpired deletes expired refresh tokens
 func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	if err := r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&domain.RefreshToken{}).Error; err != nil {
@@ -158,3 +193,113 @@ func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	}
 	return nil
 }
+
+// userSessionRepository implements the UserSessionRepository interface
+type userSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUserSessionRepository creates a new user session repository
+func NewUserSessionRepository(db *gorm.DB) repositories.UserSessionRepository {
+	return &userSessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *userSessionRepository) Create(ctx context.Context, session *domain.UserSession) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create session", 500)
+	}
+	return nil
+}
+
+// GetByID retrieves a session by ID
+func (r *userSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.UserSession, error) {
+	var session domain.UserSession
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get session", 500)
+	}
+	return &session, nil
+}
+
+// ListActiveByUserID lists the unrevoked, unexpired sessions of a user, most recently used first
+func (r *userSessionRepository) ListActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.UserSession, error) {
+	var sessions []*domain.UserSession
+
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list sessions", 500)
+	}
+
+	return sessions, nil
+}
+
+// Touch records activity on a session and extends it
+func (r *userSessionRepository) Touch(ctx context.Context, id uuid.UUID, ipAddress, userAgent string, expiresAt time.Time) error {
+	updates := map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"expires_at":   expiresAt,
+	}
+	if ipAddress != "" {
+		updates["ip_address"] = ipAddress
+	}
+	if userAgent != "" {
+		updates["user_agent"] = userAgent
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.UserSession{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update session", 500)
+	}
+	return nil
+}
+
+// Revoke revokes a session
+func (r *userSessionRepository) Revoke(ctx context.Context, id uuid.UUID, reason string) error {
+	updates := map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(updates).Error; err != nil {
+		logger.Error("Failed to revoke session", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to revoke session", 500)
+	}
+
+	return nil
+}
+
+// RevokeAllByUserID revokes every active session of a user and returns their IDs
+func (r *userSessionRepository) RevokeAllByUserID(ctx context.Context, userID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.UserSession{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		return tx.Model(&domain.UserSession{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"revoked_at":     time.Now(),
+				"revoked_reason": reason,
+			}).Error
+	})
+	if err != nil {
+		logger.Error("Failed to revoke all sessions", zap.Error(err))
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to revoke all sessions", 500)
+	}
+
+	return ids, nil
+}
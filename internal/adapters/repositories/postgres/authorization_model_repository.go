@@ -0,0 +1,555 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type authorizationModelRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthorizationModelRepository creates a new authorization model repository
+func NewAuthorizationModelRepository(db *gorm.DB) repositories.AuthorizationModelRepository {
+	return &authorizationModelRepository{db: db}
+}
+
+func (r *authorizationModelRepository) Export(ctx context.Context) (*domain.AuthorizationModel, error) {
+	db := r.db.WithContext(ctx)
+	model := &domain.AuthorizationModel{}
+
+	var modules []domain.Module
+	if err := db.Order(`"order" ASC, code ASC`).Find(&modules).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export modules", 500)
+	}
+	moduleCodes := make(map[uint]string, len(modules))
+	for _, module := range modules {
+		moduleCodes[module.ID] = module.Code
+		model.Modules = append(model.Modules, domain.ModuleSpec{
+			Code:        module.Code,
+			Name:        module.Name,
+			DisplayName: module.DisplayName,
+			Description: module.Description,
+			Icon:        module.Icon,
+			Color:       module.Color,
+			Order:       module.Order,
+			IsActive:    boolPtr(module.IsActive),
+			IsSystem:    module.IsSystem,
+		})
+	}
+
+	var departments []domain.Department
+	if err := db.Order("code ASC").Find(&departments).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export departments", 500)
+	}
+	departmentCodes := make(map[uint]string, len(departments))
+	for _, department := range departments {
+		departmentCodes[department.ID] = department.Code
+	}
+	for _, department := range departments {
+		spec := domain.DepartmentSpec{
+			Code:        department.Code,
+			Module:      moduleCodes[department.ModuleID],
+			Name:        department.Name,
+			DisplayName: department.DisplayName,
+			Description: department.Description,
+			IsActive:    boolPtr(department.IsActive),
+			IsSystem:    department.IsSystem,
+		}
+		if department.ParentID != nil {
+			spec.Parent = departmentCodes[*department.ParentID]
+		}
+		model.Departments = append(model.Departments, spec)
+	}
+
+	var services []domain.Service
+	if err := db.Order("code ASC").Find(&services).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export services", 500)
+	}
+	serviceCodes := make(map[uint]string, len(services))
+	for _, service := range services {
+		serviceCodes[service.ID] = service.Code
+		model.Services = append(model.Services, domain.ServiceSpec{
+			Code:        service.Code,
+			Department:  departmentCodes[service.DepartmentID],
+			Name:        service.Name,
+			DisplayName: service.DisplayName,
+			Description: service.Description,
+			Endpoint:    service.Endpoint,
+			IsActive:    boolPtr(service.IsActive),
+			IsSystem:    service.IsSystem,
+		})
+	}
+
+	var scopes []domain.Scope
+	if err := db.Order("priority DESC, code ASC").Find(&scopes).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export scopes", 500)
+	}
+	scopeCodes := make(map[uint]string, len(scopes))
+	for _, scope := range scopes {
+		scopeCodes[scope.ID] = scope.Code
+		model.Scopes = append(model.Scopes, domain.ScopeSpec{
+			Code:        scope.Code,
+			Name:        scope.Name,
+			DisplayName: scope.DisplayName,
+			Description: scope.Description,
+			Level:       scope.Level,
+			Priority:    scope.Priority,
+			IsSystem:    scope.IsSystem,
+		})
+	}
+
+	var permissions []domain.EnhancedPermission
+	if err := db.Order("code ASC").Find(&permissions).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export permissions", 500)
+	}
+	for _, permission := range permissions {
+		model.Permissions = append(model.Permissions, domain.PermissionSpec{
+			Module:      moduleCodes[permission.ModuleID],
+			Department:  departmentCodes[permission.DepartmentID],
+			Service:     serviceCodes[permission.ServiceID],
+			Scope:       scopeCodes[permission.ScopeID],
+			Resource:    permission.Resource,
+			Action:      permission.Action,
+			DisplayName: permission.DisplayName,
+			Description: permission.Description,
+			IsSystem:    permission.IsSystem,
+		})
+	}
+
+	var roles []domain.Role
+	if err := db.Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export roles", 500)
+	}
+	var grants []struct {
+		RoleID uint
+		Code   string
+	}
+	if err := db.Table("role_enhanced_permissions rep").
+		Select("rep.role_id, ep.code").
+		Joins("JOIN enhanced_permissions ep ON ep.id = rep.permission_id AND ep.deleted_at IS NULL").
+		Order("ep.code ASC").
+		Scan(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export role permissions", 500)
+	}
+	roleNames := make(map[uint]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.ID] = role.Name
+	}
+	rolePermissions := make(map[uint][]string, len(roles))
+	for _, grant := range grants {
+		rolePermissions[grant.RoleID] = append(rolePermissions[grant.RoleID], grant.Code)
+	}
+	for _, role := range roles {
+		spec := domain.RoleSpec{
+			Name:        role.Name,
+			DisplayName: role.DisplayName,
+			Description: role.Description,
+			Level:       role.Level,
+			IsSystem:    role.IsSystem,
+			Permissions: rolePermissions[role.ID],
+		}
+		if spec.Permissions == nil {
+			spec.Permissions = []string{}
+		}
+		if role.ParentID != nil {
+			spec.Parent = roleNames[*role.ParentID]
+		}
+		model.Roles = append(model.Roles, spec)
+	}
+
+	var policies []domain.Policy
+	if err := db.Order("name ASC").Find(&policies).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to export policies", 500)
+	}
+	for _, policy := range policies {
+		model.Policies = append(model.Policies, domain.PolicySpec{
+			Name:        policy.Name,
+			Description: policy.Description,
+			Permission:  policy.Permission,
+			Effect:      policy.Effect,
+			Condition:   policy.Condition,
+			IsActive:    boolPtr(policy.IsActive),
+		})
+	}
+
+	return model, nil
+}
+
+func (r *authorizationModelRepository) Apply(ctx context.Context, model *domain.AuthorizationModel) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		a := &modelApplier{tx: tx}
+		steps := []func(*domain.AuthorizationModel) error{
+			a.applyModules,
+			a.applyDepartments,
+			a.applyServices,
+			a.applyScopes,
+			a.applyPermissions,
+			a.applyRoles,
+			a.applyPolicies,
+			a.deleteMissing,
+		}
+		for _, step := range steps {
+			if err := step(model); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to apply authorization model", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to apply authorization model", 500)
+	}
+	return nil
+}
+
+// modelApplier applies a model within a transaction, tracking the ID of every entry
+// the model keeps
+type modelApplier struct {
+	tx          *gorm.DB
+	modules     map[string]uint
+	departments map[string]uint
+	services    map[string]uint
+	scopes      map[string]uint
+	permissions map[string]uint
+	roles       map[string]uint
+	policies    map[string]uint
+}
+
+func (a *modelApplier) applyModules(model *domain.AuthorizationModel) error {
+	a.modules = make(map[string]uint, len(model.Modules))
+	for _, spec := range model.Modules {
+		var module domain.Module
+		if err := a.tx.Unscoped().Where("code = ?", spec.Code).Limit(1).Find(&module).Error; err != nil {
+			return err
+		}
+		isNew := module.ID == 0
+
+		module.Code = spec.Code
+		module.Name = spec.Name
+		module.DisplayName = spec.DisplayName
+		module.Description = spec.Description
+		module.Icon = spec.Icon
+		module.Color = spec.Color
+		module.Order = spec.Order
+		module.IsActive = boolValue(spec.IsActive)
+		module.IsSystem = spec.IsSystem
+		module.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&module, isNew, map[string]interface{}{"is_active": module.IsActive}); err != nil {
+			return err
+		}
+		a.modules[spec.Code] = module.ID
+	}
+	return nil
+}
+
+func (a *modelApplier) applyDepartments(model *domain.AuthorizationModel) error {
+	a.departments = make(map[string]uint, len(model.Departments))
+	for _, spec := range model.Departments {
+		var department domain.Department
+		if err := a.tx.Unscoped().Where("code = ?", spec.Code).Limit(1).Find(&department).Error; err != nil {
+			return err
+		}
+		isNew := department.ID == 0
+
+		department.Code = spec.Code
+		department.ModuleID = a.modules[spec.Module]
+		department.Name = spec.Name
+		department.DisplayName = spec.DisplayName
+		department.Description = spec.Description
+		department.ParentID = nil // Set once every department exists
+		department.IsActive = boolValue(spec.IsActive)
+		department.IsSystem = spec.IsSystem
+		department.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&department, isNew, map[string]interface{}{"is_active": department.IsActive}); err != nil {
+			return err
+		}
+		a.departments[spec.Code] = department.ID
+	}
+
+	for _, spec := range model.Departments {
+		if spec.Parent == "" {
+			continue
+		}
+		if err := a.tx.Model(&domain.Department{}).
+			Where("id = ?", a.departments[spec.Code]).
+			UpdateColumn("parent_id", a.departments[spec.Parent]).Error; err != nil {
+			return err
+		}
+	}
+	return rebuildDepartmentPaths(a.tx)
+}
+
+func (a *modelApplier) applyServices(model *domain.AuthorizationModel) error {
+	a.services = make(map[string]uint, len(model.Services))
+	for _, spec := range model.Services {
+		var service domain.Service
+		if err := a.tx.Unscoped().Where("code = ?", spec.Code).Limit(1).Find(&service).Error; err != nil {
+			return err
+		}
+		isNew := service.ID == 0
+
+		service.Code = spec.Code
+		service.DepartmentID = a.departments[spec.Department]
+		service.Name = spec.Name
+		service.DisplayName = spec.DisplayName
+		service.Description = spec.Description
+		service.Endpoint = spec.Endpoint
+		service.IsActive = boolValue(spec.IsActive)
+		service.IsSystem = spec.IsSystem
+		service.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&service, isNew, map[string]interface{}{"is_active": service.IsActive}); err != nil {
+			return err
+		}
+		a.services[spec.Code] = service.ID
+	}
+	return nil
+}
+
+func (a *modelApplier) applyScopes(model *domain.AuthorizationModel) error {
+	a.scopes = make(map[string]uint, len(model.Scopes))
+	for _, spec := range model.Scopes {
+		var scope domain.Scope
+		if err := a.tx.Unscoped().Where("code = ?", spec.Code).Limit(1).Find(&scope).Error; err != nil {
+			return err
+		}
+		isNew := scope.ID == 0
+
+		scope.Code = spec.Code
+		scope.Name = spec.Name
+		scope.DisplayName = spec.DisplayName
+		scope.Description = spec.Description
+		scope.Level = spec.Level
+		scope.Priority = spec.Priority
+		scope.IsSystem = spec.IsSystem
+		scope.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&scope, isNew, nil); err != nil {
+			return err
+		}
+		a.scopes[spec.Code] = scope.ID
+	}
+	return nil
+}
+
+func (a *modelApplier) applyPermissions(model *domain.AuthorizationModel) error {
+	a.permissions = make(map[string]uint, len(model.Permissions))
+	for _, spec := range model.Permissions {
+		code := spec.Code()
+
+		var permission domain.EnhancedPermission
+		if err := a.tx.Unscoped().Where("code = ?", code).Limit(1).Find(&permission).Error; err != nil {
+			return err
+		}
+		isNew := permission.ID == 0
+
+		permission.Code = code
+		permission.ModuleID = a.modules[spec.Module]
+		permission.DepartmentID = a.departments[spec.Department]
+		permission.ServiceID = a.services[spec.Service]
+		permission.ScopeID = a.scopes[spec.Scope]
+		permission.Resource = spec.Resource
+		permission.Action = spec.Action
+		permission.DisplayName = spec.DisplayName
+		permission.Description = spec.Description
+		permission.IsSystem = spec.IsSystem
+		permission.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&permission, isNew, nil); err != nil {
+			return err
+		}
+		a.permissions[code] = permission.ID
+	}
+	return nil
+}
+
+func (a *modelApplier) applyRoles(model *domain.AuthorizationModel) error {
+	a.roles = make(map[string]uint, len(model.Roles))
+	for _, spec := range model.Roles {
+		var role domain.Role
+		if err := a.tx.Unscoped().Where("name = ?", spec.Name).Limit(1).Find(&role).Error; err != nil {
+			return err
+		}
+		isNew := role.ID == 0
+
+		role.Name = spec.Name
+		role.DisplayName = spec.DisplayName
+		role.Description = spec.Description
+		role.Level = spec.Level
+		role.ParentID = nil // Set once every role exists
+		role.IsSystem = spec.IsSystem
+		role.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&role, isNew, nil); err != nil {
+			return err
+		}
+		a.roles[spec.Name] = role.ID
+	}
+
+	for _, spec := range model.Roles {
+		roleID := a.roles[spec.Name]
+		if spec.Parent != "" {
+			if err := a.tx.Model(&domain.Role{}).
+				Where("id = ?", roleID).
+				UpdateColumn("parent_id", a.roles[spec.Parent]).Error; err != nil {
+				return err
+			}
+		}
+
+		// Replace the role's own grants with the listed permissions
+		permissionIDs := make([]uint, 0, len(spec.Permissions))
+		for _, code := range spec.Permissions {
+			permissionIDs = append(permissionIDs, a.permissions[code])
+		}
+
+		query := a.tx.Where("role_id = ?", roleID)
+		if len(permissionIDs) > 0 {
+			query = query.Where("permission_id NOT IN ?", permissionIDs)
+		}
+		if err := query.Delete(&domain.RoleEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+
+		if len(permissionIDs) > 0 {
+			grants := make([]domain.RoleEnhancedPermission, 0, len(permissionIDs))
+			for _, permissionID := range permissionIDs {
+				grants = append(grants, domain.RoleEnhancedPermission{RoleID: roleID, PermissionID: permissionID})
+			}
+			if err := a.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (a *modelApplier) applyPolicies(model *domain.AuthorizationModel) error {
+	a.policies = make(map[string]uint, len(model.Policies))
+	for _, spec := range model.Policies {
+		var policy domain.Policy
+		if err := a.tx.Unscoped().Where("name = ?", spec.Name).Limit(1).Find(&policy).Error; err != nil {
+			return err
+		}
+		isNew := policy.ID == 0
+
+		policy.Name = spec.Name
+		policy.Description = spec.Description
+		policy.Permission = spec.Permission
+		policy.Effect = spec.Effect
+		policy.Condition = spec.Condition
+		policy.IsActive = boolValue(spec.IsActive)
+		policy.DeletedAt = gorm.DeletedAt{}
+
+		if err := a.save(&policy, isNew, map[string]interface{}{"is_active": policy.IsActive}); err != nil {
+			return err
+		}
+		a.policies[spec.Name] = policy.ID
+	}
+	return nil
+}
+
+// deleteMissing deletes the entries the model no longer lists, dependents first. System
+// entries are never deleted.
+func (a *modelApplier) deleteMissing(*domain.AuthorizationModel) error {
+	policyIDs := keptIDs(a.policies)
+	if err := a.tx.Unscoped().Where("id NOT IN ?", policyIDs).Delete(&domain.Policy{}).Error; err != nil {
+		return err
+	}
+
+	// Deleted roles lose their grants and holders
+	var roleIDs []uint
+	if err := a.tx.Model(&domain.Role{}).
+		Where("id NOT IN ? AND is_system = ?", keptIDs(a.roles), false).
+		Pluck("id", &roleIDs).Error; err != nil {
+		return err
+	}
+	if len(roleIDs) > 0 {
+		if err := a.tx.Where("role_id IN ?", roleIDs).Delete(&domain.RoleEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+		if err := a.tx.Model(&domain.UserRole{}).
+			Where("role_id IN ? AND deleted_at IS NULL", roleIDs).
+			Update("deleted_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := a.tx.Where("id IN ?", roleIDs).Delete(&domain.Role{}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Deleted permissions are revoked from every role and user
+	var permissionIDs []uint
+	if err := a.tx.Model(&domain.EnhancedPermission{}).
+		Where("id NOT IN ? AND is_system = ?", keptIDs(a.permissions), false).
+		Pluck("id", &permissionIDs).Error; err != nil {
+		return err
+	}
+	if len(permissionIDs) > 0 {
+		if err := a.tx.Where("permission_id IN ?", permissionIDs).Delete(&domain.RoleEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+		if err := a.tx.Where("permission_id IN ?", permissionIDs).Delete(&domain.UserEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+		if err := a.tx.Where("id IN ?", permissionIDs).Delete(&domain.EnhancedPermission{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := a.tx.Where("id NOT IN ? AND is_system = ?", keptIDs(a.scopes), false).Delete(&domain.Scope{}).Error; err != nil {
+		return err
+	}
+	if err := a.tx.Where("id NOT IN ? AND is_system = ?", keptIDs(a.services), false).Delete(&domain.Service{}).Error; err != nil {
+		return err
+	}
+	if err := a.tx.Where("id NOT IN ? AND is_system = ?", keptIDs(a.departments), false).Delete(&domain.Department{}).Error; err != nil {
+		return err
+	}
+	return a.tx.Where("id NOT IN ? AND is_system = ?", keptIDs(a.modules), false).Delete(&domain.Module{}).Error
+}
+
+// save creates or updates a row, restoring it if it was soft-deleted. Columns with a
+// database default are written again after a create, so a false value is not replaced
+// by the default.
+func (a *modelApplier) save(row interface{}, isNew bool, defaulted map[string]interface{}) error {
+	if err := a.tx.Unscoped().Omit(clause.Associations).Save(row).Error; err != nil {
+		return err
+	}
+	if isNew && len(defaulted) > 0 {
+		return a.tx.Model(row).UpdateColumns(defaulted).Error
+	}
+	return nil
+}
+
+// keptIDs lists the IDs the model keeps. An empty list holds an ID no row has, so
+// NOT IN matches every row.
+func keptIDs(ids map[string]uint) []uint {
+	kept := make([]uint, 0, len(ids)+1)
+	for _, id := range ids {
+		kept = append(kept, id)
+	}
+	if len(kept) == 0 {
+		kept = append(kept, 0)
+	}
+	return kept
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+// boolValue reads an optional flag that defaults to true
+func boolValue(value *bool) bool {
+	return value == nil || *value
+}
@@ -112,6 +112,7 @@ func (r *customerRepository) List(ctx context.Context, filter repositories.Custo
 	if len(filter.IDs) > 0 {
 		query = query.Where("id IN ?", filter.IDs)
 	}
+	query = applyAccessScope(query, filter.Scope, "assigned_to")
 
 	// Count total
 	if err := query.Count(&total).Error; err != nil {
@@ -162,6 +163,7 @@ func (r *customerRepository) ListWithCursor(ctx context.Context, filter reposito
 		query = query.Where("first_name ILIKE ? OR last_name ILIKE ? OR email ILIKE ? OR company ILIKE ?",
 			searchPattern, searchPattern, searchPattern, searchPattern)
 	}
+	query = applyAccessScope(query, filter.Scope, "assigned_to")
 
 	// Apply cursor
 	if cursor != nil && cursor.After != "" {
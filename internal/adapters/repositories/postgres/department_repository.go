@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/pkg/errors"
@@ -22,7 +23,16 @@ func NewDepartmentRepository(db *gorm.DB) repositories.DepartmentRepository {
 }
 
 func (r *departmentRepository) Create(ctx context.Context, department *domain.Department) error {
-	if err := r.db.WithContext(ctx).Create(department).Error; err != nil {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(department).Error; err != nil {
+			return err
+		}
+		if err := rebuildDepartmentPaths(tx); err != nil {
+			return err
+		}
+		return tx.Model(department).Select("path").Where("id = ?", department.ID).Scan(&department.Path).Error
+	})
+	if err != nil {
 		logger.Error("Failed to create department", zap.Error(err))
 		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create department", 500)
 	}
@@ -33,6 +43,7 @@ func (r *departmentRepository) GetByID(ctx context.Context, id uint) (*domain.De
 	var department domain.Department
 	if err := r.db.WithContext(ctx).
 		Preload("Module").
+		Preload("Manager").
 		Preload("Parent").
 		Preload("Children").
 		Preload("Services").
@@ -137,3 +148,119 @@ func (r *departmentRepository) ListActive(ctx context.Context) ([]domain.Departm
 	}
 	return departments, nil
 }
+
+// Recomputes materialized paths from the roots down, writing only those that changed.
+// Departments whose parent no longer exists count as roots; departments on a ParentID
+// cycle are never reached and keep their old path.
+const departmentPathsSQL = `WITH RECURSIVE tree AS (
+	SELECT id, '/' || id || '/' AS path FROM departments
+	WHERE parent_id IS NULL OR NOT EXISTS (SELECT 1 FROM departments parent WHERE parent.id = departments.parent_id)
+	UNION ALL
+	SELECT d.id, tree.path || d.id || '/' FROM departments d INNER JOIN tree ON d.parent_id = tree.id
+)
+UPDATE departments SET path = tree.path FROM tree
+WHERE departments.id = tree.id AND departments.path IS DISTINCT FROM tree.path`
+
+// rebuildDepartmentPaths recomputes materialized paths after departments are created or moved
+func rebuildDepartmentPaths(tx *gorm.DB) error {
+	return tx.Exec(departmentPathsSQL).Error
+}
+
+func (r *departmentRepository) Move(ctx context.Context, id uint, parentID *uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.Department{}).Where("id = ?", id).UpdateColumn("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		return rebuildDepartmentPaths(tx)
+	})
+	if err != nil {
+		logger.Error("Failed to move department", zap.Uint("id", id), zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to move department", 500)
+	}
+	return nil
+}
+
+func (r *departmentRepository) ListSubtree(ctx context.Context, id uint) ([]domain.Department, error) {
+	var departments []domain.Department
+	if err := r.db.WithContext(ctx).
+		Where("id IN ("+departmentSubtreeSQL+")", id).
+		Preload("Manager").
+		Order("path ASC").
+		Find(&departments).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list department subtree", 500)
+	}
+	return departments, nil
+}
+
+func (r *departmentRepository) RebuildPaths(ctx context.Context) error {
+	if err := rebuildDepartmentPaths(r.db.WithContext(ctx)); err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to rebuild department paths", 500)
+	}
+	return nil
+}
+
+func (r *departmentRepository) ListManagedBy(ctx context.Context, managerID uuid.UUID) ([]domain.Department, error) {
+	var departments []domain.Department
+	if err := r.db.WithContext(ctx).
+		Where("manager_id = ?", managerID).
+		Order("path ASC").
+		Find(&departments).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list managed departments", 500)
+	}
+	return departments, nil
+}
+
+func (r *departmentRepository) IsManagedBy(ctx context.Context, departmentID uint, managerID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Table("departments d").
+		Joins("INNER JOIN departments m ON m.path <> '' AND d.path LIKE m.path || '%'").
+		Where("d.id = ? AND m.manager_id = ? AND m.deleted_at IS NULL", departmentID, managerID).
+		Count(&count).Error; err != nil {
+		return false, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to check department manager", 500)
+	}
+	return count > 0, nil
+}
+
+func (r *departmentRepository) ManagesUser(ctx context.Context, managerID, userID uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Table("users u").
+		Joins("INNER JOIN departments d ON d.id = u.department_id").
+		Joins("INNER JOIN departments m ON m.path <> '' AND d.path LIKE m.path || '%'").
+		Where("u.id = ? AND m.manager_id = ? AND m.deleted_at IS NULL", userID, managerID).
+		Count(&count).Error; err != nil {
+		return false, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to check department manager", 500)
+	}
+	return count > 0, nil
+}
+
+func (r *departmentRepository) NearestManager(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	var managerIDs []uuid.UUID
+	if err := r.db.WithContext(ctx).
+		Table("users u").
+		Joins("INNER JOIN departments d ON d.id = u.department_id").
+		Joins("INNER JOIN departments m ON m.path <> '' AND d.path LIKE m.path || '%'").
+		Where("u.id = ? AND m.manager_id IS NOT NULL AND m.manager_id <> u.id AND m.deleted_at IS NULL", userID).
+		Order("LENGTH(m.path) DESC").
+		Limit(1).
+		Pluck("m.manager_id", &managerIDs).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to find department manager", 500)
+	}
+	if len(managerIDs) == 0 {
+		return nil, nil
+	}
+	return &managerIDs[0], nil
+}
+
+func (r *departmentRepository) SetUserDepartment(ctx context.Context, userID uuid.UUID, departmentID *uint) error {
+	result := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).UpdateColumn("department_id", departmentID)
+	if result.Error != nil {
+		logger.Error("Failed to set user department", zap.String("user_id", userID.String()), zap.Error(result.Error))
+		return errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to set user department", 500)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrCodeNotFound, "user not found", 404)
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
@@ -14,6 +16,25 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// permissionLevelOrder sorts document permissions from the highest level to the lowest
+const permissionLevelOrder = "CASE permission_level " +
+	"WHEN 'owner' THEN 1 " +
+	"WHEN 'edit' THEN 2 " +
+	"WHEN 'comment' THEN 3 " +
+	"WHEN 'view' THEN 4 END"
+
+// grantCoversDocumentSQL matches grants made on the document itself or on any folder above it,
+// using the folder's materialized path
+const grantCoversDocumentSQL = `(document_permissions.document_id = documents.id OR EXISTS (
+	SELECT 1 FROM document_folders folder
+	WHERE folder.id = documents.folder_id AND folder.path LIKE '%/' || document_permissions.folder_id || '/%'
+))`
+
+// previewSummary loads a document's preview without its extracted text
+func previewSummary(db *gorm.DB) *gorm.DB {
+	return db.Select("document_id", "status", "thumbnail_path", "processed_at")
+}
+
 type documentRepository struct {
 	db *gorm.DB
 }
@@ -36,7 +57,7 @@ func (r *documentRepository) UpdateDocument(ctx context.Context, document *domai
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
-	return r.db.WithContext(ctx).Save(document).Error
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(document).Error
 }
 
 func (r *documentRepository) DeleteDocumentByID(ctx context.Context, id uint) error {
@@ -55,6 +76,9 @@ func (r *documentRepository) GetDocumentByID(ctx context.Context, id uint) (*dom
 	var document domain.Document
 	err := r.db.WithContext(ctx).
 		Preload("Uploader").
+		Preload("Folder").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
 		Preload("DocumentPermissions").
 		Preload("DocumentPermissions.User").
 		First(&document, id).Error
@@ -74,6 +98,9 @@ func (r *documentRepository) GetDocumentByCode(ctx context.Context, code string)
 	var document domain.Document
 	err := r.db.WithContext(ctx).
 		Preload("Uploader").
+		Preload("Folder").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
 		Preload("DocumentPermissions").
 		Preload("DocumentPermissions.User").
 		Where("document_code = ?", code).
@@ -104,7 +131,7 @@ func (r *documentRepository) GetDocumentByPath(ctx context.Context, path string)
 	return &document, nil
 }
 
-func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.DocumentFilter) ([]domain.Document, int, int, error) {
+func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.DocumentFilter, viewer *domain.AccessScope) ([]domain.Document, int, int, error) {
 	if r.db == nil {
 		return nil, 0, 0, errors.New("database connection is nil")
 	}
@@ -112,8 +139,11 @@ func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.Docume
 	var documents []domain.Document
 	var totalCount int64
 
-	// Build query with filters
-	query := r.db.WithContext(ctx).Model(&domain.Document{})
+	// Build query with filters, limited to what the viewer can see so totals match the results
+	query, err := r.readableBy(ctx, r.db.WithContext(ctx).Model(&domain.Document{}), viewer)
+	if err != nil {
+		return nil, 0, 0, err
+	}
 
 	// Apply filters
 	if filter.SearchTerm != "" {
@@ -140,6 +170,17 @@ func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.Docume
 		query = query.Where("uploaded_by = ?", *filter.UploadedBy)
 	}
 
+	if filter.FolderID != nil {
+		query = query.Where("folder_id = ?", *filter.FolderID)
+	}
+
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", r.db.Table("document_tags").
+			Select("document_tags.document_id").
+			Joins("JOIN tags ON tags.id = document_tags.tag_id").
+			Where("tags.slug = ?", filter.Tag))
+	}
+
 	// Count total before pagination
 	if err := query.Count(&totalCount).Error; err != nil {
 		return nil, 0, 0, err
@@ -173,8 +214,10 @@ func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.Docume
 	query = query.Offset(offset).Limit(filter.PageSize)
 
 	// Load documents with related data
-	err := query.
+	err = query.
 		Preload("Uploader").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
 		Preload("DocumentPermissions", func(db *gorm.DB) *gorm.DB {
 			return db.Preload("User")
 		}).
@@ -190,14 +233,21 @@ func (r *documentRepository) GetDocuments(ctx context.Context, filter dto.Docume
 	return documents, int(totalCount), totalPages, nil
 }
 
-func (r *documentRepository) GetDocumentsByEntityID(ctx context.Context, entityType string, entityID uint) ([]domain.Document, error) {
+func (r *documentRepository) GetDocumentsByEntityID(ctx context.Context, entityType string, entityID uint, viewer *domain.AccessScope) ([]domain.Document, error) {
 	if r.db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 
+	query, err := r.readableBy(ctx, r.db.WithContext(ctx), viewer)
+	if err != nil {
+		return nil, err
+	}
+
 	var documents []domain.Document
-	err := r.db.WithContext(ctx).
+	err = query.
 		Preload("Uploader").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
 		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
 		Order("created_at DESC").
 		Find(&documents).Error
@@ -209,219 +259,1343 @@ func (r *documentRepository) GetDocumentsByEntityID(ctx context.Context, entityT
 	return documents, nil
 }
 
-// Permission related methods
-func (r *documentRepository) CreateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error {
+func (r *documentRepository) GetDocumentsByFolderID(ctx context.Context, folderID uint) ([]domain.Document, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var documents []domain.Document
+	err := r.db.WithContext(ctx).
+		Preload("Uploader").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
+		Where("folder_id = ?", folderID).
+		Order("document_name ASC").
+		Find(&documents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (r *documentRepository) MoveDocument(ctx context.Context, documentID uint, folderID *uint) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	return r.db.WithContext(ctx).Create(permission).Error
+	return r.db.WithContext(ctx).
+		Model(&domain.Document{}).
+		Where("id = ?", documentID).
+		Update("folder_id", folderID).Error
 }
 
-func (r *documentRepository) UpdateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error {
+// Folder related methods
+func (r *documentRepository) CreateFolder(ctx context.Context, folder *domain.DocumentFolder) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	return r.db.WithContext(ctx).Save(permission).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The path contains the folder's own ID, so it can only be built after insert
+		parentPath := "/"
+		if folder.ParentID != nil {
+			var parent domain.DocumentFolder
+			if err := tx.Select("path").First(&parent, *folder.ParentID).Error; err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+
+		folder.Path = parentPath
+		if err := tx.Create(folder).Error; err != nil {
+			return err
+		}
+
+		folder.Path = fmt.Sprintf("%s%d/", parentPath, folder.ID)
+		return tx.Model(folder).Update("path", folder.Path).Error
+	})
 }
 
-func (r *documentRepository) DeleteDocumentPermission(ctx context.Context, id uint) error {
+func (r *documentRepository) UpdateFolder(ctx context.Context, folder *domain.DocumentFolder) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	return r.db.WithContext(ctx).Delete(&domain.DocumentPermission{}, id).Error
+	return r.db.WithContext(ctx).Omit("Parent", "Children").Save(folder).Error
 }
 
-func (r *documentRepository) GetDocumentPermissions(ctx context.Context, documentID uint) ([]domain.DocumentPermission, error) {
+func (r *documentRepository) DeleteFolder(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var folder domain.DocumentFolder
+		if err := tx.First(&folder, id).Error; err != nil {
+			return err
+		}
+
+		// Deleted documents awaiting purge move up so ancestor retention still applies
+		if err := tx.Unscoped().Model(&domain.Document{}).
+			Where("folder_id = ? AND deleted_at IS NOT NULL", id).
+			Update("folder_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("folder_id = ?", id).Delete(&domain.DocumentPermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.DocumentFolder{}, id).Error
+	})
+}
+
+func (r *documentRepository) GetFolderByID(ctx context.Context, id uint) (*domain.DocumentFolder, error) {
 	if r.db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 
-	var permissions []domain.DocumentPermission
+	var folder domain.DocumentFolder
+	if err := r.db.WithContext(ctx).First(&folder, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+func (r *documentRepository) GetFolderChildren(ctx context.Context, parentID *uint) ([]domain.DocumentFolder, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var folders []domain.DocumentFolder
+	if err := query.Order("name ASC").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (r *documentRepository) GetFolderSubtree(ctx context.Context, folder *domain.DocumentFolder) ([]domain.DocumentFolder, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var folders []domain.DocumentFolder
 	err := r.db.WithContext(ctx).
-		Preload("User").
-		Preload("Creator").
-		Where("document_id = ?", documentID).
-		Find(&permissions).Error
+		Where("path LIKE ? AND id <> ?", folder.Path+"%", folder.ID).
+		Order("path ASC, name ASC").
+		Find(&folders).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	return permissions, nil
+	return folders, nil
 }
 
-func (r *documentRepository) GetUserDocumentPermission(ctx context.Context, documentID uint, userID uuid.UUID) (*domain.DocumentPermission, error) {
+func (r *documentRepository) MoveFolder(ctx context.Context, folder *domain.DocumentFolder, parentID *uint, newPath string) error {
 	if r.db == nil {
-		return nil, errors.New("database connection is nil")
+		return errors.New("database connection is nil")
 	}
 
-	var permission domain.DocumentPermission
+	oldPath := folder.Path
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.DocumentFolder{}).
+			Where("id = ?", folder.ID).
+			Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
 
-	// First check for direct user permission
-	err := r.db.WithContext(ctx).
-		Where("document_id = ? AND user_id = ?", documentID, userID).
-		First(&permission).Error
+		// Rewrite the path prefix of the folder and all of its descendants
+		return tx.Model(&domain.DocumentFolder{}).
+			Where("path LIKE ?", oldPath+"%").
+			Update("path", gorm.Expr("CAST(? AS TEXT) || SUBSTRING(path FROM CAST(? AS INTEGER))", newPath, len(oldPath)+1)).Error
+	})
+}
 
-	if err == nil {
-		return &permission, nil
+func (r *documentRepository) CountFolderContents(ctx context.Context, folderID uint) (int64, error) {
+	if r.db == nil {
+		return 0, errors.New("database connection is nil")
 	}
 
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, err
+	var folders, documents int64
+	if err := r.db.WithContext(ctx).Model(&domain.DocumentFolder{}).Where("parent_id = ?", folderID).Count(&folders).Error; err != nil {
+		return 0, err
+	}
+	if err := r.db.WithContext(ctx).Model(&domain.Document{}).Where("folder_id = ?", folderID).Count(&documents).Error; err != nil {
+		return 0, err
 	}
 
-	// If no direct permission, check for role-based permissions
-	// Get user roles first
-	var userRoleIDs []uint
-	if err := r.db.WithContext(ctx).
-		Table("user_roles").
-		Select("role_id").
-		Where("user_id = ?", userID).
-		Pluck("role_id", &userRoleIDs).Error; err != nil {
+	return folders + documents, nil
+}
+
+func (r *documentRepository) GetEntityRootFolder(ctx context.Context, entityType string, entityID uint) (*domain.DocumentFolder, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var folder domain.DocumentFolder
+	err := r.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ? AND parent_id IS NULL", entityType, entityID).
+		First(&folder).Error
+
+	if err != nil {
 		return nil, err
 	}
 
-	if len(userRoleIDs) > 0 {
-		err = r.db.WithContext(ctx).
-			Where("document_id = ? AND role_id IN ?", documentID, userRoleIDs).
-			Order("CASE permission_level " +
-				"WHEN 'owner' THEN 1 " +
-				"WHEN 'edit' THEN 2 " +
-				"WHEN 'comment' THEN 3 " +
-				"WHEN 'view' THEN 4 END").
-			First(&permission).Error
+	return &folder, nil
+}
 
-		if err == nil {
-			return &permission, nil
-		}
+// Folder template related methods
+func (r *documentRepository) CreateFolderTemplate(ctx context.Context, template *domain.DocumentFolderTemplate) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
 
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, err
-		}
+	return r.db.WithContext(ctx).Create(template).Error
+}
+
+func (r *documentRepository) DeleteFolderTemplate(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
 	}
 
-	// No permission found
-	return nil, gorm.ErrRecordNotFound
+	return r.db.WithContext(ctx).Delete(&domain.DocumentFolderTemplate{}, id).Error
 }
 
-func (r *documentRepository) CheckUserPermission(ctx context.Context, documentID uint, userID uuid.UUID, requiredLevel string) (bool, error) {
+func (r *documentRepository) GetFolderTemplates(ctx context.Context, entityType string) ([]domain.DocumentFolderTemplate, error) {
 	if r.db == nil {
-		return false, errors.New("database connection is nil")
+		return nil, errors.New("database connection is nil")
 	}
 
-	// First check if user is the document owner/uploader
-	var document domain.Document
-	if err := r.db.WithContext(ctx).
-		Select("uploaded_by").
-		First(&document, documentID).Error; err != nil {
-		return false, err
+	query := r.db.WithContext(ctx)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
 	}
 
-	// Document uploader always has owner permission
-	if document.UploadedBy == userID {
-		return true, nil
+	var templates []domain.DocumentFolderTemplate
+	if err := query.Order("entity_type ASC, sort_order ASC, folder_path ASC").Find(&templates).Error; err != nil {
+		return nil, err
 	}
 
-	// Get the user's highest permission level for this document
-	permission, err := r.GetUserDocumentPermission(ctx, documentID, userID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
-		}
-		return false, err
+	return templates, nil
+}
+
+// Tag related methods
+func (r *documentRepository) FindOrCreateTag(ctx context.Context, name string, slug string) (*domain.Tag, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
 	}
 
-	// Check if permission level is sufficient
-	switch requiredLevel {
-	case domain.PermissionView:
-		// Any permission level allows viewing
-		return true, nil
-	case domain.PermissionComment:
-		return permission.PermissionLevel == domain.PermissionComment ||
-			permission.PermissionLevel == domain.PermissionEdit ||
-			permission.PermissionLevel == domain.PermissionOwner, nil
-	case domain.PermissionEdit:
-		return permission.PermissionLevel == domain.PermissionEdit ||
-			permission.PermissionLevel == domain.PermissionOwner, nil
-	case domain.PermissionOwner:
-		return permission.PermissionLevel == domain.PermissionOwner, nil
-	default:
-		return false, errors.New("invalid permission level")
+	tag := domain.Tag{Name: name, Slug: slug}
+	if err := r.db.WithContext(ctx).Where("slug = ?", slug).FirstOrCreate(&tag).Error; err != nil {
+		return nil, err
 	}
+
+	return &tag, nil
 }
 
-// Comment related methods
-func (r *documentRepository) CreateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error {
+func (r *documentRepository) AddDocumentTags(ctx context.Context, documentID uint, tags []domain.Tag) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	return r.db.WithContext(ctx).Create(comment).Error
+	document := domain.Document{ID: documentID}
+	return r.db.WithContext(ctx).Model(&document).Association("Tags").Append(tags)
 }
 
-func (r *documentRepository) UpdateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error {
+func (r *documentRepository) RemoveDocumentTag(ctx context.Context, documentID uint, tagID uint) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	return r.db.WithContext(ctx).Save(comment).Error
+	return r.db.WithContext(ctx).
+		Exec("DELETE FROM document_tags WHERE document_id = ? AND tag_id = ?", documentID, tagID).Error
 }
 
-func (r *documentRepository) DeleteDocumentComment(ctx context.Context, id uint) error {
+// Permission related methods
+func (r *documentRepository) CreateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Create(permission).Error
+}
+
+func (r *documentRepository) UpdateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Save(permission).Error
+}
+
+func (r *documentRepository) DeleteDocumentPermission(ctx context.Context, id uint) error {
 	if r.db == nil {
 		return errors.New("database connection is nil")
 	}
 
-	// Soft delete for comments
-	return r.db.WithContext(ctx).Delete(&domain.DocumentComment{}, id).Error
+	return r.db.WithContext(ctx).Delete(&domain.DocumentPermission{}, id).Error
 }
 
-func (r *documentRepository) GetDocumentComments(ctx context.Context, documentID uint) ([]domain.DocumentComment, error) {
+func (r *documentRepository) GetDocumentPermissions(ctx context.Context, documentID uint) ([]domain.DocumentPermission, error) {
 	if r.db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 
-	var comments []domain.DocumentComment
+	var permissions []domain.DocumentPermission
 	err := r.db.WithContext(ctx).
 		Preload("User").
+		Preload("Creator").
 		Where("document_id = ?", documentID).
-		Order("created_at ASC").
-		Find(&comments).Error
+		Find(&permissions).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	return comments, nil
+	return permissions, nil
 }
 
-// Version related methods
-func (r *documentRepository) CreateDocumentVersion(ctx context.Context, version *domain.DocumentVersion) error {
+func (r *documentRepository) GetDocumentPermissionByID(ctx context.Context, id uint) (*domain.DocumentPermission, error) {
 	if r.db == nil {
-		return errors.New("database connection is nil")
+		return nil, errors.New("database connection is nil")
+	}
+
+	var permission domain.DocumentPermission
+	if err := r.db.WithContext(ctx).First(&permission, id).Error; err != nil {
+		return nil, err
 	}
 
-	return r.db.WithContext(ctx).Create(version).Error
+	return &permission, nil
 }
 
-func (r *documentRepository) GetDocumentVersions(ctx context.Context, documentID uint) ([]domain.DocumentVersion, error) {
+func (r *documentRepository) GetFolderPermissions(ctx context.Context, folderID uint) ([]domain.DocumentPermission, error) {
 	if r.db == nil {
 		return nil, errors.New("database connection is nil")
 	}
 
-	var versions []domain.DocumentVersion
+	var permissions []domain.DocumentPermission
 	err := r.db.WithContext(ctx).
 		Preload("User").
-		Where("document_id = ?", documentID).
-		Order("version_number DESC").
-		Find(&versions).Error
+		Preload("Creator").
+		Where("folder_id = ?", folderID).
+		Find(&permissions).Error
 
 	if err != nil {
 		return nil, err
 	}
 
-	return versions, nil
+	return permissions, nil
+}
+
+// GetUserFolderPermission returns the highest grant the user holds on the folder or any of its ancestors
+func (r *documentRepository) GetUserFolderPermission(ctx context.Context, folder *domain.DocumentFolder, userID uuid.UUID) (*domain.DocumentPermission, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	permissions, err := r.findApplicablePermissions(ctx, r.db.Where("folder_id IN ?", folder.AncestorIDs()), userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(permissions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &permissions[0], nil
+}
+
+func (r *documentRepository) GetUserDocumentPermission(ctx context.Context, documentID uint, userID uuid.UUID) (*domain.DocumentPermission, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	scope, err := r.documentScope(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := r.findApplicablePermissions(ctx, scope, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// No permission found
+	if len(permissions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &permissions[0], nil
+}
+
+// GetApplicableDocumentPermissions returns every unexpired grant that gives the user access to a document,
+// highest level first, with the role, department and folder they come from
+func (r *documentRepository) GetApplicableDocumentPermissions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentPermission, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	scope, err := r.documentScope(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.findApplicablePermissions(ctx, scope, userID, true)
+}
+
+// documentScope matches grants on the document itself and on every folder above it
+func (r *documentRepository) documentScope(ctx context.Context, documentID uint) (*gorm.DB, error) {
+	var document domain.Document
+	if err := r.db.WithContext(ctx).Select("id", "folder_id").First(&document, documentID).Error; err != nil {
+		return nil, err
+	}
+
+	scope := r.db.Where("document_id = ?", documentID)
+	if document.FolderID != nil {
+		var folder domain.DocumentFolder
+		if err := r.db.WithContext(ctx).Select("id", "path").First(&folder, *document.FolderID).Error; err == nil {
+			scope = scope.Or("folder_id IN ?", folder.AncestorIDs())
+		}
+	}
+
+	return scope, nil
+}
+
+// subjectScope matches grants made to the user directly or through their roles, department or job title
+func (r *documentRepository) subjectScope(ctx context.Context, userID uuid.UUID) (*gorm.DB, error) {
+	var user domain.User
+	if err := r.db.WithContext(ctx).Select("id", "department_id", "position").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	var roleIDs []uint
+	if err := r.db.WithContext(ctx).
+		Table("user_roles").
+		Where("user_id = ? AND deleted_at IS NULL", userID).
+		Pluck("role_id", &roleIDs).Error; err != nil {
+		return nil, err
+	}
+
+	// The user's department followed by its ancestors, guarding against cycles in ParentID
+	var departmentIDs []uint
+	seen := make(map[uint]bool)
+	for id := user.DepartmentID; id != nil && !seen[*id]; {
+		seen[*id] = true
+		departmentIDs = append(departmentIDs, *id)
+
+		var department domain.Department
+		if err := r.db.WithContext(ctx).Select("id", "parent_id").First(&department, *id).Error; err != nil {
+			break
+		}
+		id = department.ParentID
+	}
+
+	scope := r.db.Where("user_id = ?", userID)
+	if len(roleIDs) > 0 {
+		scope = scope.Or("role_id IN ?", roleIDs)
+	}
+	if len(departmentIDs) > 0 {
+		scope = scope.Or("department_id = ?", departmentIDs[0])
+	}
+	if len(departmentIDs) > 1 {
+		scope = scope.Or("department_id IN ? AND include_sub_departments = ?", departmentIDs[1:], true)
+	}
+	if user.Position != "" {
+		scope = scope.Or("job_title = ?", user.Position)
+	}
+
+	return scope, nil
+}
+
+// readableBy limits a document query to what the viewer can see: their own uploads, documents granted to them
+// directly or through a folder above, and uploads by users within the viewer's scope. A nil or
+// organization-wide viewer is not limited.
+func (r *documentRepository) readableBy(ctx context.Context, query *gorm.DB, viewer *domain.AccessScope) (*gorm.DB, error) {
+	if viewer.Unrestricted() {
+		return query, nil
+	}
+
+	readable := applyAccessScope(r.db, viewer, "documents.uploaded_by")
+
+	subject, err := r.subjectScope(ctx, viewer.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		grants := r.db.Model(&domain.DocumentPermission{}).
+			Select("1").
+			Where(grantCoversDocumentSQL).
+			Where(subject).
+			Where("document_permissions.expires_at IS NULL OR document_permissions.expires_at > ?", time.Now())
+		readable = readable.Or("EXISTS (?)", grants)
+	}
+
+	return query.Where(readable), nil
+}
+
+// GetUserDocumentPermissionLevels returns the user's highest granted level on each of the documents in one query.
+// Documents the user holds no grant on are left out; uploaders are not treated as owners here.
+func (r *documentRepository) GetUserDocumentPermissionLevels(ctx context.Context, documentIDs []uint, userID uuid.UUID) (map[uint]string, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	levels := make(map[uint]string)
+	if len(documentIDs) == 0 {
+		return levels, nil
+	}
+
+	subject, err := r.subjectScope(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return levels, nil
+		}
+		return nil, err
+	}
+
+	var rows []struct {
+		DocumentID      uint
+		PermissionLevel string
+	}
+	err = r.db.WithContext(ctx).
+		Table("documents").
+		Select("documents.id AS document_id, document_permissions.permission_level").
+		Joins("JOIN document_permissions ON "+grantCoversDocumentSQL).
+		Where("documents.id IN ?", documentIDs).
+		Where(subject).
+		Where("document_permissions.expires_at IS NULL OR document_permissions.expires_at > ?", time.Now()).
+		Order(permissionLevelOrder).
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows come highest level first, so the first one seen per document wins
+	for _, row := range rows {
+		if _, ok := levels[row.DocumentID]; !ok {
+			levels[row.DocumentID] = row.PermissionLevel
+		}
+	}
+
+	return levels, nil
+}
+
+// findApplicablePermissions returns the unexpired grants within scope that apply to the user, highest level first
+func (r *documentRepository) findApplicablePermissions(ctx context.Context, scope *gorm.DB, userID uuid.UUID, withSources bool) ([]domain.DocumentPermission, error) {
+	subject, err := r.subjectScope(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query := r.db.WithContext(ctx)
+	if withSources {
+		query = query.Preload("User").Preload("Role").Preload("Department").Preload("Folder")
+	}
+
+	var permissions []domain.DocumentPermission
+	err = query.
+		Where(scope).
+		Where(subject).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order(permissionLevelOrder).
+		Find(&permissions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (r *documentRepository) CheckUserPermission(ctx context.Context, documentID uint, userID uuid.UUID, requiredLevel string) (bool, error) {
+	if r.db == nil {
+		return false, errors.New("database connection is nil")
+	}
+
+	// First check if user is the document owner/uploader
+	var document domain.Document
+	if err := r.db.WithContext(ctx).
+		Select("uploaded_by").
+		First(&document, documentID).Error; err != nil {
+		return false, err
+	}
+
+	// Document uploader always has owner permission
+	if document.UploadedBy == userID {
+		return true, nil
+	}
+
+	// Get the user's highest permission level for this document
+	permission, err := r.GetUserDocumentPermission(ctx, documentID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// Check if permission level is sufficient
+	switch requiredLevel {
+	case domain.PermissionView:
+		// Any permission level allows viewing
+		return true, nil
+	case domain.PermissionComment:
+		return permission.PermissionLevel == domain.PermissionComment ||
+			permission.PermissionLevel == domain.PermissionEdit ||
+			permission.PermissionLevel == domain.PermissionOwner, nil
+	case domain.PermissionEdit:
+		return permission.PermissionLevel == domain.PermissionEdit ||
+			permission.PermissionLevel == domain.PermissionOwner, nil
+	case domain.PermissionOwner:
+		return permission.PermissionLevel == domain.PermissionOwner, nil
+	default:
+		return false, errors.New("invalid permission level")
+	}
+}
+
+// Comment related methods
+func (r *documentRepository) CreateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+// UpdateDocumentComment saves the comment and replaces its mentions with comment.Mentions
+func (r *documentRepository) UpdateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(comment).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&domain.DocumentCommentMention{}).Error; err != nil {
+			return err
+		}
+
+		for i := range comment.Mentions {
+			comment.Mentions[i].ID = 0
+			comment.Mentions[i].CommentID = comment.ID
+		}
+
+		if len(comment.Mentions) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&comment.Mentions).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteDocumentComment deletes a comment together with its replies and mentions
+func (r *documentRepository) DeleteDocumentComment(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		threadIDs := tx.Model(&domain.DocumentComment{}).Select("id").Where("id = ? OR parent_id = ?", id, id)
+
+		if err := tx.Where("comment_id IN (?)", threadIDs).Delete(&domain.DocumentCommentMention{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("parent_id = ?", id).Delete(&domain.DocumentComment{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&domain.DocumentComment{}, id).Error
+	})
+}
+
+func (r *documentRepository) GetDocumentCommentByID(ctx context.Context, id uint) (*domain.DocumentComment, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var comment domain.DocumentComment
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Mentions").
+		Preload("Mentions.User").
+		First(&comment, id).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetDocumentComments returns the comment threads of a document, oldest first
+func (r *documentRepository) GetDocumentComments(ctx context.Context, documentID uint, resolved *bool) ([]domain.DocumentComment, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Mentions.User").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Replies.User").
+		Preload("Replies.Mentions.User").
+		Where("document_id = ? AND parent_id IS NULL", documentID)
+
+	if resolved != nil {
+		query = query.Where("resolved = ?", *resolved)
+	}
+
+	var comments []domain.DocumentComment
+	if err := query.Order("created_at ASC").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// GetUsersByIDsOrEmails looks up the users mentioned in a comment
+func (r *documentRepository) GetUsersByIDsOrEmails(ctx context.Context, ids []uuid.UUID, emails []string) ([]domain.User, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	if len(ids) == 0 && len(emails) == 0 {
+		return []domain.User{}, nil
+	}
+
+	query := r.db.WithContext(ctx).Model(&domain.User{})
+	switch {
+	case len(ids) > 0 && len(emails) > 0:
+		query = query.Where("id IN ? OR LOWER(email) IN ?", ids, emails)
+	case len(ids) > 0:
+		query = query.Where("id IN ?", ids)
+	default:
+		query = query.Where("LOWER(email) IN ?", emails)
+	}
+
+	var users []domain.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Version related methods
+// CreateDocumentVersion saves a version under the next version number of its document.
+// The document row stays locked while the number is assigned, so concurrent uploads get distinct numbers.
+func (r *documentRepository) CreateDocumentVersion(ctx context.Context, version *domain.DocumentVersion) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createNextVersion(tx, version)
+	})
+}
+
+// CreateCurrentDocumentVersion saves a version like CreateDocumentVersion and points the
+// document at its file in the same transaction, so the two never disagree
+func (r *documentRepository) CreateCurrentDocumentVersion(ctx context.Context, version *domain.DocumentVersion) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := createNextVersion(tx, version); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"document_path": version.DocumentPath,
+			"file_size":     version.FileSize,
+			"updated_at":    time.Now(),
+		}
+		if version.DocumentType != "" {
+			updates["document_type"] = version.DocumentType
+		}
+		return tx.Model(&domain.Document{}).Where("id = ?", version.DocumentID).Updates(updates).Error
+	})
+}
+
+// createNextVersion locks the document and saves the version under its next number
+func createNextVersion(tx *gorm.DB, version *domain.DocumentVersion) error {
+	var document domain.Document
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&document, version.DocumentID).Error; err != nil {
+		return err
+	}
+
+	var latest int
+	if err := tx.Model(&domain.DocumentVersion{}).
+		Select("COALESCE(MAX(version_number), 0)").
+		Where("document_id = ?", version.DocumentID).
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	version.VersionNumber = latest + 1
+	return tx.Create(version).Error
+}
+
+func (r *documentRepository) GetDocumentVersions(ctx context.Context, documentID uint) ([]domain.DocumentVersion, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var versions []domain.DocumentVersion
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("document_id = ?", documentID).
+		Order("version_number DESC").
+		Find(&versions).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (r *documentRepository) GetDocumentVersion(ctx context.Context, documentID uint, versionNumber int) (*domain.DocumentVersion, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var version domain.DocumentVersion
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("document_id = ? AND version_number = ?", documentID, versionNumber).
+		First(&version).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+func (r *documentRepository) GetLatestVersionNumber(ctx context.Context, documentID uint) (int, error) {
+	if r.db == nil {
+		return 0, errors.New("database connection is nil")
+	}
+
+	var latest int
+	err := r.db.WithContext(ctx).
+		Model(&domain.DocumentVersion{}).
+		Select("COALESCE(MAX(version_number), 0)").
+		Where("document_id = ?", documentID).
+		Scan(&latest).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return latest, nil
+}
+
+// Share link related methods
+func (r *documentRepository) CreateShareLink(ctx context.Context, link *domain.DocumentShareLink) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+func (r *documentRepository) GetShareLinkByID(ctx context.Context, id uint) (*domain.DocumentShareLink, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var link domain.DocumentShareLink
+	if err := r.db.WithContext(ctx).Preload("Document").First(&link, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (r *documentRepository) GetShareLinkByToken(ctx context.Context, token string) (*domain.DocumentShareLink, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var link domain.DocumentShareLink
+	err := r.db.WithContext(ctx).
+		Preload("Document").
+		Where("token = ?", token).
+		First(&link).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (r *documentRepository) GetActiveShareLinks(ctx context.Context, documentID *uint, createdBy *uuid.UUID) ([]domain.DocumentShareLink, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx).
+		Preload("Document").
+		Where("revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Where("max_downloads IS NULL OR download_count < max_downloads")
+
+	if documentID != nil {
+		query = query.Where("document_id = ?", *documentID)
+	}
+
+	if createdBy != nil {
+		query = query.Where("created_by = ?", *createdBy)
+	}
+
+	var links []domain.DocumentShareLink
+	if err := query.Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+func (r *documentRepository) RevokeShareLink(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&domain.DocumentShareLink{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *documentRepository) IncrementShareLinkViews(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).
+		Model(&domain.DocumentShareLink{}).
+		Where("id = ?", id).
+		Update("view_count", gorm.Expr("view_count + 1")).Error
+}
+
+// IncrementShareLinkDownloads counts a download, returning false when the download limit is already reached
+func (r *documentRepository) IncrementShareLinkDownloads(ctx context.Context, id uint) (bool, error) {
+	if r.db == nil {
+		return false, errors.New("database connection is nil")
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&domain.DocumentShareLink{}).
+		Where("id = ?", id).
+		Where("max_downloads IS NULL OR download_count < max_downloads").
+		Update("download_count", gorm.Expr("download_count + 1"))
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Preview related methods
+func (r *documentRepository) SaveDocumentPreview(ctx context.Context, preview *domain.DocumentPreview) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"source_path", "status", "content_text", "thumbnail_path", "error", "processed_at", "updated_at"}),
+		}).
+		Create(preview).Error
+}
+
+func (r *documentRepository) GetDocumentPreview(ctx context.Context, documentID uint) (*domain.DocumentPreview, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var preview domain.DocumentPreview
+	if err := r.db.WithContext(ctx).Scopes(previewSummary).First(&preview, "document_id = ?", documentID).Error; err != nil {
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+// MarkPreviewPending queues a document for preview generation, keeping the previous
+// text and thumbnail available until the new ones are ready
+func (r *documentRepository) MarkPreviewPending(ctx context.Context, documentID uint, sourcePath string) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	preview := &domain.DocumentPreview{
+		DocumentID: documentID,
+		SourcePath: sourcePath,
+		Status:     domain.PreviewStatusPending,
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "document_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"source_path", "status", "updated_at"}),
+		}).
+		Create(preview).Error
+}
+
+// GetPendingPreviews returns documents whose preview is pending or was never generated
+func (r *documentRepository) GetPendingPreviews(ctx context.Context, limit int) ([]uint, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var documentIDs []uint
+	err := r.db.WithContext(ctx).
+		Model(&domain.Document{}).
+		Joins("LEFT JOIN document_previews ON document_previews.document_id = documents.id").
+		Where("documents.deleted_at IS NULL").
+		Where("document_previews.document_id IS NULL OR document_previews.status = ?", domain.PreviewStatusPending).
+		Order("documents.id ASC").
+		Limit(limit).
+		Pluck("documents.id", &documentIDs).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return documentIDs, nil
+}
+
+// SearchDocuments runs a full-text search over extracted document text, best matches first,
+// limited to the documents the viewer can see
+func (r *documentRepository) SearchDocuments(ctx context.Context, query string, page, pageSize int, viewer *domain.AccessScope) ([]repositories.DocumentSearchHit, int, int, error) {
+	if r.db == nil {
+		return nil, 0, 0, errors.New("database connection is nil")
+	}
+
+	const vector = "to_tsvector('simple', COALESCE(document_previews.content_text, ''))"
+	const tsQuery = "plainto_tsquery('simple', ?)"
+
+	base, err := r.readableBy(ctx, r.db.WithContext(ctx).
+		Table("document_previews").
+		Joins("JOIN documents ON documents.id = document_previews.document_id AND documents.deleted_at IS NULL").
+		Where(vector+" @@ "+tsQuery, query), viewer)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	var totalCount int64
+	if err := base.Session(&gorm.Session{}).Count(&totalCount).Error; err != nil {
+		return nil, 0, 0, err
+	}
+
+	var matches []struct {
+		DocumentID uint
+		Snippet    string
+		Rank       float64
+	}
+	err = base.
+		Select(
+			"document_previews.document_id, "+
+				"ts_headline('simple', document_previews.content_text, "+tsQuery+", 'MaxFragments=2, MaxWords=20, MinWords=5') AS snippet, "+
+				"ts_rank("+vector+", "+tsQuery+") AS rank",
+			query, query,
+		).
+		Order("rank DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Scan(&matches).Error
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+	if len(matches) == 0 {
+		return []repositories.DocumentSearchHit{}, int(totalCount), totalPages, nil
+	}
+
+	ids := make([]uint, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.DocumentID)
+	}
+
+	var documents []domain.Document
+	err = r.db.WithContext(ctx).
+		Preload("Uploader").
+		Preload("Tags").
+		Preload("Preview", previewSummary).
+		Where("id IN ?", ids).
+		Find(&documents).Error
+
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	byID := make(map[uint]domain.Document, len(documents))
+	for _, document := range documents {
+		byID[document.ID] = document
+	}
+
+	hits := make([]repositories.DocumentSearchHit, 0, len(matches))
+	for _, match := range matches {
+		document, ok := byID[match.DocumentID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, repositories.DocumentSearchHit{
+			Document: document,
+			Snippet:  match.Snippet,
+			Rank:     match.Rank,
+		})
+	}
+
+	return hits, int(totalCount), totalPages, nil
+}
+
+// Retention related methods
+func (r *documentRepository) CreateRetentionPolicy(ctx context.Context, policy *domain.DocumentRetentionPolicy) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+func (r *documentRepository) UpdateRetentionPolicy(ctx context.Context, policy *domain.DocumentRetentionPolicy) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Save(policy).Error
+}
+
+func (r *documentRepository) DeleteRetentionPolicy(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Delete(&domain.DocumentRetentionPolicy{}, id).Error
+}
+
+func (r *documentRepository) GetRetentionPolicyByID(ctx context.Context, id uint) (*domain.DocumentRetentionPolicy, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var policy domain.DocumentRetentionPolicy
+	if err := r.db.WithContext(ctx).First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+func (r *documentRepository) GetRetentionPolicies(ctx context.Context) ([]domain.DocumentRetentionPolicy, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var policies []domain.DocumentRetentionPolicy
+	if err := r.db.WithContext(ctx).Order("entity_type ASC, folder_id ASC, id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// GetRetentionCandidates returns soft-deleted documents covered by the policy and
+// uploaded before the cutoff, in ID order after afterID. Live documents are never candidates.
+func (r *documentRepository) GetRetentionCandidates(
+	ctx context.Context,
+	policy *domain.DocumentRetentionPolicy,
+	cutoff time.Time,
+	afterID uint,
+	limit int,
+) ([]domain.Document, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx).Unscoped().
+		Preload("Folder").
+		Where("deleted_at IS NOT NULL AND created_at < ? AND id > ?", cutoff, afterID)
+
+	if policy.FolderID != nil {
+		var folder domain.DocumentFolder
+		if err := r.db.WithContext(ctx).First(&folder, *policy.FolderID).Error; err != nil {
+			return nil, err
+		}
+		query = query.Where("folder_id IN (?)",
+			r.db.Model(&domain.DocumentFolder{}).Select("id").Where("path LIKE ?", folder.Path+"%"))
+	} else {
+		query = query.Where("entity_type = ?", policy.EntityType)
+	}
+
+	var documents []domain.Document
+	if err := query.Order("id ASC").Limit(limit).Find(&documents).Error; err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// PurgeDocument permanently deletes a document and everything attached to it
+func (r *documentRepository) PurgeDocument(ctx context.Context, id uint) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		commentIDs := tx.Model(&domain.DocumentComment{}).Select("id").Where("document_id = ?", id)
+		if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&domain.DocumentCommentMention{}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&domain.DocumentComment{},
+			&domain.DocumentPermission{},
+			&domain.DocumentShareLink{},
+			&domain.DocumentVersion{},
+			&domain.DocumentPreview{},
+		} {
+			if err := tx.Where("document_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM document_tags WHERE document_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&domain.Document{}, id).Error
+	})
+}
+
+// Legal hold related methods
+func (r *documentRepository) CreateLegalHold(ctx context.Context, hold *domain.DocumentLegalHold) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Create(hold).Error
+}
+
+func (r *documentRepository) UpdateLegalHold(ctx context.Context, hold *domain.DocumentLegalHold) error {
+	if r.db == nil {
+		return errors.New("database connection is nil")
+	}
+
+	return r.db.WithContext(ctx).Save(hold).Error
+}
+
+func (r *documentRepository) GetLegalHoldByID(ctx context.Context, id uint) (*domain.DocumentLegalHold, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	var hold domain.DocumentLegalHold
+	if err := r.db.WithContext(ctx).First(&hold, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+func (r *documentRepository) GetLegalHolds(ctx context.Context, activeOnly bool) ([]domain.DocumentLegalHold, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx)
+	if activeOnly {
+		query = query.Where("released_at IS NULL")
+	}
+
+	var holds []domain.DocumentLegalHold
+	if err := query.Order("created_at DESC").Find(&holds).Error; err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// GetActiveLegalHolds returns the unreleased holds on a document or on any of the given folders
+func (r *documentRepository) GetActiveLegalHolds(ctx context.Context, documentID uint, folderIDs []uint) ([]domain.DocumentLegalHold, error) {
+	if r.db == nil {
+		return nil, errors.New("database connection is nil")
+	}
+
+	query := r.db.WithContext(ctx).Where("released_at IS NULL")
+	if len(folderIDs) > 0 {
+		query = query.Where("document_id = ? OR folder_id IN ?", documentID, folderIDs)
+	} else {
+		query = query.Where("document_id = ?", documentID)
+	}
+
+	var holds []domain.DocumentLegalHold
+	if err := query.Order("created_at ASC").Find(&holds).Error; err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+// FolderHasRetentionRules reports whether a retention policy or active legal hold targets the folder
+func (r *documentRepository) FolderHasRetentionRules(ctx context.Context, folderID uint) (bool, error) {
+	if r.db == nil {
+		return false, errors.New("database connection is nil")
+	}
+
+	var policies, holds int64
+	if err := r.db.WithContext(ctx).Model(&domain.DocumentRetentionPolicy{}).Where("folder_id = ?", folderID).Count(&policies).Error; err != nil {
+		return false, err
+	}
+	if err := r.db.WithContext(ctx).Model(&domain.DocumentLegalHold{}).Where("folder_id = ? AND released_at IS NULL", folderID).Count(&holds).Error; err != nil {
+		return false, err
+	}
+
+	return policies+holds > 0, nil
+}
+
+// IsUserInScope reports whether records owned by the user fall within the scope
+func (r *documentRepository) IsUserInScope(ctx context.Context, scope *domain.AccessScope, userID uuid.UUID) (bool, error) {
+	if r.db == nil {
+		return false, errors.New("database connection is nil")
+	}
+
+	var count int64
+	query := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID)
+	if err := applyAccessScope(query, scope, "id").Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
 }
@@ -0,0 +1,285 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type enhancedPermissionRepository struct {
+	db *gorm.DB
+}
+
+// NewEnhancedPermissionRepository creates a new enhanced permission repository
+func NewEnhancedPermissionRepository(db *gorm.DB) repositories.EnhancedPermissionRepository {
+	return &enhancedPermissionRepository{db: db}
+}
+
+// withHierarchy preloads the module, department, service and scope a permission belongs to
+func withHierarchy(db *gorm.DB) *gorm.DB {
+	return db.Preload("Module").Preload("Department").Preload("Service").Preload("Scope")
+}
+
+func (r *enhancedPermissionRepository) Create(ctx context.Context, permission *domain.EnhancedPermission) error {
+	if err := r.db.WithContext(ctx).Create(permission).Error; err != nil {
+		logger.Error("Failed to create permission", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create permission", 500)
+	}
+	return nil
+}
+
+func (r *enhancedPermissionRepository) GetByID(ctx context.Context, id uint) (*domain.EnhancedPermission, error) {
+	var permission domain.EnhancedPermission
+	if err := withHierarchy(r.db.WithContext(ctx)).First(&permission, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "permission not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get permission", 500)
+	}
+	return &permission, nil
+}
+
+func (r *enhancedPermissionRepository) GetByCode(ctx context.Context, code string) (*domain.EnhancedPermission, error) {
+	var permission domain.EnhancedPermission
+	if err := withHierarchy(r.db.WithContext(ctx)).Where("code = ?", code).First(&permission).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "permission not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get permission", 500)
+	}
+	return &permission, nil
+}
+
+func (r *enhancedPermissionRepository) List(ctx context.Context, filter repositories.PermissionFilter) ([]domain.EnhancedPermission, error) {
+	var permissions []domain.EnhancedPermission
+	query := withHierarchy(r.db.WithContext(ctx)).Model(&domain.EnhancedPermission{})
+
+	if filter.Module != "" {
+		query = query.Where("module_id IN (SELECT id FROM modules WHERE code = ?)", filter.Module)
+	}
+	if filter.Department != "" {
+		query = query.Where("department_id IN (SELECT id FROM departments WHERE code = ?)", filter.Department)
+	}
+	if filter.Service != "" {
+		query = query.Where("service_id IN (SELECT id FROM services WHERE code = ?)", filter.Service)
+	}
+	if filter.Scope != "" {
+		query = query.Where("scope_id IN (SELECT id FROM scopes WHERE code = ? OR level = ?)", filter.Scope, filter.Scope)
+	}
+	if filter.Resource != "" {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if err := query.Order("code ASC").Find(&permissions).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list permissions", 500)
+	}
+	return permissions, nil
+}
+
+func (r *enhancedPermissionRepository) ListByModule(ctx context.Context, moduleID uint) ([]domain.EnhancedPermission, error) {
+	return r.listBy(ctx, "module_id = ?", moduleID)
+}
+
+func (r *enhancedPermissionRepository) ListByDepartment(ctx context.Context, departmentID uint) ([]domain.EnhancedPermission, error) {
+	return r.listBy(ctx, "department_id = ?", departmentID)
+}
+
+func (r *enhancedPermissionRepository) ListByService(ctx context.Context, serviceID uint) ([]domain.EnhancedPermission, error) {
+	return r.listBy(ctx, "service_id = ?", serviceID)
+}
+
+func (r *enhancedPermissionRepository) ListByScope(ctx context.Context, scopeID uint) ([]domain.EnhancedPermission, error) {
+	return r.listBy(ctx, "scope_id = ?", scopeID)
+}
+
+func (r *enhancedPermissionRepository) listBy(ctx context.Context, condition string, id uint) ([]domain.EnhancedPermission, error) {
+	var permissions []domain.EnhancedPermission
+	if err := withHierarchy(r.db.WithContext(ctx)).
+		Where(condition, id).
+		Order("code ASC").
+		Find(&permissions).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list permissions", 500)
+	}
+	return permissions, nil
+}
+
+func (r *enhancedPermissionRepository) Update(ctx context.Context, permission *domain.EnhancedPermission) error {
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Save(permission).Error; err != nil {
+		logger.Error("Failed to update permission", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update permission", 500)
+	}
+	return nil
+}
+
+// Delete removes a permission together with every grant of it
+func (r *enhancedPermissionRepository) Delete(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("permission_id = ?", id).Delete(&domain.RoleEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("permission_id = ?", id).Delete(&domain.UserEnhancedPermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.EnhancedPermission{}, id).Error
+	})
+	if err != nil {
+		logger.Error("Failed to delete permission", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to delete permission", 500)
+	}
+	return nil
+}
+
+// AssignToRole grants permissions to a role, skipping ones it already has
+func (r *enhancedPermissionRepository) AssignToRole(ctx context.Context, roleID uint, permissionIDs []uint) error {
+	if len(permissionIDs) == 0 {
+		return nil
+	}
+
+	grants := make([]domain.RoleEnhancedPermission, 0, len(permissionIDs))
+	for _, permissionID := range permissionIDs {
+		grants = append(grants, domain.RoleEnhancedPermission{RoleID: roleID, PermissionID: permissionID})
+	}
+
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error; err != nil {
+		logger.Error("Failed to assign permissions to role", zap.Uint("roleID", roleID), zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to assign permissions", 500)
+	}
+	return nil
+}
+
+func (r *enhancedPermissionRepository) RemoveFromRole(ctx context.Context, roleID uint, permissionIDs []uint) error {
+	if len(permissionIDs) == 0 {
+		return nil
+	}
+
+	if err := r.db.WithContext(ctx).
+		Where("role_id = ? AND permission_id IN ?", roleID, permissionIDs).
+		Delete(&domain.RoleEnhancedPermission{}).Error; err != nil {
+		logger.Error("Failed to remove permissions from role", zap.Uint("roleID", roleID), zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to remove permissions", 500)
+	}
+	return nil
+}
+
+func (r *enhancedPermissionRepository) GetRolePermissions(ctx context.Context, roleID uint) ([]domain.EnhancedPermission, error) {
+	var permissions []domain.EnhancedPermission
+	if err := withHierarchy(r.db.WithContext(ctx)).
+		Joins("JOIN role_enhanced_permissions ON enhanced_permissions.id = role_enhanced_permissions.permission_id").
+		Where("role_enhanced_permissions.role_id = ?", roleID).
+		Order("enhanced_permissions.code ASC").
+		Find(&permissions).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get role permissions", 500)
+	}
+	return permissions, nil
+}
+
+// withGrantedPermission preloads the permission of a user grant with its hierarchy
+func withGrantedPermission(db *gorm.DB) *gorm.DB {
+	return db.Preload("Permission").
+		Preload("Permission.Module").
+		Preload("Permission.Department").
+		Preload("Permission.Service").
+		Preload("Permission.Scope")
+}
+
+// GrantToUser grants a permission to a user. Granting a permission the user already
+// holds, or held before it was revoked, replaces the existing grant.
+func (r *enhancedPermissionRepository) GrantToUser(ctx context.Context, grant *domain.UserEnhancedPermission) error {
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "permission_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"granted_by", "granted_at", "expires_at", "is_revoked", "revoked_at", "revoked_by",
+		}),
+	}).Create(grant).Error; err != nil {
+		logger.Error("Failed to grant permission to user", zap.String("userID", grant.UserID.String()), zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to grant permission", 500)
+	}
+	return nil
+}
+
+// RevokeFromUser marks a user's grant as revoked. revokedBy is nil when the grant is
+// revoked because it expired.
+func (r *enhancedPermissionRepository) RevokeFromUser(ctx context.Context, userID uuid.UUID, permissionID uint, revokedBy *uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&domain.UserEnhancedPermission{}).
+		Where("user_id = ? AND permission_id = ? AND is_revoked = ?", userID, permissionID, false).
+		Updates(map[string]interface{}{
+			"is_revoked": true,
+			"revoked_at": time.Now(),
+			"revoked_by": revokedBy,
+		})
+	if result.Error != nil {
+		logger.Error("Failed to revoke permission from user", zap.String("userID", userID.String()), zap.Error(result.Error))
+		return errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to revoke permission", 500)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrCodeNotFound, "permission grant not found", 404)
+	}
+	return nil
+}
+
+func (r *enhancedPermissionRepository) GetUserGrant(ctx context.Context, userID uuid.UUID, permissionID uint) (*domain.UserEnhancedPermission, error) {
+	var grant domain.UserEnhancedPermission
+	if err := withGrantedPermission(r.db.WithContext(ctx)).
+		Where("user_id = ? AND permission_id = ?", userID, permissionID).
+		First(&grant).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "permission grant not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get permission grant", 500)
+	}
+	return &grant, nil
+}
+
+func (r *enhancedPermissionRepository) ListUserGrants(ctx context.Context, userID uuid.UUID) ([]domain.UserEnhancedPermission, error) {
+	var grants []domain.UserEnhancedPermission
+	if err := withGrantedPermission(r.db.WithContext(ctx)).
+		Where("user_id = ? AND is_revoked = ?", userID, false).
+		Where("expires_at IS NULL OR expires_at > NOW()").
+		Order("granted_at ASC").
+		Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list permission grants", 500)
+	}
+	return grants, nil
+}
+
+// ListExpiredUserGrants lists grants that expired before the given time and have not
+// been revoked yet, oldest first
+func (r *enhancedPermissionRepository) ListExpiredUserGrants(ctx context.Context, before time.Time, limit int) ([]domain.UserEnhancedPermission, error) {
+	var grants []domain.UserEnhancedPermission
+	if err := withGrantedPermission(r.db.WithContext(ctx)).
+		Where("is_revoked = ? AND expires_at <= ?", false, before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list expired permission grants", 500)
+	}
+	return grants, nil
+}
+
+// ExpireUserGrant revokes an expired grant as read by ListExpiredUserGrants. A grant
+// renewed since then has a different expiry and is left alone.
+func (r *enhancedPermissionRepository) ExpireUserGrant(ctx context.Context, grant *domain.UserEnhancedPermission) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.UserEnhancedPermission{}).
+		Where("user_id = ? AND permission_id = ? AND is_revoked = ? AND expires_at = ?",
+			grant.UserID, grant.PermissionID, false, grant.ExpiresAt).
+		Updates(map[string]interface{}{
+			"is_revoked": true,
+			"revoked_at": time.Now(),
+		})
+	if result.Error != nil {
+		logger.Error("Failed to expire permission grant", zap.String("userID", grant.UserID.String()), zap.Error(result.Error))
+		return false, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to expire permission grant", 500)
+	}
+	return result.RowsAffected > 0, nil
+}
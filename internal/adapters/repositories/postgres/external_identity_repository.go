@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// externalIdentityRepository implements the ExternalIdentityRepository interface
+type externalIdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewExternalIdentityRepository creates a new external identity repository
+func NewExternalIdentityRepository(db *gorm.DB) repositories.ExternalIdentityRepository {
+	return &externalIdentityRepository{db: db}
+}
+
+// Create links a user to an identity provider account
+func (r *externalIdentityRepository) Create(ctx context.Context, identity *domain.ExternalIdentity) error {
+	if err := r.db.WithContext(ctx).Create(identity).Error; err != nil {
+		logger.Error("Failed to create external identity", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to link external identity", 500)
+	}
+	return nil
+}
+
+// GetByProviderSubject finds the identity a provider asserted for a subject
+func (r *externalIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	var identity domain.ExternalIdentity
+	err := r.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get external identity", 500)
+	}
+	return &identity, nil
+}
+
+// ListByUserID lists the identity provider accounts linked to a user
+func (r *externalIdentityRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.ExternalIdentity, error) {
+	var identities []*domain.ExternalIdentity
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&identities).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list external identities", 500)
+	}
+	return identities, nil
+}
+
+// RecordLogin stores the time of a login and the email the provider reported
+func (r *externalIdentityRepository) RecordLogin(ctx context.Context, id uuid.UUID, email string) error {
+	if err := r.db.WithContext(ctx).Model(&domain.ExternalIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"email":         email,
+			"last_login_at": time.Now(),
+		}).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update external identity", 500)
+	}
+	return nil
+}
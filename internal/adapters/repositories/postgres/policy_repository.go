@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+// NewPolicyRepository creates a new policy repository
+func NewPolicyRepository(db *gorm.DB) repositories.PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+func (r *policyRepository) Create(ctx context.Context, policy *domain.Policy) error {
+	if err := r.db.WithContext(ctx).Create(policy).Error; err != nil {
+		logger.Error("Failed to create policy", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to create policy", 500)
+	}
+	return nil
+}
+
+func (r *policyRepository) GetByID(ctx context.Context, id uint) (*domain.Policy, error) {
+	var policy domain.Policy
+	if err := r.db.WithContext(ctx).First(&policy, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "policy not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get policy", 500)
+	}
+	return &policy, nil
+}
+
+func (r *policyRepository) GetByName(ctx context.Context, name string) (*domain.Policy, error) {
+	var policy domain.Policy
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&policy).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeNotFound, "policy not found", 404)
+		}
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get policy", 500)
+	}
+	return &policy, nil
+}
+
+func (r *policyRepository) List(ctx context.Context, filter repositories.PolicyFilter) ([]domain.Policy, error) {
+	var policies []domain.Policy
+	query := r.db.WithContext(ctx).Model(&domain.Policy{})
+
+	if filter.Permission != "" {
+		query = query.Where("permission = ?", filter.Permission)
+	}
+	if filter.Effect != "" {
+		query = query.Where("effect = ?", filter.Effect)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+
+	if err := query.Order("name ASC").Find(&policies).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list policies", 500)
+	}
+	return policies, nil
+}
+
+func (r *policyRepository) Update(ctx context.Context, policy *domain.Policy) error {
+	if err := r.db.WithContext(ctx).Save(policy).Error; err != nil {
+		logger.Error("Failed to update policy", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update policy", 500)
+	}
+	return nil
+}
+
+// Delete removes a policy for good, so its name can be reused
+func (r *policyRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Unscoped().Delete(&domain.Policy{}, id).Error; err != nil {
+		logger.Error("Failed to delete policy", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to delete policy", 500)
+	}
+	return nil
+}
@@ -3,12 +3,14 @@ package postgres
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/pkg/errors"
 	"github.com/owner/go-cms/pkg/logger"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type roleRepository struct {
@@ -31,7 +33,7 @@ func (r *roleRepository) Create(ctx context.Context, role *domain.Role) error {
 func (r *roleRepository) GetByID(ctx context.Context, id uint) (*domain.Role, error) {
 	var role domain.Role
 	if err := r.db.WithContext(ctx).
-		Preload("Permissions").
+		Preload("Permissions.Scope").
 		Preload("Parent").
 		Preload("Children").
 		First(&role, id).Error; err != nil {
@@ -47,7 +49,7 @@ func (r *roleRepository) GetByName(ctx context.Context, name string) (*domain.Ro
 	var role domain.Role
 	if err := r.db.WithContext(ctx).
 		Where("name = ?", name).
-		Preload("Permissions").
+		Preload("Permissions.Scope").
 		First(&role).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, errors.New(errors.ErrCodeNotFound, "role not found", 404)
@@ -58,7 +60,8 @@ func (r *roleRepository) GetByName(ctx context.Context, name string) (*domain.Ro
 }
 
 func (r *roleRepository) Update(ctx context.Context, role *domain.Role) error {
-	if err := r.db.WithContext(ctx).Save(role).Error; err != nil {
+	// Grants are managed through the permission repository, not the preloaded associations
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Save(role).Error; err != nil {
 		logger.Error("Failed to update role", zap.Error(err))
 		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update role", 500)
 	}
@@ -75,7 +78,7 @@ func (r *roleRepository) Delete(ctx context.Context, id uint) error {
 
 func (r *roleRepository) List(ctx context.Context, filter repositories.RoleFilter) ([]*domain.Role, error) {
 	var roles []*domain.Role
-	query := r.db.WithContext(ctx).Model(&domain.Role{}).Preload("Permissions")
+	query := r.db.WithContext(ctx).Model(&domain.Role{}).Preload("Permissions.Scope")
 
 	if filter.Name != "" {
 		query = query.Where("name LIKE ?", "%"+filter.Name+"%")
@@ -120,64 +123,6 @@ func (r *roleRepository) GetChildren(ctx context.Context, parentID uint) ([]*dom
 	return roles, nil
 }
 
-func (r *roleRepository) AssignPermission(ctx context.Context, roleID, permissionID uint) error {
-	logger.Info("Assigning permission to role",
-		zap.Uint("roleID", roleID),
-		zap.Uint("permissionID", permissionID))
-
-	rolePermission := domain.RolePermission{
-		RoleID:       roleID,
-		PermissionID: permissionID,
-	}
-
-	// Check if already exists
-	var existing domain.RolePermission
-	err := r.db.WithContext(ctx).
-		Where("role_id = ? AND permission_id = ?", roleID, permissionID).
-		First(&existing).Error
-
-	if err == nil {
-		logger.Info("Permission already assigned to role",
-			zap.Uint("roleID", roleID),
-			zap.Uint("permissionID", permissionID))
-		return nil // Already exists, not an error
-	}
-
-	if err := r.db.WithContext(ctx).Create(&rolePermission).Error; err != nil {
-		logger.Error("Failed to assign permission to role",
-			zap.Uint("roleID", roleID),
-			zap.Uint("permissionID", permissionID),
-			zap.Error(err))
-		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to assign permission", 500)
-	}
-
-	logger.Info("Successfully assigned permission to role",
-		zap.Uint("roleID", roleID),
-		zap.Uint("permissionID", permissionID))
-	return nil
-}
-
-func (r *roleRepository) RemovePermission(ctx context.Context, roleID, permissionID uint) error {
-	if err := r.db.WithContext(ctx).
-		Where("role_id = ? AND permission_id = ?", roleID, permissionID).
-		Delete(&domain.RolePermission{}).Error; err != nil {
-		logger.Error("Failed to remove permission from role", zap.Error(err))
-		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to remove permission", 500)
-	}
-	return nil
-}
-
-func (r *roleRepository) GetRolePermissions(ctx context.Context, roleID uint) ([]*domain.Permission, error) {
-	var permissions []*domain.Permission
-	if err := r.db.WithContext(ctx).
-		Joins("JOIN role_permissions ON permissions.id = role_permissions.permission_id").
-		Where("role_permissions.role_id = ?", roleID).
-		Find(&permissions).Error; err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get role permissions", 500)
-	}
-	return permissions, nil
-}
-
 func (r *roleRepository) GetRoleUsers(ctx context.Context, roleID uint) ([]*domain.User, error) {
 	var users []*domain.User
 	if err := r.db.WithContext(ctx).
@@ -188,3 +133,19 @@ func (r *roleRepository) GetRoleUsers(ctx context.Context, roleID uint) ([]*doma
 	}
 	return users, nil
 }
+
+func (r *roleRepository) GetRoleUserIDs(ctx context.Context, roleIDs []uint) ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	if len(roleIDs) == 0 {
+		return userIDs, nil
+	}
+
+	if err := r.db.WithContext(ctx).
+		Model(&domain.UserRole{}).
+		Distinct("user_id").
+		Where("role_id IN ? AND deleted_at IS NULL", roleIDs).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get role user IDs", 500)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// signingKeyRepository implements the SigningKeyRepository interface
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewSigningKeyRepository creates a new signing key repository
+func NewSigningKeyRepository(db *gorm.DB) repositories.SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+// ListUnexpired lists the keys that still verify tokens, newest first
+func (r *signingKeyRepository) ListUnexpired(ctx context.Context) ([]*domain.SigningKey, error) {
+	var keys []*domain.SigningKey
+	if err := r.db.WithContext(ctx).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("activates_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list signing keys", 500)
+	}
+	return keys, nil
+}
+
+// Rotate stores a new key and schedules the retirement of the keys it replaces
+func (r *signingKeyRepository) Rotate(ctx context.Context, key *domain.SigningKey, retireAt, expireAt time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&domain.SigningKey{}).
+			Where("retires_at IS NULL").
+			Updates(map[string]interface{}{
+				"retires_at": retireAt,
+				"expires_at": expireAt,
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
+	if err != nil {
+		logger.Error("Failed to rotate signing key", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to rotate signing key", 500)
+	}
+	return nil
+}
+
+// DeleteExpired deletes keys that no longer verify any token
+func (r *signingKeyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Delete(&domain.SigningKey{})
+	if result.Error != nil {
+		return 0, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to delete expired signing keys", 500)
+	}
+	return result.RowsAffected, nil
+}
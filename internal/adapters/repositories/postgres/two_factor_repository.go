@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeRepository implements the RecoveryCodeRepository interface
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository
+func NewRecoveryCodeRepository(db *gorm.DB) repositories.RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser replaces all recovery codes of a user with a new set
+func (r *recoveryCodeRepository) ReplaceForUser(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	codes := make([]domain.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		logger.Error("Failed to replace recovery codes", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to save recovery codes", 500)
+	}
+
+	return nil
+}
+
+// Use marks an unused code as used. It reports false if no unused code matches,
+// including when a concurrent request used it first.
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to use recovery code", 500)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnused counts the recovery codes a user has left
+func (r *recoveryCodeRepository) CountUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to count recovery codes", 500)
+	}
+	return count, nil
+}
+
+// DeleteByUserID deletes all recovery codes of a user
+func (r *recoveryCodeRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to delete recovery codes", 500)
+	}
+	return nil
+}
+
+// webAuthnCredentialRepository implements the WebAuthnCredentialRepository interface
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository creates a new WebAuthn credential repository
+func NewWebAuthnCredentialRepository(db *gorm.DB) repositories.WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
+
+// Create registers a new credential
+func (r *webAuthnCredentialRepository) Create(ctx context.Context, credential *domain.WebAuthnCredential) error {
+	if err := r.db.WithContext(ctx).Create(credential).Error; err != nil {
+		logger.Error("Failed to create WebAuthn credential", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to save security key", 500)
+	}
+	return nil
+}
+
+// ListByUserID lists the credentials of a user, oldest first
+func (r *webAuthnCredentialRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	var credentials []*domain.WebAuthnCredential
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list security keys", 500)
+	}
+	return credentials, nil
+}
+
+// RecordUse stores the authenticator state after a successful assertion
+func (r *webAuthnCredentialRepository) RecordUse(ctx context.Context, id uuid.UUID, signCount uint32, cloneWarning, backupState bool) error {
+	updates := map[string]interface{}{
+		"sign_count":    signCount,
+		"clone_warning": cloneWarning,
+		"backup_state":  backupState,
+		"last_used_at":  time.Now(),
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.WebAuthnCredential{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to update security key", 500)
+	}
+	return nil
+}
+
+// Delete removes one of a user's credentials
+func (r *webAuthnCredentialRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Unscoped().Where("id = ? AND user_id = ?", id, userID).Delete(&domain.WebAuthnCredential{})
+	if result.Error != nil {
+		return errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to delete security key", 500)
+	}
+	if result.RowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
@@ -11,6 +12,7 @@ import (
 	"github.com/owner/go-cms/pkg/pagination"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepository struct {
@@ -166,6 +168,22 @@ func (r *userRepository) applyFilters(query *gorm.DB, filter repositories.UserFi
 		query = query.Where("id IN ?", filter.IDs)
 	}
 
+	if filter.Locked {
+		query = query.Where("locked_until > NOW()")
+	}
+
+	if filter.DepartmentID != nil {
+		if filter.IncludeSubDepartments {
+			query = query.Where("users.department_id IN ("+departmentSubtreeSQL+")", *filter.DepartmentID)
+		} else {
+			query = query.Where("users.department_id = ?", *filter.DepartmentID)
+		}
+	}
+
+	if filter.ServiceAccount != nil {
+		query = query.Where("is_service_account = ?", *filter.ServiceAccount)
+	}
+
 	// if filter.RoleID != nil {
 	// 	query = query.Joins("JOIN user_roles ON users.id = user_roles.user_id").
 	// 		Where("user_roles.role_id = ?", *filter.RoleID)
@@ -179,14 +197,18 @@ func (r *userRepository) applyFilters(query *gorm.DB, filter repositories.UserFi
 	return query
 }
 
-// AssignRole assigns a role to a user
+// AssignRole assigns a role to a user without an expiry
 func (r *userRepository) AssignRole(ctx context.Context, userID uuid.UUID, roleID uint) error {
-	userRole := domain.UserRole{
-		UserID: userID,
-		RoleID: roleID,
-	}
+	return r.GrantRole(ctx, &domain.UserRole{UserID: userID, RoleID: roleID})
+}
 
-	if err := r.db.WithContext(ctx).Create(&userRole).Error; err != nil {
+// GrantRole grants a role to a user. Granting a role the user already holds replaces
+// the grantor and expiry, so a temporary grant can be extended or made permanent.
+func (r *userRepository) GrantRole(ctx context.Context, grant *domain.UserRole) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "role_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"granted_by", "expires_at", "deleted_at"}),
+	}).Create(grant).Error; err != nil {
 		logger.Error("Failed to assign role", zap.Error(err))
 		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to assign role", 500)
 	}
@@ -212,7 +234,8 @@ func (r *userRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 
 	if err := r.db.WithContext(ctx).
 		Joins("JOIN user_roles ON roles.id = user_roles.role_id").
-		Where("user_roles.user_id = ?", userID).
+		Where("user_roles.user_id = ? AND user_roles.deleted_at IS NULL", userID).
+		Where("user_roles.expires_at IS NULL OR user_roles.expires_at > NOW()").
 		Find(&roles).Error; err != nil {
 		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get user roles", 500)
 	}
@@ -220,45 +243,48 @@ func (r *userRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 	return roles, nil
 }
 
-// AssignPermission assigns a permission to a user
-func (r *userRepository) AssignPermission(ctx context.Context, userID uuid.UUID, permissionID uint) error {
-	userPermission := domain.UserPermission{
-		UserID:       userID,
-		PermissionID: permissionID,
-	}
+// ListRoleGrants lists the unexpired role grants of a user
+func (r *userRepository) ListRoleGrants(ctx context.Context, userID uuid.UUID) ([]*domain.UserRole, error) {
+	var grants []*domain.UserRole
 
-	if err := r.db.WithContext(ctx).Create(&userPermission).Error; err != nil {
-		logger.Error("Failed to assign permission", zap.Error(err))
-		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to assign permission", 500)
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND deleted_at IS NULL", userID).
+		Where("expires_at IS NULL OR expires_at > NOW()").
+		Order("created_at ASC").
+		Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list role grants", 500)
 	}
 
-	return nil
+	return grants, nil
 }
 
-// RemovePermission removes a permission from a user
-func (r *userRepository) RemovePermission(ctx context.Context, userID uuid.UUID, permissionID uint) error {
+// ListExpiredRoleGrants lists role grants that expired before the given time, oldest first
+func (r *userRepository) ListExpiredRoleGrants(ctx context.Context, before time.Time, limit int) ([]*domain.UserRole, error) {
+	var grants []*domain.UserRole
+
 	if err := r.db.WithContext(ctx).
-		Where("user_id = ? AND permission_id = ?", userID, permissionID).
-		Delete(&domain.UserPermission{}).Error; err != nil {
-		logger.Error("Failed to remove permission", zap.Error(err))
-		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to remove permission", 500)
+		Where("expires_at <= ?", before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&grants).Error; err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to list expired role grants", 500)
 	}
 
-	return nil
+	return grants, nil
 }
 
-// GetUserPermissions gets all permissions for a user
-func (r *userRepository) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]*domain.Permission, error) {
-	var permissions []*domain.Permission
-
-	if err := r.db.WithContext(ctx).
-		Joins("JOIN user_permissions ON permissions.id = user_permissions.permission_id").
-		Where("user_permissions.user_id = ?", userID).
-		Find(&permissions).Error; err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to get user permissions", 500)
+// RemoveExpiredRoleGrant removes an expired role grant as read by ListExpiredRoleGrants.
+// A grant renewed since then has a different expiry and is left alone.
+func (r *userRepository) RemoveExpiredRoleGrant(ctx context.Context, grant *domain.UserRole) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND role_id = ? AND expires_at = ?", grant.UserID, grant.RoleID, grant.ExpiresAt).
+		Delete(&domain.UserRole{})
+	if result.Error != nil {
+		logger.Error("Failed to remove expired role grant", zap.Error(result.Error))
+		return false, errors.Wrap(result.Error, errors.ErrCodeDatabaseError, "failed to remove expired role grant", 500)
 	}
 
-	return permissions, nil
+	return result.RowsAffected > 0, nil
 }
 
 // UpdateLastLogin updates the last login time and IP
@@ -324,6 +350,30 @@ func (r *userRepository) Disable2FA(ctx context.Context, userID uuid.UUID) error
 	return nil
 }
 
+// LockAccount locks a user out of logging in until the given time
+func (r *userRepository) LockAccount(ctx context.Context, userID uuid.UUID, until time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", userID).
+		Update("locked_until", until).Error; err != nil {
+		logger.Error("Failed to lock account", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to lock account", 500)
+	}
+
+	return nil
+}
+
+// UnlockAccount lifts a login lockout
+func (r *userRepository) UnlockAccount(ctx context.Context, userID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", userID).
+		Update("locked_until", nil).Error; err != nil {
+		logger.Error("Failed to unlock account", zap.Error(err))
+		return errors.Wrap(err, errors.ErrCodeDatabaseError, "failed to unlock account", 500)
+	}
+
+	return nil
+}
+
 // UpdateStatus updates the user status
 func (r *userRepository) UpdateStatus(ctx context.Context, userID uuid.UUID, status domain.UserStatus) error {
 	if err := r.db.WithContext(ctx).Model(&domain.User{}).
@@ -16,12 +16,17 @@ type Config struct {
 	JWT        JWTConfig
 	OTP        OTPConfig
 	TwoFA      TwoFAConfig
+	OIDC       OIDCConfig
+	APITokens  APITokenConfig
+	Grants     GrantConfig
 	SMTP       SMTPConfig
 	CORS       CORSConfig
 	RateLimit  RateLimitConfig
+	Login      LoginProtectionConfig
 	FileUpload FileUploadConfig
 	Logging    LoggingConfig
 	Pagination PaginationConfig
+	Documents  DocumentConfig
 }
 
 type ServerConfig struct {
@@ -60,9 +65,13 @@ type MinIOConfig struct {
 }
 
 type JWTConfig struct {
-	Secret             string
-	AccessTokenExpire  time.Duration
-	RefreshTokenExpire time.Duration
+	Secret              string
+	AccessTokenExpire   time.Duration
+	RefreshTokenExpire  time.Duration
+	Algorithm           string        // HS256 signs with Secret; RS256 and ES256 sign with rotated key pairs
+	KeyRotationInterval time.Duration // How long a key pair signs before it is replaced
+	KeyPublishLead      time.Duration // How long a new key is published in the JWKS before it signs
+	ImpersonationExpire time.Duration // Lifetime of the token an admin gets to impersonate a user
 }
 
 // OTPConfig holds OTP configuration
@@ -73,7 +82,38 @@ type OTPConfig struct {
 
 // TwoFAConfig holds 2FA configuration
 type TwoFAConfig struct {
-	Issuer string
+	Issuer            string
+	RecoveryCodes     int      // Number of recovery codes issued at a time
+	WebAuthnRPID      string   // Relying party ID, the site's domain without scheme or port
+	WebAuthnRPOrigins []string // Origins allowed to use security keys, e.g. https://app.example.com
+}
+
+// APITokenConfig holds personal access token and service account key configuration
+type APITokenConfig struct {
+	DefaultLifetime time.Duration // Used when a token is created without an expiry
+	MaxLifetime     time.Duration // Longest expiry a token may be given
+}
+
+// GrantConfig holds configuration for time-limited permission and role grants
+type GrantConfig struct {
+	ExpiryInterval         time.Duration // How often expired grants are cleaned up
+	RequestDefaultDuration time.Duration // How long an approved access request grants access when no expiry is chosen
+	RequestMaxDuration     time.Duration // Longest access an approved access request can grant
+}
+
+// OIDCConfig holds single sign-on configuration for an OpenID Connect provider
+type OIDCConfig struct {
+	Enabled         bool
+	Name            string // Provider name used in the SSO routes
+	IssuerURL       string // Endpoints and signing keys are discovered from the issuer
+	ClientID        string
+	ClientSecret    string
+	RedirectURL     string
+	Scopes          []string
+	AllowedDomains  []string // Email domains allowed to log in; empty allows all
+	AutoCreateUsers bool     // Create users on their first login
+	GroupsClaim     string   // ID token claim listing the user's groups
+	GroupRoles      []string // group:role pairs, e.g. "cms-admins:admin"
 }
 
 // SMTPConfig holds SMTP email configuration
@@ -96,8 +136,23 @@ type CORSConfig struct {
 
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
-	Requests int
-	Duration time.Duration
+	Requests     int
+	Duration     time.Duration
+	Algorithm    string   // "sliding_window" or "token_bucket"
+	Allowlist    []string // IPs, CIDR ranges or user IDs that are never limited
+	AuthRequests int      // Limit per client IP on login, registration and password reset
+	AuthDuration time.Duration
+}
+
+// LoginProtectionConfig holds brute-force protection configuration for login and 2FA
+type LoginProtectionConfig struct {
+	MaxFailedAttempts   int           // Failures within the window before an account is locked
+	IPMaxFailedAttempts int           // Failures within the window before a client IP is blocked
+	FailureWindow       time.Duration // How long failures are remembered
+	LockoutDuration     time.Duration // How long a locked account or blocked IP stays locked
+	DelayAfter          int           // Failures before each further attempt is delayed
+	BaseDelay           time.Duration // First delay; it doubles with every further failure
+	MaxDelay            time.Duration
 }
 
 // FileUploadConfig holds file upload configuration
@@ -119,6 +174,12 @@ type PaginationConfig struct {
 	MaxPageSize     int
 }
 
+// DocumentConfig holds document management configuration
+type DocumentConfig struct {
+	LockTimeout   time.Duration // How long a check-out lasts unless renewed
+	PurgeInterval time.Duration // How often documents past their retention period are purged
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Get environment (default to development)
@@ -184,16 +245,45 @@ func Load() (*Config, error) {
 			BucketName:      viper.GetString("MINIO_BUCKET"),
 		},
 		JWT: JWTConfig{
-			Secret:             viper.GetString("JWT_SECRET"),
-			AccessTokenExpire:  viper.GetDuration("JWT_ACCESS_TOKEN_EXPIRE"),
-			RefreshTokenExpire: viper.GetDuration("JWT_REFRESH_TOKEN_EXPIRE"),
+			Secret:              viper.GetString("JWT_SECRET"),
+			AccessTokenExpire:   viper.GetDuration("JWT_ACCESS_TOKEN_EXPIRE"),
+			RefreshTokenExpire:  viper.GetDuration("JWT_REFRESH_TOKEN_EXPIRE"),
+			Algorithm:           viper.GetString("JWT_ALGORITHM"),
+			KeyRotationInterval: viper.GetDuration("JWT_KEY_ROTATION_INTERVAL"),
+			KeyPublishLead:      viper.GetDuration("JWT_KEY_PUBLISH_LEAD"),
+			ImpersonationExpire: viper.GetDuration("JWT_IMPERSONATION_EXPIRE"),
 		},
 		OTP: OTPConfig{
 			Expire: viper.GetDuration("OTP_EXPIRE"),
 			Length: viper.GetInt("OTP_LENGTH"),
 		},
 		TwoFA: TwoFAConfig{
-			Issuer: viper.GetString("TWO_FA_ISSUER"),
+			Issuer:            viper.GetString("TWO_FA_ISSUER"),
+			RecoveryCodes:     viper.GetInt("TWO_FA_RECOVERY_CODES"),
+			WebAuthnRPID:      viper.GetString("WEBAUTHN_RP_ID"),
+			WebAuthnRPOrigins: viper.GetStringSlice("WEBAUTHN_RP_ORIGINS"),
+		},
+		APITokens: APITokenConfig{
+			DefaultLifetime: viper.GetDuration("API_TOKEN_DEFAULT_LIFETIME"),
+			MaxLifetime:     viper.GetDuration("API_TOKEN_MAX_LIFETIME"),
+		},
+		Grants: GrantConfig{
+			ExpiryInterval:         viper.GetDuration("GRANT_EXPIRY_INTERVAL"),
+			RequestDefaultDuration: viper.GetDuration("ACCESS_REQUEST_DEFAULT_DURATION"),
+			RequestMaxDuration:     viper.GetDuration("ACCESS_REQUEST_MAX_DURATION"),
+		},
+		OIDC: OIDCConfig{
+			Enabled:         viper.GetBool("OIDC_ENABLED"),
+			Name:            viper.GetString("OIDC_NAME"),
+			IssuerURL:       viper.GetString("OIDC_ISSUER_URL"),
+			ClientID:        viper.GetString("OIDC_CLIENT_ID"),
+			ClientSecret:    viper.GetString("OIDC_CLIENT_SECRET"),
+			RedirectURL:     viper.GetString("OIDC_REDIRECT_URL"),
+			Scopes:          viper.GetStringSlice("OIDC_SCOPES"),
+			AllowedDomains:  viper.GetStringSlice("OIDC_ALLOWED_DOMAINS"),
+			AutoCreateUsers: viper.GetBool("OIDC_AUTO_CREATE_USERS"),
+			GroupsClaim:     viper.GetString("OIDC_GROUPS_CLAIM"),
+			GroupRoles:      viper.GetStringSlice("OIDC_GROUP_ROLES"),
 		},
 		SMTP: SMTPConfig{
 			Host:      viper.GetString("SMTP_HOST"),
@@ -210,8 +300,21 @@ func Load() (*Config, error) {
 			AllowCredentials: viper.GetBool("CORS_ALLOW_CREDENTIALS"),
 		},
 		RateLimit: RateLimitConfig{
-			Requests: viper.GetInt("RATE_LIMIT_REQUESTS"),
-			Duration: viper.GetDuration("RATE_LIMIT_DURATION"),
+			Requests:     viper.GetInt("RATE_LIMIT_REQUESTS"),
+			Duration:     viper.GetDuration("RATE_LIMIT_DURATION"),
+			Algorithm:    viper.GetString("RATE_LIMIT_ALGORITHM"),
+			Allowlist:    viper.GetStringSlice("RATE_LIMIT_ALLOWLIST"),
+			AuthRequests: viper.GetInt("RATE_LIMIT_AUTH_REQUESTS"),
+			AuthDuration: viper.GetDuration("RATE_LIMIT_AUTH_DURATION"),
+		},
+		Login: LoginProtectionConfig{
+			MaxFailedAttempts:   viper.GetInt("LOGIN_MAX_FAILED_ATTEMPTS"),
+			IPMaxFailedAttempts: viper.GetInt("LOGIN_IP_MAX_FAILED_ATTEMPTS"),
+			FailureWindow:       viper.GetDuration("LOGIN_FAILURE_WINDOW"),
+			LockoutDuration:     viper.GetDuration("LOGIN_LOCKOUT_DURATION"),
+			DelayAfter:          viper.GetInt("LOGIN_DELAY_AFTER"),
+			BaseDelay:           viper.GetDuration("LOGIN_BASE_DELAY"),
+			MaxDelay:            viper.GetDuration("LOGIN_MAX_DELAY"),
 		},
 		FileUpload: FileUploadConfig{
 			MaxUploadSize:    viper.GetInt64("MAX_UPLOAD_SIZE"),
@@ -226,11 +329,37 @@ func Load() (*Config, error) {
 			DefaultPageSize: viper.GetInt("DEFAULT_PAGE_SIZE"),
 			MaxPageSize:     viper.GetInt("MAX_PAGE_SIZE"),
 		},
+		Documents: DocumentConfig{
+			LockTimeout:   viper.GetDuration("DOCUMENT_LOCK_TIMEOUT"),
+			PurgeInterval: viper.GetDuration("DOCUMENT_PURGE_INTERVAL"),
+		},
+	}
+
+	if err := config.RateLimit.Validate(); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// Validate rejects rate limit windows shorter than a millisecond, the resolution the
+// counters are kept at. A zero window disables the limit.
+func (c *RateLimitConfig) Validate() error {
+	windows := []struct {
+		name   string
+		window time.Duration
+	}{
+		{"RATE_LIMIT_DURATION", c.Duration},
+		{"RATE_LIMIT_AUTH_DURATION", c.AuthDuration},
+	}
+	for _, w := range windows {
+		if w.window < 0 || (w.window > 0 && w.window < time.Millisecond) {
+			return fmt.Errorf("%s must be at least 1ms, got %s", w.name, w.window)
+		}
+	}
+	return nil
+}
+
 // setDefaults sets default values for configuration
 func setDefaults() {
 	// Server defaults
@@ -268,6 +397,10 @@ func setDefaults() {
 	viper.SetDefault("JWT_SECRET", "change-this-secret-key")
 	viper.SetDefault("JWT_ACCESS_TOKEN_EXPIRE", "15m")
 	viper.SetDefault("JWT_REFRESH_TOKEN_EXPIRE", "168h") // 7 days
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("JWT_KEY_ROTATION_INTERVAL", "720h") // 30 days
+	viper.SetDefault("JWT_KEY_PUBLISH_LEAD", "10m")
+	viper.SetDefault("JWT_IMPERSONATION_EXPIRE", "10m")
 
 	// OTP defaults
 	viper.SetDefault("OTP_EXPIRE", "30s")
@@ -275,6 +408,26 @@ func setDefaults() {
 
 	// 2FA defaults
 	viper.SetDefault("TWO_FA_ISSUER", "GO-CMS")
+	viper.SetDefault("TWO_FA_RECOVERY_CODES", 10)
+	viper.SetDefault("WEBAUTHN_RP_ID", "localhost")
+	viper.SetDefault("WEBAUTHN_RP_ORIGINS", []string{"http://localhost:3000"})
+
+	// API token defaults
+	viper.SetDefault("API_TOKEN_DEFAULT_LIFETIME", "2160h") // 90 days
+	viper.SetDefault("API_TOKEN_MAX_LIFETIME", "8760h")     // 1 year
+
+	// Grant defaults
+	viper.SetDefault("GRANT_EXPIRY_INTERVAL", "1m")
+	viper.SetDefault("ACCESS_REQUEST_DEFAULT_DURATION", "168h") // 7 days
+	viper.SetDefault("ACCESS_REQUEST_MAX_DURATION", "2160h")    // 90 days
+
+	// OIDC defaults
+	viper.SetDefault("OIDC_ENABLED", false)
+	viper.SetDefault("OIDC_NAME", "oidc")
+	viper.SetDefault("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/auth/sso/oidc/callback")
+	viper.SetDefault("OIDC_SCOPES", []string{"openid", "email", "profile"})
+	viper.SetDefault("OIDC_AUTO_CREATE_USERS", true)
+	viper.SetDefault("OIDC_GROUPS_CLAIM", "groups")
 
 	// SMTP defaults
 	viper.SetDefault("SMTP_HOST", "smtp.gmail.com")
@@ -293,6 +446,19 @@ func setDefaults() {
 	// Rate limit defaults
 	viper.SetDefault("RATE_LIMIT_REQUESTS", 100)
 	viper.SetDefault("RATE_LIMIT_DURATION", "1m")
+	viper.SetDefault("RATE_LIMIT_ALGORITHM", "sliding_window")
+	viper.SetDefault("RATE_LIMIT_ALLOWLIST", []string{})
+	viper.SetDefault("RATE_LIMIT_AUTH_REQUESTS", 10)
+	viper.SetDefault("RATE_LIMIT_AUTH_DURATION", "1m")
+
+	// Login protection defaults
+	viper.SetDefault("LOGIN_MAX_FAILED_ATTEMPTS", 5)
+	viper.SetDefault("LOGIN_IP_MAX_FAILED_ATTEMPTS", 50)
+	viper.SetDefault("LOGIN_FAILURE_WINDOW", "15m")
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", "30m")
+	viper.SetDefault("LOGIN_DELAY_AFTER", 3)
+	viper.SetDefault("LOGIN_BASE_DELAY", "1s")
+	viper.SetDefault("LOGIN_MAX_DELAY", "30s")
 
 	// File upload defaults
 	viper.SetDefault("MAX_UPLOAD_SIZE", 10485760) // 10MB
@@ -306,6 +472,10 @@ func setDefaults() {
 	// Pagination defaults
 	viper.SetDefault("DEFAULT_PAGE_SIZE", 20)
 	viper.SetDefault("MAX_PAGE_SIZE", 100)
+
+	// Document defaults
+	viper.SetDefault("DOCUMENT_LOCK_TIMEOUT", "2h")
+	viper.SetDefault("DOCUMENT_PURGE_INTERVAL", "24h")
 }
 
 // GetDSN returns the database connection string
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessRequestStatus represents where an access request is in its approval flow
+type AccessRequestStatus string
+
+const (
+	AccessRequestStatusPending   AccessRequestStatus = "pending"
+	AccessRequestStatusApproved  AccessRequestStatus = "approved"
+	AccessRequestStatusDenied    AccessRequestStatus = "denied"
+	AccessRequestStatusCancelled AccessRequestStatus = "cancelled"
+)
+
+// AccessRequest is a user's request for a role or a permission. It is routed to the
+// role's owner or the requester's department manager, and approving it grants the
+// access until GrantExpiresAt.
+type AccessRequest struct {
+	BaseModel
+	RequesterID    uuid.UUID           `gorm:"type:uuid;not null;index" json:"requester_id"`
+	RoleID         *uint               `gorm:"index" json:"role_id,omitempty"`       // Set for a role request
+	PermissionID   *uint               `gorm:"index" json:"permission_id,omitempty"` // Set for a permission request
+	Justification  string              `gorm:"type:text;not null" json:"justification"`
+	RequestedUntil *time.Time          `json:"requested_until,omitempty"` // How long the requester needs the access
+	Status         AccessRequestStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	ApproverID     *uuid.UUID          `gorm:"type:uuid;index" json:"approver_id,omitempty"` // Who the request is routed to; nil leaves it to admins
+	DecidedBy      *uuid.UUID          `gorm:"type:uuid" json:"decided_by,omitempty"`
+	DecidedAt      *time.Time          `json:"decided_at,omitempty"`
+	DecisionNote   string              `gorm:"type:text" json:"decision_note,omitempty"`
+	GrantExpiresAt *time.Time          `json:"grant_expires_at,omitempty"` // Expiry of the grant an approval created
+
+	// Relationships
+	Requester  *User               `gorm:"foreignKey:RequesterID" json:"requester,omitempty"`
+	Approver   *User               `gorm:"foreignKey:ApproverID" json:"approver,omitempty"`
+	Role       *Role               `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+	Permission *EnhancedPermission `gorm:"foreignKey:PermissionID" json:"permission,omitempty"`
+}
+
+// TableName specifies the table name for AccessRequest
+func (AccessRequest) TableName() string {
+	return "access_requests"
+}
+
+// IsPending reports whether the request still awaits a decision
+func (r *AccessRequest) IsPending() bool {
+	return r.Status == AccessRequestStatusPending
+}
+
+// AccessName describes the requested access, e.g. "role editor"
+func (r *AccessRequest) AccessName() string {
+	switch {
+	case r.Role != nil:
+		return "role " + r.Role.Name
+	case r.Permission != nil:
+		return "permission " + r.Permission.Code
+	case r.RoleID != nil:
+		return "a role"
+	default:
+		return "a permission"
+	}
+}
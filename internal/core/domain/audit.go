@@ -2,43 +2,52 @@ package domain
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // AuditAction represents the type of action performed
 type AuditAction string
 
 const (
-	AuditActionCreate AuditAction = "create"
-	AuditActionRead   AuditAction = "read"
-	AuditActionUpdate AuditAction = "update"
-	AuditActionDelete AuditAction = "delete"
-	AuditActionLogin  AuditAction = "login"
-	AuditActionLogout AuditAction = "logout"
-	AuditActionExport AuditAction = "export"
-	AuditActionImport AuditAction = "import"
+	AuditActionCreate  AuditAction = "create"
+	AuditActionRead    AuditAction = "read"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionLogin   AuditAction = "login"
+	AuditActionLogout  AuditAction = "logout"
+	AuditActionExport  AuditAction = "export"
+	AuditActionImport  AuditAction = "import"
+	AuditActionShare   AuditAction = "share"
+	AuditActionPurge   AuditAction = "purge"
+	AuditActionApprove AuditAction = "approve"
+	AuditActionDeny    AuditAction = "deny"
+	AuditActionCancel  AuditAction = "cancel"
 )
 
 // AuditLog represents an audit log entry for tracking user actions
 type AuditLog struct {
-	ID           uint        `gorm:"primarykey" json:"id"`
-	UserID       *uint       `gorm:"index" json:"user_id,omitempty"`
-	Action       AuditAction `gorm:"type:varchar(50);not null;index" json:"action"`
-	Resource     string      `gorm:"size:100;not null;index" json:"resource"` // e.g., "users", "posts"
-	ResourceID   *uint       `gorm:"index" json:"resource_id,omitempty"`
-	Description  string      `gorm:"type:text" json:"description"`
-	IPAddress    string      `gorm:"size:45" json:"ip_address"`
-	UserAgent    string      `gorm:"size:500" json:"user_agent"`
-	Method       string      `gorm:"size:10" json:"method"` // HTTP method
-	Path         string      `gorm:"size:500" json:"path"`  // Request path
-	StatusCode   int         `json:"status_code"`
-	Duration     int64       `json:"duration"`                                 // Request duration in milliseconds
-	RequestBody  *string     `gorm:"type:text" json:"request_body,omitempty"`  // Full request body (CLOB)
-	ResponseBody *string     `gorm:"type:text" json:"response_body,omitempty"` // Full response body (CLOB)
-	OldValues    *string     `gorm:"type:jsonb" json:"old_values,omitempty"`   // JSON of old values (for updates)
-	NewValues    *string     `gorm:"type:jsonb" json:"new_values,omitempty"`   // JSON of new values (for updates)
-	Metadata     *string     `gorm:"type:jsonb" json:"metadata,omitempty"`     // Additional metadata
-	CreatedAt    time.Time   `gorm:"index" json:"created_at"`                  // Start time
-	FinishedAt   *time.Time  `gorm:"index" json:"finished_at,omitempty"`       // Finish time
+	ID             uint        `gorm:"primarykey" json:"id"`
+	UserID         *uint       `gorm:"index" json:"user_id,omitempty"`
+	APITokenID     *uuid.UUID  `gorm:"type:uuid;index" json:"api_token_id,omitempty"`    // Set when the request used an API token
+	ImpersonatorID *uuid.UUID  `gorm:"type:uuid;index" json:"impersonator_id,omitempty"` // Set when an admin made the request while impersonating a user
+	Action         AuditAction `gorm:"type:varchar(50);not null;index" json:"action"`
+	Resource       string      `gorm:"size:100;not null;index" json:"resource"` // e.g., "users", "posts"
+	ResourceID     *uint       `gorm:"index" json:"resource_id,omitempty"`
+	Description    string      `gorm:"type:text" json:"description"`
+	IPAddress      string      `gorm:"size:45" json:"ip_address"`
+	UserAgent      string      `gorm:"size:500" json:"user_agent"`
+	Method         string      `gorm:"size:10" json:"method"` // HTTP method
+	Path           string      `gorm:"size:500" json:"path"`  // Request path
+	StatusCode     int         `json:"status_code"`
+	Duration       int64       `json:"duration"`                                 // Request duration in milliseconds
+	RequestBody    *string     `gorm:"type:text" json:"request_body,omitempty"`  // Full request body (CLOB)
+	ResponseBody   *string     `gorm:"type:text" json:"response_body,omitempty"` // Full response body (CLOB)
+	OldValues      *string     `gorm:"type:jsonb" json:"old_values,omitempty"`   // JSON of old values (for updates)
+	NewValues      *string     `gorm:"type:jsonb" json:"new_values,omitempty"`   // JSON of new values (for updates)
+	Metadata       *string     `gorm:"type:jsonb" json:"metadata,omitempty"`     // Additional metadata
+	CreatedAt      time.Time   `gorm:"index" json:"created_at"`                  // Start time
+	FinishedAt     *time.Time  `gorm:"index" json:"finished_at,omitempty"`       // Finish time
 
 	// Relationships
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -1,8 +1,10 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -32,18 +34,20 @@ func (Module) TableName() string {
 // Department represents a department/division within an organization
 type Department struct {
 	BaseModel
-	ModuleID    uint   `gorm:"index;not null" json:"module_id"`
-	Code        string `gorm:"uniqueIndex;size:50;not null" json:"code"` // e.g., "sales", "editorial", "it"
-	Name        string `gorm:"size:100;not null" json:"name"`
-	DisplayName string `gorm:"size:200" json:"display_name"`
-	Description string `gorm:"type:text" json:"description"`
-	ParentID    *uint  `gorm:"index" json:"parent_id,omitempty"`  // For hierarchical departments
-	ManagerID   *uint  `gorm:"index" json:"manager_id,omitempty"` // Department manager (User ID would be UUID in real case)
-	IsActive    bool   `gorm:"default:true" json:"is_active"`
-	IsSystem    bool   `gorm:"default:false" json:"is_system"`
+	ModuleID    uint       `gorm:"index;not null" json:"module_id"`
+	Code        string     `gorm:"uniqueIndex;size:50;not null" json:"code"` // e.g., "sales", "editorial", "it"
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	DisplayName string     `gorm:"size:200" json:"display_name"`
+	Description string     `gorm:"type:text" json:"description"`
+	ParentID    *uint      `gorm:"index" json:"parent_id,omitempty"`            // For hierarchical departments
+	Path        string     `gorm:"size:500" json:"path"`                        // IDs from the root down to this department, e.g. /1/4/9/
+	ManagerID   *uuid.UUID `gorm:"type:uuid;index" json:"manager_id,omitempty"` // Manages this department and every department below it
+	IsActive    bool       `gorm:"default:true" json:"is_active"`
+	IsSystem    bool       `gorm:"default:false" json:"is_system"`
 
 	// Relationships
 	Module   Module       `gorm:"foreignKey:ModuleID" json:"module,omitempty"`
+	Manager  *User        `gorm:"foreignKey:ManagerID" json:"manager,omitempty"`
 	Parent   *Department  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
 	Children []Department `gorm:"foreignKey:ParentID" json:"children,omitempty"`
 	Services []Service    `gorm:"foreignKey:DepartmentID" json:"services,omitempty"`
@@ -54,6 +58,11 @@ func (Department) TableName() string {
 	return "departments"
 }
 
+// Contains reports whether the department is this one or below it in the tree
+func (d *Department) Contains(other *Department) bool {
+	return d.Path != "" && strings.HasPrefix(other.Path, d.Path)
+}
+
 // Service represents a specific service/functionality within a department
 type Service struct {
 	BaseModel
@@ -86,6 +95,40 @@ const (
 	ScopeLevelPersonal     ScopeLevel = "personal"     // Personal/own resources only
 )
 
+// Rank orders scope levels from narrowest to broadest. Unknown levels rank 0.
+func (l ScopeLevel) Rank() int {
+	switch l {
+	case ScopeLevelPersonal:
+		return 1
+	case ScopeLevelTeam:
+		return 2
+	case ScopeLevelDepartment:
+		return 3
+	case ScopeLevelOrganization:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// AccessScope is the scope a user holds a permission at. It limits the records they
+// can reach: their own at personal level, their department's at team level, their
+// department's and its child departments' at department level, and all of them at
+// organization level. Permission and Request let resource policies on the permission
+// be evaluated once the record is loaded.
+type AccessScope struct {
+	UserID     uuid.UUID
+	Level      ScopeLevel
+	Permission string
+	Request    *PolicyRequest
+}
+
+// Unrestricted reports whether the scope reaches every record. A nil scope is
+// unrestricted, for callers that leave access to route-level permission checks.
+func (s *AccessScope) Unrestricted() bool {
+	return s == nil || s.Level == ScopeLevelOrganization
+}
+
 // Scope represents permission scope configuration
 type Scope struct {
 	BaseModel
@@ -110,20 +153,22 @@ func (Scope) TableName() string {
 type PermissionAction string
 
 const (
-	ActionCreate  PermissionAction = "create"
-	ActionRead    PermissionAction = "read"
-	ActionUpdate  PermissionAction = "update"
-	ActionDelete  PermissionAction = "delete"
-	ActionExecute PermissionAction = "execute"
-	ActionManage  PermissionAction = "manage" // Full control
-	ActionApprove PermissionAction = "approve"
-	ActionPublish PermissionAction = "publish"
-	ActionExport  PermissionAction = "export"
-	ActionImport  PermissionAction = "import"
+	ActionCreate      PermissionAction = "create"
+	ActionRead        PermissionAction = "read"
+	ActionUpdate      PermissionAction = "update"
+	ActionDelete      PermissionAction = "delete"
+	ActionExecute     PermissionAction = "execute"
+	ActionManage      PermissionAction = "manage" // Full control
+	ActionApprove     PermissionAction = "approve"
+	ActionPublish     PermissionAction = "publish"
+	ActionExport      PermissionAction = "export"
+	ActionImport      PermissionAction = "import"
+	ActionUpload      PermissionAction = "upload"
+	ActionImpersonate PermissionAction = "impersonate"
 )
 
-// EnhancedPermission represents a granular permission with full hierarchy
-// This will replace the existing Permission model
+// EnhancedPermission represents a granular permission with full hierarchy. It is the
+// permission model roles are granted and the permission checker evaluates.
 type EnhancedPermission struct {
 	BaseModel
 	ModuleID     uint             `gorm:"index;not null" json:"module_id"`
@@ -142,7 +187,7 @@ type EnhancedPermission struct {
 	Department Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
 	Service    Service    `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
 	Scope      Scope      `gorm:"foreignKey:ScopeID" json:"scope,omitempty"`
-	Roles      []Role     `gorm:"many2many:role_enhanced_permissions;" json:"roles,omitempty"`
+	Roles      []Role     `gorm:"many2many:role_enhanced_permissions;joinForeignKey:PermissionID;joinReferences:RoleID" json:"roles,omitempty"`
 }
 
 // TableName specifies the table name for EnhancedPermission
@@ -170,6 +215,14 @@ func (ep *EnhancedPermission) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// PermissionKey returns the key the permission checker matches against:
+// module:department:service:resource:action. The scope is left out, since it limits
+// which records a permission reaches rather than whether it is held. Module,
+// Department and Service must be loaded.
+func (ep *EnhancedPermission) PermissionKey() string {
+	return ep.Module.Code + ":" + ep.Department.Code + ":" + ep.Service.Code + ":" + ep.Resource + ":" + string(ep.Action)
+}
+
 // RoleEnhancedPermission represents the many-to-many relationship
 type RoleEnhancedPermission struct {
 	RoleID       uint      `gorm:"primaryKey" json:"role_id"`
@@ -182,16 +235,25 @@ func (RoleEnhancedPermission) TableName() string {
 	return "role_enhanced_permissions"
 }
 
-// UserEnhancedPermission represents direct user permissions
+// UserEnhancedPermission is a permission granted directly to a user, optionally until
+// ExpiresAt. Revoked and expired grants are ignored by the permission checker.
 type UserEnhancedPermission struct {
-	UserID       uint       `gorm:"primaryKey" json:"user_id"` // Will be UUID in migration
+	UserID       uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
 	PermissionID uint       `gorm:"primaryKey" json:"permission_id"`
-	GrantedBy    *uint      `gorm:"index" json:"granted_by,omitempty"` // Who granted this permission
+	GrantedBy    *uuid.UUID `gorm:"type:uuid;index" json:"granted_by,omitempty"` // Who granted this permission
 	GrantedAt    time.Time  `json:"granted_at"`
-	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // Optional expiration
+	ExpiresAt    *time.Time `gorm:"index" json:"expires_at,omitempty"` // Optional expiration
 	IsRevoked    bool       `gorm:"default:false" json:"is_revoked"`
 	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
-	RevokedBy    *uint      `json:"revoked_by,omitempty"`
+	RevokedBy    *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+
+	// Relationships
+	Permission EnhancedPermission `gorm:"foreignKey:PermissionID" json:"permission,omitempty"`
+}
+
+// IsActive reports whether the grant is neither revoked nor expired at the given time
+func (g *UserEnhancedPermission) IsActive(now time.Time) bool {
+	return !g.IsRevoked && (g.ExpiresAt == nil || g.ExpiresAt.After(now))
 }
 
 // TableName specifies the table name for UserEnhancedPermission
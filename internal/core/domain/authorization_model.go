@@ -0,0 +1,104 @@
+package domain
+
+import "strings"
+
+// AuthorizationModel is the whole authorization model: modules, departments, services,
+// scopes, permissions, roles and policies. Entries refer to each other by code or name
+// rather than database ID, so a model exported from one environment can be reviewed in
+// git and applied to another.
+type AuthorizationModel struct {
+	Modules     []ModuleSpec     `json:"modules" yaml:"modules"`
+	Departments []DepartmentSpec `json:"departments" yaml:"departments"`
+	Services    []ServiceSpec    `json:"services" yaml:"services"`
+	Scopes      []ScopeSpec      `json:"scopes" yaml:"scopes"`
+	Permissions []PermissionSpec `json:"permissions" yaml:"permissions"`
+	Roles       []RoleSpec       `json:"roles" yaml:"roles"`
+	Policies    []PolicySpec     `json:"policies" yaml:"policies"`
+}
+
+// ModuleSpec describes a module, keyed by code
+type ModuleSpec struct {
+	Code        string `json:"code" yaml:"code"`
+	Name        string `json:"name" yaml:"name"`
+	DisplayName string `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Icon        string `json:"icon,omitempty" yaml:"icon,omitempty"`
+	Color       string `json:"color,omitempty" yaml:"color,omitempty"`
+	Order       int    `json:"order" yaml:"order"`
+	IsActive    *bool  `json:"is_active,omitempty" yaml:"is_active,omitempty"` // Defaults to true
+	IsSystem    bool   `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+}
+
+// DepartmentSpec describes a department, keyed by code
+type DepartmentSpec struct {
+	Code        string `json:"code" yaml:"code"`
+	Module      string `json:"module" yaml:"module"`                     // Module code
+	Parent      string `json:"parent,omitempty" yaml:"parent,omitempty"` // Parent department code
+	Name        string `json:"name" yaml:"name"`
+	DisplayName string `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	IsActive    *bool  `json:"is_active,omitempty" yaml:"is_active,omitempty"` // Defaults to true
+	IsSystem    bool   `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+}
+
+// ServiceSpec describes a service, keyed by code
+type ServiceSpec struct {
+	Code        string `json:"code" yaml:"code"`
+	Department  string `json:"department" yaml:"department"` // Department code
+	Name        string `json:"name" yaml:"name"`
+	DisplayName string `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Endpoint    string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	IsActive    *bool  `json:"is_active,omitempty" yaml:"is_active,omitempty"` // Defaults to true
+	IsSystem    bool   `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+}
+
+// ScopeSpec describes a scope, keyed by code
+type ScopeSpec struct {
+	Code        string     `json:"code" yaml:"code"`
+	Name        string     `json:"name" yaml:"name"`
+	DisplayName string     `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Level       ScopeLevel `json:"level" yaml:"level"`
+	Priority    int        `json:"priority" yaml:"priority"`
+	IsSystem    bool       `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+}
+
+// PermissionSpec describes a permission, keyed by the code its parts make up
+type PermissionSpec struct {
+	Module      string           `json:"module" yaml:"module"`         // Module code
+	Department  string           `json:"department" yaml:"department"` // Department code
+	Service     string           `json:"service" yaml:"service"`       // Service code
+	Scope       string           `json:"scope" yaml:"scope"`           // Scope code
+	Resource    string           `json:"resource" yaml:"resource"`
+	Action      PermissionAction `json:"action" yaml:"action"`
+	DisplayName string           `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	IsSystem    bool             `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+}
+
+// Code returns the permission code, module:department:service:scope:resource:action
+func (p PermissionSpec) Code() string {
+	return strings.Join([]string{p.Module, p.Department, p.Service, p.Scope, p.Resource, string(p.Action)}, ":")
+}
+
+// RoleSpec describes a role, keyed by name
+type RoleSpec struct {
+	Name        string    `json:"name" yaml:"name"`
+	DisplayName string    `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+	Level       RoleLevel `json:"level" yaml:"level"`
+	Parent      string    `json:"parent,omitempty" yaml:"parent,omitempty"` // Parent role name
+	IsSystem    bool      `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+	Permissions []string  `json:"permissions" yaml:"permissions"` // Permission codes granted to the role itself
+}
+
+// PolicySpec describes a policy, keyed by name
+type PolicySpec struct {
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description,omitempty" yaml:"description,omitempty"`
+	Permission  string       `json:"permission" yaml:"permission"`
+	Effect      PolicyEffect `json:"effect" yaml:"effect"`
+	Condition   string       `json:"condition" yaml:"condition"`
+	IsActive    *bool        `json:"is_active,omitempty" yaml:"is_active,omitempty"` // Defaults to true
+}
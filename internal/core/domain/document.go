@@ -3,25 +3,29 @@ package domain
 import (
 	"context"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // Document represents a file in the system
 type Document struct {
-	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
-	DocumentCode string     `json:"document_code" gorm:"size:100;not null;uniqueIndex"`
-	EntityType   string     `json:"entity_type" gorm:"size:50;index"` // "order", "customer", "contract", etc.
-	EntityID     uint       `json:"entity_id" gorm:"index"`           // ID of the related entity
-	DocumentName string     `json:"document_name" gorm:"size:255;not null"`
-	DocumentPath string     `json:"document_path" gorm:"size:500;not null"`
-	DocumentType string     `json:"document_type" gorm:"size:100;not null"` // MIME type or file extension
-	FileSize     int64      `json:"file_size" gorm:"not null"`              // Size in bytes
-	UploadedBy   uuid.UUID  `json:"uploaded_by" gorm:"type:char(36);not null;index"`
-	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt    *time.Time `json:"deleted_at" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentCode string         `json:"document_code" gorm:"size:100;not null;uniqueIndex"`
+	EntityType   string         `json:"entity_type" gorm:"size:50;index"` // "order", "customer", "contract", etc.
+	EntityID     uint           `json:"entity_id" gorm:"index"`           // ID of the related entity
+	FolderID     *uint          `json:"folder_id" gorm:"index"`           // Folder the document is filed under, if any
+	DocumentName string         `json:"document_name" gorm:"size:255;not null"`
+	DocumentPath string         `json:"document_path" gorm:"size:500;not null"`
+	DocumentType string         `json:"document_type" gorm:"size:100;not null"` // MIME type or file extension
+	FileSize     int64          `json:"file_size" gorm:"not null"`              // Size in bytes
+	UploadedBy   uuid.UUID      `json:"uploaded_by" gorm:"type:char(36);not null;index"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	// Polymorphic relation
 	AttachableID   uint   `json:"-"`
@@ -29,47 +33,151 @@ type Document struct {
 
 	// Relations
 	Uploader            User                 `json:"uploader" gorm:"foreignKey:UploadedBy"`
+	Folder              *DocumentFolder      `json:"folder,omitempty" gorm:"foreignKey:FolderID"`
+	Preview             *DocumentPreview     `json:"preview,omitempty" gorm:"foreignKey:DocumentID"`
 	DocumentPermissions []DocumentPermission `json:"document_permissions" gorm:"foreignKey:DocumentID"`
+	Tags                []Tag                `json:"tags" gorm:"many2many:document_tags;"`
+
+	// Check-out state, loaded from the lock store rather than the database
+	Lock *DocumentLock `json:"lock,omitempty" gorm:"-"`
+}
+
+// DocumentLock describes who has a document checked out and until when
+type DocumentLock struct {
+	UserID    uuid.UUID `json:"user_id"`
+	LockedAt  time.Time `json:"locked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DocumentFolder groups documents into a tree. A root folder with EntityType/EntityID
+// set is the workspace of that entity; its subfolders carry the same entity link.
+type DocumentFolder struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string     `json:"name" gorm:"size:255;not null"`
+	ParentID   *uint      `json:"parent_id" gorm:"index"`
+	Path       string     `json:"path" gorm:"size:1000;not null;index"` // Materialized path of folder IDs, e.g. "/1/4/9/"
+	EntityType string     `json:"entity_type" gorm:"size:50;index"`
+	EntityID   uint       `json:"entity_id" gorm:"index"`
+	CreatedBy  *uuid.UUID `json:"created_by" gorm:"type:char(36);index"` // Nil for folders created from templates
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relations
+	Parent   *DocumentFolder  `json:"-" gorm:"foreignKey:ParentID"`
+	Children []DocumentFolder `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// AncestorIDs returns the IDs on the folder's path, root first, including the folder itself
+func (f *DocumentFolder) AncestorIDs() []uint {
+	ids := make([]uint, 0)
+	for _, part := range strings.Split(strings.Trim(f.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// DocumentFolderTemplate describes a folder created in every new workspace of an entity type
+type DocumentFolderTemplate struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	EntityType string    `json:"entity_type" gorm:"size:50;not null;index"`
+	FolderPath string    `json:"folder_path" gorm:"size:500;not null"` // Slash separated, e.g. "Contracts/Signed"
+	SortOrder  int       `json:"sort_order" gorm:"default:0"`
+	CreatedBy  uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
-// DocumentPermission defines access control for documents
+// DocumentPermission defines access control for documents. Exactly one subject is set:
+// a user, a role, a department (optionally with its child departments) or a job title.
 type DocumentPermission struct {
-	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	DocumentID      uint      `json:"document_id" gorm:"not null;index"`
-	UserID          uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"` // If null, applies to a role
-	JobTitle        string    `json:"job_title" gorm:"index"`                      // If null, applies to a specific user
-	PermissionLevel string    `json:"permission_level" gorm:"size:20;not null"`    // view, edit, comment, owner
-	CreatedBy       uuid.UUID `json:"created_by" gorm:"type:char(36);not null;index"`
-	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID                    uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentID            *uint      `json:"document_id" gorm:"index"`                     // Set for a grant on a single document
+	FolderID              *uint      `json:"folder_id" gorm:"index"`                       // Set for a grant inherited by everything in the folder
+	UserID                *uuid.UUID `json:"user_id" gorm:"type:char(36);index"`           // Grant to a single user
+	RoleID                *uint      `json:"role_id" gorm:"index"`                         // Grant to every holder of a role
+	DepartmentID          *uint      `json:"department_id" gorm:"index"`                   // Grant to every member of a department
+	IncludeSubDepartments bool       `json:"include_sub_departments" gorm:"default:false"` // Also members of child departments
+	JobTitle              string     `json:"job_title" gorm:"index"`                       // Grant to every user whose position matches
+	PermissionLevel       string     `json:"permission_level" gorm:"size:20;not null"`     // view, edit, comment, owner
+	ExpiresAt             *time.Time `json:"expires_at" gorm:"index"`                      // Nil for a permanent grant
+	CreatedBy             uuid.UUID  `json:"created_by" gorm:"type:char(36);not null;index"`
+	CreatedAt             time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt             time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 
 	// Relations
-	Document Document `json:"-" gorm:"foreignKey:DocumentID"`
-	User     User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Creator  User     `json:"creator" gorm:"foreignKey:CreatedBy"`
+	Document   *Document       `json:"-" gorm:"foreignKey:DocumentID"`
+	Folder     *DocumentFolder `json:"-" gorm:"foreignKey:FolderID"`
+	User       *User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Role       *Role           `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+	Department *Department     `json:"department,omitempty" gorm:"foreignKey:DepartmentID"`
+	Creator    User            `json:"creator" gorm:"foreignKey:CreatedBy"`
+}
+
+// IsExpired reports whether a time-limited grant has lapsed
+func (p *DocumentPermission) IsExpired() bool {
+	return p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now())
 }
 
 // DocumentComment allows users to add comments to documents
 type DocumentComment struct {
 	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
 	DocumentID uint       `json:"document_id" gorm:"not null;index"`
-	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"` // If null, applies to a role
+	ParentID   *uint      `json:"parent_id" gorm:"index"` // Thread starter this comment replies to
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
 	Comment    string     `json:"comment" gorm:"type:text;not null"`
+	Resolved   bool       `json:"resolved" gorm:"default:false;index"`
+	ResolvedBy *uuid.UUID `json:"resolved_by" gorm:"type:char(36)"`
+	ResolvedAt *time.Time `json:"resolved_at"`
 	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt  *time.Time `json:"deleted_at" gorm:"index"`
 
+	// Anchor pins a thread to part of the document: a region of a PDF page, with
+	// coordinates as fractions of the page size, or a character range of the text
+	AnchorType   string   `json:"anchor_type,omitempty" gorm:"size:20"` // pdf_region, text_range
+	AnchorPage   *int     `json:"anchor_page,omitempty"`
+	AnchorX      *float64 `json:"anchor_x,omitempty"`
+	AnchorY      *float64 `json:"anchor_y,omitempty"`
+	AnchorWidth  *float64 `json:"anchor_width,omitempty"`
+	AnchorHeight *float64 `json:"anchor_height,omitempty"`
+	AnchorStart  *int     `json:"anchor_start,omitempty"`
+	AnchorEnd    *int     `json:"anchor_end,omitempty"`
+
 	// Relations
-	Document Document `json:"-" gorm:"foreignKey:DocumentID"`
-	User     User     `json:"user" gorm:"foreignKey:UserID"`
+	Document Document                 `json:"-" gorm:"foreignKey:DocumentID"`
+	User     User                     `json:"user" gorm:"foreignKey:UserID"`
+	Replies  []DocumentComment        `json:"replies,omitempty" gorm:"foreignKey:ParentID"`
+	Mentions []DocumentCommentMention `json:"mentions,omitempty" gorm:"foreignKey:CommentID"`
+}
+
+// DocumentCommentMention records a user @mentioned in a comment
+type DocumentCommentMention struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CommentID uint      `json:"comment_id" gorm:"not null;uniqueIndex:idx_comment_mention"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_comment_mention;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relations
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// Comment anchor types
+const (
+	CommentAnchorPDFRegion = "pdf_region"
+	CommentAnchorTextRange = "text_range"
+)
+
 // DocumentVersion tracks document revisions
 type DocumentVersion struct {
 	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	DocumentID    uint      `json:"document_id" gorm:"not null;index"`
-	VersionNumber int       `json:"version_number" gorm:"not null"`
+	DocumentID    uint      `json:"document_id" gorm:"not null;index;uniqueIndex:idx_document_version_number"`
+	VersionNumber int       `json:"version_number" gorm:"not null;uniqueIndex:idx_document_version_number"`
 	DocumentPath  string    `json:"document_path" gorm:"size:500;not null"`
+	DocumentType  string    `json:"document_type" gorm:"size:100"` // MIME type of this revision
 	FileSize      int64     `json:"file_size" gorm:"not null"`
 	ChangedBy     uuid.UUID `json:"changed_by" gorm:"type:char(36);not null;index"`
 	ChangeNote    string    `json:"change_note" gorm:"type:text"`
@@ -80,6 +188,100 @@ type DocumentVersion struct {
 	User     User     `json:"user" gorm:"foreignKey:ChangedBy"`
 }
 
+// DocumentPreview holds the text extracted from a document's current file and its thumbnail
+type DocumentPreview struct {
+	DocumentID    uint       `json:"document_id" gorm:"primaryKey;autoIncrement:false"`
+	SourcePath    string     `json:"-" gorm:"size:500"` // Document file the preview was generated from
+	Status        string     `json:"status" gorm:"size:20;not null;default:pending;index"`
+	ContentText   string     `json:"-" gorm:"type:text"`
+	ThumbnailPath string     `json:"-" gorm:"size:500"`
+	Error         string     `json:"error,omitempty" gorm:"size:500"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Preview statuses
+const (
+	PreviewStatusPending     = "pending"
+	PreviewStatusReady       = "ready"
+	PreviewStatusFailed      = "failed"
+	PreviewStatusUnsupported = "unsupported"
+)
+
+// DocumentShareLink gives anyone holding the token access to a document without signing in
+type DocumentShareLink struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentID    uint       `json:"document_id" gorm:"not null;index"`
+	Token         string     `json:"token" gorm:"size:64;not null;uniqueIndex"`
+	PasswordHash  string     `json:"-" gorm:"size:255"`                         // Empty when no password is required
+	Mode          string     `json:"mode" gorm:"size:20;not null;default:view"` // view, download
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	MaxDownloads  *int       `json:"max_downloads"` // Nil for unlimited downloads
+	DownloadCount int        `json:"download_count" gorm:"default:0"`
+	ViewCount     int        `json:"view_count" gorm:"default:0"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"index"`
+	CreatedBy     uuid.UUID  `json:"created_by" gorm:"type:char(36);not null;index"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relations
+	Document *Document `json:"-" gorm:"foreignKey:DocumentID"`
+}
+
+// IsActive reports whether the link can still be used
+func (l *DocumentShareLink) IsActive() bool {
+	if l.RevokedAt != nil || !l.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	return l.MaxDownloads == nil || l.DownloadCount < *l.MaxDownloads
+}
+
+// Share link modes
+const (
+	ShareLinkModeView     = "view"
+	ShareLinkModeDownload = "download"
+)
+
+// DocumentRetentionPolicy sets how long documents are kept. A policy applies either to
+// every document of an entity type or to everything filed under a folder; a folder
+// policy overrides the entity type policy, and the nearest folder wins.
+type DocumentRetentionPolicy struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name          string    `json:"name" gorm:"size:255;not null"`
+	EntityType    string    `json:"entity_type" gorm:"size:50;index"` // Set for a policy on an entity type
+	FolderID      *uint     `json:"folder_id" gorm:"index"`           // Set for a policy on a folder subtree
+	RetentionDays int       `json:"retention_days" gorm:"not null"`   // Counted from the document's upload
+	Description   string    `json:"description" gorm:"type:text"`
+	CreatedBy     uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RetainUntil returns when a document uploaded at createdAt leaves retention
+func (p *DocumentRetentionPolicy) RetainUntil(createdAt time.Time) time.Time {
+	return createdAt.AddDate(0, 0, p.RetentionDays)
+}
+
+// DocumentLegalHold blocks deletion and purging of a document, or of everything
+// filed under a folder, until it is released
+type DocumentLegalHold struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentID *uint      `json:"document_id" gorm:"index"` // Set for a hold on a single document
+	FolderID   *uint      `json:"folder_id" gorm:"index"`   // Set for a hold on a folder subtree
+	Reason     string     `json:"reason" gorm:"type:text;not null"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	ReleasedAt *time.Time `json:"released_at" gorm:"index"`
+	ReleasedBy *uuid.UUID `json:"released_by" gorm:"type:char(36)"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// IsActive reports whether the hold still applies
+func (h *DocumentLegalHold) IsActive() bool {
+	return h.ReleasedAt == nil
+}
+
 // Constants for permission levels
 const (
 	PermissionView    = "view"
@@ -88,6 +290,11 @@ const (
 	PermissionOwner   = "owner"
 )
 
+// Entity types with document workspaces
+const (
+	EntityTypeCustomer = "customer"
+)
+
 type DocumentRepository interface {
 	Create(ctx context.Context, doc *Document) error
 	GetByID(ctx context.Context, id string) (*Document, error)
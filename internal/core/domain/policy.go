@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PolicyEffect is how a policy's condition affects access
+type PolicyEffect string
+
+const (
+	PolicyEffectAllow PolicyEffect = "allow" // Access requires the condition to hold
+	PolicyEffectDeny  PolicyEffect = "deny"  // Access is refused when the condition holds
+)
+
+// Policy is an attribute-based condition on a permission. Policies narrow what a
+// permission grants: every active allow policy that applies must hold, and no deny
+// policy may. The condition is written in the pkg/expression language over subject,
+// resource and request attributes.
+type Policy struct {
+	BaseModel
+	Name        string       `gorm:"uniqueIndex;size:100;not null" json:"name"`
+	Description string       `gorm:"type:text" json:"description"`
+	Permission  string       `gorm:"size:255;not null;index" json:"permission"` // module:department:service:resource:action, each part may be *
+	Effect      PolicyEffect `gorm:"type:varchar(10);not null" json:"effect"`
+	Condition   string       `gorm:"type:text;not null" json:"condition"`
+	IsActive    bool         `gorm:"default:true" json:"is_active"`
+	CreatedBy   *uuid.UUID   `gorm:"type:uuid" json:"created_by,omitempty"`
+}
+
+// TableName specifies the table name for Policy
+func (Policy) TableName() string {
+	return "policies"
+}
+
+// AppliesTo reports whether the policy covers a permission key. A * part in the
+// policy's permission matches any value.
+func (p *Policy) AppliesTo(permission string) bool {
+	patternParts := strings.Split(p.Permission, ":")
+	permissionParts := strings.Split(permission, ":")
+	if len(patternParts) != len(permissionParts) {
+		return false
+	}
+
+	for i, part := range patternParts {
+		if part != "*" && part != permissionParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PolicyRequest holds the attributes of the request being authorized
+type PolicyRequest struct {
+	IP     string    `json:"ip"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Time   time.Time `json:"time"`
+}
+
+// PolicyInput is what policies on a permission are evaluated against. Without a
+// resource only policies that do not read resource attributes are evaluated; with one,
+// only policies that do.
+type PolicyInput struct {
+	UserID     uuid.UUID
+	Permission string
+	Resource   interface{}    // Record being accessed, read through its JSON fields
+	Request    *PolicyRequest // Defaults to the current time only
+}
+
+// PolicyResult is the outcome of one policy
+type PolicyResult struct {
+	PolicyID  uint         `json:"policy_id,omitempty"` // Zero for a draft policy being simulated
+	Name      string       `json:"name"`
+	Effect    PolicyEffect `json:"effect"`
+	Condition string       `json:"condition"`
+	Matched   bool         `json:"matched"` // Whether the condition held
+	Allowed   bool         `json:"allowed"` // Whether the policy lets the access through
+	Error     string       `json:"error,omitempty"`
+}
+
+// PolicyDecision is the combined outcome of the policies on a permission
+type PolicyDecision struct {
+	Allowed bool           `json:"allowed"`
+	Reason  string         `json:"reason,omitempty"` // Why access was refused
+	Results []PolicyResult `json:"results"`
+}
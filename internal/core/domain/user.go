@@ -68,6 +68,8 @@ type User struct {
 	TwoFactorSecret  string     `json:"-"`
 	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
 	LastLoginIP      string     `gorm:"size:45" json:"last_login_ip,omitempty"`
+	LockedUntil      *time.Time `gorm:"index" json:"locked_until,omitempty"`           // Set after too many failed logins
+	IsServiceAccount bool       `gorm:"default:false;index" json:"is_service_account"` // Non-human account that only authenticates with API keys
 
 	// Relationships
 	Department  *Department  `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
@@ -82,6 +84,11 @@ func (User) TableName() string {
 	return "users"
 }
 
+// IsLocked checks if the account is locked out after too many failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // Customer represents a customer in the CRM
 type Customer struct {
 	BaseModel
@@ -123,18 +130,19 @@ const (
 // Role represents a user role with hierarchical structure
 type Role struct {
 	BaseModel
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
-	DisplayName string    `gorm:"size:200" json:"display_name"`
-	Description string    `gorm:"type:text" json:"description"`
-	Level       RoleLevel `gorm:"type:varchar(20);not null" json:"level"`
-	ParentID    *uint     `json:"parent_id,omitempty"`
-	IsSystem    bool      `gorm:"default:false" json:"is_system"` // System roles cannot be deleted
+	Name        string     `gorm:"uniqueIndex;not null" json:"name"`
+	DisplayName string     `gorm:"size:200" json:"display_name"`
+	Description string     `gorm:"type:text" json:"description"`
+	Level       RoleLevel  `gorm:"type:varchar(20);not null" json:"level"`
+	ParentID    *uint      `json:"parent_id,omitempty"`
+	OwnerID     *uuid.UUID `gorm:"type:uuid;index" json:"owner_id,omitempty"` // Decides access requests for the role
+	IsSystem    bool       `gorm:"default:false" json:"is_system"`            // System roles cannot be deleted
 
 	// Relationships
-	Parent      *Role        `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
-	Children    []Role       `gorm:"foreignKey:ParentID" json:"children,omitempty"`
-	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
-	Users       []User       `gorm:"many2many:user_roles;" json:"users,omitempty"`
+	Parent      *Role                `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Children    []Role               `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Permissions []EnhancedPermission `gorm:"many2many:role_enhanced_permissions;joinForeignKey:RoleID;joinReferences:PermissionID" json:"permissions,omitempty"`
+	Users       []User               `gorm:"many2many:user_roles;" json:"users,omitempty"`
 }
 
 // TableName specifies the table name for Role
@@ -142,7 +150,9 @@ func (Role) TableName() string {
 	return "roles"
 }
 
-// Permission represents a granular permission
+// Permission is a legacy permission keyed by string module, department and service.
+// EnhancedPermission is the source of truth; legacy rows are kept so existing grants can
+// be mapped onto it.
 type Permission struct {
 	BaseModel
 	Resource    string `gorm:"size:100;not null" json:"resource"` // e.g., "users", "posts", "customers"
@@ -167,12 +177,15 @@ func (p *Permission) GetPermissionKey() string {
 	return p.Module + ":" + p.Department + ":" + p.Service + ":" + p.Resource + ":" + p.Action
 }
 
-// UserRole represents the many-to-many relationship between users and roles
+// UserRole represents the many-to-many relationship between users and roles. A role
+// granted with ExpiresAt stops applying at that time and is removed by the grant expiry job.
 type UserRole struct {
-	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
-	RoleID    uint      `gorm:"primaryKey" json:"role_id"`
-	CreatedAt time.Time `json:"created_at"`
-	DeletedAt time.Time `json:"deleted_at"`
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	RoleID    uint       `gorm:"primaryKey" json:"role_id"`
+	GrantedBy *uuid.UUID `gorm:"type:uuid" json:"granted_by,omitempty"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // TableName specifies the table name for UserRole
@@ -225,10 +238,12 @@ func (o *OTP) IsExpired() bool {
 	return time.Now().After(o.ExpiresAt)
 }
 
-// RefreshToken represents a refresh token for JWT
+// RefreshToken represents a refresh token for JWT. Each refresh replaces the token;
+// the tokens of one session form a rotation family.
 type RefreshToken struct {
 	BaseModel
 	UserID    uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	SessionID uuid.UUID  `gorm:"type:uuid;index" json:"session_id"`
 	Token     string     `gorm:"uniqueIndex;not null" json:"token"`
 	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
 	Revoked   bool       `gorm:"default:false" json:"revoked"`
@@ -247,3 +262,174 @@ func (RefreshToken) TableName() string {
 func (r *RefreshToken) IsExpired() bool {
 	return time.Now().After(r.ExpiresAt)
 }
+
+// UserSession represents a signed-in device
+type UserSession struct {
+	UUIDModel
+	UserID        uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	IPAddress     string     `gorm:"size:45" json:"ip_address"`
+	UserAgent     string     `gorm:"size:500" json:"user_agent"`
+	LastSeenAt    time.Time  `gorm:"not null" json:"last_seen_at"` // Updated on sign-in and on every token refresh
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"size:50" json:"revoked_reason,omitempty"` // logout, revoked, password_changed, token_reuse
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+// TableName specifies the table name for UserSession
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive checks if the session can still be used
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout          = "logout"
+	SessionRevokedByUser          = "revoked"
+	SessionRevokedPasswordChanged = "password_changed"
+	SessionRevokedTokenReuse      = "token_reuse"
+)
+
+// RecoveryCode is a one-time code that stands in for a second factor. Only a hash
+// of the code is stored.
+type RecoveryCode struct {
+	BaseModel
+	UserID   uuid.UUID  `gorm:"type:uuid;index;not null" json:"-"`
+	CodeHash string     `gorm:"size:64;not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+// TableName specifies the table name for RecoveryCode
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// WebAuthnCredential is a security key or passkey registered as a second factor
+type WebAuthnCredential struct {
+	UUIDModel
+	UserID          uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	Name            string     `gorm:"size:100" json:"name"`
+	CredentialID    []byte     `gorm:"type:bytea;uniqueIndex;not null" json:"-"`
+	PublicKey       []byte     `gorm:"type:bytea;not null" json:"-"`
+	AttestationType string     `gorm:"size:50" json:"attestation_type"`
+	Transports      string     `gorm:"size:100" json:"transports"` // Comma-separated, e.g. "usb,nfc"
+	AAGUID          []byte     `gorm:"type:bytea" json:"-"`
+	SignCount       uint32     `json:"-"`
+	CloneWarning    bool       `gorm:"default:false" json:"clone_warning"`
+	UserPresent     bool       `json:"-"`
+	UserVerified    bool       `json:"-"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+// TableName specifies the table name for WebAuthnCredential
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// Second factors a user can complete login with
+const (
+	TwoFactorMethodTOTP         = "totp"
+	TwoFactorMethodWebAuthn     = "webauthn"
+	TwoFactorMethodRecoveryCode = "recovery_code"
+)
+
+// ExternalIdentity links a user to their account at a single sign-on identity provider
+type ExternalIdentity struct {
+	UUIDModel
+	UserID      uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_external_identity_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_external_identity_subject" json:"subject"`
+	Email       string     `gorm:"size:255" json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+// TableName specifies the table name for ExternalIdentity
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
+
+// SigningKey is a key pair for signing access and refresh tokens. A key signs from
+// ActivatesAt until RetiresAt and verifies tokens until ExpiresAt.
+type SigningKey struct {
+	BaseModel
+	KeyID       string     `gorm:"size:64;uniqueIndex;not null" json:"kid"`
+	Algorithm   string     `gorm:"size:10;not null" json:"alg"`
+	PrivateKey  string     `gorm:"type:text;not null" json:"-"` // PKCS #8 PEM
+	PublicKey   string     `gorm:"type:text;not null" json:"-"` // PKIX PEM
+	ActivatesAt time.Time  `gorm:"not null" json:"activates_at"`
+	RetiresAt   *time.Time `json:"retires_at,omitempty"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"`
+}
+
+// TableName specifies the table name for SigningKey
+func (SigningKey) TableName() string {
+	return "signing_keys"
+}
+
+// APITokenType distinguishes tokens users issue for themselves from service account keys
+type APITokenType string
+
+const (
+	APITokenTypePersonal APITokenType = "personal"
+	APITokenTypeService  APITokenType = "service"
+)
+
+// APITokenPrefix starts every API token, so they can be told apart from JWTs
+const APITokenPrefix = "gcms_"
+
+// APIToken is a personal access token or service account API key. Only a hash of
+// the token is stored; it acts as its owner, limited to its scopes.
+type APIToken struct {
+	UUIDModel
+	UserID     uuid.UUID    `gorm:"type:uuid;index;not null" json:"user_id"` // Owner, or the service account
+	Name       string       `gorm:"size:100;not null" json:"name"`
+	Type       APITokenType `gorm:"type:varchar(20);not null;index" json:"type"`
+	Prefix     string       `gorm:"size:20;not null" json:"prefix"` // Start of the token, to recognize it
+	TokenHash  string       `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes     []string     `gorm:"serializer:json;type:jsonb" json:"scopes"` // Permission codes the token may use
+	ExpiresAt  time.Time    `gorm:"index;not null" json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	LastUsedIP string       `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time   `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedBy  uuid.UUID    `gorm:"type:uuid" json:"created_by"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+// TableName specifies the table name for APIToken
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// IsActive checks that the token is neither revoked nor expired
+func (t *APIToken) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// HasScope checks if the token may use a permission
+func (t *APIToken) HasScope(permission string) bool {
+	for _, scope := range t.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // DocumentFilter represents search and pagination parameters for documents
 type DocumentFilter struct {
@@ -9,6 +13,8 @@ type DocumentFilter struct {
 	EntityID     *uint  `form:"entity_id" json:"entity_id"`
 	DocumentType string `form:"document_type" json:"document_type"`
 	UploadedBy   *uint  `form:"uploaded_by" json:"uploaded_by"`
+	FolderID     *uint  `form:"folder_id" json:"folder_id"`
+	Tag          string `form:"tag" json:"tag"` // Tag slug
 	SortBy       string `form:"sort_by" json:"sort_by"`
 	SortDir      string `form:"sort_dir" json:"sort_dir"`
 	Page         int    `form:"page" json:"page" binding:"min=1"`
@@ -20,6 +26,7 @@ type DocumentUploadRequest struct {
 	EntityType   string `form:"entity_type" binding:"required"`
 	EntityID     uint   `form:"entity_id" binding:"required"`
 	DocumentName string `form:"document_name" binding:"required"`
+	FolderID     *uint  `form:"folder_id"`
 }
 
 // DocumentUpdateRequest contains fields that can be updated
@@ -29,16 +36,54 @@ type DocumentUpdateRequest struct {
 
 // DocumentPermissionRequest for assigning permissions
 type DocumentPermissionRequest struct {
-	DocumentID      uint       `json:"document_id" binding:"required"`
-	UserID          *uuid.UUID `json:"user_id"`
-	JobTitle        string     `json:"job_title"`
-	PermissionLevel string     `json:"permission_level" binding:"required,oneof=view comment edit owner"`
+	DocumentID uint `json:"document_id" binding:"required"`
+	DocumentGrantRequest
+}
+
+// DocumentGrantRequest identifies who receives a document or folder permission.
+// Exactly one of UserID, RoleID, DepartmentID and JobTitle must be set.
+type DocumentGrantRequest struct {
+	UserID                *uuid.UUID `json:"user_id"`
+	RoleID                *uint      `json:"role_id"`
+	DepartmentID          *uint      `json:"department_id"`
+	IncludeSubDepartments bool       `json:"include_sub_departments"`
+	JobTitle              string     `json:"job_title"`
+	PermissionLevel       string     `json:"permission_level" binding:"required,oneof=view comment edit owner"`
+	ExpiresAt             *time.Time `json:"expires_at"`
+}
+
+// DocumentVersionRequest carries the change note for a new or restored version
+type DocumentVersionRequest struct {
+	ChangeNote string `form:"change_note" json:"change_note"`
 }
 
-// DocumentCommentRequest for adding comments
+// DocumentCommentRequest for adding comments. Users are mentioned either by
+// "@email" in the text or by listing their IDs.
 type DocumentCommentRequest struct {
-	DocumentID uint   `json:"document_id" binding:"required"`
-	Comment    string `json:"comment" binding:"required"`
+	DocumentID       uint                   `json:"document_id" binding:"required"`
+	Comment          string                 `json:"comment" binding:"required"`
+	ParentID         *uint                  `json:"parent_id"` // Comment being replied to
+	Anchor           *DocumentCommentAnchor `json:"anchor"`    // Only for thread starters
+	MentionedUserIDs []uuid.UUID            `json:"mentioned_user_ids"`
+}
+
+// DocumentCommentUpdateRequest for editing a comment
+type DocumentCommentUpdateRequest struct {
+	Comment          string      `json:"comment" binding:"required"`
+	MentionedUserIDs []uuid.UUID `json:"mentioned_user_ids"`
+}
+
+// DocumentCommentAnchor pins a comment to a PDF region (page plus rectangle with
+// coordinates as fractions of the page size) or a character range of the text
+type DocumentCommentAnchor struct {
+	Type   string   `json:"type" binding:"required,oneof=pdf_region text_range"`
+	Page   *int     `json:"page"`
+	X      *float64 `json:"x"`
+	Y      *float64 `json:"y"`
+	Width  *float64 `json:"width"`
+	Height *float64 `json:"height"`
+	Start  *int     `json:"start"`
+	End    *int     `json:"end"`
 }
 
 // DocumentResponse standard response with permissions
@@ -50,11 +95,24 @@ type DocumentResponse struct {
 	DocumentName   string    `json:"document_name"`
 	DocumentType   string    `json:"document_type"`
 	FileSize       int64     `json:"file_size"`
+	FolderID       *uint     `json:"folder_id"`
+	Tags           []string  `json:"tags"`
 	UploadedBy     uuid.UUID `json:"uploaded_by"`
 	UploaderName   string    `json:"uploader_name"`
 	CreatedAt      string    `json:"created_at"`
 	UpdatedAt      string    `json:"updated_at"`
 	UserPermission string    `json:"user_permission"` // Current user's permission level
+	PreviewStatus  string    `json:"preview_status,omitempty"`
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty"`
+
+	Lock *DocumentLockResponse `json:"lock,omitempty"` // Set while the document is checked out
+}
+
+// DocumentLockResponse describes a document check-out
+type DocumentLockResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	LockedAt  time.Time `json:"locked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // PaginatedDocumentsResponse for list responses
@@ -65,3 +123,189 @@ type PaginatedDocumentsResponse struct {
 	PageSize   int                `json:"page_size"`
 	TotalPages int                `json:"total_pages"`
 }
+
+// DocumentFolderRequest for creating or renaming a folder
+type DocumentFolderRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// DocumentFolderMoveRequest for moving a folder under a new parent (nil moves it to the top level)
+type DocumentFolderMoveRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+// DocumentMoveRequest for moving a document into a folder (nil removes it from any folder)
+type DocumentMoveRequest struct {
+	FolderID *uint `json:"folder_id"`
+}
+
+// DocumentFolderPermissionRequest for granting access to a folder and everything below it
+type DocumentFolderPermissionRequest struct {
+	DocumentGrantRequest
+}
+
+// DocumentTagsRequest for tagging a document
+type DocumentTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// DocumentFolderTemplateRequest for defining a workspace folder template
+type DocumentFolderTemplateRequest struct {
+	EntityType string `json:"entity_type" binding:"required"`
+	FolderPath string `json:"folder_path" binding:"required"`
+	SortOrder  int    `json:"sort_order"`
+}
+
+// DocumentFolderResponse describes a folder, its subfolders and the current user's access
+type DocumentFolderResponse struct {
+	ID             uint                     `json:"id"`
+	Name           string                   `json:"name"`
+	ParentID       *uint                    `json:"parent_id"`
+	EntityType     string                   `json:"entity_type"`
+	EntityID       uint                     `json:"entity_id"`
+	UserPermission string                   `json:"user_permission"`
+	Children       []DocumentFolderResponse `json:"children"`
+}
+
+// DocumentFolderContentsResponse lists the direct contents of a folder
+type DocumentFolderContentsResponse struct {
+	Folder    DocumentFolderResponse   `json:"folder"`
+	Folders   []DocumentFolderResponse `json:"folders"`
+	Documents []DocumentResponse       `json:"documents"`
+}
+
+// EntityWorkspaceResponse is the folder tree and documents of a business entity
+type EntityWorkspaceResponse struct {
+	EntityType string                 `json:"entity_type"`
+	EntityID   uint                   `json:"entity_id"`
+	Root       DocumentFolderResponse `json:"root"`
+	Documents  []DocumentResponse     `json:"documents"`
+}
+
+// DocumentPermissionSource explains one grant that contributes to a user's access
+type DocumentPermissionSource struct {
+	PermissionID    uint       `json:"permission_id,omitempty"`
+	PermissionLevel string     `json:"permission_level"`
+	Subject         string     `json:"subject"` // uploader, user, role, department, job_title
+	SubjectName     string     `json:"subject_name"`
+	FolderID        *uint      `json:"folder_id,omitempty"` // Set when inherited from a folder
+	FolderName      string     `json:"folder_name,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+}
+
+// EffectiveDocumentPermissionResponse explains why a user has a given level on a document
+type EffectiveDocumentPermissionResponse struct {
+	DocumentID      uint                       `json:"document_id"`
+	UserID          uuid.UUID                  `json:"user_id"`
+	PermissionLevel string                     `json:"permission_level"`
+	Sources         []DocumentPermissionSource `json:"sources"`
+}
+
+// DocumentShareLinkRequest for creating a public share link
+type DocumentShareLinkRequest struct {
+	Mode         string    `json:"mode" binding:"required,oneof=view download"`
+	ExpiresAt    time.Time `json:"expires_at" binding:"required"`
+	Password     string    `json:"password"`
+	MaxDownloads *int      `json:"max_downloads" binding:"omitempty,min=1"`
+}
+
+// DocumentShareLinkResponse describes a share link to its owner
+type DocumentShareLinkResponse struct {
+	ID            uint       `json:"id"`
+	DocumentID    uint       `json:"document_id"`
+	DocumentName  string     `json:"document_name,omitempty"`
+	Token         string     `json:"token"`
+	URL           string     `json:"url"`
+	Mode          string     `json:"mode"`
+	HasPassword   bool       `json:"has_password"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	MaxDownloads  *int       `json:"max_downloads"`
+	DownloadCount int        `json:"download_count"`
+	ViewCount     int        `json:"view_count"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ShareLinkAccessRequest describes an anonymous request made through a share link
+type ShareLinkAccessRequest struct {
+	Token     string
+	Password  string
+	IPAddress string
+	UserAgent string
+}
+
+// SharedDocumentResponse is the public view of a shared document
+type SharedDocumentResponse struct {
+	DocumentName string    `json:"document_name"`
+	DocumentType string    `json:"document_type"`
+	FileSize     int64     `json:"file_size"`
+	Mode         string    `json:"mode"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// DocumentSearchRequest for full-text search over document contents
+type DocumentSearchRequest struct {
+	Query    string `form:"q" binding:"required"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// DocumentSearchResult is a matched document with a highlighted excerpt
+type DocumentSearchResult struct {
+	DocumentResponse
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+// DocumentSearchResponse for search results
+type DocumentSearchResponse struct {
+	Data       []DocumentSearchResult `json:"data"`
+	TotalCount int                    `json:"total_count"`
+	Page       int                    `json:"page"`
+	PageSize   int                    `json:"page_size"`
+	TotalPages int                    `json:"total_pages"`
+}
+
+// DocumentRetentionPolicyRequest for creating or updating a retention policy.
+// Exactly one of EntityType and FolderID is set.
+type DocumentRetentionPolicyRequest struct {
+	Name          string `json:"name" binding:"required"`
+	EntityType    string `json:"entity_type"`
+	FolderID      *uint  `json:"folder_id"`
+	RetentionDays int    `json:"retention_days" binding:"required,min=1"`
+	Description   string `json:"description"`
+}
+
+// DocumentLegalHoldRequest for placing a legal hold on a document or folder.
+// Exactly one of DocumentID and FolderID is set.
+type DocumentLegalHoldRequest struct {
+	DocumentID *uint  `json:"document_id"`
+	FolderID   *uint  `json:"folder_id"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+// DocumentRetentionResponse explains how long a document is kept and what blocks its deletion
+type DocumentRetentionResponse struct {
+	DocumentID    uint                   `json:"document_id"`
+	PolicyID      *uint                  `json:"policy_id"` // Nil when no policy applies
+	PolicyName    string                 `json:"policy_name,omitempty"`
+	RetentionDays int                    `json:"retention_days,omitempty"`
+	RetainUntil   *time.Time             `json:"retain_until"`
+	LegalHolds    []DocumentLegalHoldRef `json:"legal_holds"`
+	Deletable     bool                   `json:"deletable"`
+}
+
+// DocumentLegalHoldRef identifies a legal hold that covers a document
+type DocumentLegalHoldRef struct {
+	ID        uint      `json:"id"`
+	FolderID  *uint     `json:"folder_id,omitempty"` // Set when the hold covers the document's folder
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentPurgeResponse reports the outcome of a purge run
+type DocumentPurgeResponse struct {
+	Purged int `json:"purged"`
+	Failed int `json:"failed"`
+}
@@ -0,0 +1,35 @@
+package ports
+
+import "context"
+
+// ExternalClaims holds what an identity provider asserts about a user
+type ExternalClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Groups        []string
+}
+
+// SSOPolicy controls who may log in through an identity provider and with which roles
+type SSOPolicy struct {
+	AllowedDomains  []string          // Email domains allowed to log in; empty allows all
+	AutoCreateUsers bool              // Create a user on their first login
+	GroupRoles      map[string]string // Provider group to role name
+}
+
+// IdentityProvider defines the interface for single sign-on identity providers
+type IdentityProvider interface {
+	// Name identifies the provider in routes and linked identities
+	Name() string
+
+	// Policy returns the login and role mapping rules for the provider
+	Policy() SSOPolicy
+
+	// AuthCodeURL returns the URL that starts an authorization code flow with PKCE
+	AuthCodeURL(state, nonce, codeVerifier string) string
+
+	// Exchange redeems an authorization code and returns the verified claims
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalClaims, error)
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/pkg/pagination"
 )
@@ -27,13 +28,15 @@ type AuditLogRepository interface {
 
 // AuditLogFilter represents filters for audit log queries
 type AuditLogFilter struct {
-	UserID     *uint
-	Action     domain.AuditAction
-	Resource   string
-	ResourceID *uint
-	IPAddress  string
-	DateFrom   string
-	DateTo     string
+	UserID         *uint
+	APITokenID     *uuid.UUID
+	ImpersonatorID *uuid.UUID
+	Action         domain.AuditAction
+	Resource       string
+	ResourceID     *uint
+	IPAddress      string
+	DateFrom       string
+	DateTo         string
 }
 
 // SystemSettingRepository defines the interface for system setting data operations
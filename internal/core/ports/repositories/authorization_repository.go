@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/pkg/pagination"
 )
@@ -29,6 +31,16 @@ type DepartmentRepository interface {
 	Update(ctx context.Context, department *domain.Department) error
 	Delete(ctx context.Context, id uint) error
 	ListActive(ctx context.Context) ([]domain.Department, error)
+
+	// Tree operations
+	Move(ctx context.Context, id uint, parentID *uint) error               // Reparents the department, carrying its subtree along
+	ListSubtree(ctx context.Context, id uint) ([]domain.Department, error) // The department and every department below it, ordered by path
+	RebuildPaths(ctx context.Context) error                                // Recomputes every materialized path from ParentID
+	ListManagedBy(ctx context.Context, managerID uuid.UUID) ([]domain.Department, error)
+	IsManagedBy(ctx context.Context, departmentID uint, managerID uuid.UUID) (bool, error) // Manager of the department or one above it
+	ManagesUser(ctx context.Context, managerID, userID uuid.UUID) (bool, error)            // The user belongs to a department the manager manages
+	NearestManager(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error)              // Closest manager above the user other than themselves; nil if there is none
+	SetUserDepartment(ctx context.Context, userID uuid.UUID, departmentID *uint) error
 }
 
 // ServiceRepository defines the interface for service data access
@@ -59,7 +71,7 @@ type EnhancedPermissionRepository interface {
 	Create(ctx context.Context, permission *domain.EnhancedPermission) error
 	GetByID(ctx context.Context, id uint) (*domain.EnhancedPermission, error)
 	GetByCode(ctx context.Context, code string) (*domain.EnhancedPermission, error)
-	List(ctx context.Context, params pagination.Params) (*pagination.Result[domain.EnhancedPermission], error)
+	List(ctx context.Context, filter PermissionFilter) ([]domain.EnhancedPermission, error)
 	ListByModule(ctx context.Context, moduleID uint) ([]domain.EnhancedPermission, error)
 	ListByDepartment(ctx context.Context, departmentID uint) ([]domain.EnhancedPermission, error)
 	ListByService(ctx context.Context, serviceID uint) ([]domain.EnhancedPermission, error)
@@ -72,9 +84,66 @@ type EnhancedPermissionRepository interface {
 	RemoveFromRole(ctx context.Context, roleID uint, permissionIDs []uint) error
 	GetRolePermissions(ctx context.Context, roleID uint) ([]domain.EnhancedPermission, error)
 
-	// User-Permission relationships (direct grants)
-	GrantToUser(ctx context.Context, userID uint, permissionID uint, grantedBy uint) error
-	RevokeFromUser(ctx context.Context, userID uint, permissionID uint, revokedBy uint) error
-	GetUserDirectPermissions(ctx context.Context, userID uint) ([]domain.EnhancedPermission, error)
-	GetUserAllPermissions(ctx context.Context, userID uint) ([]domain.EnhancedPermission, error) // From roles + direct
+	// Direct user grants
+	GrantToUser(ctx context.Context, grant *domain.UserEnhancedPermission) error // Creates or replaces the grant
+	RevokeFromUser(ctx context.Context, userID uuid.UUID, permissionID uint, revokedBy *uuid.UUID) error
+	GetUserGrant(ctx context.Context, userID uuid.UUID, permissionID uint) (*domain.UserEnhancedPermission, error)
+	ListUserGrants(ctx context.Context, userID uuid.UUID) ([]domain.UserEnhancedPermission, error) // Active grants only
+	ListExpiredUserGrants(ctx context.Context, before time.Time, limit int) ([]domain.UserEnhancedPermission, error)
+	ExpireUserGrant(ctx context.Context, grant *domain.UserEnhancedPermission) (bool, error) // False if the grant changed since it was read
+}
+
+// PolicyRepository defines the interface for attribute-based policy data access
+type PolicyRepository interface {
+	Create(ctx context.Context, policy *domain.Policy) error
+	GetByID(ctx context.Context, id uint) (*domain.Policy, error)
+	GetByName(ctx context.Context, name string) (*domain.Policy, error)
+	List(ctx context.Context, filter PolicyFilter) ([]domain.Policy, error)
+	Update(ctx context.Context, policy *domain.Policy) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// AccessRequestRepository defines the interface for access request data access
+type AccessRequestRepository interface {
+	Create(ctx context.Context, request *domain.AccessRequest) error
+	GetByID(ctx context.Context, id uint) (*domain.AccessRequest, error)
+	List(ctx context.Context, filter AccessRequestFilter, page *pagination.OffsetPagination) ([]*domain.AccessRequest, int64, error)
+	FindPending(ctx context.Context, requesterID uuid.UUID, roleID, permissionID *uint) (*domain.AccessRequest, error) // Nil if there is none
+	Decide(ctx context.Context, request *domain.AccessRequest) (bool, error)                                           // False if the request was no longer pending
+	Reopen(ctx context.Context, id uint) error                                                                         // Returns a decided request to pending, e.g. when its grant failed
+}
+
+// AccessRequestFilter represents filters for access request queries
+type AccessRequestFilter struct {
+	RequesterID *uuid.UUID
+	ApproverID  *uuid.UUID
+	Status      domain.AccessRequestStatus
+}
+
+// PolicyFilter represents filters for policy queries
+type PolicyFilter struct {
+	Permission string // Exact permission pattern
+	Effect     domain.PolicyEffect
+	IsActive   *bool
+}
+
+// PermissionFilter represents filters for permission queries. Module, department,
+// service and scope are matched by code.
+type PermissionFilter struct {
+	Module     string
+	Department string
+	Service    string
+	Scope      string
+	Resource   string
+	Action     string
+}
+
+// AuthorizationModelRepository reads and writes the authorization model as a whole
+type AuthorizationModelRepository interface {
+	// Export returns the current model, with entries in a stable order
+	Export(ctx context.Context) (*domain.AuthorizationModel, error)
+	// Apply makes the stored model match the given one in a single transaction: entries
+	// are created, updated or restored by code or name, and entries missing from the
+	// model are deleted, except system entries
+	Apply(ctx context.Context, model *domain.AuthorizationModel) error
 }
@@ -3,12 +3,20 @@ package repositories
 import (
 	"context"
 	"mime/multipart"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/dto"
 )
 
+// DocumentSearchHit is a document matched by full-text search
+type DocumentSearchHit struct {
+	Document domain.Document
+	Snippet  string
+	Rank     float64
+}
+
 type DocumentRepository interface {
 	CreateDocument(ctx context.Context, document *domain.Document) error
 	UpdateDocument(ctx context.Context, document *domain.Document) error
@@ -16,26 +24,93 @@ type DocumentRepository interface {
 	GetDocumentByID(ctx context.Context, id uint) (*domain.Document, error)
 	GetDocumentByCode(ctx context.Context, code string) (*domain.Document, error)
 	GetDocumentByPath(ctx context.Context, path string) (*domain.Document, error)
-	GetDocuments(ctx context.Context, filter dto.DocumentFilter) ([]domain.Document, int, int, error)
-	GetDocumentsByEntityID(ctx context.Context, entityType string, entityID uint) ([]domain.Document, error)
+	GetDocuments(ctx context.Context, filter dto.DocumentFilter, viewer *domain.AccessScope) ([]domain.Document, int, int, error)
+	GetDocumentsByEntityID(ctx context.Context, entityType string, entityID uint, viewer *domain.AccessScope) ([]domain.Document, error)
+	GetDocumentsByFolderID(ctx context.Context, folderID uint) ([]domain.Document, error)
+	MoveDocument(ctx context.Context, documentID uint, folderID *uint) error
+
+	// Folder related methods
+	CreateFolder(ctx context.Context, folder *domain.DocumentFolder) error
+	UpdateFolder(ctx context.Context, folder *domain.DocumentFolder) error
+	DeleteFolder(ctx context.Context, id uint) error
+	GetFolderByID(ctx context.Context, id uint) (*domain.DocumentFolder, error)
+	GetFolderChildren(ctx context.Context, parentID *uint) ([]domain.DocumentFolder, error)
+	GetFolderSubtree(ctx context.Context, folder *domain.DocumentFolder) ([]domain.DocumentFolder, error)
+	MoveFolder(ctx context.Context, folder *domain.DocumentFolder, parentID *uint, newPath string) error
+	CountFolderContents(ctx context.Context, folderID uint) (int64, error)
+	GetEntityRootFolder(ctx context.Context, entityType string, entityID uint) (*domain.DocumentFolder, error)
+
+	// Folder template related methods
+	CreateFolderTemplate(ctx context.Context, template *domain.DocumentFolderTemplate) error
+	DeleteFolderTemplate(ctx context.Context, id uint) error
+	GetFolderTemplates(ctx context.Context, entityType string) ([]domain.DocumentFolderTemplate, error)
+
+	// Tag related methods
+	FindOrCreateTag(ctx context.Context, name string, slug string) (*domain.Tag, error)
+	AddDocumentTags(ctx context.Context, documentID uint, tags []domain.Tag) error
+	RemoveDocumentTag(ctx context.Context, documentID uint, tagID uint) error
 
 	// Permission related methods
 	CreateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error
 	UpdateDocumentPermission(ctx context.Context, permission *domain.DocumentPermission) error
 	DeleteDocumentPermission(ctx context.Context, id uint) error
 	GetDocumentPermissions(ctx context.Context, documentID uint) ([]domain.DocumentPermission, error)
+	GetDocumentPermissionByID(ctx context.Context, id uint) (*domain.DocumentPermission, error)
+	GetFolderPermissions(ctx context.Context, folderID uint) ([]domain.DocumentPermission, error)
+	GetUserFolderPermission(ctx context.Context, folder *domain.DocumentFolder, userID uuid.UUID) (*domain.DocumentPermission, error)
 	GetUserDocumentPermission(ctx context.Context, documentID uint, userID uuid.UUID) (*domain.DocumentPermission, error)
+	GetUserDocumentPermissionLevels(ctx context.Context, documentIDs []uint, userID uuid.UUID) (map[uint]string, error)
+	GetApplicableDocumentPermissions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentPermission, error)
 	CheckUserPermission(ctx context.Context, documentID uint, userID uuid.UUID, requiredLevel string) (bool, error)
 
 	// Comment related methods
 	CreateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error
 	UpdateDocumentComment(ctx context.Context, comment *domain.DocumentComment) error
 	DeleteDocumentComment(ctx context.Context, id uint) error
-	GetDocumentComments(ctx context.Context, documentID uint) ([]domain.DocumentComment, error)
+	GetDocumentCommentByID(ctx context.Context, id uint) (*domain.DocumentComment, error)
+	GetDocumentComments(ctx context.Context, documentID uint, resolved *bool) ([]domain.DocumentComment, error)
+	GetUsersByIDsOrEmails(ctx context.Context, ids []uuid.UUID, emails []string) ([]domain.User, error)
+	IsUserInScope(ctx context.Context, scope *domain.AccessScope, userID uuid.UUID) (bool, error)
+
+	// Preview related methods
+	SaveDocumentPreview(ctx context.Context, preview *domain.DocumentPreview) error
+	GetDocumentPreview(ctx context.Context, documentID uint) (*domain.DocumentPreview, error)
+	MarkPreviewPending(ctx context.Context, documentID uint, sourcePath string) error
+	GetPendingPreviews(ctx context.Context, limit int) ([]uint, error)
+	SearchDocuments(ctx context.Context, query string, page, pageSize int, viewer *domain.AccessScope) ([]DocumentSearchHit, int, int, error)
+
+	// Share link related methods
+	CreateShareLink(ctx context.Context, link *domain.DocumentShareLink) error
+	GetShareLinkByID(ctx context.Context, id uint) (*domain.DocumentShareLink, error)
+	GetShareLinkByToken(ctx context.Context, token string) (*domain.DocumentShareLink, error)
+	GetActiveShareLinks(ctx context.Context, documentID *uint, createdBy *uuid.UUID) ([]domain.DocumentShareLink, error)
+	RevokeShareLink(ctx context.Context, id uint) error
+	IncrementShareLinkViews(ctx context.Context, id uint) error
+	IncrementShareLinkDownloads(ctx context.Context, id uint) (bool, error)
+
+	// Retention related methods
+	CreateRetentionPolicy(ctx context.Context, policy *domain.DocumentRetentionPolicy) error
+	UpdateRetentionPolicy(ctx context.Context, policy *domain.DocumentRetentionPolicy) error
+	DeleteRetentionPolicy(ctx context.Context, id uint) error
+	GetRetentionPolicyByID(ctx context.Context, id uint) (*domain.DocumentRetentionPolicy, error)
+	GetRetentionPolicies(ctx context.Context) ([]domain.DocumentRetentionPolicy, error)
+	GetRetentionCandidates(ctx context.Context, policy *domain.DocumentRetentionPolicy, cutoff time.Time, afterID uint, limit int) ([]domain.Document, error)
+	PurgeDocument(ctx context.Context, id uint) error
+
+	// Legal hold related methods
+	CreateLegalHold(ctx context.Context, hold *domain.DocumentLegalHold) error
+	UpdateLegalHold(ctx context.Context, hold *domain.DocumentLegalHold) error
+	GetLegalHoldByID(ctx context.Context, id uint) (*domain.DocumentLegalHold, error)
+	GetLegalHolds(ctx context.Context, activeOnly bool) ([]domain.DocumentLegalHold, error)
+	GetActiveLegalHolds(ctx context.Context, documentID uint, folderIDs []uint) ([]domain.DocumentLegalHold, error)
+	FolderHasRetentionRules(ctx context.Context, folderID uint) (bool, error)
 
 	// Version related methods
 	CreateDocumentVersion(ctx context.Context, version *domain.DocumentVersion) error
+	CreateCurrentDocumentVersion(ctx context.Context, version *domain.DocumentVersion) error
 	GetDocumentVersions(ctx context.Context, documentID uint) ([]domain.DocumentVersion, error)
+	GetDocumentVersion(ctx context.Context, documentID uint, versionNumber int) (*domain.DocumentVersion, error)
+	GetLatestVersionNumber(ctx context.Context, documentID uint) (int, error)
 }
 type DocumentUsecase interface {
 	UploadDocument(ctx context.Context, file *multipart.FileHeader, uploadRequest dto.DocumentUploadRequest, userID uuid.UUID) (*domain.Document, error)
@@ -53,13 +128,64 @@ type DocumentUsecase interface {
 	RemoveDocumentPermission(ctx context.Context, id uint, userID uuid.UUID) error
 	GetDocumentPermissions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentPermission, error)
 	CheckUserPermission(ctx context.Context, documentID uint, userID uuid.UUID, requiredLevel string) (bool, error)
+	GetEffectiveDocumentPermission(ctx context.Context, documentID uint, targetUserID uuid.UUID, userID uuid.UUID) (*dto.EffectiveDocumentPermissionResponse, error)
 
 	// Document comments
 	AddDocumentComment(ctx context.Context, request dto.DocumentCommentRequest, userID uuid.UUID) (*domain.DocumentComment, error)
-	UpdateDocumentComment(ctx context.Context, id uint, comment string, userID uuid.UUID) (*domain.DocumentComment, error)
+	UpdateDocumentComment(ctx context.Context, id uint, request dto.DocumentCommentUpdateRequest, userID uuid.UUID) (*domain.DocumentComment, error)
 	DeleteDocumentComment(ctx context.Context, id uint, userID uuid.UUID) error
-	GetDocumentComments(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentComment, error)
+	GetDocumentComments(ctx context.Context, documentID uint, resolved *bool, userID uuid.UUID) ([]domain.DocumentComment, error)
+	SetCommentResolved(ctx context.Context, id uint, resolved bool, userID uuid.UUID) (*domain.DocumentComment, error)
 
 	// Document versions
 	GetDocumentVersions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentVersion, error)
+	UploadDocumentVersion(ctx context.Context, documentID uint, file *multipart.FileHeader, request dto.DocumentVersionRequest, userID uuid.UUID) (*domain.DocumentVersion, error)
+	DownloadDocumentVersion(ctx context.Context, documentID uint, versionNumber int, userID uuid.UUID) ([]byte, string, string, error)
+	RestoreDocumentVersion(ctx context.Context, documentID uint, versionNumber int, request dto.DocumentVersionRequest, userID uuid.UUID) (*domain.DocumentVersion, error)
+
+	// Folders and workspaces
+	CreateFolder(ctx context.Context, request dto.DocumentFolderRequest, userID uuid.UUID) (*domain.DocumentFolder, error)
+	RenameFolder(ctx context.Context, id uint, name string, userID uuid.UUID) (*domain.DocumentFolder, error)
+	MoveFolder(ctx context.Context, id uint, parentID *uint, userID uuid.UUID) (*domain.DocumentFolder, error)
+	DeleteFolder(ctx context.Context, id uint, userID uuid.UUID) error
+	GetFolders(ctx context.Context, userID uuid.UUID) ([]dto.DocumentFolderResponse, error)
+	GetFolderContents(ctx context.Context, id uint, userID uuid.UUID) (*dto.DocumentFolderContentsResponse, error)
+	MoveDocument(ctx context.Context, id uint, folderID *uint, userID uuid.UUID) error
+	AddFolderPermission(ctx context.Context, folderID uint, request dto.DocumentFolderPermissionRequest, userID uuid.UUID) error
+	GetFolderPermissions(ctx context.Context, folderID uint, userID uuid.UUID) ([]domain.DocumentPermission, error)
+	InitializeEntityWorkspace(ctx context.Context, entityType string, entityID uint) (*domain.DocumentFolder, error)
+	GetEntityWorkspace(ctx context.Context, entityType string, entityID uint, userID uuid.UUID) (*dto.EntityWorkspaceResponse, error)
+
+	// Previews and search
+	SearchDocuments(ctx context.Context, request dto.DocumentSearchRequest, userID uuid.UUID) (*dto.DocumentSearchResponse, error)
+	GetDocumentThumbnail(ctx context.Context, id uint, userID uuid.UUID) ([]byte, error)
+
+	// Share links
+	CreateShareLink(ctx context.Context, documentID uint, request dto.DocumentShareLinkRequest, userID uuid.UUID) (*dto.DocumentShareLinkResponse, error)
+	GetDocumentShareLinks(ctx context.Context, documentID uint, userID uuid.UUID) ([]dto.DocumentShareLinkResponse, error)
+	GetMyShareLinks(ctx context.Context, userID uuid.UUID) ([]dto.DocumentShareLinkResponse, error)
+	RevokeShareLink(ctx context.Context, id uint, userID uuid.UUID) error
+	GetSharedDocument(ctx context.Context, access dto.ShareLinkAccessRequest) (*dto.SharedDocumentResponse, error)
+	DownloadSharedDocument(ctx context.Context, access dto.ShareLinkAccessRequest, download bool) ([]byte, string, string, error)
+
+	// Check-out locking
+	CheckOutDocument(ctx context.Context, id uint, userID uuid.UUID) (*domain.DocumentLock, error)
+	CheckInDocument(ctx context.Context, id uint, userID uuid.UUID) error
+	ForceUnlockDocument(ctx context.Context, id uint, adminID uuid.UUID) error
+	GetDocumentLock(ctx context.Context, id uint, userID uuid.UUID) (*domain.DocumentLock, error)
+
+	// Retention and legal hold
+	CreateRetentionPolicy(ctx context.Context, request dto.DocumentRetentionPolicyRequest, userID uuid.UUID) (*domain.DocumentRetentionPolicy, error)
+	UpdateRetentionPolicy(ctx context.Context, id uint, request dto.DocumentRetentionPolicyRequest) (*domain.DocumentRetentionPolicy, error)
+	DeleteRetentionPolicy(ctx context.Context, id uint) error
+	GetRetentionPolicies(ctx context.Context) ([]domain.DocumentRetentionPolicy, error)
+	PlaceLegalHold(ctx context.Context, request dto.DocumentLegalHoldRequest, userID uuid.UUID) (*domain.DocumentLegalHold, error)
+	ReleaseLegalHold(ctx context.Context, id uint, userID uuid.UUID) (*domain.DocumentLegalHold, error)
+	GetLegalHolds(ctx context.Context, activeOnly bool) ([]domain.DocumentLegalHold, error)
+	GetDocumentRetention(ctx context.Context, id uint, userID uuid.UUID) (*dto.DocumentRetentionResponse, error)
+	PurgeExpiredDocuments(ctx context.Context) (*dto.DocumentPurgeResponse, error)
+
+	// Document tags
+	AddDocumentTags(ctx context.Context, documentID uint, names []string, userID uuid.UUID) ([]domain.Tag, error)
+	RemoveDocumentTag(ctx context.Context, documentID uint, tagID uint, userID uuid.UUID) error
 }
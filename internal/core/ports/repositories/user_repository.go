@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
@@ -23,19 +24,20 @@ type UserRepository interface {
 
 	// Role operations
 	AssignRole(ctx context.Context, userID uuid.UUID, roleID uint) error
+	GrantRole(ctx context.Context, grant *domain.UserRole) error // Creates or replaces the grant, including its expiry
 	RemoveRole(ctx context.Context, userID uuid.UUID, roleID uint) error
-	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*domain.Role, error)
-
-	// Permission operations
-	AssignPermission(ctx context.Context, userID uuid.UUID, permissionID uint) error
-	RemovePermission(ctx context.Context, userID uuid.UUID, permissionID uint) error
-	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]*domain.Permission, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*domain.Role, error) // Unexpired grants only
+	ListRoleGrants(ctx context.Context, userID uuid.UUID) ([]*domain.UserRole, error)
+	ListExpiredRoleGrants(ctx context.Context, before time.Time, limit int) ([]*domain.UserRole, error)
+	RemoveExpiredRoleGrant(ctx context.Context, grant *domain.UserRole) (bool, error) // False if the grant changed since it was read
 
 	// Authentication
 	UpdateLastLogin(ctx context.Context, userID uuid.UUID, ip string) error
 	UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
 	Enable2FA(ctx context.Context, userID uuid.UUID, secret string) error
 	Disable2FA(ctx context.Context, userID uuid.UUID) error
+	LockAccount(ctx context.Context, userID uuid.UUID, until time.Time) error
+	UnlockAccount(ctx context.Context, userID uuid.UUID) error
 
 	// Status
 	UpdateStatus(ctx context.Context, userID uuid.UUID, status domain.UserStatus) error
@@ -49,6 +51,12 @@ type UserFilter struct {
 	Search string // Search in email, first_name, last_name
 	RoleID *uint
 	IDs    []uuid.UUID
+	Locked bool // Only accounts currently locked out
+
+	DepartmentID          *uint
+	IncludeSubDepartments bool // Also members of departments below DepartmentID
+
+	ServiceAccount *bool // Only service accounts, or only people
 }
 
 // CustomerRepository defines the interface for customer data operations
@@ -82,6 +90,7 @@ type CustomerFilter struct {
 	AssignedTo *uuid.UUID
 	Source     string
 	IDs        []uint
+	Scope      *domain.AccessScope // Limits results to customers assigned within the scope
 }
 
 // RoleRepository defines the interface for role data operations
@@ -98,13 +107,9 @@ type RoleRepository interface {
 	GetHierarchy(ctx context.Context) ([]*domain.Role, error)
 	GetChildren(ctx context.Context, parentID uint) ([]*domain.Role, error)
 
-	// Permission operations
-	AssignPermission(ctx context.Context, roleID, permissionID uint) error
-	RemovePermission(ctx context.Context, roleID, permissionID uint) error
-	GetRolePermissions(ctx context.Context, roleID uint) ([]*domain.Permission, error)
-
 	// User operations
 	GetRoleUsers(ctx context.Context, roleID uint) ([]*domain.User, error)
+	GetRoleUserIDs(ctx context.Context, roleIDs []uint) ([]uuid.UUID, error) // Users holding any of the roles
 }
 
 // RoleFilter represents filters for role queries
@@ -115,33 +120,6 @@ type RoleFilter struct {
 	IsSystem *bool
 }
 
-// PermissionRepository defines the interface for permission data operations
-type PermissionRepository interface {
-	// Basic CRUD
-	Create(ctx context.Context, permission *domain.Permission) error
-	GetByID(ctx context.Context, id uint) (*domain.Permission, error)
-	Update(ctx context.Context, permission *domain.Permission) error
-	Delete(ctx context.Context, id uint) error
-
-	// List operations
-	List(ctx context.Context, filter PermissionFilter) ([]*domain.Permission, error)
-	GetByKey(ctx context.Context, key string) (*domain.Permission, error)
-
-	// Module/Department/Service operations
-	GetByModule(ctx context.Context, module string) ([]*domain.Permission, error)
-	GetByDepartment(ctx context.Context, department string) ([]*domain.Permission, error)
-	GetByService(ctx context.Context, service string) ([]*domain.Permission, error)
-}
-
-// PermissionFilter represents filters for permission queries
-type PermissionFilter struct {
-	Module     string
-	Department string
-	Service    string
-	Resource   string
-	Action     string
-}
-
 // OTPRepository defines the interface for OTP data operations
 type OTPRepository interface {
 	Create(ctx context.Context, otp *domain.OTP) error
@@ -158,5 +136,58 @@ type RefreshTokenRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.RefreshToken, error)
 	Revoke(ctx context.Context, token string) error
 	RevokeAllByUserID(ctx context.Context, userID uuid.UUID) error
+	RevokeBySessionID(ctx context.Context, sessionID uuid.UUID) error
+	Rotate(ctx context.Context, token string) (bool, error)
 	DeleteExpired(ctx context.Context) error
 }
+
+// UserSessionRepository defines the interface for device session data operations
+type UserSessionRepository interface {
+	Create(ctx context.Context, session *domain.UserSession) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.UserSession, error)
+	ListActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.UserSession, error)
+	Touch(ctx context.Context, id uuid.UUID, ipAddress, userAgent string, expiresAt time.Time) error
+	Revoke(ctx context.Context, id uuid.UUID, reason string) error
+	RevokeAllByUserID(ctx context.Context, userID uuid.UUID, reason string) ([]uuid.UUID, error)
+}
+
+// RecoveryCodeRepository defines the interface for 2FA recovery code operations
+type RecoveryCodeRepository interface {
+	ReplaceForUser(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	Use(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CountUnused(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
+}
+
+// WebAuthnCredentialRepository defines the interface for WebAuthn credential operations
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, credential *domain.WebAuthnCredential) error
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error)
+	RecordUse(ctx context.Context, id uuid.UUID, signCount uint32, cloneWarning, backupState bool) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// ExternalIdentityRepository defines the interface for single sign-on identity links
+type ExternalIdentityRepository interface {
+	Create(ctx context.Context, identity *domain.ExternalIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.ExternalIdentity, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.ExternalIdentity, error)
+	RecordLogin(ctx context.Context, id uuid.UUID, email string) error
+}
+
+// SigningKeyRepository defines the interface for token signing key operations
+type SigningKeyRepository interface {
+	ListUnexpired(ctx context.Context) ([]*domain.SigningKey, error)
+	Rotate(ctx context.Context, key *domain.SigningKey, retireAt, expireAt time.Time) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// APITokenRepository defines the interface for personal access token and API key operations
+type APITokenRepository interface {
+	Create(ctx context.Context, token *domain.APIToken) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.APIToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*domain.APIToken, error) // Preloads the owner
+	ListByUserID(ctx context.Context, userID uuid.UUID, tokenType domain.APITokenType) ([]*domain.APIToken, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	RecordUse(ctx context.Context, id uuid.UUID, ip string) error
+}
@@ -0,0 +1,325 @@
+package apitoken
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"github.com/owner/go-cms/pkg/pagination"
+	"github.com/owner/go-cms/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// tokenLength is the number of random characters after the prefix
+	tokenLength = 40
+	// displayPrefixLength is how much of the token is kept to recognize it
+	displayPrefixLength = 12
+	// lastUsedInterval limits how often last-used tracking writes to the database
+	lastUsedInterval = time.Minute
+)
+
+// PermissionLister lists the permission codes a user holds
+type PermissionLister interface {
+	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+// CreateTokenRequest describes a new personal access token or service account key
+type CreateTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"` // Defaults to the configured lifetime
+}
+
+// CreateServiceAccountRequest describes a new service account
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=100"`
+}
+
+// CreatedToken is returned once, when a token is created. The token itself cannot
+// be retrieved again.
+type CreatedToken struct {
+	*domain.APIToken
+	Token string `json:"token"`
+}
+
+// UseCase handles personal access token and service account business logic
+type UseCase struct {
+	tokenRepo   repositories.APITokenRepository
+	userRepo    repositories.UserRepository
+	permissions PermissionLister
+	config      *config.APITokenConfig
+}
+
+// NewUseCase creates a new API token use case
+func NewUseCase(
+	tokenRepo repositories.APITokenRepository,
+	userRepo repositories.UserRepository,
+	permissions PermissionLister,
+	cfg *config.APITokenConfig,
+) *UseCase {
+	return &UseCase{
+		tokenRepo:   tokenRepo,
+		userRepo:    userRepo,
+		permissions: permissions,
+		config:      cfg,
+	}
+}
+
+// CreatePersonalToken creates a token that acts as the user, limited to its scopes
+func (uc *UseCase) CreatePersonalToken(ctx context.Context, userID uuid.UUID, req CreateTokenRequest) (*CreatedToken, error) {
+	return uc.createToken(ctx, userID, domain.APITokenTypePersonal, req, userID)
+}
+
+// ListPersonalTokens lists the user's personal access tokens
+func (uc *UseCase) ListPersonalTokens(ctx context.Context, userID uuid.UUID) ([]*domain.APIToken, error) {
+	return uc.tokenRepo.ListByUserID(ctx, userID, domain.APITokenTypePersonal)
+}
+
+// RevokePersonalToken revokes one of the user's personal access tokens
+func (uc *UseCase) RevokePersonalToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	return uc.revokeToken(ctx, userID, domain.APITokenTypePersonal, tokenID)
+}
+
+// CreateServiceAccount creates a user that cannot log in and only authenticates
+// with API keys. Its permissions come from the roles assigned to it.
+func (uc *UseCase) CreateServiceAccount(ctx context.Context, req CreateServiceAccountRequest) (*domain.User, error) {
+	slug := utils.GenerateSlug(req.Name)
+	if slug == "" {
+		return nil, errors.New(errors.ErrCodeValidation, "name must contain letters or digits", 400)
+	}
+
+	suffix, err := utils.GenerateRandomString(8)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate service account email", 500)
+	}
+
+	// The password is never handed out; login is refused for service accounts anyway
+	password, err := utils.GenerateRandomString(48)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate password", 500)
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to hash password", 500)
+	}
+
+	user := &domain.User{
+		Email:            fmt.Sprintf("%s.%s@service.invalid", slug, strings.ToLower(suffix)),
+		Password:         hashedPassword,
+		FirstName:        req.Name,
+		Position:         req.Description,
+		Status:           domain.UserStatusActive,
+		IsServiceAccount: true,
+	}
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		logger.Error("Failed to create service account", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Service account created", zap.String("id", user.ID.String()), zap.String("name", req.Name))
+	return user, nil
+}
+
+// ListServiceAccounts lists service accounts
+func (uc *UseCase) ListServiceAccounts(ctx context.Context, page *pagination.OffsetPagination) ([]*domain.User, int64, error) {
+	serviceAccount := true
+	return uc.userRepo.List(ctx, repositories.UserFilter{ServiceAccount: &serviceAccount}, page)
+}
+
+// CreateServiceAccountKey creates an API key for a service account, limited to its scopes
+func (uc *UseCase) CreateServiceAccountKey(ctx context.Context, accountID uuid.UUID, req CreateTokenRequest, createdBy uuid.UUID) (*CreatedToken, error) {
+	if _, err := uc.getServiceAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return uc.createToken(ctx, accountID, domain.APITokenTypeService, req, createdBy)
+}
+
+// ListServiceAccountKeys lists a service account's API keys
+func (uc *UseCase) ListServiceAccountKeys(ctx context.Context, accountID uuid.UUID) ([]*domain.APIToken, error) {
+	if _, err := uc.getServiceAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return uc.tokenRepo.ListByUserID(ctx, accountID, domain.APITokenTypeService)
+}
+
+// RevokeServiceAccountKey revokes one of a service account's API keys
+func (uc *UseCase) RevokeServiceAccountKey(ctx context.Context, accountID, keyID uuid.UUID) error {
+	return uc.revokeToken(ctx, accountID, domain.APITokenTypeService, keyID)
+}
+
+// AuthenticateAPIToken returns the active token matching the presented value. The
+// owner must still be active and not locked out.
+func (uc *UseCase) AuthenticateAPIToken(ctx context.Context, value, ip string) (*domain.APIToken, error) {
+	if !strings.HasPrefix(value, domain.APITokenPrefix) {
+		return nil, errors.ErrInvalidToken
+	}
+
+	token, err := uc.tokenRepo.GetByHash(ctx, hashToken(value))
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, errors.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if !token.IsActive() {
+		return nil, errors.ErrInvalidToken
+	}
+
+	// The owner is not loaded when it has been deleted
+	owner := token.User
+	if owner.ID == uuid.Nil || owner.Status != domain.UserStatusActive || owner.IsLocked() {
+		return nil, errors.ErrInvalidToken
+	}
+
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > lastUsedInterval {
+		go func(id uuid.UUID) {
+			if err := uc.tokenRepo.RecordUse(context.Background(), id, ip); err != nil {
+				logger.Warn("Failed to record API token use", zap.String("token_id", id.String()), zap.Error(err))
+			}
+		}(token.ID)
+	}
+
+	return token, nil
+}
+
+// createToken generates a token for the owner after checking its scopes and expiry
+func (uc *UseCase) createToken(ctx context.Context, ownerID uuid.UUID, tokenType domain.APITokenType, req CreateTokenRequest, createdBy uuid.UUID) (*CreatedToken, error) {
+	scopes, err := uc.validateScopes(ctx, ownerID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := uc.expiry(req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	random, err := utils.GenerateRandomString(tokenLength)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate API token", 500)
+	}
+	value := domain.APITokenPrefix + random
+
+	token := &domain.APIToken{
+		UserID:    ownerID,
+		Name:      req.Name,
+		Type:      tokenType,
+		Prefix:    value[:displayPrefixLength],
+		TokenHash: hashToken(value),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedBy: createdBy,
+	}
+
+	if err := uc.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	logger.Info("API token created",
+		zap.String("token_id", token.ID.String()),
+		zap.String("user_id", ownerID.String()),
+		zap.String("type", string(tokenType)),
+	)
+
+	return &CreatedToken{APIToken: token, Token: value}, nil
+}
+
+// revokeToken revokes a token after checking that it belongs to the owner
+func (uc *UseCase) revokeToken(ctx context.Context, ownerID uuid.UUID, tokenType domain.APITokenType, tokenID uuid.UUID) error {
+	token, err := uc.tokenRepo.GetByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+
+	if token.UserID != ownerID || token.Type != tokenType {
+		return errors.ErrNotFound
+	}
+
+	if err := uc.tokenRepo.Revoke(ctx, tokenID); err != nil {
+		return err
+	}
+
+	logger.Info("API token revoked", zap.String("token_id", tokenID.String()), zap.String("user_id", ownerID.String()))
+	return nil
+}
+
+// validateScopes checks that every scope is a permission the owner holds
+func (uc *UseCase) validateScopes(ctx context.Context, ownerID uuid.UUID, scopes []string) ([]string, error) {
+	scopes = utils.Unique(scopes)
+	if len(scopes) == 0 {
+		return nil, errors.New(errors.ErrCodeValidation, "at least one scope is required", 400)
+	}
+
+	held, err := uc.permissions.GetUserPermissions(ctx, ownerID)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to get permissions", 500)
+	}
+
+	var missing []string
+	for _, scope := range scopes {
+		if !utils.Contains(held, scope) {
+			missing = append(missing, scope)
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, errors.New(errors.ErrCodeValidation,
+			fmt.Sprintf("scopes must be permissions the account holds: %s", strings.Join(missing, ", ")), 400)
+	}
+
+	return scopes, nil
+}
+
+// expiry applies the default lifetime and enforces the maximum
+func (uc *UseCase) expiry(requested *time.Time) (time.Time, error) {
+	now := time.Now()
+	if requested == nil {
+		return now.Add(uc.config.DefaultLifetime), nil
+	}
+
+	if !requested.After(now) {
+		return time.Time{}, errors.New(errors.ErrCodeValidation, "expires_at must be in the future", 400)
+	}
+
+	if requested.After(now.Add(uc.config.MaxLifetime)) {
+		return time.Time{}, errors.New(errors.ErrCodeValidation,
+			fmt.Sprintf("expires_at cannot be more than %s from now", uc.config.MaxLifetime), 400)
+	}
+
+	return *requested, nil
+}
+
+// getServiceAccount gets a user, treating people as not found
+func (uc *UseCase) getServiceAccount(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsServiceAccount {
+		return nil, errors.New(errors.ErrCodeNotFound, "Service account not found", 404)
+	}
+
+	return user, nil
+}
+
+// hashToken hashes a token for storage. Tokens are long and random, so a fast
+// unsalted hash is enough and allows lookup by hash.
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
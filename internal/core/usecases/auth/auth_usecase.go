@@ -3,11 +3,16 @@ package auth
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/config"
 	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
@@ -26,8 +31,13 @@ type UseCase interface {
 
 	// Login
 	Login(ctx context.Context, req LoginRequest) (*AuthResponse, error)
-	Logout(ctx context.Context, userID uuid.UUID, token string) error
-	RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error)
+	Logout(ctx context.Context, userID uuid.UUID, refreshToken string, accessToken AccessToken) error
+	RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*AuthResponse, error)
+
+	// Sessions
+	ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID) error
 
 	// Password Management
 	ForgotPassword(ctx context.Context, email string) error
@@ -36,8 +46,23 @@ type UseCase interface {
 
 	// 2FA
 	Enable2FA(ctx context.Context, userID uuid.UUID) (*Enable2FAResponse, error)
-	Verify2FA(ctx context.Context, userID uuid.UUID, code string) error
+	Verify2FA(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
 	Disable2FA(ctx context.Context, userID uuid.UUID, password string) error
+	CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest) (*AuthResponse, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password string) ([]string, error)
+	GetRecoveryCodeStatus(ctx context.Context, userID uuid.UUID) (*RecoveryCodeStatus, error)
+	BeginWebAuthnRegistration(ctx context.Context, userID uuid.UUID) (*protocol.CredentialCreation, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID uuid.UUID, name string, response []byte) (*WebAuthnRegistrationResponse, error)
+	ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID, credentialID uuid.UUID, password string) error
+
+	// Single sign-on
+	ListSSOProviders() []string
+	BeginSSOLogin(ctx context.Context, provider string) (*SSOLoginResponse, error)
+	CompleteSSOLogin(ctx context.Context, req SSOCallbackRequest) (*AuthResponse, error)
+
+	// Impersonation
+	Impersonate(ctx context.Context, impersonatorID, userID uuid.UUID) (*ImpersonationResponse, error)
 
 	// User Info
 	GetCurrentUser(ctx context.Context, userID uuid.UUID) (*domain.User, error)
@@ -49,8 +74,24 @@ type useCase struct {
 	userRepo         repositories.UserRepository
 	otpRepo          repositories.OTPRepository
 	refreshTokenRepo repositories.RefreshTokenRepository
+	sessionRepo      repositories.UserSessionRepository
+	recoveryCodeRepo repositories.RecoveryCodeRepository
+	webAuthnRepo     repositories.WebAuthnCredentialRepository
+	identityRepo     repositories.ExternalIdentityRepository
+	roleRepo         repositories.RoleRepository
+	permissions      PermissionScopeLister
 	config           *config.Config
 	emailService     EmailService
+	tokenSigner      utils.TokenSigner
+	webAuthn         *webauthn.WebAuthn // Nil when the relying party is not configured
+
+	identityProviders map[string]ports.IdentityProvider
+}
+
+// PermissionScopeLister lists the permissions a user holds with the broadest scope each
+// is held at
+type PermissionScopeLister interface {
+	GetUserPermissionScopes(ctx context.Context, userID uuid.UUID) (map[string]domain.ScopeLevel, error)
 }
 
 // EmailService defines the interface for email operations
@@ -58,6 +99,7 @@ type EmailService interface {
 	SendVerifyEmailOTP(to, name, otp string, expirySeconds int) error
 	SendResetPasswordOTP(to, name, otp string, expirySeconds int) error
 	SendWelcomeEmail(to, name string) error
+	SendAccountLockedEmail(to, name string, lockedUntil time.Time, ipAddress string) error
 }
 
 // NewUseCase creates a new authentication use case
@@ -65,15 +107,48 @@ func NewUseCase(
 	userRepo repositories.UserRepository,
 	otpRepo repositories.OTPRepository,
 	refreshTokenRepo repositories.RefreshTokenRepository,
+	sessionRepo repositories.UserSessionRepository,
+	recoveryCodeRepo repositories.RecoveryCodeRepository,
+	webAuthnRepo repositories.WebAuthnCredentialRepository,
+	identityRepo repositories.ExternalIdentityRepository,
+	roleRepo repositories.RoleRepository,
+	permissions PermissionScopeLister,
 	config *config.Config,
 	emailService EmailService,
+	tokenSigner utils.TokenSigner,
+	identityProviders []ports.IdentityProvider,
 ) UseCase {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          config.TwoFA.WebAuthnRPID,
+		RPDisplayName: config.TwoFA.Issuer,
+		RPOrigins:     config.TwoFA.WebAuthnRPOrigins,
+	})
+	if err != nil {
+		logger.Warn("Security keys disabled: invalid WebAuthn configuration", zap.Error(err))
+		webAuthn = nil
+	}
+
+	providers := make(map[string]ports.IdentityProvider, len(identityProviders))
+	for _, provider := range identityProviders {
+		providers[provider.Name()] = provider
+	}
+
 	return &useCase{
 		userRepo:         userRepo,
 		otpRepo:          otpRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		webAuthnRepo:     webAuthnRepo,
+		identityRepo:     identityRepo,
+		roleRepo:         roleRepo,
+		permissions:      permissions,
 		config:           config,
 		emailService:     emailService,
+		tokenSigner:      tokenSigner,
+		webAuthn:         webAuthn,
+
+		identityProviders: providers,
 	}
 }
 
@@ -84,13 +159,41 @@ type RegisterRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Phone     string `json:"phone"`
+
+	Client ClientInfo `json:"-"`
 }
 
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
-	Code2FA  string `json:"code_2fa"` // Optional 2FA code
+	Code2FA  string `json:"code_2fa"` // Optional TOTP code; other factors use CompleteTwoFactorLogin
+
+	Client ClientInfo `json:"-"`
+}
+
+// ClientInfo describes the device a request comes from
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// AccessToken identifies the access token a request was made with
+type AccessToken struct {
+	ID        string
+	SessionID uuid.UUID
+	ExpiresAt time.Time
+}
+
+// SessionResponse represents a signed-in device
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
 // AuthResponse represents an authentication response
@@ -100,6 +203,19 @@ type AuthResponse struct {
 	ExpiresIn    int64        `json:"expires_in"`
 	User         *domain.User `json:"user"`
 	Requires2FA  bool         `json:"requires_2fa,omitempty"`
+
+	// Set when Requires2FA is; the token completes the login with one of the methods
+	TwoFactorToken   string                        `json:"two_factor_token,omitempty"`
+	TwoFactorMethods []string                      `json:"two_factor_methods,omitempty"`
+	WebAuthn         *protocol.CredentialAssertion `json:"webauthn,omitempty"`
+}
+
+// ImpersonationResponse represents a token that lets an admin act as a user
+type ImpersonationResponse struct {
+	AccessToken    string       `json:"access_token"`
+	ExpiresIn      int64        `json:"expires_in"`
+	User           *domain.User `json:"user"` // The impersonated user
+	ImpersonatorID uuid.UUID    `json:"impersonator_id"`
 }
 
 // Enable2FAResponse represents a 2FA enable response
@@ -181,38 +297,13 @@ func (uc *useCase) Register(ctx context.Context, req RegisterRequest) (*AuthResp
 
 	logger.Info("User registered successfully", zap.String("email", req.Email), zap.String("otp", otpCode))
 
-	// Generate tokens
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, &uc.config.JWT)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate access token", 500)
-	}
-
-	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.Email, &uc.config.JWT)
+	// Start a session for this device
+	session, err := uc.startSession(ctx, user.ID, req.Client)
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate refresh token", 500)
-	}
-
-	// Save refresh token
-	refreshTokenModel := &domain.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(uc.config.JWT.RefreshTokenExpire),
-	}
-
-	if err := uc.refreshTokenRepo.Create(ctx, refreshTokenModel); err != nil {
-		logger.Error("Failed to save refresh token", zap.Error(err))
+		return nil, err
 	}
 
-	// Hide sensitive data
-	user.Password = ""
-	user.TwoFactorSecret = ""
-
-	return &AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(uc.config.JWT.AccessTokenExpire.Seconds()),
-		User:         user,
-	}, nil
+	return uc.issueTokens(ctx, user, session.ID)
 }
 
 // VerifyEmail verifies a user's email with OTP
@@ -332,89 +423,111 @@ func (uc *useCase) ResendOTP(ctx context.Context, email string) error {
 
 // Login authenticates a user
 func (uc *useCase) Login(ctx context.Context, req LoginRequest) (*AuthResponse, error) {
+	accountKey := cache.LoginAccountAttemptKey(req.Email)
+	var ipKey string
+	if req.Client.IPAddress != "" {
+		ipKey = cache.LoginIPAttemptKey(req.Client.IPAddress)
+	}
+
+	// Refuse attempts while failed ones are being delayed
+	if err := uc.checkAttemptDelay(ctx, accountKey, ipKey); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := uc.userRepo.GetByEmail(ctx, req.Email)
-	if err != nil {
-		return nil, errors.ErrInvalidCredentials
+	if err != nil || user.IsServiceAccount {
+		// Unknown emails count too, so they behave like existing accounts. Service
+		// accounts only authenticate with API keys.
+		return nil, uc.recordFailure(ctx, accountKey, nil, req.Client, errors.ErrInvalidCredentials)
 	}
 
-	// Check password
+	// Check password. Until the right one is given a locked account answers like any
+	// other, so neither the lock nor the failure that sets it reveals the account exists.
 	if !utils.CheckPassword(user.Password, req.Password) {
+		lockable := user
+		if user.IsLocked() {
+			lockable = nil
+		}
+		_ = uc.recordFailure(ctx, accountKey, lockable, req.Client, errors.ErrInvalidCredentials)
 		return nil, errors.ErrInvalidCredentials
 	}
 
+	if user.IsLocked() {
+		return nil, accountLockedError(*user.LockedUntil)
+	}
+
 	// Check user status
 	if user.Status != domain.UserStatusActive {
 		return nil, errors.New("USER_INACTIVE", fmt.Sprintf("User account is %s", user.Status), 403)
 	}
 
 	// Check 2FA
-	if user.TwoFactorEnabled {
+	methods, credentials, err := uc.twoFactorMethods(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(methods) > 0 {
 		if req.Code2FA == "" {
-			return &AuthResponse{
-				Requires2FA: true,
-			}, nil
+			uc.clearFailedAttempts(ctx, accountKey)
+			return uc.beginTwoFactorLogin(ctx, user, methods, credentials)
+		}
+
+		twoFactorKey := cache.TwoFactorAttemptKey(user.ID)
+		if err := uc.checkAttemptDelay(ctx, twoFactorKey); err != nil {
+			return nil, err
 		}
 
 		// Verify 2FA code
-		valid := totp.Validate(req.Code2FA, user.TwoFactorSecret)
+		valid := user.TwoFactorEnabled && totp.Validate(req.Code2FA, user.TwoFactorSecret)
 		if !valid {
-			return nil, errors.ErrInvalid2FA
+			return nil, uc.recordFailure(ctx, twoFactorKey, user, req.Client, errors.ErrInvalid2FA)
 		}
 	}
 
-	// Generate tokens
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, &uc.config.JWT)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate access token", 500)
-	}
+	uc.clearFailedAttempts(ctx, accountKey, cache.TwoFactorAttemptKey(user.ID))
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.Email, &uc.config.JWT)
-	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate refresh token", 500)
-	}
+	return uc.completeLogin(ctx, user, req.Client)
+}
 
-	// Save refresh token
-	refreshTokenModel := &domain.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(uc.config.JWT.RefreshTokenExpire),
+// Logout logs out a user, ending the session of the access token and of the refresh token
+func (uc *useCase) Logout(ctx context.Context, userID uuid.UUID, refreshToken string, accessToken AccessToken) error {
+	// Revoke the session of the refresh token, or the token itself if it predates sessions
+	if refreshToken != "" {
+		tokenModel, err := uc.refreshTokenRepo.GetByToken(ctx, refreshToken)
+		if err == nil && tokenModel.UserID == userID {
+			if tokenModel.SessionID != uuid.Nil {
+				if err := uc.revokeSession(ctx, tokenModel.SessionID, domain.SessionRevokedLogout); err != nil {
+					logger.Error("Failed to revoke session", zap.Error(err))
+				}
+			} else if err := uc.refreshTokenRepo.Revoke(ctx, refreshToken); err != nil {
+				logger.Error("Failed to revoke refresh token", zap.Error(err))
+			}
+		}
 	}
 
-	if err := uc.refreshTokenRepo.Create(ctx, refreshTokenModel); err != nil {
-		logger.Error("Failed to save refresh token", zap.Error(err))
+	// Revoke the session of the access token
+	if accessToken.SessionID != uuid.Nil {
+		if err := uc.revokeSession(ctx, accessToken.SessionID, domain.SessionRevokedLogout); err != nil {
+			logger.Error("Failed to revoke session", zap.Error(err))
+		}
 	}
 
-	// Update last login
-	// TODO: Get IP from context
-	_ = uc.userRepo.UpdateLastLogin(ctx, user.ID, "")
-
-	// Hide sensitive data
-	user.Password = ""
-	user.TwoFactorSecret = ""
-
-	return &AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(uc.config.JWT.AccessTokenExpire.Seconds()),
-		User:         user,
-	}, nil
-}
-
-// Logout logs out a user
-func (uc *useCase) Logout(ctx context.Context, userID uuid.UUID, token string) error {
-	// Revoke refresh token
-	if err := uc.refreshTokenRepo.Revoke(ctx, token); err != nil {
-		logger.Error("Failed to revoke refresh token", zap.Error(err))
+	// Blacklist access token in Redis
+	if accessToken.ID != "" {
+		if err := cache.BlacklistToken(ctx, accessToken.ID, time.Until(accessToken.ExpiresAt)); err != nil {
+			logger.Error("Failed to blacklist access token", zap.Error(err))
+		}
 	}
 
-	// TODO: Blacklist access token in Redis
-
 	return nil
 }
 
-// RefreshToken refreshes an access token
-func (uc *useCase) RefreshToken(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh token can be
+// used once; presenting a used one again revokes its whole session, since either the
+// client or an attacker holds a stolen copy.
+func (uc *useCase) RefreshToken(ctx context.Context, refreshToken string, client ClientInfo) (*AuthResponse, error) {
 	// Get refresh token from database
 	tokenModel, err := uc.refreshTokenRepo.GetByToken(ctx, refreshToken)
 	if err != nil {
@@ -422,6 +535,7 @@ func (uc *useCase) RefreshToken(ctx context.Context, refreshToken string) (*Auth
 	}
 
 	if tokenModel.Revoked {
+		uc.handleTokenReuse(ctx, tokenModel)
 		return nil, errors.ErrInvalidToken
 	}
 
@@ -435,29 +549,85 @@ func (uc *useCase) RefreshToken(ctx context.Context, refreshToken string) (*Auth
 		return nil, err
 	}
 
-	// Generate new access token
-	accessToken, err := utils.GenerateJWT(user.ID, user.Email, &uc.config.JWT)
+	if user.Status != domain.UserStatusActive {
+		return nil, errors.New("USER_INACTIVE", fmt.Sprintf("User account is %s", user.Status), 403)
+	}
+
+	// Tokens issued before sessions existed start one now
+	sessionID := tokenModel.SessionID
+	if sessionID == uuid.Nil {
+		session, err := uc.startSession(ctx, user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+		sessionID = session.ID
+	} else {
+		session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+		if err != nil || !session.IsActive() {
+			return nil, errors.ErrInvalidToken
+		}
+	}
+
+	// Use up the token; losing the race to a concurrent refresh counts as reuse
+	rotated, err := uc.refreshTokenRepo.Rotate(ctx, refreshToken)
 	if err != nil {
-		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate access token", 500)
+		return nil, err
+	}
+	if !rotated {
+		uc.handleTokenReuse(ctx, tokenModel)
+		return nil, errors.ErrInvalidToken
 	}
 
-	// Hide sensitive data
-	user.Password = ""
-	user.TwoFactorSecret = ""
+	if err := uc.sessionRepo.Touch(ctx, sessionID, client.IPAddress, client.UserAgent, time.Now().Add(uc.config.JWT.RefreshTokenExpire)); err != nil {
+		logger.Warn("Failed to update session", zap.String("session_id", sessionID.String()), zap.Error(err))
+	}
 
-	return &AuthResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int64(uc.config.JWT.AccessTokenExpire.Seconds()),
-		User:         user,
-	}, nil
+	return uc.issueTokens(ctx, user, sessionID)
+}
+
+// ListSessions lists the signed-in devices of a user
+func (uc *useCase) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]SessionResponse, error) {
+	sessions, err := uc.sessionRepo.ListActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return response, nil
+}
+
+// RevokeSession signs out one of the user's devices
+func (uc *useCase) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.UserID != userID {
+		return errors.ErrNotFound
+	}
+
+	return uc.revokeSession(ctx, sessionID, domain.SessionRevokedByUser)
+}
+
+// RevokeAllSessions signs out all of the user's devices, including the current one
+func (uc *useCase) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	return uc.revokeAllSessions(ctx, userID, domain.SessionRevokedByUser)
 }
 
 // ForgotPassword initiates password reset
 func (uc *useCase) ForgotPassword(ctx context.Context, email string) error {
 	// Check if user exists
 	user, err := uc.userRepo.GetByEmail(ctx, email)
-	if err != nil {
+	if err != nil || user.IsServiceAccount {
 		// Don't reveal if user exists or not
 		return nil
 	}
@@ -534,8 +704,8 @@ func (uc *useCase) ResetPassword(ctx context.Context, email, code, newPassword s
 	// Mark OTP as used
 	_ = uc.otpRepo.MarkAsUsed(ctx, otp.ID)
 
-	// Revoke all refresh tokens
-	_ = uc.refreshTokenRepo.RevokeAllByUserID(ctx, user.ID)
+	// Sign out all devices
+	_ = uc.revokeAllSessions(ctx, user.ID, domain.SessionRevokedPasswordChanged)
 
 	return nil
 }
@@ -569,8 +739,8 @@ func (uc *useCase) ChangePassword(ctx context.Context, userID uuid.UUID, oldPass
 		return err
 	}
 
-	// Revoke all refresh tokens
-	_ = uc.refreshTokenRepo.RevokeAllByUserID(ctx, userID)
+	// Sign out all devices
+	_ = uc.revokeAllSessions(ctx, userID, domain.SessionRevokedPasswordChanged)
 
 	return nil
 }
@@ -608,28 +778,36 @@ func (uc *useCase) Enable2FA(ctx context.Context, userID uuid.UUID) (*Enable2FAR
 }
 
 // Verify2FA verifies and enables 2FA
-func (uc *useCase) Verify2FA(ctx context.Context, userID uuid.UUID, code string) error {
+// and returns recovery codes if the user has none yet
+func (uc *useCase) Verify2FA(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
 	// Get temporary secret from cache
 	secret, err := cache.Get(ctx, fmt.Sprintf("2fa_setup:%s", userID))
 	if err != nil {
-		return errors.New("2FA_SETUP_NOT_FOUND", "2FA setup not found or expired", 400)
+		return nil, errors.New("2FA_SETUP_NOT_FOUND", "2FA setup not found or expired", 400)
+	}
+
+	twoFactorKey := cache.TwoFactorAttemptKey(userID)
+	if err := uc.checkAttemptDelay(ctx, twoFactorKey); err != nil {
+		return nil, err
 	}
 
-	// Verify code
+	// Verify code; failures here are delayed but do not lock the account
 	valid := totp.Validate(code, secret)
 	if !valid {
-		return errors.ErrInvalid2FA
+		return nil, uc.recordFailure(ctx, twoFactorKey, nil, ClientInfo{}, errors.ErrInvalid2FA)
 	}
 
+	uc.clearFailedAttempts(ctx, twoFactorKey)
+
 	// Enable 2FA
 	if err := uc.userRepo.Enable2FA(ctx, userID, secret); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Delete temporary secret
 	_ = cache.Delete(ctx, fmt.Sprintf("2fa_setup:%s", userID))
 
-	return nil
+	return uc.ensureRecoveryCodes(ctx, userID)
 }
 
 // Disable2FA disables 2FA for a user
@@ -654,7 +832,8 @@ func (uc *useCase) Disable2FA(ctx context.Context, userID uuid.UUID, password st
 		return err
 	}
 
-	return nil
+	user.TwoFactorEnabled = false
+	return uc.dropUnneededRecoveryCodes(ctx, user)
 }
 
 // GetCurrentUser retrieves the current user
@@ -671,6 +850,87 @@ func (uc *useCase) GetCurrentUser(ctx context.Context, userID uuid.UUID) (*domai
 	return user, nil
 }
 
+// superAdminRole is the seeded role that holds every permission
+const superAdminRole = "super_admin"
+
+// Impersonate issues a short-lived token that lets an admin see the CMS as a user does.
+// Super admins and users holding a permission the admin lacks, or holding it at a
+// broader scope, cannot be impersonated, so impersonation never widens an admin's
+// access.
+func (uc *useCase) Impersonate(ctx context.Context, impersonatorID, userID uuid.UUID) (*ImpersonationResponse, error) {
+	if impersonatorID == userID {
+		return nil, errors.New(errors.ErrCodeValidation, "you cannot impersonate yourself", 400)
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != domain.UserStatusActive {
+		return nil, errors.New(errors.ErrCodeValidation, "only active users can be impersonated", 400)
+	}
+
+	roles, err := uc.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role.Name == superAdminRole {
+			return nil, errors.New(errors.ErrCodeForbidden, "super admins cannot be impersonated", 403)
+		}
+	}
+	if err := uc.requireWiderAccess(ctx, impersonatorID, userID); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateImpersonationJWT(user.ID, user.Email, impersonatorID, &uc.config.JWT, uc.tokenSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate impersonation token", 500)
+	}
+
+	logger.Info("Impersonation started",
+		zap.String("impersonatorID", impersonatorID.String()),
+		zap.String("userID", userID.String()),
+	)
+
+	// Hide sensitive data
+	user.Password = ""
+	user.TwoFactorSecret = ""
+
+	return &ImpersonationResponse{
+		AccessToken:    accessToken,
+		ExpiresIn:      int64(uc.config.JWT.ImpersonationExpire.Seconds()),
+		User:           user,
+		ImpersonatorID: impersonatorID,
+	}, nil
+}
+
+// requireWiderAccess rejects impersonating a user who holds a permission the impersonator
+// does not, or holds it at a broader scope
+func (uc *useCase) requireWiderAccess(ctx context.Context, impersonatorID, userID uuid.UUID) error {
+	held, err := uc.permissions.GetUserPermissionScopes(ctx, impersonatorID)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to get permissions", 500)
+	}
+	target, err := uc.permissions.GetUserPermissionScopes(ctx, userID)
+	if err != nil {
+		return errors.Wrap(err, errors.ErrCodeInternal, "failed to get permissions", 500)
+	}
+
+	var missing []string
+	for permission, scope := range target {
+		if held[permission].Rank() < scope.Rank() {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.New(errors.ErrCodeForbidden,
+			fmt.Sprintf("you cannot impersonate a user with access you do not hold: %s", strings.Join(missing, ", ")), 403)
+	}
+	return nil
+}
+
 // UpdateProfile updates user profile
 func (uc *useCase) UpdateProfile(ctx context.Context, userID uuid.UUID, req UpdateProfileRequest) (*domain.User, error) {
 	// Get user
@@ -704,3 +964,118 @@ func (uc *useCase) UpdateProfile(ctx context.Context, userID uuid.UUID, req Upda
 
 	return user, nil
 }
+
+// startSession records a new signed-in device
+func (uc *useCase) startSession(ctx context.Context, userID uuid.UUID, client ClientInfo) (*domain.UserSession, error) {
+	session := &domain.UserSession{
+		UserID:     userID,
+		IPAddress:  client.IPAddress,
+		UserAgent:  truncate(client.UserAgent, 500),
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(uc.config.JWT.RefreshTokenExpire),
+	}
+
+	if err := uc.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// issueTokens signs an access and refresh token pair for a session
+func (uc *useCase) issueTokens(ctx context.Context, user *domain.User, sessionID uuid.UUID) (*AuthResponse, error) {
+	accessToken, err := utils.GenerateJWT(user.ID, user.Email, sessionID, &uc.config.JWT, uc.tokenSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate access token", 500)
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken(user.ID, user.Email, sessionID, &uc.config.JWT, uc.tokenSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate refresh token", 500)
+	}
+
+	// Save refresh token
+	refreshTokenModel := &domain.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		Token:     refreshToken,
+		ExpiresAt: time.Now().Add(uc.config.JWT.RefreshTokenExpire),
+	}
+
+	if err := uc.refreshTokenRepo.Create(ctx, refreshTokenModel); err != nil {
+		return nil, err
+	}
+
+	// Hide sensitive data
+	user.Password = ""
+	user.TwoFactorSecret = ""
+
+	return &AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(uc.config.JWT.AccessTokenExpire.Seconds()),
+		User:         user,
+	}, nil
+}
+
+// handleTokenReuse revokes the token family of a refresh token presented after it was used
+func (uc *useCase) handleTokenReuse(ctx context.Context, token *domain.RefreshToken) {
+	logger.Warn("Refresh token reuse detected",
+		zap.String("user_id", token.UserID.String()),
+		zap.String("session_id", token.SessionID.String()),
+	)
+
+	if token.SessionID == uuid.Nil {
+		return
+	}
+
+	if err := uc.revokeSession(ctx, token.SessionID, domain.SessionRevokedTokenReuse); err != nil {
+		logger.Error("Failed to revoke session after token reuse", zap.Error(err))
+	}
+}
+
+// revokeSession ends a session: its refresh tokens stop working at once, and so do
+// access tokens already issued to it. The session stays blacklisted for as long as any
+// token issued to it could still be valid.
+func (uc *useCase) revokeSession(ctx context.Context, sessionID uuid.UUID, reason string) error {
+	if err := uc.sessionRepo.Revoke(ctx, sessionID, reason); err != nil {
+		return err
+	}
+
+	if err := uc.refreshTokenRepo.RevokeBySessionID(ctx, sessionID); err != nil {
+		return err
+	}
+
+	if err := cache.RevokeSessionTokens(ctx, sessionID, uc.config.JWT.RefreshTokenExpire); err != nil {
+		logger.Error("Failed to blacklist session access tokens", zap.Error(err))
+	}
+
+	return nil
+}
+
+// revokeAllSessions ends every session of a user
+func (uc *useCase) revokeAllSessions(ctx context.Context, userID uuid.UUID, reason string) error {
+	sessionIDs, err := uc.sessionRepo.RevokeAllByUserID(ctx, userID, reason)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.refreshTokenRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := cache.RevokeSessionTokens(ctx, sessionID, uc.config.JWT.RefreshTokenExpire); err != nil {
+			logger.Error("Failed to blacklist session access tokens", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,453 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// startFakeRedis points the cache package at an in-process server speaking enough of
+// the Redis protocol for the keys the auth flows store
+func startFakeRedis(t *testing.T) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := &fakeRedis{data: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	previous := cache.Client
+	cache.Client = redis.NewClient(&redis.Options{
+		Addr:            listener.Addr().String(),
+		Protocol:        2,
+		DisableIdentity: true,
+	})
+	t.Cleanup(func() {
+		_ = cache.Client.Close()
+		cache.Client = previous
+		_ = listener.Close()
+	})
+}
+
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.execute(args)); err != nil {
+			return
+		}
+	}
+}
+
+// execute runs a command; expiry is ignored as tests finish well within any TTL
+func (s *fakeRedis) execute(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		s.data[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "GETDEL":
+		value, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		delete(s.data, args[1])
+		return bulk(value)
+	case "DEL", "UNLINK", "EXISTS":
+		count := 0
+		for _, key := range args[1:] {
+			if _, ok := s.data[key]; ok {
+				count++
+				if !strings.EqualFold(args[0], "EXISTS") {
+					delete(s.data, key)
+				}
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", count)
+	case "INCR":
+		n, _ := strconv.Atoi(s.data[args[1]])
+		n++
+		s.data[args[1]] = strconv.Itoa(n)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "EXPIRE", "PEXPIRE":
+		return ":1\r\n"
+	case "TTL", "PTTL":
+		return ":-1\r\n"
+	case "PUBLISH":
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "*") {
+		return nil, fmt.Errorf("unexpected command header %q", header)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// testSigner signs tokens with a fixed HMAC key
+type testSigner struct{}
+
+func (testSigner) Sign(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+}
+
+// Unimplemented methods of the embedded interfaces panic, flagging flows that reach
+// storage these fakes do not cover
+
+type fakeUserRepo struct {
+	repositories.UserRepository
+	mu      sync.Mutex
+	users   map[uuid.UUID]*domain.User
+	roles   map[uuid.UUID]map[uint]*domain.Role
+	catalog *fakeRoleRepo // Resolves assigned role IDs
+}
+
+func newFakeUserRepo(catalog *fakeRoleRepo) *fakeUserRepo {
+	return &fakeUserRepo{
+		users:   make(map[uuid.UUID]*domain.User),
+		roles:   make(map[uuid.UUID]map[uint]*domain.Role),
+		catalog: catalog,
+	}
+}
+
+func (r *fakeUserRepo) Create(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *fakeUserRepo) UpdateLastLogin(context.Context, uuid.UUID, string) error {
+	return nil
+}
+
+func (r *fakeUserRepo) AssignRole(_ context.Context, userID uuid.UUID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.roles[userID] == nil {
+		r.roles[userID] = make(map[uint]*domain.Role)
+	}
+	for _, role := range r.catalog.roles {
+		if role.ID == roleID {
+			r.roles[userID][roleID] = role
+			return nil
+		}
+	}
+	return errors.ErrNotFound
+}
+
+func (r *fakeUserRepo) RemoveRole(_ context.Context, userID uuid.UUID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.roles[userID], roleID)
+	return nil
+}
+
+func (r *fakeUserRepo) GetUserRoles(_ context.Context, userID uuid.UUID) ([]*domain.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roles := make([]*domain.Role, 0, len(r.roles[userID]))
+	for _, role := range r.roles[userID] {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (r *fakeUserRepo) Disable2FA(_ context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].TwoFactorEnabled = false
+	r.users[userID].TwoFactorSecret = ""
+	return nil
+}
+
+type fakeRoleRepo struct {
+	repositories.RoleRepository
+	roles []*domain.Role
+}
+
+func (r *fakeRoleRepo) GetByName(_ context.Context, name string) (*domain.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+type fakeIdentityRepo struct {
+	repositories.ExternalIdentityRepository
+	identities []*domain.ExternalIdentity
+}
+
+func (r *fakeIdentityRepo) Create(_ context.Context, identity *domain.ExternalIdentity) error {
+	identity.ID = uuid.New()
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepo) GetByProviderSubject(_ context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeIdentityRepo) RecordLogin(context.Context, uuid.UUID, string) error {
+	return nil
+}
+
+type fakeSessionRepo struct {
+	repositories.UserSessionRepository
+}
+
+func (r *fakeSessionRepo) Create(_ context.Context, session *domain.UserSession) error {
+	session.ID = uuid.New()
+	return nil
+}
+
+type fakeRefreshTokenRepo struct {
+	repositories.RefreshTokenRepository
+}
+
+func (r *fakeRefreshTokenRepo) Create(context.Context, *domain.RefreshToken) error {
+	return nil
+}
+
+type fakeRecoveryCodeRepo struct {
+	repositories.RecoveryCodeRepository
+	unused map[uuid.UUID]map[string]bool // Hashes of each user's unused codes
+}
+
+func (r *fakeRecoveryCodeRepo) ReplaceForUser(_ context.Context, userID uuid.UUID, codeHashes []string) error {
+	r.unused[userID] = make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		r.unused[userID][hash] = true
+	}
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepo) Use(_ context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	if !r.unused[userID][codeHash] {
+		return false, nil
+	}
+	delete(r.unused[userID], codeHash)
+	return true, nil
+}
+
+func (r *fakeRecoveryCodeRepo) CountUnused(_ context.Context, userID uuid.UUID) (int64, error) {
+	return int64(len(r.unused[userID])), nil
+}
+
+func (r *fakeRecoveryCodeRepo) DeleteByUserID(_ context.Context, userID uuid.UUID) error {
+	delete(r.unused, userID)
+	return nil
+}
+
+type fakeWebAuthnRepo struct {
+	repositories.WebAuthnCredentialRepository
+	credentials []*domain.WebAuthnCredential
+}
+
+func (r *fakeWebAuthnRepo) Create(_ context.Context, credential *domain.WebAuthnCredential) error {
+	credential.ID = uuid.New()
+	r.credentials = append(r.credentials, credential)
+	return nil
+}
+
+func (r *fakeWebAuthnRepo) ListByUserID(_ context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	var credentials []*domain.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, credential)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *fakeWebAuthnRepo) RecordUse(_ context.Context, id uuid.UUID, signCount uint32, cloneWarning, backupState bool) error {
+	for _, credential := range r.credentials {
+		if credential.ID == id {
+			credential.SignCount = signCount
+			credential.CloneWarning = cloneWarning
+			credential.BackupState = backupState
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebAuthnRepo) Delete(_ context.Context, userID, id uuid.UUID) error {
+	for i, credential := range r.credentials {
+		if credential.ID == id && credential.UserID == userID {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return errors.ErrNotFound
+}
+
+// authFakes holds the in-memory storage behind a use case built by newTestUseCase
+type authFakes struct {
+	users         *fakeUserRepo
+	roles         *fakeRoleRepo
+	identities    *fakeIdentityRepo
+	recoveryCodes *fakeRecoveryCodeRepo
+	webAuthn      *fakeWebAuthnRepo
+}
+
+// newTestUseCase builds the auth use case on in-memory storage and a fake Redis
+func newTestUseCase(t *testing.T, cfg *config.Config, providers ...ports.IdentityProvider) (UseCase, *authFakes) {
+	t.Helper()
+	startFakeRedis(t)
+
+	if cfg.JWT.AccessTokenExpire == 0 {
+		cfg.JWT.AccessTokenExpire = expireSoon
+		cfg.JWT.RefreshTokenExpire = expireSoon
+	}
+
+	roles := &fakeRoleRepo{}
+	fakes := &authFakes{
+		users:         newFakeUserRepo(roles),
+		roles:         roles,
+		identities:    &fakeIdentityRepo{},
+		recoveryCodes: &fakeRecoveryCodeRepo{unused: make(map[uuid.UUID]map[string]bool)},
+		webAuthn:      &fakeWebAuthnRepo{},
+	}
+
+	uc := NewUseCase(
+		fakes.users,
+		nil,
+		&fakeRefreshTokenRepo{},
+		&fakeSessionRepo{},
+		fakes.recoveryCodes,
+		fakes.webAuthn,
+		fakes.identities,
+		fakes.roles,
+		nil,
+		cfg,
+		nil,
+		testSigner{},
+		providers,
+	)
+	return uc, fakes
+}
+
+// expireSoon is long enough for any test and short enough to spot leaked tokens
+const expireSoon = 5 * time.Minute
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// maxDelayDoublings caps the exponent of the progressive delay
+const maxDelayDoublings = 16
+
+// checkAttemptDelay refuses an attempt while any of the keys is delayed or blocked.
+// It fails open: if Redis is unreachable the attempt goes through.
+func (uc *useCase) checkAttemptDelay(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		delay, err := cache.AttemptDelay(ctx, key)
+		if err != nil {
+			logger.Warn("Failed to check attempt delay", zap.String("key", key), zap.Error(err))
+			continue
+		}
+
+		if delay > 0 {
+			return tooManyAttemptsError(delay)
+		}
+	}
+
+	return nil
+}
+
+// recordFailure counts a failed login or 2FA attempt against key and the client IP.
+// Repeated failures delay further attempts, block the IP and finally lock the account.
+// It returns the error to report for the attempt.
+func (uc *useCase) recordFailure(ctx context.Context, key string, user *domain.User, client ClientInfo, failure error) error {
+	protection := uc.config.Login
+
+	if client.IPAddress != "" {
+		ipKey := cache.LoginIPAttemptKey(client.IPAddress)
+		count := uc.countFailure(ctx, ipKey)
+		if protection.IPMaxFailedAttempts > 0 && count >= int64(protection.IPMaxFailedAttempts) {
+			logger.Warn("Blocking client IP after failed attempts", zap.String("ip", client.IPAddress), zap.Int64("failures", count))
+			if err := cache.DelayAttempts(ctx, ipKey, protection.LockoutDuration); err != nil {
+				logger.Warn("Failed to block client IP", zap.Error(err))
+			}
+		}
+	}
+
+	count := uc.countFailure(ctx, key)
+	if delay := uc.attemptDelay(count); delay > 0 {
+		if err := cache.DelayAttempts(ctx, key, delay); err != nil {
+			logger.Warn("Failed to delay attempts", zap.String("key", key), zap.Error(err))
+		}
+	}
+
+	if user != nil && protection.MaxFailedAttempts > 0 && count >= int64(protection.MaxFailedAttempts) {
+		if err := uc.lockAccount(ctx, user, client); err != nil {
+			logger.Error("Failed to lock account", zap.String("user_id", user.ID.String()), zap.Error(err))
+			return failure
+		}
+		return accountLockedError(*user.LockedUntil)
+	}
+
+	return failure
+}
+
+// countFailure records a failure against key and returns the failures within the window
+func (uc *useCase) countFailure(ctx context.Context, key string) int64 {
+	count, err := cache.RecordFailedAttempt(ctx, key, uc.config.Login.FailureWindow)
+	if err != nil {
+		logger.Warn("Failed to record failed attempt", zap.String("key", key), zap.Error(err))
+		return 0
+	}
+
+	return count
+}
+
+// attemptDelay returns how long to refuse attempts after the given number of failures.
+// The delay starts at BaseDelay once DelayAfter failures are reached and doubles with
+// each further failure, up to MaxDelay.
+func (uc *useCase) attemptDelay(failures int64) time.Duration {
+	protection := uc.config.Login
+	if protection.BaseDelay <= 0 || failures < int64(protection.DelayAfter) {
+		return 0
+	}
+
+	doublings := failures - int64(protection.DelayAfter)
+	if doublings > maxDelayDoublings {
+		doublings = maxDelayDoublings
+	}
+
+	delay := protection.BaseDelay << doublings
+	if protection.MaxDelay > 0 && delay > protection.MaxDelay {
+		delay = protection.MaxDelay
+	}
+
+	return delay
+}
+
+// lockAccount locks the user out for the lockout duration and tells them by email
+func (uc *useCase) lockAccount(ctx context.Context, user *domain.User, client ClientInfo) error {
+	until := time.Now().Add(uc.config.Login.LockoutDuration)
+	if err := uc.userRepo.LockAccount(ctx, user.ID, until); err != nil {
+		return err
+	}
+	user.LockedUntil = &until
+
+	// The lock replaces the counters; failures start over once it expires
+	if err := cache.ClearFailedAttempts(ctx, cache.LoginAccountAttemptKey(user.Email), cache.TwoFactorAttemptKey(user.ID)); err != nil {
+		logger.Warn("Failed to clear failed attempts", zap.Error(err))
+	}
+
+	logger.Warn("Account locked after failed attempts",
+		zap.String("user_id", user.ID.String()),
+		zap.String("ip", client.IPAddress),
+		zap.Time("locked_until", until),
+	)
+
+	name := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	if err := uc.emailService.SendAccountLockedEmail(user.Email, name, until, client.IPAddress); err != nil {
+		logger.Error("Failed to send account locked email", zap.Error(err))
+	}
+
+	return nil
+}
+
+// clearFailedAttempts forgets the failures of an account after a successful attempt
+func (uc *useCase) clearFailedAttempts(ctx context.Context, keys ...string) {
+	if err := cache.ClearFailedAttempts(ctx, keys...); err != nil {
+		logger.Warn("Failed to clear failed attempts", zap.Error(err))
+	}
+}
+
+func tooManyAttemptsError(delay time.Duration) error {
+	seconds := int(math.Ceil(delay.Seconds()))
+	return errors.New(errors.ErrCodeTooManyAttempts, fmt.Sprintf("Too many failed attempts, try again in %d seconds", seconds), http.StatusTooManyRequests)
+}
+
+func accountLockedError(until time.Time) error {
+	return errors.New(errors.ErrCodeAccountLocked, fmt.Sprintf("Account is locked until %s", until.UTC().Format(time.RFC3339)), http.StatusLocked)
+}
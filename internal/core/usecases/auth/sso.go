@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"github.com/owner/go-cms/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ssoStateTTL bounds how long a user has to log in at the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+var (
+	errSSOProviderNotFound = errors.New("SSO_PROVIDER_NOT_FOUND", "SSO provider not found", 404)
+	errSSOStateInvalid     = errors.New("SSO_STATE_INVALID", "SSO login expired or invalid, please try again", 400)
+)
+
+// SSOLoginResponse tells the client where to send the user to log in
+type SSOLoginResponse struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"state"`
+}
+
+// SSOCallbackRequest completes an SSO login with the identity provider's redirect
+type SSOCallbackRequest struct {
+	Provider string     `json:"-"`
+	Code     string     `form:"code" binding:"required"`
+	State    string     `form:"state" binding:"required"`
+	Client   ClientInfo `json:"-"`
+}
+
+// pendingSSOLogin is kept in Redis while the user is at the identity provider
+type pendingSSOLogin struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// ListSSOProviders lists the names of the configured identity providers
+func (uc *useCase) ListSSOProviders() []string {
+	names := make([]string, 0, len(uc.identityProviders))
+	for name := range uc.identityProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginSSOLogin starts an authorization code flow with PKCE at an identity provider
+func (uc *useCase) BeginSSOLogin(ctx context.Context, providerName string) (*SSOLoginResponse, error) {
+	provider, ok := uc.identityProviders[providerName]
+	if !ok {
+		return nil, errSSOProviderNotFound
+	}
+
+	state, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate SSO state", 500)
+	}
+	nonce, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate SSO nonce", 500)
+	}
+	// PKCE verifiers must be 43 to 128 characters long
+	codeVerifier, err := utils.GenerateRandomString(64)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate PKCE verifier", 500)
+	}
+
+	data, err := json.Marshal(pendingSSOLogin{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to encode SSO login", 500)
+	}
+
+	if err := cache.Set(ctx, ssoStateKey(state), data, ssoStateTTL); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to store SSO login", 500)
+	}
+
+	return &SSOLoginResponse{
+		AuthURL: provider.AuthCodeURL(state, nonce, codeVerifier),
+		State:   state,
+	}, nil
+}
+
+// CompleteSSOLogin redeems the authorization code, finds or creates the user and logs them in.
+// Second factors are left to the identity provider.
+func (uc *useCase) CompleteSSOLogin(ctx context.Context, req SSOCallbackRequest) (*AuthResponse, error) {
+	provider, ok := uc.identityProviders[req.Provider]
+	if !ok {
+		return nil, errSSOProviderNotFound
+	}
+
+	// The state is single use
+	data, err := cache.GetDel(ctx, ssoStateKey(req.State))
+	if err != nil {
+		return nil, errSSOStateInvalid
+	}
+
+	var pending pendingSSOLogin
+	if err := json.Unmarshal([]byte(data), &pending); err != nil || pending.Provider != req.Provider {
+		return nil, errSSOStateInvalid
+	}
+
+	claims, err := provider.Exchange(ctx, req.Code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		logger.Warn("SSO code exchange failed", zap.String("provider", req.Provider), zap.Error(err))
+		return nil, errors.Wrap(err, errors.ErrCodeUnauthorized, "SSO login failed", 401)
+	}
+
+	user, err := uc.resolveSSOUser(ctx, provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsLocked() {
+		return nil, accountLockedError(*user.LockedUntil)
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, errors.New("USER_INACTIVE", fmt.Sprintf("User account is %s", user.Status), 403)
+	}
+
+	if err := uc.syncSSORoles(ctx, user, provider.Policy(), claims.Groups); err != nil {
+		return nil, err
+	}
+
+	logger.Info("User logged in with SSO", zap.String("user_id", user.ID.String()), zap.String("provider", req.Provider))
+
+	return uc.completeLogin(ctx, user, req.Client)
+}
+
+// resolveSSOUser returns the user linked to the provider account. Unlinked accounts
+// are linked to the user with the same verified email, or to a newly created user.
+func (uc *useCase) resolveSSOUser(ctx context.Context, provider ports.IdentityProvider, claims *ports.ExternalClaims) (*domain.User, error) {
+	policy := provider.Policy()
+	if !emailDomainAllowed(claims.Email, policy.AllowedDomains) {
+		return nil, errors.New("SSO_DOMAIN_NOT_ALLOWED", "Your email domain is not allowed to log in with SSO", 403)
+	}
+
+	identity, err := uc.identityRepo.GetByProviderSubject(ctx, provider.Name(), claims.Subject)
+	if err == nil {
+		if err := uc.identityRepo.RecordLogin(ctx, identity.ID, claims.Email); err != nil {
+			logger.Warn("Failed to record SSO login", zap.Error(err))
+		}
+		return uc.userRepo.GetByID(ctx, identity.UserID)
+	}
+	if err != errors.ErrNotFound {
+		return nil, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("SSO_EMAIL_NOT_VERIFIED", "Your identity provider has not verified your email address", 403)
+	}
+
+	user, err := uc.userRepo.GetByEmail(ctx, claims.Email)
+	if err != nil {
+		if err != errors.ErrUserNotFound {
+			return nil, err
+		}
+		if !policy.AutoCreateUsers {
+			return nil, errors.New("SSO_USER_NOT_FOUND", "No account exists for this user", 403)
+		}
+		if user, err = uc.createSSOUser(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := uc.identityRepo.Create(ctx, &domain.ExternalIdentity{
+		UserID:      user.ID,
+		Provider:    provider.Name(),
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Linked SSO identity",
+		zap.String("user_id", user.ID.String()),
+		zap.String("provider", provider.Name()),
+	)
+
+	return user, nil
+}
+
+// createSSOUser creates an active user for a first SSO login. The random password
+// can only be replaced through the password reset flow.
+func (uc *useCase) createSSOUser(ctx context.Context, claims *ports.ExternalClaims) (*domain.User, error) {
+	password, err := utils.GenerateRandomString(48)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate password", 500)
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to hash password", 500)
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		Email:           claims.Email,
+		Password:        hashedPassword,
+		FirstName:       claims.FirstName,
+		LastName:        claims.LastName,
+		Status:          domain.UserStatusActive,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		logger.Error("Failed to create SSO user", zap.Error(err))
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// syncSSORoles grants the roles mapped from the user's provider groups and revokes
+// mapped roles for groups the user has left. Roles outside the mapping are left alone.
+func (uc *useCase) syncSSORoles(ctx context.Context, user *domain.User, policy ports.SSOPolicy, groups []string) error {
+	if len(policy.GroupRoles) == 0 {
+		return nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, group := range groups {
+		if roleName, ok := policy.GroupRoles[group]; ok {
+			wanted[roleName] = true
+		}
+	}
+
+	mapped := make(map[string]bool)
+	for _, roleName := range policy.GroupRoles {
+		mapped[roleName] = true
+	}
+
+	current, err := uc.userRepo.GetUserRoles(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	held := make(map[string]bool)
+	changed := false
+	for _, role := range current {
+		held[role.Name] = true
+		if mapped[role.Name] && !wanted[role.Name] {
+			if err := uc.userRepo.RemoveRole(ctx, user.ID, role.ID); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+
+	for roleName := range wanted {
+		if held[roleName] {
+			continue
+		}
+
+		role, err := uc.roleRepo.GetByName(ctx, roleName)
+		if err != nil {
+			logger.Warn("SSO group mapped to unknown role", zap.String("role", roleName), zap.Error(err))
+			continue
+		}
+
+		if err := uc.userRepo.AssignRole(ctx, user.ID, role.ID); err != nil {
+			return err
+		}
+		changed = true
+	}
+
+	if changed {
+		_ = cache.InvalidatePermissions(ctx, user.ID)
+	}
+
+	return nil
+}
+
+// emailDomainAllowed checks the email's domain against the allowed domains
+func emailDomainAllowed(email string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domainName := strings.ToLower(email[at+1:])
+
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(candidate), domainName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ssoStateKey(state string) string {
+	return "sso_state:" + state
+}
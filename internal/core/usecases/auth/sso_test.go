@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/owner/go-cms/internal/adapters/external/oidc"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports"
+	"github.com/owner/go-cms/pkg/errors"
+)
+
+const (
+	mockClientID     = "cms"
+	mockClientSecret = "cms-secret"
+	mockRedirectURL  = "https://cms.example.com/api/v1/auth/sso/mock/callback"
+)
+
+// mockIdPUser is the account the mock identity provider logs in
+type mockIdPUser struct {
+	Subject   string
+	Email     string
+	Verified  bool
+	FirstName string
+	LastName  string
+	Groups    []string
+}
+
+// mockIdP is a local OpenID Connect provider with discovery, JWKS, an authorization
+// endpoint that logs in a fixed user, and a token endpoint enforcing PKCE
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	user  mockIdPUser
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+	user      mockIdPUser
+}
+
+func newMockIdP(t *testing.T, user mockIdPUser) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	idp := &mockIdP{key: key, user: user, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+
+	return idp
+}
+
+func (idp *mockIdP) setUser(user mockIdPUser) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.user = user
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                                idp.URL,
+		"authorization_endpoint":                idp.URL + "/authorize",
+		"token_endpoint":                        idp.URL + "/token",
+		"jwks_uri":                              idp.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       &idp.key.PublicKey,
+		KeyID:     "mock-key",
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}})
+}
+
+// authorize logs the current user in and redirects back with a code
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != mockClientID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	code := randomString()
+	idp.codes[code] = mockAuthorization{
+		challenge: query.Get("code_challenge"),
+		nonce:     query.Get("nonce"),
+		user:      idp.user,
+	}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking the client and the PKCE verifier
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != mockClientID || clientSecret != mockClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	authorization, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	user := authorization.user
+	idToken, err := idp.sign(map[string]interface{}{
+		"iss":            idp.URL,
+		"sub":            user.Subject,
+		"aud":            mockClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          authorization.nonce,
+		"email":          user.Email,
+		"email_verified": user.Verified,
+		"given_name":     user.FirstName,
+		"family_name":    user.LastName,
+		"groups":         user.Groups,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (idp *mockIdP) sign(claims map[string]interface{}) (string, error) {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: idp.key, KeyID: "mock-key"}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	object, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
+
+// login follows an authorization URL at the IdP and returns the code and state it
+// redirects back with
+func (idp *mockIdP) login(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize redirect: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newMockProvider(t *testing.T, idp *mockIdP, allowedDomains []string) ports.IdentityProvider {
+	t.Helper()
+
+	provider, err := oidc.NewProvider(context.Background(), &config.OIDCConfig{
+		Name:            "mock",
+		IssuerURL:       idp.URL,
+		ClientID:        mockClientID,
+		ClientSecret:    mockClientSecret,
+		RedirectURL:     mockRedirectURL,
+		Scopes:          []string{"email", "profile"},
+		AllowedDomains:  allowedDomains,
+		AutoCreateUsers: true,
+		GroupsClaim:     "groups",
+		GroupRoles:      []string{"cms-editors:editor"},
+	})
+	if err != nil {
+		t.Fatalf("discover mock IdP: %v", err)
+	}
+	return provider
+}
+
+func TestSSOLoginCreatesLinksAndMapsRoles(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t, mockIdPUser{
+		Subject:   "idp-user-1",
+		Email:     "jane@example.com",
+		Verified:  true,
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Groups:    []string{"cms-editors"},
+	})
+	uc, fakes := newTestUseCase(t, &config.Config{}, newMockProvider(t, idp, []string{"example.com"}))
+	editor := &domain.Role{BaseModel: domain.BaseModel{ID: 7}, Name: "editor"}
+	fakes.roles.roles = append(fakes.roles.roles, editor)
+
+	// First login creates the user, links the identity and maps the group
+	login, err := uc.BeginSSOLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginSSOLogin: %v", err)
+	}
+	code, state := idp.login(t, login.AuthURL)
+	if state != login.State {
+		t.Fatalf("state = %q, want %q", state, login.State)
+	}
+
+	first, err := uc.CompleteSSOLogin(ctx, SSOCallbackRequest{Provider: "mock", Code: code, State: state})
+	if err != nil {
+		t.Fatalf("CompleteSSOLogin: %v", err)
+	}
+	if first.AccessToken == "" || first.RefreshToken == "" {
+		t.Fatal("expected an access and refresh token")
+	}
+	if first.User.Email != "jane@example.com" || first.User.FirstName != "Jane" || first.User.Status != domain.UserStatusActive {
+		t.Fatalf("unexpected user %+v", first.User)
+	}
+	if len(fakes.identities.identities) != 1 || fakes.identities.identities[0].Subject != "idp-user-1" {
+		t.Fatalf("identity not linked: %+v", fakes.identities.identities)
+	}
+	roles, _ := fakes.users.GetUserRoles(ctx, first.User.ID)
+	if len(roles) != 1 || roles[0].Name != "editor" {
+		t.Fatalf("roles = %+v, want editor", roles)
+	}
+
+	// The state is single use
+	if _, err := uc.CompleteSSOLogin(ctx, SSOCallbackRequest{Provider: "mock", Code: code, State: state}); err == nil {
+		t.Fatal("expected a reused state to be rejected")
+	}
+
+	// A later login finds the linked user and revokes roles of groups they left
+	idp.setUser(mockIdPUser{Subject: "idp-user-1", Email: "jane@example.com", Verified: true})
+	login, err = uc.BeginSSOLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginSSOLogin: %v", err)
+	}
+	code, state = idp.login(t, login.AuthURL)
+
+	second, err := uc.CompleteSSOLogin(ctx, SSOCallbackRequest{Provider: "mock", Code: code, State: state})
+	if err != nil {
+		t.Fatalf("second CompleteSSOLogin: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Fatalf("second login resolved user %s, want %s", second.User.ID, first.User.ID)
+	}
+	if len(fakes.identities.identities) != 1 {
+		t.Fatalf("identity linked twice: %d", len(fakes.identities.identities))
+	}
+	if roles, _ := fakes.users.GetUserRoles(ctx, first.User.ID); len(roles) != 0 {
+		t.Fatalf("roles = %+v, want none after leaving the group", roles)
+	}
+}
+
+func TestSSOLoginRejectsDisallowedDomain(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t, mockIdPUser{Subject: "idp-user-2", Email: "mallory@other.org", Verified: true})
+	uc, fakes := newTestUseCase(t, &config.Config{}, newMockProvider(t, idp, []string{"example.com"}))
+
+	login, err := uc.BeginSSOLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginSSOLogin: %v", err)
+	}
+	code, state := idp.login(t, login.AuthURL)
+
+	_, err = uc.CompleteSSOLogin(ctx, SSOCallbackRequest{Provider: "mock", Code: code, State: state})
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) || appErr.Code != "SSO_DOMAIN_NOT_ALLOWED" {
+		t.Fatalf("err = %v, want SSO_DOMAIN_NOT_ALLOWED", err)
+	}
+	if len(fakes.users.users) != 0 || len(fakes.identities.identities) != 0 {
+		t.Fatal("a user from a disallowed domain was created")
+	}
+}
+
+func TestSSOLoginRequiresVerifiedEmailToLink(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t, mockIdPUser{Subject: "idp-user-3", Email: "jane@example.com", Verified: false})
+	uc, fakes := newTestUseCase(t, &config.Config{}, newMockProvider(t, idp, nil))
+	existing := &domain.User{Email: "jane@example.com", Status: domain.UserStatusActive}
+	_ = fakes.users.Create(ctx, existing)
+
+	login, err := uc.BeginSSOLogin(ctx, "mock")
+	if err != nil {
+		t.Fatalf("BeginSSOLogin: %v", err)
+	}
+	code, state := idp.login(t, login.AuthURL)
+
+	if _, err := uc.CompleteSSOLogin(ctx, SSOCallbackRequest{Provider: "mock", Code: code, State: state}); err == nil {
+		t.Fatal("expected an unverified email not to be linked to an existing user")
+	}
+	if len(fakes.identities.identities) != 0 {
+		t.Fatal("identity linked without a verified email")
+	}
+}
+
+func TestMockProviderEnforcesPKCEAndNonce(t *testing.T) {
+	ctx := context.Background()
+	idp := newMockIdP(t, mockIdPUser{Subject: "idp-user-4", Email: "jane@example.com", Verified: true})
+	provider := newMockProvider(t, idp, nil)
+
+	verifier := randomString() + randomString()
+	code, _ := idp.login(t, provider.AuthCodeURL("state", "nonce", verifier))
+	if _, err := provider.Exchange(ctx, code, verifier+"x", "nonce"); err == nil {
+		t.Fatal("expected a wrong PKCE verifier to be rejected")
+	}
+
+	code, _ = idp.login(t, provider.AuthCodeURL("state", "nonce", verifier))
+	if _, err := provider.Exchange(ctx, code, verifier, "other-nonce"); err == nil {
+		t.Fatal("expected a mismatched nonce to be rejected")
+	}
+
+	code, _ = idp.login(t, provider.AuthCodeURL("state", "nonce", verifier))
+	claims, err := provider.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "idp-user-4" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,580 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"github.com/owner/go-cms/pkg/utils"
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+)
+
+const (
+	// twoFactorCeremonyTTL bounds how long a pending 2FA login or key registration stays valid
+	twoFactorCeremonyTTL = 5 * time.Minute
+
+	defaultRecoveryCodes = 10
+
+	// recoveryCodeAlphabet leaves out characters that are easily confused when typed
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+)
+
+var (
+	errWebAuthnDisabled         = errors.New("WEBAUTHN_DISABLED", "Security keys are not configured on this server", 503)
+	errTwoFactorLoginExpired    = errors.New("2FA_LOGIN_EXPIRED", "2FA login expired, please log in again", 401)
+	errWebAuthnRegistrationGone = errors.New("WEBAUTHN_REGISTRATION_EXPIRED", "Security key registration expired, please start again", 400)
+)
+
+// TwoFactorLoginRequest completes a login that requires a second factor
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string          `json:"two_factor_token" binding:"required"`
+	Method         string          `json:"method" binding:"required,oneof=totp webauthn recovery_code"`
+	Code           string          `json:"code"`       // TOTP or recovery code
+	Credential     json.RawMessage `json:"credential"` // WebAuthn assertion response
+	Client         ClientInfo      `json:"-"`
+}
+
+// RecoveryCodeStatus reports how many recovery codes a user has left
+type RecoveryCodeStatus struct {
+	Remaining int64 `json:"remaining"`
+}
+
+// WebAuthnRegistrationResponse represents a newly registered security key
+type WebAuthnRegistrationResponse struct {
+	Credential    *domain.WebAuthnCredential `json:"credential"`
+	RecoveryCodes []string                   `json:"recovery_codes,omitempty"` // Only set for the user's first factor
+}
+
+// pendingTwoFactorLogin is kept in Redis between the password and second factor steps
+type pendingTwoFactorLogin struct {
+	UserID   uuid.UUID             `json:"user_id"`
+	WebAuthn *webauthn.SessionData `json:"webauthn,omitempty"`
+}
+
+// webAuthnUser adapts a user and their credentials to the WebAuthn library
+type webAuthnUser struct {
+	user        *domain.User
+	credentials []*domain.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	name := strings.TrimSpace(fmt.Sprintf("%s %s", u.user.FirstName, u.user.LastName))
+	if name == "" {
+		return u.user.Email
+	}
+	return name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, credential := range u.credentials {
+		credentials = append(credentials, toWebAuthnCredential(credential))
+	}
+	return credentials
+}
+
+// CompleteTwoFactorLogin finishes a login with a TOTP code, security key or recovery code
+func (uc *useCase) CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest) (*AuthResponse, error) {
+	key := twoFactorLoginKey(req.TwoFactorToken)
+	data, err := cache.Get(ctx, key)
+	if err != nil {
+		return nil, errTwoFactorLoginExpired
+	}
+
+	var pending pendingTwoFactorLogin
+	if err := json.Unmarshal([]byte(data), &pending); err != nil {
+		return nil, errTwoFactorLoginExpired
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, pending.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsLocked() {
+		_ = cache.Delete(ctx, key)
+		return nil, accountLockedError(*user.LockedUntil)
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, errors.New("USER_INACTIVE", fmt.Sprintf("User account is %s", user.Status), 403)
+	}
+
+	twoFactorKey := cache.TwoFactorAttemptKey(user.ID)
+	if err := uc.checkAttemptDelay(ctx, twoFactorKey); err != nil {
+		return nil, err
+	}
+
+	valid, err := uc.verifySecondFactor(ctx, user, &pending, req)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		failure := uc.recordFailure(ctx, twoFactorKey, user, req.Client, errors.ErrInvalid2FA)
+		if user.IsLocked() {
+			_ = cache.Delete(ctx, key)
+		}
+		return nil, failure
+	}
+
+	// The token is single use
+	_ = cache.Delete(ctx, key)
+	uc.clearFailedAttempts(ctx, cache.LoginAccountAttemptKey(user.Email), twoFactorKey)
+
+	return uc.completeLogin(ctx, user, req.Client)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user with a new set
+func (uc *useCase) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password string) ([]string, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify password
+	if !utils.CheckPassword(user.Password, password) {
+		return nil, errors.New("INVALID_PASSWORD", "Invalid password", 400)
+	}
+
+	methods, _, err := uc.twoFactorMethods(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("2FA_NOT_ENABLED", "2FA is not enabled", 400)
+	}
+
+	return uc.generateRecoveryCodes(ctx, userID)
+}
+
+// GetRecoveryCodeStatus returns how many unused recovery codes a user has
+func (uc *useCase) GetRecoveryCodeStatus(ctx context.Context, userID uuid.UUID) (*RecoveryCodeStatus, error) {
+	remaining, err := uc.recoveryCodeRepo.CountUnused(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodeStatus{Remaining: remaining}, nil
+}
+
+// BeginWebAuthnRegistration starts registering a new security key for a user
+func (uc *useCase) BeginWebAuthnRegistration(ctx context.Context, userID uuid.UUID) (*protocol.CredentialCreation, error) {
+	if uc.webAuthn == nil {
+		return nil, errWebAuthnDisabled
+	}
+
+	user, credentials, err := uc.getWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser := &webAuthnUser{user: user, credentials: credentials}
+	exclusions := webauthn.Credentials(waUser.WebAuthnCredentials()).CredentialDescriptors()
+
+	creation, session, err := uc.webAuthn.BeginRegistration(waUser, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to start security key registration", 500)
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to encode registration session", 500)
+	}
+
+	if err := cache.Set(ctx, webAuthnRegistrationKey(userID), data, twoFactorCeremonyTTL); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to store registration session", 500)
+	}
+
+	return creation, nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response and stores the new key.
+// Recovery codes are issued when this is the user's first second factor.
+func (uc *useCase) FinishWebAuthnRegistration(ctx context.Context, userID uuid.UUID, name string, response []byte) (*WebAuthnRegistrationResponse, error) {
+	if uc.webAuthn == nil {
+		return nil, errWebAuthnDisabled
+	}
+
+	data, err := cache.GetDel(ctx, webAuthnRegistrationKey(userID))
+	if err != nil {
+		return nil, errWebAuthnRegistrationGone
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, errWebAuthnRegistrationGone
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeValidation, "Invalid security key response", 400)
+	}
+
+	user, credentials, err := uc.getWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := uc.webAuthn.CreateCredential(&webAuthnUser{user: user, credentials: credentials}, session, parsed)
+	if err != nil {
+		return nil, errors.Wrap(err, "WEBAUTHN_VERIFICATION_FAILED", "Security key could not be verified", 400)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Security key"
+	}
+
+	record := fromWebAuthnCredential(userID, truncate(name, 100), credential)
+	if err := uc.webAuthnRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	codes, err := uc.ensureRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnRegistrationResponse{Credential: record, RecoveryCodes: codes}, nil
+}
+
+// ListWebAuthnCredentials lists the security keys registered by a user
+func (uc *useCase) ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	return uc.webAuthnRepo.ListByUserID(ctx, userID)
+}
+
+// DeleteWebAuthnCredential removes one of the user's security keys after verifying their
+// password, so a stolen session cannot strip the account of its second factor
+func (uc *useCase) DeleteWebAuthnCredential(ctx context.Context, userID, credentialID uuid.UUID, password string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Verify password
+	if !utils.CheckPassword(user.Password, password) {
+		return errors.New("INVALID_PASSWORD", "Invalid password", 400)
+	}
+
+	if err := uc.webAuthnRepo.Delete(ctx, userID, credentialID); err != nil {
+		return err
+	}
+
+	return uc.dropUnneededRecoveryCodes(ctx, user)
+}
+
+// twoFactorMethods returns the second factors a user can log in with, along with
+// their security keys
+func (uc *useCase) twoFactorMethods(ctx context.Context, user *domain.User) ([]string, []*domain.WebAuthnCredential, error) {
+	credentials, err := uc.webAuthnRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var methods []string
+	if user.TwoFactorEnabled {
+		methods = append(methods, domain.TwoFactorMethodTOTP)
+	}
+	if len(credentials) > 0 {
+		methods = append(methods, domain.TwoFactorMethodWebAuthn)
+	}
+
+	// Recovery codes are only a fallback for another factor
+	if len(methods) > 0 {
+		remaining, err := uc.recoveryCodeRepo.CountUnused(ctx, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if remaining > 0 {
+			methods = append(methods, domain.TwoFactorMethodRecoveryCode)
+		}
+	}
+
+	return methods, credentials, nil
+}
+
+// beginTwoFactorLogin stores a pending login and returns the token that completes it
+func (uc *useCase) beginTwoFactorLogin(ctx context.Context, user *domain.User, methods []string, credentials []*domain.WebAuthnCredential) (*AuthResponse, error) {
+	pending := pendingTwoFactorLogin{UserID: user.ID}
+	response := &AuthResponse{
+		Requires2FA:      true,
+		TwoFactorMethods: methods,
+	}
+
+	if len(credentials) > 0 && uc.webAuthn != nil {
+		assertion, session, err := uc.webAuthn.BeginLogin(&webAuthnUser{user: user, credentials: credentials})
+		if err != nil {
+			logger.Warn("Failed to start security key login", zap.String("user_id", user.ID.String()), zap.Error(err))
+		} else {
+			pending.WebAuthn = session
+			response.WebAuthn = assertion
+		}
+	}
+
+	token, err := utils.GenerateRandomString(48)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate 2FA token", 500)
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to encode 2FA login", 500)
+	}
+
+	if err := cache.Set(ctx, twoFactorLoginKey(token), data, twoFactorCeremonyTTL); err != nil {
+		return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to store 2FA login", 500)
+	}
+
+	response.TwoFactorToken = token
+	return response, nil
+}
+
+// verifySecondFactor checks the factor submitted for a pending login
+func (uc *useCase) verifySecondFactor(ctx context.Context, user *domain.User, pending *pendingTwoFactorLogin, req TwoFactorLoginRequest) (bool, error) {
+	switch req.Method {
+	case domain.TwoFactorMethodTOTP:
+		return user.TwoFactorEnabled && req.Code != "" && totp.Validate(req.Code, user.TwoFactorSecret), nil
+
+	case domain.TwoFactorMethodRecoveryCode:
+		if req.Code == "" {
+			return false, nil
+		}
+
+		used, err := uc.recoveryCodeRepo.Use(ctx, user.ID, hashRecoveryCode(req.Code))
+		if err != nil {
+			return false, err
+		}
+		if used {
+			logger.Info("Recovery code used for login", zap.String("user_id", user.ID.String()))
+		}
+		return used, nil
+
+	case domain.TwoFactorMethodWebAuthn:
+		if uc.webAuthn == nil {
+			return false, errWebAuthnDisabled
+		}
+		if pending.WebAuthn == nil || len(req.Credential) == 0 {
+			return false, nil
+		}
+
+		parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+		if err != nil {
+			return false, nil
+		}
+
+		credentials, err := uc.webAuthnRepo.ListByUserID(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+
+		credential, err := uc.webAuthn.ValidateLogin(&webAuthnUser{user: user, credentials: credentials}, *pending.WebAuthn, parsed)
+		if err != nil {
+			logger.Debug("Security key assertion rejected", zap.String("user_id", user.ID.String()), zap.Error(err))
+			return false, nil
+		}
+
+		if credential.Authenticator.CloneWarning {
+			logger.Warn("Security key sign count went backwards, the key may be cloned", zap.String("user_id", user.ID.String()))
+		}
+
+		for _, record := range credentials {
+			if string(record.CredentialID) == string(credential.ID) {
+				if err := uc.webAuthnRepo.RecordUse(ctx, record.ID, credential.Authenticator.SignCount, credential.Authenticator.CloneWarning, credential.Flags.BackupState); err != nil {
+					logger.Warn("Failed to record security key use", zap.Error(err))
+				}
+				break
+			}
+		}
+		return true, nil
+
+	default:
+		return false, errors.New(errors.ErrCodeValidation, "Unsupported 2FA method", 400)
+	}
+}
+
+// completeLogin starts a session for an authenticated user and issues its tokens
+func (uc *useCase) completeLogin(ctx context.Context, user *domain.User, client ClientInfo) (*AuthResponse, error) {
+	// Start a session for this device
+	session, err := uc.startSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update last login
+	_ = uc.userRepo.UpdateLastLogin(ctx, user.ID, client.IPAddress)
+
+	return uc.issueTokens(ctx, user, session.ID)
+}
+
+// ensureRecoveryCodes issues recovery codes if the user has none left
+func (uc *useCase) ensureRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	remaining, err := uc.recoveryCodeRepo.CountUnused(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		return nil, nil
+	}
+
+	return uc.generateRecoveryCodes(ctx, userID)
+}
+
+// generateRecoveryCodes replaces the user's recovery codes. Only hashes are stored,
+// so the codes are returned once for the user to write down.
+func (uc *useCase) generateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	count := uc.config.TwoFA.RecoveryCodes
+	if count <= 0 {
+		count = defaultRecoveryCodes
+	}
+
+	codes := make([]string, count)
+	hashes := make([]string, count)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrCodeInternal, "failed to generate recovery codes", 500)
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := uc.recoveryCodeRepo.ReplaceForUser(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// dropUnneededRecoveryCodes deletes the recovery codes of a user without any second factor left
+func (uc *useCase) dropUnneededRecoveryCodes(ctx context.Context, user *domain.User) error {
+	methods, _, err := uc.twoFactorMethods(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	for _, method := range methods {
+		if method != domain.TwoFactorMethodRecoveryCode {
+			return nil
+		}
+	}
+
+	return uc.recoveryCodeRepo.DeleteByUserID(ctx, user.ID)
+}
+
+func (uc *useCase) getWebAuthnUser(ctx context.Context, userID uuid.UUID) (*domain.User, []*domain.WebAuthnCredential, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentials, err := uc.webAuthnRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, credentials, nil
+}
+
+// newRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	code := make([]byte, recoveryCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = recoveryCodeAlphabet[n.Int64()]
+	}
+
+	half := recoveryCodeLength / 2
+	return string(code[:half]) + "-" + string(code[half:]), nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, dashes and spaces
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func toWebAuthnCredential(credential *domain.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, transport := range strings.Split(credential.Transports, ",") {
+		if transport != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              credential.CredentialID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserPresent:    credential.UserPresent,
+			UserVerified:   credential.UserVerified,
+			BackupEligible: credential.BackupEligible,
+			BackupState:    credential.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       credential.AAGUID,
+			SignCount:    credential.SignCount,
+			CloneWarning: credential.CloneWarning,
+		},
+	}
+}
+
+func fromWebAuthnCredential(userID uuid.UUID, name string, credential *webauthn.Credential) *domain.WebAuthnCredential {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	return &domain.WebAuthnCredential{
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		CloneWarning:    credential.Authenticator.CloneWarning,
+		UserPresent:     credential.Flags.UserPresent,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+}
+
+func twoFactorLoginKey(token string) string {
+	return "2fa_login:" + token
+}
+
+func webAuthnRegistrationKey(userID uuid.UUID) string {
+	return "webauthn_registration:" + userID.String()
+}
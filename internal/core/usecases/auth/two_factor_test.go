@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/utils"
+)
+
+const (
+	testRPID     = "cms.example.com"
+	testOrigin   = "https://cms.example.com"
+	testPassword = "correct-horse-battery"
+)
+
+// softAuthenticator is an in-memory security key holding one P-256 credential. It
+// answers registration with "none" attestation and signs login assertions.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 32)
+	_, _ = rand.Read(credentialID)
+
+	return &softAuthenticator{key: key, credentialID: credentialID}
+}
+
+// register answers the creation options with an attestation response
+func (a *softAuthenticator) register(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatalf("encode public key: %v", err)
+	}
+
+	// Attested credential data: AAGUID, credential ID length, credential ID, public key
+	attested := make([]byte, 16, 18+len(a.credentialID)+len(publicKey))
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	authData := a.authenticatorData(byte(protocol.FlagUserPresent|protocol.FlagUserVerified|protocol.FlagAttestedCredentialData), attested)
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("encode attestation: %v", err)
+	}
+
+	return encodeJSON(t, map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge)),
+			"attestationObject": b64(attestation),
+		},
+	})
+}
+
+// assert signs the login challenge
+func (a *softAuthenticator) assert(t *testing.T, assertion *protocol.CredentialAssertion, userID uuid.UUID) []byte {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authenticatorData(byte(protocol.FlagUserPresent|protocol.FlagUserVerified), nil)
+	clientDataJSON := clientData(t, "webauthn.get", assertion.Response.Challenge)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("sign assertion: %v", err)
+	}
+
+	return encodeJSON(t, map[string]interface{}{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientDataJSON),
+			"authenticatorData": b64(authData),
+			"signature":         b64(signature),
+			"userHandle":        b64(userID[:]),
+		},
+	})
+}
+
+// authenticatorData lays out the RP ID hash, flags, sign count and any attested data
+func (a *softAuthenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, ceremony string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	return encodeJSON(t, map[string]string{
+		"type":      ceremony,
+		"challenge": b64(challenge),
+		"origin":    testOrigin,
+	})
+}
+
+func encodeJSON(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func newTwoFactorTestUseCase(t *testing.T) (UseCase, *authFakes, *domain.User) {
+	t.Helper()
+
+	uc, fakes := newTestUseCase(t, &config.Config{TwoFA: config.TwoFAConfig{
+		Issuer:            "CMS",
+		WebAuthnRPID:      testRPID,
+		WebAuthnRPOrigins: []string{testOrigin},
+	}})
+
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := &domain.User{Email: "jane@example.com", Password: hash, Status: domain.UserStatusActive}
+	if err := fakes.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return uc, fakes, user
+}
+
+// registerKey registers the authenticator for the user and returns the response
+func registerKey(t *testing.T, uc UseCase, userID uuid.UUID, authenticator *softAuthenticator) *WebAuthnRegistrationResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	creation, err := uc.BeginWebAuthnRegistration(ctx, userID)
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration: %v", err)
+	}
+	registration, err := uc.FinishWebAuthnRegistration(ctx, userID, "Test key", authenticator.register(t, creation))
+	if err != nil {
+		t.Fatalf("FinishWebAuthnRegistration: %v", err)
+	}
+	return registration
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+	ctx := context.Background()
+	uc, fakes, user := newTwoFactorTestUseCase(t)
+	authenticator := newSoftAuthenticator(t)
+
+	// The first factor comes with recovery codes
+	registration := registerKey(t, uc, user.ID, authenticator)
+	if registration.Credential.Name != "Test key" || len(registration.RecoveryCodes) != defaultRecoveryCodes {
+		t.Fatalf("unexpected registration %+v", registration)
+	}
+	if len(fakes.webAuthn.credentials) != 1 {
+		t.Fatalf("stored %d credentials, want 1", len(fakes.webAuthn.credentials))
+	}
+
+	// The password alone now only starts a 2FA login
+	login, err := uc.Login(ctx, LoginRequest{Email: user.Email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !login.Requires2FA || login.WebAuthn == nil || login.AccessToken != "" {
+		t.Fatalf("expected a pending 2FA login with a security key challenge, got %+v", login)
+	}
+
+	// An assertion from another key is rejected
+	impostor := newSoftAuthenticator(t)
+	impostor.credentialID = authenticator.credentialID
+	_, err = uc.CompleteTwoFactorLogin(ctx, TwoFactorLoginRequest{
+		TwoFactorToken: login.TwoFactorToken,
+		Method:         domain.TwoFactorMethodWebAuthn,
+		Credential:     impostor.assert(t, login.WebAuthn, user.ID),
+	})
+	if !stderrors.Is(err, errors.ErrInvalid2FA) {
+		t.Fatalf("err = %v, want invalid 2FA", err)
+	}
+
+	// The registered key completes the login
+	result, err := uc.CompleteTwoFactorLogin(ctx, TwoFactorLoginRequest{
+		TwoFactorToken: login.TwoFactorToken,
+		Method:         domain.TwoFactorMethodWebAuthn,
+		Credential:     authenticator.assert(t, login.WebAuthn, user.ID),
+	})
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		t.Fatal("expected tokens after the second factor")
+	}
+	if fakes.webAuthn.credentials[0].SignCount != authenticator.signCount {
+		t.Fatalf("sign count = %d, want %d", fakes.webAuthn.credentials[0].SignCount, authenticator.signCount)
+	}
+
+	// The 2FA token is single use
+	if _, err := uc.CompleteTwoFactorLogin(ctx, TwoFactorLoginRequest{
+		TwoFactorToken: login.TwoFactorToken,
+		Method:         domain.TwoFactorMethodWebAuthn,
+		Credential:     authenticator.assert(t, login.WebAuthn, user.ID),
+	}); err == nil {
+		t.Fatal("expected a used 2FA token to be rejected")
+	}
+}
+
+func TestRecoveryCodeLogin(t *testing.T) {
+	ctx := context.Background()
+	uc, fakes, user := newTwoFactorTestUseCase(t)
+	codes := registerKey(t, uc, user.ID, newSoftAuthenticator(t)).RecoveryCodes
+
+	login, err := uc.Login(ctx, LoginRequest{Email: user.Email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	// Codes are accepted regardless of case and dashes, once
+	code := codes[0]
+	if _, err := uc.CompleteTwoFactorLogin(ctx, TwoFactorLoginRequest{
+		TwoFactorToken: login.TwoFactorToken,
+		Method:         domain.TwoFactorMethodRecoveryCode,
+		Code:           code[:5] + code[6:],
+	}); err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if remaining, _ := fakes.recoveryCodes.CountUnused(ctx, user.ID); remaining != int64(len(codes)-1) {
+		t.Fatalf("remaining codes = %d, want %d", remaining, len(codes)-1)
+	}
+
+	login, err = uc.Login(ctx, LoginRequest{Email: user.Email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := uc.CompleteTwoFactorLogin(ctx, TwoFactorLoginRequest{
+		TwoFactorToken: login.TwoFactorToken,
+		Method:         domain.TwoFactorMethodRecoveryCode,
+		Code:           code,
+	}); !stderrors.Is(err, errors.ErrInvalid2FA) {
+		t.Fatalf("err = %v, want a used recovery code to be rejected", err)
+	}
+}
+
+func TestDeleteWebAuthnCredentialRequiresPassword(t *testing.T) {
+	ctx := context.Background()
+	uc, fakes, user := newTwoFactorTestUseCase(t)
+	credential := registerKey(t, uc, user.ID, newSoftAuthenticator(t)).Credential
+
+	err := uc.DeleteWebAuthnCredential(ctx, user.ID, credential.ID, "wrong-password")
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) || appErr.Code != "INVALID_PASSWORD" {
+		t.Fatalf("err = %v, want INVALID_PASSWORD", err)
+	}
+	if len(fakes.webAuthn.credentials) != 1 {
+		t.Fatal("security key deleted without the password")
+	}
+
+	if err := uc.DeleteWebAuthnCredential(ctx, user.ID, credential.ID, testPassword); err != nil {
+		t.Fatalf("DeleteWebAuthnCredential: %v", err)
+	}
+	if len(fakes.webAuthn.credentials) != 0 {
+		t.Fatal("security key not deleted")
+	}
+
+	// Without a second factor left, recovery codes go too and login needs the password only
+	if remaining, _ := fakes.recoveryCodes.CountUnused(ctx, user.ID); remaining != 0 {
+		t.Fatalf("remaining codes = %d, want 0", remaining)
+	}
+	login, err := uc.Login(ctx, LoginRequest{Email: user.Email, Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login.Requires2FA || login.AccessToken == "" {
+		t.Fatalf("expected a direct login, got %+v", login)
+	}
+}
@@ -3,6 +3,7 @@ package authorization
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/pkg/errors"
@@ -21,44 +22,69 @@ type DepartmentUseCase interface {
 	UpdateDepartment(ctx context.Context, id uint, req UpdateDepartmentRequest) (*domain.Department, error)
 	DeleteDepartment(ctx context.Context, id uint) error
 	ListActiveDepartments(ctx context.Context) ([]domain.Department, error)
+
+	// Tree operations
+	MoveDepartment(ctx context.Context, id uint, parentID *uint) (*domain.Department, error)
+	GetDepartmentSubtree(ctx context.Context, id uint) ([]domain.Department, error)
+	ListMembers(ctx context.Context, id uint, includeSubDepartments bool, page *pagination.OffsetPagination) ([]*domain.User, int64, error)
+	AddMember(ctx context.Context, id uint, userID uuid.UUID, managerID *uuid.UUID) error
+	RemoveMember(ctx context.Context, id uint, userID uuid.UUID) error
+
+	// Manager delegation
+	ListManagedDepartments(ctx context.Context, managerID uuid.UUID) ([]domain.Department, error)
+	IsDepartmentManager(ctx context.Context, managerID uuid.UUID, departmentID uint) (bool, error)
+	ManagesUser(ctx context.Context, managerID, userID uuid.UUID) (bool, error)
 }
 
 type departmentUseCase struct {
 	departmentRepo repositories.DepartmentRepository
 	moduleRepo     repositories.ModuleRepository
+	userRepo       repositories.UserRepository
 }
 
 // NewDepartmentUseCase creates a new department use case
 func NewDepartmentUseCase(
 	departmentRepo repositories.DepartmentRepository,
 	moduleRepo repositories.ModuleRepository,
+	userRepo repositories.UserRepository,
 ) DepartmentUseCase {
 	return &departmentUseCase{
 		departmentRepo: departmentRepo,
 		moduleRepo:     moduleRepo,
+		userRepo:       userRepo,
 	}
 }
 
 // CreateDepartmentRequest represents a request to create a department
 type CreateDepartmentRequest struct {
-	ModuleID    uint   `json:"module_id" binding:"required"`
-	Code        string `json:"code" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	DisplayName string `json:"display_name"`
-	Description string `json:"description"`
-	ParentID    *uint  `json:"parent_id"`
-	ManagerID   *uint  `json:"manager_id"`
-	IsActive    bool   `json:"is_active"`
+	ModuleID    uint       `json:"module_id" binding:"required"`
+	Code        string     `json:"code" binding:"required"`
+	Name        string     `json:"name" binding:"required"`
+	DisplayName string     `json:"display_name"`
+	Description string     `json:"description"`
+	ParentID    *uint      `json:"parent_id"`
+	ManagerID   *uuid.UUID `json:"manager_id"`
+	IsActive    bool       `json:"is_active"`
 }
 
 // UpdateDepartmentRequest represents a request to update a department
 type UpdateDepartmentRequest struct {
-	Name        string `json:"name"`
-	DisplayName string `json:"display_name"`
-	Description string `json:"description"`
-	ParentID    *uint  `json:"parent_id"`
-	ManagerID   *uint  `json:"manager_id"`
-	IsActive    *bool  `json:"is_active"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"display_name"`
+	Description string     `json:"description"`
+	ParentID    *uint      `json:"parent_id"`
+	ManagerID   *uuid.UUID `json:"manager_id"`
+	IsActive    *bool      `json:"is_active"`
+}
+
+// MoveDepartmentRequest represents a request to move a department in the tree
+type MoveDepartmentRequest struct {
+	ParentID *uint `json:"parent_id"` // Omit to make the department a root
+}
+
+// AddMemberRequest represents a request to add a user to a department
+type AddMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" binding:"required"`
 }
 
 // CreateDepartment creates a new department
@@ -87,6 +113,10 @@ func (uc *departmentUseCase) CreateDepartment(ctx context.Context, req CreateDep
 		}
 	}
 
+	if err := uc.validateManager(ctx, req.ManagerID); err != nil {
+		return nil, err
+	}
+
 	department := &domain.Department{
 		ModuleID:    req.ModuleID,
 		Code:        req.Code,
@@ -163,21 +193,15 @@ func (uc *departmentUseCase) UpdateDepartment(ctx context.Context, id uint, req
 		return nil, errors.New(errors.ErrCodeForbidden, "cannot modify system department", 403)
 	}
 
-	// Validate parent department if provided
-	if req.ParentID != nil {
-		// Prevent self-reference
-		if *req.ParentID == id {
-			return nil, errors.New(errors.ErrCodeValidation, "department cannot be its own parent", 400)
+	moved := req.ParentID != nil && (department.ParentID == nil || *department.ParentID != *req.ParentID)
+	if moved {
+		if err := uc.validateParent(ctx, department, req.ParentID); err != nil {
+			return nil, err
 		}
+	}
 
-		parent, err := uc.departmentRepo.GetByID(ctx, *req.ParentID)
-		if err != nil {
-			return nil, errors.New(errors.ErrCodeNotFound, "parent department not found", 404)
-		}
-		// Ensure parent is in the same module
-		if parent.ModuleID != department.ModuleID {
-			return nil, errors.New(errors.ErrCodeValidation, "parent department must be in the same module", 400)
-		}
+	if err := uc.validateManager(ctx, req.ManagerID); err != nil {
+		return nil, err
 	}
 
 	// Update fields
@@ -190,11 +214,9 @@ func (uc *departmentUseCase) UpdateDepartment(ctx context.Context, id uint, req
 	if req.Description != "" {
 		department.Description = req.Description
 	}
-	if req.ParentID != nil {
-		department.ParentID = req.ParentID
-	}
 	if req.ManagerID != nil {
 		department.ManagerID = req.ManagerID
+		department.Manager = nil // Saving the loaded manager would restore the old ID
 	}
 	if req.IsActive != nil {
 		department.IsActive = *req.IsActive
@@ -205,6 +227,14 @@ func (uc *departmentUseCase) UpdateDepartment(ctx context.Context, id uint, req
 		return nil, err
 	}
 
+	// A new parent moves the whole subtree
+	if moved {
+		if err := uc.departmentRepo.Move(ctx, id, req.ParentID); err != nil {
+			return nil, err
+		}
+		return uc.departmentRepo.GetByID(ctx, id)
+	}
+
 	logger.Info("Department updated successfully", zap.Uint("id", id))
 	return department, nil
 }
@@ -222,7 +252,9 @@ func (uc *departmentUseCase) DeleteDepartment(ctx context.Context, id uint) erro
 		return errors.New(errors.ErrCodeForbidden, "cannot delete system department", 403)
 	}
 
-	// TODO: Check if department has children or dependencies
+	if len(department.Children) > 0 {
+		return errors.New(errors.ErrCodeConflict, "cannot delete a department that has sub-departments", 409)
+	}
 
 	if err := uc.departmentRepo.Delete(ctx, id); err != nil {
 		logger.Error("Failed to delete department", zap.Error(err))
@@ -241,3 +273,149 @@ func (uc *departmentUseCase) ListActiveDepartments(ctx context.Context) ([]domai
 	}
 	return departments, nil
 }
+
+// MoveDepartment moves a department, with every department below it, under a new parent
+func (uc *departmentUseCase) MoveDepartment(ctx context.Context, id uint, parentID *uint) (*domain.Department, error) {
+	department, err := uc.departmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if department.IsSystem {
+		return nil, errors.New(errors.ErrCodeForbidden, "cannot move system department", 403)
+	}
+
+	if err := uc.validateParent(ctx, department, parentID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.departmentRepo.Move(ctx, id, parentID); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Department moved successfully", zap.Uint("id", id), zap.Any("parentId", parentID))
+	return uc.departmentRepo.GetByID(ctx, id)
+}
+
+// GetDepartmentSubtree returns a department and every department below it, ordered by path
+func (uc *departmentUseCase) GetDepartmentSubtree(ctx context.Context, id uint) ([]domain.Department, error) {
+	if _, err := uc.departmentRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return uc.departmentRepo.ListSubtree(ctx, id)
+}
+
+// ListMembers lists the users of a department, and optionally of every department below it
+func (uc *departmentUseCase) ListMembers(ctx context.Context, id uint, includeSubDepartments bool, page *pagination.OffsetPagination) ([]*domain.User, int64, error) {
+	if _, err := uc.departmentRepo.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	filter := repositories.UserFilter{
+		DepartmentID:          &id,
+		IncludeSubDepartments: includeSubDepartments,
+	}
+	return uc.userRepo.List(ctx, filter, page)
+}
+
+// AddMember moves a user into a department. A manager acting on their own subtree
+// (managerID set) can only take in users who have no department or already belong to
+// one they manage.
+func (uc *departmentUseCase) AddMember(ctx context.Context, id uint, userID uuid.UUID, managerID *uuid.UUID) error {
+	if _, err := uc.departmentRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if managerID != nil && user.DepartmentID != nil {
+		manages, err := uc.departmentRepo.IsManagedBy(ctx, *user.DepartmentID, *managerID)
+		if err != nil {
+			return err
+		}
+		if !manages {
+			return errors.New(errors.ErrCodeForbidden, "user belongs to a department you do not manage", 403)
+		}
+	}
+
+	if err := uc.departmentRepo.SetUserDepartment(ctx, userID, &id); err != nil {
+		return err
+	}
+
+	logger.Info("Department member added", zap.Uint("departmentId", id), zap.String("userId", userID.String()))
+	return nil
+}
+
+// RemoveMember takes a user out of a department, leaving them without one
+func (uc *departmentUseCase) RemoveMember(ctx context.Context, id uint, userID uuid.UUID) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.DepartmentID == nil || *user.DepartmentID != id {
+		return errors.New(errors.ErrCodeNotFound, "user is not a member of this department", 404)
+	}
+
+	if err := uc.departmentRepo.SetUserDepartment(ctx, userID, nil); err != nil {
+		return err
+	}
+
+	logger.Info("Department member removed", zap.Uint("departmentId", id), zap.String("userId", userID.String()))
+	return nil
+}
+
+// ListManagedDepartments lists the departments a user manages directly. They also
+// manage every department below these.
+func (uc *departmentUseCase) ListManagedDepartments(ctx context.Context, managerID uuid.UUID) ([]domain.Department, error) {
+	return uc.departmentRepo.ListManagedBy(ctx, managerID)
+}
+
+// IsDepartmentManager reports whether a user manages a department, directly or through
+// a department above it
+func (uc *departmentUseCase) IsDepartmentManager(ctx context.Context, managerID uuid.UUID, departmentID uint) (bool, error) {
+	return uc.departmentRepo.IsManagedBy(ctx, departmentID, managerID)
+}
+
+// ManagesUser reports whether a user's department is managed by the manager, directly
+// or through a department above it
+func (uc *departmentUseCase) ManagesUser(ctx context.Context, managerID, userID uuid.UUID) (bool, error) {
+	return uc.departmentRepo.ManagesUser(ctx, managerID, userID)
+}
+
+// validateParent checks that a department can be moved under a parent: one in the same
+// module that is not the department itself or below it
+func (uc *departmentUseCase) validateParent(ctx context.Context, department *domain.Department, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == department.ID {
+		return errors.New(errors.ErrCodeValidation, "department cannot be its own parent", 400)
+	}
+
+	parent, err := uc.departmentRepo.GetByID(ctx, *parentID)
+	if err != nil {
+		return errors.New(errors.ErrCodeNotFound, "parent department not found", 404)
+	}
+	if parent.ModuleID != department.ModuleID {
+		return errors.New(errors.ErrCodeValidation, "parent department must be in the same module", 400)
+	}
+	if department.Contains(parent) {
+		return errors.New(errors.ErrCodeValidation, "cannot move a department below one of its own sub-departments", 400)
+	}
+	return nil
+}
+
+// validateManager checks that a department's manager is an existing user
+func (uc *departmentUseCase) validateManager(ctx context.Context, managerID *uuid.UUID) error {
+	if managerID == nil {
+		return nil
+	}
+	if _, err := uc.userRepo.GetByID(ctx, *managerID); err != nil {
+		return errors.New(errors.ErrCodeNotFound, "manager not found", 404)
+	}
+	return nil
+}
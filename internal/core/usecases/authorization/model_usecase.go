@@ -0,0 +1,563 @@
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Model formats
+const (
+	ModelFormatYAML = "yaml"
+	ModelFormatJSON = "json"
+)
+
+// ModelUseCase exports the authorization model and applies it back declaratively. A
+// model lists every entry: applying it creates and updates what it lists and deletes
+// what it leaves out, except system entries, which it must keep.
+type ModelUseCase struct {
+	modelRepo repositories.AuthorizationModelRepository
+}
+
+// NewModelUseCase creates a new authorization model use case
+func NewModelUseCase(modelRepo repositories.AuthorizationModelRepository) *ModelUseCase {
+	return &ModelUseCase{modelRepo: modelRepo}
+}
+
+// ModelChangeAction is what applying a model does to an entry
+type ModelChangeAction string
+
+const (
+	ModelChangeCreate ModelChangeAction = "create"
+	ModelChangeUpdate ModelChangeAction = "update"
+	ModelChangeDelete ModelChangeAction = "delete"
+)
+
+// ModelChange is one entry applying a model creates, updates or deletes
+type ModelChange struct {
+	Kind   string             `json:"kind"` // module, department, service, scope, permission, role or policy
+	Key    string             `json:"key"`  // Code, or name for roles and policies
+	Action ModelChangeAction  `json:"action"`
+	Fields []ModelFieldChange `json:"fields,omitempty"` // Changed fields of an update
+}
+
+// ModelFieldChange is a field an update changes. List fields report the items added
+// and removed instead of both values.
+type ModelFieldChange struct {
+	Field   string        `json:"field"`
+	From    interface{}   `json:"from,omitempty"`
+	To      interface{}   `json:"to,omitempty"`
+	Added   []interface{} `json:"added,omitempty"`
+	Removed []interface{} `json:"removed,omitempty"`
+}
+
+// ModelPlan is what applying a model would change
+type ModelPlan struct {
+	Changes []ModelChange `json:"changes"`
+	Errors  []string      `json:"errors,omitempty"` // Problems that stop the model being applied
+	Applied bool          `json:"applied"`
+}
+
+// ExportModel returns the current authorization model
+func (uc *ModelUseCase) ExportModel(ctx context.Context) (*domain.AuthorizationModel, error) {
+	return uc.modelRepo.Export(ctx)
+}
+
+// PlanModel returns what applying a model would change, without applying it
+func (uc *ModelUseCase) PlanModel(ctx context.Context, model *domain.AuthorizationModel) (*ModelPlan, error) {
+	current, err := uc.modelRepo.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizeModel(current)
+	normalizeModel(model)
+
+	return &ModelPlan{
+		Changes: diffModels(current, model),
+		Errors:  validateModel(current, model),
+	}, nil
+}
+
+// ApplyModel makes the stored authorization model match the given one. Nothing is
+// applied if the plan has errors.
+func (uc *ModelUseCase) ApplyModel(ctx context.Context, model *domain.AuthorizationModel, appliedBy uuid.UUID) (*ModelPlan, error) {
+	plan, err := uc.PlanModel(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Errors) > 0 {
+		return nil, errors.New(errors.ErrCodeValidation, "authorization model cannot be applied: "+strings.Join(plan.Errors, "; "), 400)
+	}
+	if len(plan.Changes) == 0 {
+		return plan, nil
+	}
+
+	if err := uc.modelRepo.Apply(ctx, model); err != nil {
+		return nil, err
+	}
+	plan.Applied = true
+
+	// Any user's permissions and any policy may have changed
+	if err := cache.InvalidateAllPermissions(ctx); err != nil {
+		logger.Warn("Failed to invalidate permission cache", zap.Error(err))
+	}
+	if err := cache.PublishPermissionEvent(ctx, cache.PermissionEvent{Policies: true}); err != nil {
+		logger.Warn("Failed to announce policy change", zap.Error(err))
+	}
+
+	logger.Info("Authorization model applied",
+		zap.String("appliedBy", appliedBy.String()),
+		zap.Int("changes", len(plan.Changes)),
+	)
+	return plan, nil
+}
+
+// DecodeModel reads a model in YAML or JSON. Unknown fields are rejected, so a typo
+// does not silently drop a setting.
+func DecodeModel(data []byte, format string) (*domain.AuthorizationModel, error) {
+	var model domain.AuthorizationModel
+
+	switch format {
+	case ModelFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&model); err != nil {
+			return nil, errors.New(errors.ErrCodeValidation, "invalid JSON model: "+err.Error(), 400)
+		}
+	case ModelFormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&model); err != nil {
+			return nil, errors.New(errors.ErrCodeValidation, "invalid YAML model: "+err.Error(), 400)
+		}
+	default:
+		return nil, errors.New(errors.ErrCodeValidation, "format must be yaml or json", 400)
+	}
+
+	return &model, nil
+}
+
+// EncodeModel writes a model in YAML or JSON
+func EncodeModel(model *domain.AuthorizationModel, format string) ([]byte, error) {
+	switch format {
+	case ModelFormatJSON:
+		return json.MarshalIndent(model, "", "  ")
+	case ModelFormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(model); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.New(errors.ErrCodeValidation, "format must be yaml or json", 400)
+	}
+}
+
+// normalizeModel fills in defaults and sorts role permissions, so equal models compare
+// equal however they were written
+func normalizeModel(model *domain.AuthorizationModel) {
+	for i := range model.Modules {
+		model.Modules[i].IsActive = activeFlag(model.Modules[i].IsActive)
+	}
+	for i := range model.Departments {
+		model.Departments[i].IsActive = activeFlag(model.Departments[i].IsActive)
+	}
+	for i := range model.Services {
+		model.Services[i].IsActive = activeFlag(model.Services[i].IsActive)
+	}
+	for i := range model.Roles {
+		seen := make(map[string]bool, len(model.Roles[i].Permissions))
+		permissions := make([]string, 0, len(model.Roles[i].Permissions))
+		for _, code := range model.Roles[i].Permissions {
+			if !seen[code] {
+				seen[code] = true
+				permissions = append(permissions, code)
+			}
+		}
+		sort.Strings(permissions)
+		model.Roles[i].Permissions = permissions
+	}
+	for i := range model.Policies {
+		model.Policies[i].IsActive = activeFlag(model.Policies[i].IsActive)
+	}
+}
+
+func activeFlag(value *bool) *bool {
+	active := value == nil || *value
+	return &active
+}
+
+// validateModel checks that every entry has a key, keys are unique, references resolve
+// within the model, and no system entry is deleted or loses its system flag
+func validateModel(current, model *domain.AuthorizationModel) []string {
+	v := &modelValidator{}
+
+	modules := make(map[string]bool)
+	for _, spec := range model.Modules {
+		if v.key("module", spec.Code, modules) {
+			v.required("module", spec.Code, "name", spec.Name)
+		}
+	}
+
+	departments := make(map[string]domain.DepartmentSpec)
+	departmentCodes := make(map[string]bool)
+	for _, spec := range model.Departments {
+		if !v.key("department", spec.Code, departmentCodes) {
+			continue
+		}
+		departments[spec.Code] = spec
+		v.required("department", spec.Code, "name", spec.Name)
+		v.reference("department", spec.Code, "module", spec.Module, modules[spec.Module])
+	}
+	for _, spec := range model.Departments {
+		if spec.Parent != "" {
+			_, ok := departments[spec.Parent]
+			v.reference("department", spec.Code, "parent", spec.Parent, ok)
+		}
+	}
+	v.acyclic("department", departmentParents(model.Departments))
+
+	services := make(map[string]domain.ServiceSpec)
+	serviceCodes := make(map[string]bool)
+	for _, spec := range model.Services {
+		if !v.key("service", spec.Code, serviceCodes) {
+			continue
+		}
+		services[spec.Code] = spec
+		v.required("service", spec.Code, "name", spec.Name)
+		_, ok := departments[spec.Department]
+		v.reference("service", spec.Code, "department", spec.Department, ok)
+	}
+
+	scopes := make(map[string]bool)
+	for _, spec := range model.Scopes {
+		if !v.key("scope", spec.Code, scopes) {
+			continue
+		}
+		v.required("scope", spec.Code, "name", spec.Name)
+		if spec.Level.Rank() == 0 {
+			v.errorf("scope %s: level must be organization, department, team or personal", spec.Code)
+		}
+	}
+
+	permissions := make(map[string]bool)
+	for _, spec := range model.Permissions {
+		code := spec.Code()
+		if !v.key("permission", code, permissions) {
+			continue
+		}
+		v.required("permission", code, "resource", spec.Resource)
+		v.required("permission", code, "action", string(spec.Action))
+		v.reference("permission", code, "module", spec.Module, modules[spec.Module])
+		v.reference("permission", code, "scope", spec.Scope, scopes[spec.Scope])
+		if department, ok := departments[spec.Department]; !ok {
+			v.reference("permission", code, "department", spec.Department, false)
+		} else if department.Module != spec.Module {
+			v.errorf("permission %s: department %s belongs to module %s", code, spec.Department, department.Module)
+		}
+		if service, ok := services[spec.Service]; !ok {
+			v.reference("permission", code, "service", spec.Service, false)
+		} else if service.Department != spec.Department {
+			v.errorf("permission %s: service %s belongs to department %s", code, spec.Service, service.Department)
+		}
+	}
+
+	roles := make(map[string]bool)
+	for _, spec := range model.Roles {
+		if !v.key("role", spec.Name, roles) {
+			continue
+		}
+		switch spec.Level {
+		case domain.RoleLevelOrganization, domain.RoleLevelDepartment, domain.RoleLevelService, domain.RoleLevelAction:
+		default:
+			v.errorf("role %s: level must be organization, department, service or action", spec.Name)
+		}
+		for _, code := range spec.Permissions {
+			v.reference("role", spec.Name, "permission", code, permissions[code])
+		}
+	}
+	for _, spec := range model.Roles {
+		if spec.Parent != "" {
+			v.reference("role", spec.Name, "parent", spec.Parent, roles[spec.Parent])
+		}
+	}
+	v.acyclic("role", roleParents(model.Roles))
+
+	policies := make(map[string]bool)
+	for _, spec := range model.Policies {
+		if !v.key("policy", spec.Name, policies) {
+			continue
+		}
+		if err := validatePolicy(spec.Permission, spec.Effect, spec.Condition); err != nil {
+			v.errorf("policy %s: %s", spec.Name, err.Error())
+		}
+	}
+
+	// System entries cannot be deleted, nor made deletable
+	for _, kind := range []struct {
+		name    string
+		current []systemEntry
+		desired []systemEntry
+	}{
+		{"module", systemEntries(current.Modules, func(s domain.ModuleSpec) (string, bool) { return s.Code, s.IsSystem }),
+			systemEntries(model.Modules, func(s domain.ModuleSpec) (string, bool) { return s.Code, s.IsSystem })},
+		{"department", systemEntries(current.Departments, func(s domain.DepartmentSpec) (string, bool) { return s.Code, s.IsSystem }),
+			systemEntries(model.Departments, func(s domain.DepartmentSpec) (string, bool) { return s.Code, s.IsSystem })},
+		{"service", systemEntries(current.Services, func(s domain.ServiceSpec) (string, bool) { return s.Code, s.IsSystem }),
+			systemEntries(model.Services, func(s domain.ServiceSpec) (string, bool) { return s.Code, s.IsSystem })},
+		{"scope", systemEntries(current.Scopes, func(s domain.ScopeSpec) (string, bool) { return s.Code, s.IsSystem }),
+			systemEntries(model.Scopes, func(s domain.ScopeSpec) (string, bool) { return s.Code, s.IsSystem })},
+		{"permission", systemEntries(current.Permissions, func(s domain.PermissionSpec) (string, bool) { return s.Code(), s.IsSystem }),
+			systemEntries(model.Permissions, func(s domain.PermissionSpec) (string, bool) { return s.Code(), s.IsSystem })},
+		{"role", systemEntries(current.Roles, func(s domain.RoleSpec) (string, bool) { return s.Name, s.IsSystem }),
+			systemEntries(model.Roles, func(s domain.RoleSpec) (string, bool) { return s.Name, s.IsSystem })},
+	} {
+		desired := make(map[string]bool, len(kind.desired))
+		for _, entry := range kind.desired {
+			desired[entry.key] = entry.isSystem
+		}
+		for _, entry := range kind.current {
+			if !entry.isSystem {
+				continue
+			}
+			isSystem, kept := desired[entry.key]
+			switch {
+			case !kept:
+				v.errorf("%s %s is a system entry and cannot be deleted", kind.name, entry.key)
+			case !isSystem:
+				v.errorf("%s %s is a system entry and must keep is_system", kind.name, entry.key)
+			}
+		}
+	}
+
+	return v.errors
+}
+
+// modelValidator collects the problems found in a model
+type modelValidator struct {
+	errors []string
+}
+
+func (v *modelValidator) errorf(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+// key checks an entry has a key not used before, and records it
+func (v *modelValidator) key(kind, key string, seen map[string]bool) bool {
+	if key == "" {
+		v.errorf("%s without a code or name", kind)
+		return false
+	}
+	if seen[key] {
+		v.errorf("%s %s is listed more than once", kind, key)
+		return false
+	}
+	seen[key] = true
+	return true
+}
+
+func (v *modelValidator) required(kind, key, field, value string) {
+	if value == "" {
+		v.errorf("%s %s: %s is required", kind, key, field)
+	}
+}
+
+func (v *modelValidator) reference(kind, key, field, value string, ok bool) {
+	if !ok {
+		v.errorf("%s %s: %s %q is not in the model", kind, key, field, value)
+	}
+}
+
+// acyclic reports every entry whose parent chain loops back on itself
+func (v *modelValidator) acyclic(kind string, parents map[string]string) {
+	keys := make([]string, 0, len(parents))
+	for key := range parents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		seen := map[string]bool{key: true}
+		for parent := parents[key]; parent != ""; parent = parents[parent] {
+			if parent == key {
+				v.errorf("%s %s is its own ancestor", kind, key)
+				break
+			}
+			if seen[parent] {
+				break
+			}
+			seen[parent] = true
+		}
+	}
+}
+
+func departmentParents(specs []domain.DepartmentSpec) map[string]string {
+	parents := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		parents[spec.Code] = spec.Parent
+	}
+	return parents
+}
+
+func roleParents(specs []domain.RoleSpec) map[string]string {
+	parents := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		parents[spec.Name] = spec.Parent
+	}
+	return parents
+}
+
+type systemEntry struct {
+	key      string
+	isSystem bool
+}
+
+func systemEntries[T any](specs []T, entry func(T) (string, bool)) []systemEntry {
+	entries := make([]systemEntry, 0, len(specs))
+	for _, spec := range specs {
+		key, isSystem := entry(spec)
+		entries = append(entries, systemEntry{key: key, isSystem: isSystem})
+	}
+	return entries
+}
+
+// diffModels lists the entries applying the model creates, updates and deletes, in
+// the order they are applied
+func diffModels(current, model *domain.AuthorizationModel) []ModelChange {
+	changes := []ModelChange{}
+	changes = append(changes, diffEntries("module", current.Modules, model.Modules, func(s domain.ModuleSpec) string { return s.Code })...)
+	changes = append(changes, diffEntries("department", current.Departments, model.Departments, func(s domain.DepartmentSpec) string { return s.Code })...)
+	changes = append(changes, diffEntries("service", current.Services, model.Services, func(s domain.ServiceSpec) string { return s.Code })...)
+	changes = append(changes, diffEntries("scope", current.Scopes, model.Scopes, func(s domain.ScopeSpec) string { return s.Code })...)
+	changes = append(changes, diffEntries("permission", current.Permissions, model.Permissions, domain.PermissionSpec.Code)...)
+	changes = append(changes, diffEntries("role", current.Roles, model.Roles, func(s domain.RoleSpec) string { return s.Name })...)
+	changes = append(changes, diffEntries("policy", current.Policies, model.Policies, func(s domain.PolicySpec) string { return s.Name })...)
+	return changes
+}
+
+// diffEntries compares one kind of entry by key. System entries left out of the model
+// are not listed as deletions; validation reports them instead.
+func diffEntries[T any](kind string, current, desired []T, key func(T) string) []ModelChange {
+	var changes []ModelChange
+
+	existing := make(map[string]T, len(current))
+	for _, spec := range current {
+		existing[key(spec)] = spec
+	}
+
+	kept := make(map[string]bool, len(desired))
+	for _, spec := range desired {
+		k := key(spec)
+		if kept[k] {
+			continue
+		}
+		kept[k] = true
+
+		before, ok := existing[k]
+		if !ok {
+			changes = append(changes, ModelChange{Kind: kind, Key: k, Action: ModelChangeCreate})
+			continue
+		}
+		if fields := diffFields(before, spec); len(fields) > 0 {
+			changes = append(changes, ModelChange{Kind: kind, Key: k, Action: ModelChangeUpdate, Fields: fields})
+		}
+	}
+
+	for _, spec := range current {
+		k := key(spec)
+		if !kept[k] && !specFields(spec)["is_system"].(bool) {
+			changes = append(changes, ModelChange{Kind: kind, Key: k, Action: ModelChangeDelete})
+		}
+	}
+
+	return changes
+}
+
+// diffFields compares two entries field by field, by their JSON names
+func diffFields(before, after interface{}) []ModelFieldChange {
+	from, to := specFields(before), specFields(after)
+
+	names := make([]string, 0, len(to))
+	for name := range to {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []ModelFieldChange
+	for _, name := range names {
+		if reflect.DeepEqual(from[name], to[name]) {
+			continue
+		}
+
+		fromList, fromIsList := from[name].([]interface{})
+		toList, toIsList := to[name].([]interface{})
+		if fromIsList && toIsList {
+			fields = append(fields, ModelFieldChange{
+				Field:   name,
+				Added:   listDifference(toList, fromList),
+				Removed: listDifference(fromList, toList),
+			})
+			continue
+		}
+
+		fields = append(fields, ModelFieldChange{Field: name, From: from[name], To: to[name]})
+	}
+	return fields
+}
+
+// specFields reads an entry as its JSON fields, with every field present
+func specFields(spec interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	value := reflect.ValueOf(spec)
+	specType := value.Type()
+	for i := 0; i < specType.NumField(); i++ {
+		name := strings.Split(specType.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		data, _ := json.Marshal(value.Field(i).Interface())
+		var field interface{}
+		_ = json.Unmarshal(data, &field)
+		fields[name] = field
+	}
+	return fields
+}
+
+// listDifference returns the items of a that are not in b
+func listDifference(a, b []interface{}) []interface{} {
+	var difference []interface{}
+	for _, item := range a {
+		found := false
+		for _, other := range b {
+			if reflect.DeepEqual(item, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			difference = append(difference, item)
+		}
+	}
+	return difference
+}
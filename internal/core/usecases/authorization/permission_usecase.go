@@ -5,6 +5,7 @@ import (
 
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
 	"github.com/owner/go-cms/pkg/logger"
 	"go.uber.org/zap"
@@ -12,140 +13,182 @@ import (
 
 // PermissionUseCase handles permission business logic
 type PermissionUseCase struct {
-	permissionRepo repositories.PermissionRepository
+	permissionRepo repositories.EnhancedPermissionRepository
+	moduleRepo     repositories.ModuleRepository
+	departmentRepo repositories.DepartmentRepository
+	serviceRepo    repositories.ServiceRepository
+	scopeRepo      repositories.ScopeRepository
 }
 
 // NewPermissionUseCase creates a new permission use case
-func NewPermissionUseCase(permissionRepo repositories.PermissionRepository) *PermissionUseCase {
+func NewPermissionUseCase(
+	permissionRepo repositories.EnhancedPermissionRepository,
+	moduleRepo repositories.ModuleRepository,
+	departmentRepo repositories.DepartmentRepository,
+	serviceRepo repositories.ServiceRepository,
+	scopeRepo repositories.ScopeRepository,
+) *PermissionUseCase {
 	return &PermissionUseCase{
 		permissionRepo: permissionRepo,
+		moduleRepo:     moduleRepo,
+		departmentRepo: departmentRepo,
+		serviceRepo:    serviceRepo,
+		scopeRepo:      scopeRepo,
 	}
 }
 
+// CreatePermissionRequest represents a request to create a permission
+type CreatePermissionRequest struct {
+	ModuleID     uint                    `json:"module_id" binding:"required"`
+	DepartmentID uint                    `json:"department_id" binding:"required"`
+	ServiceID    uint                    `json:"service_id" binding:"required"`
+	ScopeID      uint                    `json:"scope_id" binding:"required"`
+	Resource     string                  `json:"resource" binding:"required,max=100"`
+	Action       domain.PermissionAction `json:"action" binding:"required,max=50"`
+	DisplayName  string                  `json:"display_name"`
+	Description  string                  `json:"description"`
+}
+
+// UpdatePermissionRequest represents a request to update a permission. The hierarchy,
+// resource and action make up the permission code and cannot change.
+type UpdatePermissionRequest struct {
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+}
+
 // CreatePermission creates a new permission
-func (uc *PermissionUseCase) CreatePermission(ctx context.Context, permission *domain.Permission) error {
-	// Validate permission
-	if permission.Resource == "" || permission.Action == "" {
-		return errors.New(errors.ErrCodeValidation, "resource and action are required", 400)
+func (uc *PermissionUseCase) CreatePermission(ctx context.Context, req CreatePermissionRequest) (*domain.EnhancedPermission, error) {
+	module, err := uc.moduleRepo.GetByID(ctx, req.ModuleID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "module not found", 404)
+	}
+
+	department, err := uc.departmentRepo.GetByID(ctx, req.DepartmentID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "department not found", 404)
+	}
+	if department.ModuleID != module.ID {
+		return nil, errors.New(errors.ErrCodeValidation, "department does not belong to module", 400)
+	}
+
+	service, err := uc.serviceRepo.GetByID(ctx, req.ServiceID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "service not found", 404)
+	}
+	if service.DepartmentID != department.ID {
+		return nil, errors.New(errors.ErrCodeValidation, "service does not belong to department", 400)
 	}
 
-	// Check if permission with same key already exists
-	key := permission.GetPermissionKey()
-	existing, err := uc.permissionRepo.GetByKey(ctx, key)
+	scope, err := uc.scopeRepo.GetByID(ctx, req.ScopeID)
+	if err != nil {
+		return nil, errors.New(errors.ErrCodeNotFound, "scope not found", 404)
+	}
+
+	permission := &domain.EnhancedPermission{
+		ModuleID:     module.ID,
+		DepartmentID: department.ID,
+		ServiceID:    service.ID,
+		ScopeID:      scope.ID,
+		Resource:     req.Resource,
+		Action:       req.Action,
+		Code:         module.Code + ":" + department.Code + ":" + service.Code + ":" + scope.Code + ":" + req.Resource + ":" + string(req.Action),
+		DisplayName:  req.DisplayName,
+		Description:  req.Description,
+	}
+
+	// Check if permission with same code already exists
+	existing, err := uc.permissionRepo.GetByCode(ctx, permission.Code)
 	if err == nil && existing != nil {
-		return errors.New(errors.ErrCodeConflict, "permission with this key already exists", 409)
+		return nil, errors.New(errors.ErrCodeConflict, "permission with this code already exists", 409)
 	}
 
 	if err := uc.permissionRepo.Create(ctx, permission); err != nil {
 		logger.Error("Failed to create permission", zap.Error(err))
-		return err
+		return nil, err
 	}
 
-	logger.Info("Permission created successfully",
-		zap.String("resource", permission.Resource),
-		zap.String("action", permission.Action),
-		zap.Uint("id", permission.ID))
-	return nil
+	logger.Info("Permission created successfully", zap.String("code", permission.Code), zap.Uint("id", permission.ID))
+	return uc.permissionRepo.GetByID(ctx, permission.ID)
 }
 
 // GetPermission retrieves a permission by ID
-func (uc *PermissionUseCase) GetPermission(ctx context.Context, id uint) (*domain.Permission, error) {
-	permission, err := uc.permissionRepo.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
-	return permission, nil
+func (uc *PermissionUseCase) GetPermission(ctx context.Context, id uint) (*domain.EnhancedPermission, error) {
+	return uc.permissionRepo.GetByID(ctx, id)
 }
 
-// GetPermissionByKey retrieves a permission by its unique key
-func (uc *PermissionUseCase) GetPermissionByKey(ctx context.Context, key string) (*domain.Permission, error) {
-	permission, err := uc.permissionRepo.GetByKey(ctx, key)
-	if err != nil {
-		return nil, err
-	}
-	return permission, nil
+// GetPermissionByCode retrieves a permission by its unique code
+func (uc *PermissionUseCase) GetPermissionByCode(ctx context.Context, code string) (*domain.EnhancedPermission, error) {
+	return uc.permissionRepo.GetByCode(ctx, code)
 }
 
 // ListPermissions retrieves all permissions with optional filters
-func (uc *PermissionUseCase) ListPermissions(ctx context.Context, filter repositories.PermissionFilter) ([]*domain.Permission, error) {
-	permissions, err := uc.permissionRepo.List(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-	return permissions, nil
+func (uc *PermissionUseCase) ListPermissions(ctx context.Context, filter repositories.PermissionFilter) ([]domain.EnhancedPermission, error) {
+	return uc.permissionRepo.List(ctx, filter)
 }
 
-// GetPermissionsByModule retrieves permissions by module
-func (uc *PermissionUseCase) GetPermissionsByModule(ctx context.Context, module string) ([]*domain.Permission, error) {
-	permissions, err := uc.permissionRepo.GetByModule(ctx, module)
-	if err != nil {
-		return nil, err
-	}
-	return permissions, nil
+// GetPermissionsByModule retrieves permissions by module code
+func (uc *PermissionUseCase) GetPermissionsByModule(ctx context.Context, module string) ([]domain.EnhancedPermission, error) {
+	return uc.permissionRepo.List(ctx, repositories.PermissionFilter{Module: module})
 }
 
-// GetPermissionsByDepartment retrieves permissions by department
-func (uc *PermissionUseCase) GetPermissionsByDepartment(ctx context.Context, department string) ([]*domain.Permission, error) {
-	permissions, err := uc.permissionRepo.GetByDepartment(ctx, department)
-	if err != nil {
-		return nil, err
-	}
-	return permissions, nil
+// GetPermissionsByDepartment retrieves permissions by department code
+func (uc *PermissionUseCase) GetPermissionsByDepartment(ctx context.Context, department string) ([]domain.EnhancedPermission, error) {
+	return uc.permissionRepo.List(ctx, repositories.PermissionFilter{Department: department})
 }
 
-// GetPermissionsByService retrieves permissions by service
-func (uc *PermissionUseCase) GetPermissionsByService(ctx context.Context, service string) ([]*domain.Permission, error) {
-	permissions, err := uc.permissionRepo.GetByService(ctx, service)
-	if err != nil {
-		return nil, err
-	}
-	return permissions, nil
+// GetPermissionsByService retrieves permissions by service code
+func (uc *PermissionUseCase) GetPermissionsByService(ctx context.Context, service string) ([]domain.EnhancedPermission, error) {
+	return uc.permissionRepo.List(ctx, repositories.PermissionFilter{Service: service})
 }
 
 // UpdatePermission updates an existing permission
-func (uc *PermissionUseCase) UpdatePermission(ctx context.Context, id uint, updates *domain.Permission) error {
+func (uc *PermissionUseCase) UpdatePermission(ctx context.Context, id uint, req UpdatePermissionRequest) (*domain.EnhancedPermission, error) {
 	// Get existing permission
 	existing, err := uc.permissionRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Update fields
-	if updates.Description != "" {
-		existing.Description = updates.Description
-	}
-	if updates.Module != "" {
-		existing.Module = updates.Module
+	if req.DisplayName != "" {
+		existing.DisplayName = req.DisplayName
 	}
-	if updates.Department != "" {
-		existing.Department = updates.Department
-	}
-	if updates.Service != "" {
-		existing.Service = updates.Service
+	if req.Description != "" {
+		existing.Description = req.Description
 	}
 
 	if err := uc.permissionRepo.Update(ctx, existing); err != nil {
 		logger.Error("Failed to update permission", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	logger.Info("Permission updated successfully", zap.Uint("id", id))
-	return nil
+	return existing, nil
 }
 
-// DeletePermission deletes a permission
+// DeletePermission deletes a permission and revokes it from every role
 func (uc *PermissionUseCase) DeletePermission(ctx context.Context, id uint) error {
 	// Get existing permission
-	_, err := uc.permissionRepo.GetByID(ctx, id)
+	permission, err := uc.permissionRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	// Check if it's a system permission
+	if permission.IsSystem {
+		return errors.New(errors.ErrCodeForbidden, "cannot delete system permission", 403)
+	}
+
 	if err := uc.permissionRepo.Delete(ctx, id); err != nil {
 		logger.Error("Failed to delete permission", zap.Error(err))
 		return err
 	}
 
+	// Any role, through the role tree, or direct grant may have held it
+	if err := cache.InvalidateAllPermissions(ctx); err != nil {
+		logger.Warn("Failed to invalidate permission cache", zap.Uint("id", id), zap.Error(err))
+	}
+
 	logger.Info("Permission deleted successfully", zap.Uint("id", id))
 	return nil
 }
@@ -0,0 +1,500 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/expression"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// policyCacheTTL bounds how long a policy change announced while this instance was not
+// subscribed takes to apply here
+const policyCacheTTL = time.Minute
+
+// policyAttributes are the top-level attributes a condition may read
+var policyAttributes = map[string]bool{"subject": true, "resource": true, "request": true}
+
+// ScopeLookup resolves the scope a user holds a permission at
+type ScopeLookup interface {
+	GetPermissionScope(ctx context.Context, userID uuid.UUID, permission string) (domain.ScopeLevel, error)
+}
+
+// PolicyUseCase handles attribute-based policies on permissions and evaluates them.
+// Active policies are compiled once and cached.
+type PolicyUseCase struct {
+	policyRepo repositories.PolicyRepository
+	userRepo   repositories.UserRepository
+	scopes     ScopeLookup
+
+	mu       sync.RWMutex
+	active   []compiledPolicy
+	loadedAt time.Time
+}
+
+// compiledPolicy is a policy with its parsed condition. A condition that fails to
+// parse is kept with a nil expression and refuses access when evaluated.
+type compiledPolicy struct {
+	policy    domain.Policy
+	condition *expression.Expression
+	err       error
+}
+
+// NewPolicyUseCase creates a new policy use case
+func NewPolicyUseCase(
+	policyRepo repositories.PolicyRepository,
+	userRepo repositories.UserRepository,
+	scopes ScopeLookup,
+) *PolicyUseCase {
+	return &PolicyUseCase{
+		policyRepo: policyRepo,
+		userRepo:   userRepo,
+		scopes:     scopes,
+	}
+}
+
+// CreatePolicyRequest represents a request to create a policy
+type CreatePolicyRequest struct {
+	Name        string              `json:"name" binding:"required,max=100"`
+	Description string              `json:"description"`
+	Permission  string              `json:"permission" binding:"required"` // Parts may be *, e.g. crm:sales:customers:customers:*
+	Effect      domain.PolicyEffect `json:"effect" binding:"required,oneof=allow deny"`
+	Condition   string              `json:"condition" binding:"required"`
+	IsActive    *bool               `json:"is_active"` // Defaults to true
+}
+
+// UpdatePolicyRequest represents a request to update a policy
+type UpdatePolicyRequest struct {
+	Description *string              `json:"description"`
+	Permission  *string              `json:"permission"`
+	Effect      *domain.PolicyEffect `json:"effect" binding:"omitempty,oneof=allow deny"`
+	Condition   *string              `json:"condition"`
+	IsActive    *bool                `json:"is_active"`
+}
+
+// SimulatePolicyRequest describes an access to evaluate without performing it
+type SimulatePolicyRequest struct {
+	UserID     uuid.UUID              `json:"user_id" binding:"required"`
+	Permission string                 `json:"permission" binding:"required"`
+	Resource   map[string]interface{} `json:"resource"` // Resource attributes; omit to evaluate request policies only
+	Request    *domain.PolicyRequest  `json:"request"`  // Defaults to the current time
+	Drafts     []CreatePolicyRequest  `json:"drafts"`   // Unsaved policies to evaluate alongside the stored ones
+}
+
+// PolicySimulation is the outcome of a simulated access
+type PolicySimulation struct {
+	Permission    string                 `json:"permission"`
+	HasPermission bool                   `json:"has_permission"` // Whether the user's roles or grants include the permission
+	Scope         domain.ScopeLevel      `json:"scope,omitempty"`
+	Request       *domain.PolicyDecision `json:"request"`            // Policies that only read subject and request attributes
+	Resource      *domain.PolicyDecision `json:"resource,omitempty"` // Policies that read resource attributes
+	Allowed       bool                   `json:"allowed"`
+}
+
+// CreatePolicy creates a new policy
+func (uc *PolicyUseCase) CreatePolicy(ctx context.Context, req CreatePolicyRequest, createdBy uuid.UUID) (*domain.Policy, error) {
+	if err := validatePolicy(req.Permission, req.Effect, req.Condition); err != nil {
+		return nil, err
+	}
+
+	if existing, err := uc.policyRepo.GetByName(ctx, req.Name); err == nil && existing != nil {
+		return nil, errors.New(errors.ErrCodeConflict, "policy with this name already exists", 409)
+	}
+
+	policy := &domain.Policy{
+		Name:        req.Name,
+		Description: req.Description,
+		Permission:  req.Permission,
+		Effect:      req.Effect,
+		Condition:   req.Condition,
+		IsActive:    true,
+		CreatedBy:   &createdBy,
+	}
+	if err := uc.policyRepo.Create(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	// is_active defaults to true in the database, so an inactive policy is saved in two steps
+	if req.IsActive != nil && !*req.IsActive {
+		policy.IsActive = false
+		if err := uc.policyRepo.Update(ctx, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	uc.policiesChanged(ctx)
+
+	logger.Info("Policy created successfully", zap.String("name", policy.Name), zap.Uint("id", policy.ID))
+	return policy, nil
+}
+
+// GetPolicy retrieves a policy by ID
+func (uc *PolicyUseCase) GetPolicy(ctx context.Context, id uint) (*domain.Policy, error) {
+	return uc.policyRepo.GetByID(ctx, id)
+}
+
+// ListPolicies retrieves policies with optional filters
+func (uc *PolicyUseCase) ListPolicies(ctx context.Context, filter repositories.PolicyFilter) ([]domain.Policy, error) {
+	return uc.policyRepo.List(ctx, filter)
+}
+
+// UpdatePolicy updates an existing policy
+func (uc *PolicyUseCase) UpdatePolicy(ctx context.Context, id uint, req UpdatePolicyRequest) (*domain.Policy, error) {
+	policy, err := uc.policyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		policy.Description = *req.Description
+	}
+	if req.Permission != nil {
+		policy.Permission = *req.Permission
+	}
+	if req.Effect != nil {
+		policy.Effect = *req.Effect
+	}
+	if req.Condition != nil {
+		policy.Condition = *req.Condition
+	}
+	if req.IsActive != nil {
+		policy.IsActive = *req.IsActive
+	}
+
+	if err := validatePolicy(policy.Permission, policy.Effect, policy.Condition); err != nil {
+		return nil, err
+	}
+
+	if err := uc.policyRepo.Update(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	uc.policiesChanged(ctx)
+
+	logger.Info("Policy updated successfully", zap.Uint("id", id))
+	return policy, nil
+}
+
+// DeletePolicy deletes a policy
+func (uc *PolicyUseCase) DeletePolicy(ctx context.Context, id uint) error {
+	if _, err := uc.policyRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	if err := uc.policyRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.policiesChanged(ctx)
+
+	logger.Info("Policy deleted successfully", zap.Uint("id", id))
+	return nil
+}
+
+// EvaluatePolicies evaluates the active policies on a permission. Without a resource
+// only policies that do not read resource attributes apply; with one, only those that do.
+func (uc *PolicyUseCase) EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error) {
+	policies, err := uc.activePolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.evaluate(ctx, applicablePolicies(policies, input.Permission, input.Resource != nil), input)
+}
+
+// Simulate evaluates a user's access to a permission without performing it: whether
+// they hold the permission, and how each policy on it decides
+func (uc *PolicyUseCase) Simulate(ctx context.Context, req SimulatePolicyRequest) (*PolicySimulation, error) {
+	if _, err := uc.userRepo.GetByID(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	policies, err := uc.activePolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, draft := range req.Drafts {
+		if err := validatePolicy(draft.Permission, draft.Effect, draft.Condition); err != nil {
+			return nil, errors.New(errors.ErrCodeValidation, fmt.Sprintf("draft %d: %s", i+1, err.Error()), 400)
+		}
+		condition, _ := expression.Compile(draft.Condition)
+		policies = append(policies, compiledPolicy{
+			policy: domain.Policy{
+				Name:       draft.Name,
+				Permission: draft.Permission,
+				Effect:     draft.Effect,
+				Condition:  draft.Condition,
+				IsActive:   true,
+			},
+			condition: condition,
+		})
+	}
+
+	simulation := &PolicySimulation{Permission: req.Permission}
+
+	level, err := uc.scopes.GetPermissionScope(ctx, req.UserID, req.Permission)
+	if err != nil {
+		return nil, err
+	}
+	simulation.HasPermission = level != ""
+	simulation.Scope = level
+
+	input := domain.PolicyInput{UserID: req.UserID, Permission: req.Permission, Request: req.Request}
+	simulation.Request, err = uc.evaluate(ctx, applicablePolicies(policies, req.Permission, false), input)
+	if err != nil {
+		return nil, err
+	}
+	simulation.Allowed = simulation.HasPermission && simulation.Request.Allowed
+
+	if req.Resource != nil {
+		input.Resource = req.Resource
+		simulation.Resource, err = uc.evaluate(ctx, applicablePolicies(policies, req.Permission, true), input)
+		if err != nil {
+			return nil, err
+		}
+		simulation.Allowed = simulation.Allowed && simulation.Resource.Allowed
+	}
+
+	return simulation, nil
+}
+
+// evaluate combines the policies: every allow condition must hold and no deny
+// condition may. A condition that fails to evaluate refuses access.
+func (uc *PolicyUseCase) evaluate(ctx context.Context, policies []compiledPolicy, input domain.PolicyInput) (*domain.PolicyDecision, error) {
+	decision := &domain.PolicyDecision{Allowed: true, Results: []domain.PolicyResult{}}
+	if len(policies) == 0 {
+		return decision, nil
+	}
+
+	attributes, err := uc.attributes(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, compiled := range policies {
+		result := domain.PolicyResult{
+			PolicyID:  compiled.policy.ID,
+			Name:      compiled.policy.Name,
+			Effect:    compiled.policy.Effect,
+			Condition: compiled.policy.Condition,
+		}
+
+		evalErr := compiled.err
+		if evalErr == nil {
+			result.Matched, evalErr = compiled.condition.Evaluate(attributes)
+		}
+
+		switch {
+		case evalErr != nil:
+			result.Error = evalErr.Error()
+		case compiled.policy.Effect == domain.PolicyEffectDeny:
+			result.Allowed = !result.Matched
+		default:
+			result.Allowed = result.Matched
+		}
+
+		if !result.Allowed && decision.Allowed {
+			decision.Allowed = false
+			decision.Reason = fmt.Sprintf("refused by policy %q", compiled.policy.Name)
+		}
+		decision.Results = append(decision.Results, result)
+	}
+
+	return decision, nil
+}
+
+// attributes builds the subject, resource and request attributes conditions read
+func (uc *PolicyUseCase) attributes(ctx context.Context, input domain.PolicyInput) (map[string]interface{}, error) {
+	user, err := uc.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := uc.userRepo.GetUserRoles(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	var departmentID interface{}
+	if user.DepartmentID != nil {
+		departmentID = float64(*user.DepartmentID)
+	}
+
+	resource, err := resourceAttributes(input.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"subject": map[string]interface{}{
+			"id":                 user.ID.String(),
+			"email":              user.Email,
+			"department_id":      departmentID,
+			"position":           user.Position,
+			"status":             string(user.Status),
+			"is_service_account": user.IsServiceAccount,
+			"roles":              roleNames,
+		},
+		"resource": resource,
+		"request":  requestAttributes(input.Request),
+	}, nil
+}
+
+// activePolicies returns the active policies, reloading them once the cache is stale
+func (uc *PolicyUseCase) activePolicies(ctx context.Context) ([]compiledPolicy, error) {
+	uc.mu.RLock()
+	if time.Since(uc.loadedAt) < policyCacheTTL {
+		active := uc.active
+		uc.mu.RUnlock()
+		return active, nil
+	}
+	uc.mu.RUnlock()
+
+	isActive := true
+	policies, err := uc.policyRepo.List(ctx, repositories.PolicyFilter{IsActive: &isActive})
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]compiledPolicy, 0, len(policies))
+	for _, policy := range policies {
+		condition, err := expression.Compile(policy.Condition)
+		if err != nil {
+			logger.Warn("Policy condition does not compile", zap.String("policy", policy.Name), zap.Error(err))
+		}
+		active = append(active, compiledPolicy{policy: policy, condition: condition, err: err})
+	}
+
+	uc.mu.Lock()
+	uc.active = active
+	uc.loadedAt = time.Now()
+	uc.mu.Unlock()
+
+	return active, nil
+}
+
+// HandlePermissionEvent reloads the active policies when another instance changed them
+func (uc *PolicyUseCase) HandlePermissionEvent(event cache.PermissionEvent) {
+	if event.Policies {
+		uc.invalidate()
+	}
+}
+
+// policiesChanged reloads the active policies here and tells every other instance to
+func (uc *PolicyUseCase) policiesChanged(ctx context.Context) {
+	uc.invalidate()
+	if err := cache.PublishPermissionEvent(ctx, cache.PermissionEvent{Policies: true}); err != nil {
+		logger.Warn("Failed to announce policy change", zap.Error(err))
+	}
+}
+
+// invalidate makes the next evaluation reload the active policies
+func (uc *PolicyUseCase) invalidate() {
+	uc.mu.Lock()
+	uc.loadedAt = time.Time{}
+	uc.mu.Unlock()
+}
+
+// applicablePolicies picks the policies on a permission for one evaluation stage
+func applicablePolicies(policies []compiledPolicy, permission string, withResource bool) []compiledPolicy {
+	var applicable []compiledPolicy
+	for _, compiled := range policies {
+		if !compiled.policy.AppliesTo(permission) {
+			continue
+		}
+		readsResource := compiled.condition != nil && compiled.condition.Reads("resource")
+		if readsResource == withResource {
+			applicable = append(applicable, compiled)
+		}
+	}
+	return applicable
+}
+
+// validatePolicy checks a policy's permission pattern, effect and condition
+func validatePolicy(permission string, effect domain.PolicyEffect, condition string) error {
+	parts := strings.Split(permission, ":")
+	if len(parts) != 5 {
+		return errors.New(errors.ErrCodeValidation, "permission must be module:department:service:resource:action", 400)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return errors.New(errors.ErrCodeValidation, "permission parts cannot be empty", 400)
+		}
+	}
+
+	if effect != domain.PolicyEffectAllow && effect != domain.PolicyEffectDeny {
+		return errors.New(errors.ErrCodeValidation, "effect must be allow or deny", 400)
+	}
+
+	compiled, err := expression.Compile(condition)
+	if err != nil {
+		return errors.New(errors.ErrCodeValidation, "invalid condition: "+err.Error(), 400)
+	}
+	for _, ref := range compiled.References() {
+		if !policyAttributes[ref] {
+			return errors.New(errors.ErrCodeValidation, fmt.Sprintf("invalid condition: unknown attribute %q, use subject, resource or request", ref), 400)
+		}
+	}
+
+	return nil
+}
+
+// resourceAttributes reads a resource through its JSON fields, so conditions use the
+// names the API exposes, e.g. resource.assigned_to
+func resourceAttributes(resource interface{}) (map[string]interface{}, error) {
+	if resource == nil {
+		return nil, nil
+	}
+	if attributes, ok := resource.(map[string]interface{}); ok {
+		return attributes, nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource attributes: %w", err)
+	}
+
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(data, &attributes); err != nil {
+		return nil, fmt.Errorf("failed to read resource attributes: %w", err)
+	}
+	return attributes, nil
+}
+
+// requestAttributes describes the request. Times are in the server's time zone unless
+// the request carries its own time.
+func requestAttributes(req *domain.PolicyRequest) map[string]interface{} {
+	now := time.Now()
+	if req != nil && !req.Time.IsZero() {
+		now = req.Time
+	}
+
+	attributes := map[string]interface{}{
+		"time":    now.Format(time.RFC3339),
+		"date":    now.Format("2006-01-02"),
+		"hour":    float64(now.Hour()),
+		"minute":  float64(now.Minute()),
+		"weekday": strings.ToLower(now.Weekday().String()),
+	}
+	if req != nil {
+		attributes["ip"] = req.IP
+		attributes["method"] = req.Method
+		attributes["path"] = req.Path
+	}
+	return attributes
+}
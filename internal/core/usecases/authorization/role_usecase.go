@@ -2,28 +2,69 @@ package authorization
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
 	"github.com/owner/go-cms/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// RoleUseCase handles role business logic
+// RoleUseCase handles role business logic. Roles form a tree through ParentID, and a
+// role holds its own permissions and those of every role below it.
 type RoleUseCase struct {
 	roleRepo       repositories.RoleRepository
-	permissionRepo repositories.PermissionRepository
+	permissionRepo repositories.EnhancedPermissionRepository
+	userRepo       repositories.UserRepository
 }
 
 // NewRoleUseCase creates a new role use case
-func NewRoleUseCase(roleRepo repositories.RoleRepository, permissionRepo repositories.PermissionRepository) *RoleUseCase {
+func NewRoleUseCase(
+	roleRepo repositories.RoleRepository,
+	permissionRepo repositories.EnhancedPermissionRepository,
+	userRepo repositories.UserRepository,
+) *RoleUseCase {
 	return &RoleUseCase{
 		roleRepo:       roleRepo,
 		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
 	}
 }
 
+// PermissionSourceType is how a role or user comes to hold a permission
+type PermissionSourceType string
+
+const (
+	PermissionSourceRole   PermissionSourceType = "role"   // Granted to a role, or a role below it
+	PermissionSourceDirect PermissionSourceType = "direct" // Granted to the user directly
+)
+
+// PermissionSource is one grant behind an effective permission
+type PermissionSource struct {
+	Type     PermissionSourceType `json:"type"`
+	Code     string               `json:"code"` // Permission code, including its scope
+	Scope    domain.ScopeLevel    `json:"scope"`
+	RoleID   uint                 `json:"role_id,omitempty"`
+	RoleName string               `json:"role_name,omitempty"`
+	// Path lists role names from the role asked about, or assigned to the user, down
+	// to the role holding the grant
+	Path []string `json:"path,omitempty"`
+	// ExpiresAt is when the user's direct grant, or their grant of the role, ends
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// EffectivePermission is a permission as the permission checker sees it, with every
+// grant it comes from
+type EffectivePermission struct {
+	Permission string             `json:"permission"` // module:department:service:resource:action
+	Scope      domain.ScopeLevel  `json:"scope"`      // Broadest scope among the sources
+	Sources    []PermissionSource `json:"sources"`
+}
+
 // CreateRole creates a new role
 func (uc *RoleUseCase) CreateRole(ctx context.Context, role *domain.Role) error {
 	// Validate role
@@ -37,7 +78,8 @@ func (uc *RoleUseCase) CreateRole(ctx context.Context, role *domain.Role) error
 		return errors.New(errors.ErrCodeConflict, "role with this name already exists", 409)
 	}
 
-	// Validate parent role if specified
+	// Validate parent role if specified. A new role has no children, so it cannot
+	// close a cycle.
 	if role.ParentID != nil {
 		parent, err := uc.roleRepo.GetByID(ctx, *role.ParentID)
 		if err != nil {
@@ -49,6 +91,10 @@ func (uc *RoleUseCase) CreateRole(ctx context.Context, role *domain.Role) error
 		}
 	}
 
+	if err := uc.validateOwner(ctx, role.OwnerID); err != nil {
+		return err
+	}
+
 	if err := uc.roleRepo.Create(ctx, role); err != nil {
 		logger.Error("Failed to create role", zap.Error(err))
 		return err
@@ -120,12 +166,37 @@ func (uc *RoleUseCase) UpdateRole(ctx context.Context, id uint, updates *domain.
 	if updates.Level != "" {
 		existing.Level = updates.Level
 	}
+	if updates.OwnerID != nil {
+		if err := uc.validateOwner(ctx, updates.OwnerID); err != nil {
+			return err
+		}
+		existing.OwnerID = updates.OwnerID
+	}
+	// Moving the role changes which roles above it inherit its permissions
+	var formerAncestors []uint
+	parentChanged := updates.ParentID != nil && (existing.ParentID == nil || *existing.ParentID != *updates.ParentID)
+	if updates.ParentID != nil {
+		if err := uc.validateParent(ctx, id, *updates.ParentID); err != nil {
+			return err
+		}
+		if parentChanged {
+			formerAncestors, err = uc.roleWithAncestors(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+		existing.ParentID = updates.ParentID
+	}
 
 	if err := uc.roleRepo.Update(ctx, existing); err != nil {
 		logger.Error("Failed to update role", zap.Error(err))
 		return err
 	}
 
+	if parentChanged {
+		uc.invalidateRoleHolders(ctx, id, formerAncestors...)
+	}
+
 	logger.Info("Role updated successfully", zap.Uint("id", id))
 	return nil
 }
@@ -152,24 +223,37 @@ func (uc *RoleUseCase) DeleteRole(ctx context.Context, id uint) error {
 		return errors.New(errors.ErrCodeConflict, "cannot delete role with child roles", 409)
 	}
 
+	// Holders of the role and of the roles above it lose its permissions
+	formerAncestors, err := uc.roleWithAncestors(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	if err := uc.roleRepo.Delete(ctx, id); err != nil {
 		logger.Error("Failed to delete role", zap.Error(err))
 		return err
 	}
 
+	uc.invalidateRoleHolders(ctx, id, formerAncestors...)
+
 	logger.Info("Role deleted successfully", zap.Uint("id", id))
 	return nil
 }
 
 // GetRolePermissions retrieves all permissions for a role
-func (uc *RoleUseCase) GetRolePermissions(ctx context.Context, roleID uint) ([]*domain.Permission, error) {
-	permissions, err := uc.roleRepo.GetRolePermissions(ctx, roleID)
+func (uc *RoleUseCase) GetRolePermissions(ctx context.Context, roleID uint) ([]domain.EnhancedPermission, error) {
+	if _, err := uc.roleRepo.GetByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	permissions, err := uc.permissionRepo.GetRolePermissions(ctx, roleID)
 	if err != nil {
 		return nil, err
 	}
 	return permissions, nil
 }
 
+// AssignPermission grants a permission to a role
 func (uc *RoleUseCase) AssignPermission(ctx context.Context, roleID, permissionID uint) error {
 	_, err := uc.roleRepo.GetByID(ctx, roleID)
 	if err != nil {
@@ -181,22 +265,26 @@ func (uc *RoleUseCase) AssignPermission(ctx context.Context, roleID, permissionI
 		return err
 	}
 
-	if err := uc.roleRepo.AssignPermission(ctx, roleID, permissionID); err != nil {
+	if err := uc.permissionRepo.AssignToRole(ctx, roleID, []uint{permissionID}); err != nil {
 		logger.Error("Failed to assign permission to role", zap.Error(err))
 		return err
 	}
 
+	uc.invalidateRoleHolders(ctx, roleID)
+
 	logger.Info("Permission assigned to role", zap.Uint("roleID", roleID), zap.Uint("permissionID", permissionID))
 	return nil
 }
 
 // RemovePermission removes a permission from a role
 func (uc *RoleUseCase) RemovePermission(ctx context.Context, roleID, permissionID uint) error {
-	if err := uc.roleRepo.RemovePermission(ctx, roleID, permissionID); err != nil {
+	if err := uc.permissionRepo.RemoveFromRole(ctx, roleID, []uint{permissionID}); err != nil {
 		logger.Error("Failed to remove permission from role", zap.Error(err))
 		return err
 	}
 
+	uc.invalidateRoleHolders(ctx, roleID)
+
 	logger.Info("Permission removed from role", zap.Uint("roleID", roleID), zap.Uint("permissionID", permissionID))
 	return nil
 }
@@ -209,3 +297,274 @@ func (uc *RoleUseCase) GetRoleUsers(ctx context.Context, roleID uint) ([]*domain
 	}
 	return users, nil
 }
+
+// GetEffectivePermissions returns every permission a role holds, its own and those
+// inherited from the roles below it, with where each comes from
+func (uc *RoleUseCase) GetEffectivePermissions(ctx context.Context, roleID uint) ([]EffectivePermission, error) {
+	tree, err := uc.loadRoleTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := tree.roles[roleID]; !ok {
+		return nil, errors.New(errors.ErrCodeNotFound, "role not found", 404)
+	}
+
+	effective := newEffectivePermissions()
+	if err := uc.collectRolePermissions(ctx, tree, roleID, nil, effective); err != nil {
+		return nil, err
+	}
+
+	return effective.list(), nil
+}
+
+// GetUserEffectivePermissions returns every permission a user holds through their
+// roles and direct grants, with where each comes from
+func (uc *RoleUseCase) GetUserEffectivePermissions(ctx context.Context, userID uuid.UUID) ([]EffectivePermission, error) {
+	if _, err := uc.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	roleGrants, err := uc.userRepo.ListRoleGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := uc.loadRoleTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	effective := newEffectivePermissions()
+	for _, grant := range roleGrants {
+		if err := uc.collectRolePermissions(ctx, tree, grant.RoleID, grant.ExpiresAt, effective); err != nil {
+			return nil, err
+		}
+	}
+
+	direct, err := uc.permissionRepo.ListUserGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range direct {
+		effective.add(grant.Permission.PermissionKey(), PermissionSource{
+			Type:      PermissionSourceDirect,
+			Code:      grant.Permission.Code,
+			Scope:     grant.Permission.Scope.Level,
+			ExpiresAt: grant.ExpiresAt,
+		})
+	}
+
+	return effective.list(), nil
+}
+
+// validateOwner checks that a role's owner is an existing user
+func (uc *RoleUseCase) validateOwner(ctx context.Context, ownerID *uuid.UUID) error {
+	if ownerID == nil {
+		return nil
+	}
+	if _, err := uc.userRepo.GetByID(ctx, *ownerID); err != nil {
+		return errors.New(errors.ErrCodeNotFound, "role owner not found", 404)
+	}
+	return nil
+}
+
+// validateParent checks that making parentID the parent of roleID keeps the roles a tree
+func (uc *RoleUseCase) validateParent(ctx context.Context, roleID, parentID uint) error {
+	if parentID == roleID {
+		return errors.New(errors.ErrCodeValidation, "role cannot be its own parent", 400)
+	}
+
+	tree, err := uc.loadRoleTree(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tree.roles[parentID]; !ok {
+		return errors.New(errors.ErrCodeNotFound, "parent role not found", 404)
+	}
+
+	for _, descendant := range tree.walk(roleID) {
+		if descendant.role.ID == parentID {
+			return errors.New(errors.ErrCodeValidation, "parent role cannot be a role below this one", 400)
+		}
+	}
+
+	return nil
+}
+
+// collectRolePermissions adds the grants of a role and every role below it. expiresAt
+// is when the user's grant of the role ends, if it does.
+func (uc *RoleUseCase) collectRolePermissions(ctx context.Context, tree *roleTree, roleID uint, expiresAt *time.Time, effective *effectivePermissions) error {
+	for _, visited := range tree.walk(roleID) {
+		permissions, err := uc.permissionRepo.GetRolePermissions(ctx, visited.role.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, permission := range permissions {
+			effective.add(permission.PermissionKey(), PermissionSource{
+				Type:      PermissionSourceRole,
+				Code:      permission.Code,
+				Scope:     permission.Scope.Level,
+				RoleID:    visited.role.ID,
+				RoleName:  visited.role.Name,
+				Path:      visited.path,
+				ExpiresAt: expiresAt,
+			})
+		}
+	}
+	return nil
+}
+
+// roleWithAncestors returns the role and every role above it. A role holds the
+// permissions of the roles below it, so a change to a role reaches holders of all of these.
+func (uc *RoleUseCase) roleWithAncestors(ctx context.Context, roleID uint) ([]uint, error) {
+	tree, err := uc.loadRoleTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ancestors(roleID), nil
+}
+
+// invalidateRoleHolders drops the cached permissions of every user holding the role or
+// a role above it, and of those holding the former roles, i.e. the roles that were above
+// it before it moved or was deleted. Other instances are told through a permission event.
+func (uc *RoleUseCase) invalidateRoleHolders(ctx context.Context, roleID uint, formerRoleIDs ...uint) {
+	roleIDs, err := uc.roleWithAncestors(ctx, roleID)
+	if err != nil {
+		uc.invalidateAllPermissions(ctx, err)
+		return
+	}
+	roleIDs = append(roleIDs, formerRoleIDs...)
+
+	userIDs, err := uc.roleRepo.GetRoleUserIDs(ctx, roleIDs)
+	if err != nil {
+		uc.invalidateAllPermissions(ctx, err)
+		return
+	}
+
+	if err := cache.InvalidatePermissions(ctx, userIDs...); err != nil {
+		logger.Warn("Failed to invalidate permission cache", zap.Uint("roleID", roleID), zap.Error(err))
+	}
+}
+
+// invalidateAllPermissions drops everyone's cached permissions when the users a role
+// change affects cannot be worked out
+func (uc *RoleUseCase) invalidateAllPermissions(ctx context.Context, cause error) {
+	logger.Warn("Failed to find users affected by role change, invalidating all permissions", zap.Error(cause))
+	if err := cache.InvalidateAllPermissions(ctx); err != nil {
+		logger.Warn("Failed to invalidate permission cache", zap.Error(err))
+	}
+}
+
+// loadRoleTree loads every role, indexed for walking down the hierarchy
+func (uc *RoleUseCase) loadRoleTree(ctx context.Context) (*roleTree, error) {
+	roles, err := uc.roleRepo.List(ctx, repositories.RoleFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &roleTree{
+		roles:    make(map[uint]*domain.Role, len(roles)),
+		children: make(map[uint][]uint),
+	}
+	for _, role := range roles {
+		tree.roles[role.ID] = role
+		if role.ParentID != nil {
+			tree.children[*role.ParentID] = append(tree.children[*role.ParentID], role.ID)
+		}
+	}
+
+	return tree, nil
+}
+
+// roleTree indexes roles by ID and by parent
+type roleTree struct {
+	roles    map[uint]*domain.Role
+	children map[uint][]uint
+}
+
+// visitedRole is a role reached while walking the tree, with the role names leading to it
+type visitedRole struct {
+	role *domain.Role
+	path []string
+}
+
+// walk returns the role and every role below it, depth first. Each role is visited
+// once, so a cycle in ParentID ends the walk instead of looping.
+func (t *roleTree) walk(roleID uint) []visitedRole {
+	var visited []visitedRole
+	seen := make(map[uint]bool)
+
+	var visit func(id uint, path []string)
+	visit = func(id uint, path []string) {
+		role, ok := t.roles[id]
+		if !ok || seen[id] {
+			return
+		}
+		seen[id] = true
+
+		path = append(path[:len(path):len(path)], role.Name)
+		visited = append(visited, visitedRole{role: role, path: path})
+
+		for _, child := range t.children[id] {
+			visit(child, path)
+		}
+	}
+	visit(roleID, nil)
+
+	return visited
+}
+
+// ancestors returns the role and every role above it, nearest first. Each role is
+// included once, so a cycle in ParentID ends the climb.
+func (t *roleTree) ancestors(roleID uint) []uint {
+	ids := []uint{roleID}
+	seen := map[uint]bool{roleID: true}
+
+	for role, ok := t.roles[roleID]; ok && role.ParentID != nil; role, ok = t.roles[*role.ParentID] {
+		if seen[*role.ParentID] {
+			break
+		}
+		seen[*role.ParentID] = true
+		ids = append(ids, *role.ParentID)
+	}
+
+	return ids
+}
+
+// effectivePermissions gathers grants by the permission key the checker matches
+type effectivePermissions struct {
+	byKey map[string]*EffectivePermission
+}
+
+func newEffectivePermissions() *effectivePermissions {
+	return &effectivePermissions{byKey: make(map[string]*EffectivePermission)}
+}
+
+// add records a grant, keeping the broadest scope per permission
+func (e *effectivePermissions) add(key string, source PermissionSource) {
+	permission, ok := e.byKey[key]
+	if !ok {
+		permission = &EffectivePermission{Permission: key}
+		e.byKey[key] = permission
+	}
+
+	permission.Sources = append(permission.Sources, source)
+	if source.Scope.Rank() > permission.Scope.Rank() {
+		permission.Scope = source.Scope
+	}
+}
+
+// list returns the permissions sorted by key
+func (e *effectivePermissions) list() []EffectivePermission {
+	permissions := make([]EffectivePermission, 0, len(e.byKey))
+	for _, permission := range e.byKey {
+		permissions = append(permissions, *permission)
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Permission < permissions[j].Permission
+	})
+	return permissions
+}
@@ -16,9 +16,9 @@ import (
 type UseCase interface {
 	// Customer CRUD
 	CreateCustomer(ctx context.Context, req CreateCustomerRequest) (*domain.Customer, error)
-	GetCustomer(ctx context.Context, id uint) (*domain.Customer, error)
-	UpdateCustomer(ctx context.Context, id uint, req UpdateCustomerRequest) (*domain.Customer, error)
-	DeleteCustomer(ctx context.Context, id uint) error
+	GetCustomer(ctx context.Context, id uint, scope *domain.AccessScope) (*domain.Customer, error)
+	UpdateCustomer(ctx context.Context, id uint, req UpdateCustomerRequest, scope *domain.AccessScope) (*domain.Customer, error)
+	DeleteCustomer(ctx context.Context, id uint, scope *domain.AccessScope) error
 	ListCustomers(ctx context.Context, filter repositories.CustomerFilter, page *pagination.OffsetPagination) ([]*domain.Customer, int64, error)
 
 	// Customer Assignment
@@ -33,20 +33,36 @@ type UseCase interface {
 	SearchCustomers(ctx context.Context, query string) ([]*domain.Customer, error)
 }
 
+// WorkspaceInitializer prepares the document workspace of a newly created entity
+type WorkspaceInitializer interface {
+	InitializeEntityWorkspace(ctx context.Context, entityType string, entityID uint) (*domain.DocumentFolder, error)
+}
+
+// PolicyEvaluator evaluates the attribute-based policies on a permission
+type PolicyEvaluator interface {
+	EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error)
+}
+
 // useCase implements the UseCase interface
 type useCase struct {
 	customerRepo repositories.CustomerRepository
 	userRepo     repositories.UserRepository
+	workspaces   WorkspaceInitializer
+	policies     PolicyEvaluator
 }
 
 // NewUseCase creates a new customer use case
 func NewUseCase(
 	customerRepo repositories.CustomerRepository,
 	userRepo repositories.UserRepository,
+	workspaces WorkspaceInitializer,
+	policies PolicyEvaluator,
 ) UseCase {
 	return &useCase{
 		customerRepo: customerRepo,
 		userRepo:     userRepo,
+		workspaces:   workspaces,
+		policies:     policies,
 	}
 }
 
@@ -132,12 +148,19 @@ func (uc *useCase) CreateCustomer(ctx context.Context, req CreateCustomerRequest
 		zap.Uint("customer_id", customer.ID),
 		zap.String("email", customer.Email))
 
+	// Create the customer's document workspace from the folder templates
+	if uc.workspaces != nil {
+		if _, err := uc.workspaces.InitializeEntityWorkspace(ctx, domain.EntityTypeCustomer, customer.ID); err != nil {
+			logger.Warn("Failed to create customer document workspace", zap.Error(err), zap.Uint("customer_id", customer.ID))
+		}
+	}
+
 	return customer, nil
 }
 
-// GetCustomer gets a customer by ID
-func (uc *useCase) GetCustomer(ctx context.Context, id uint) (*domain.Customer, error) {
-	customer, err := uc.customerRepo.GetByID(ctx, id)
+// GetCustomer gets a customer by ID within the caller's scope
+func (uc *useCase) GetCustomer(ctx context.Context, id uint, scope *domain.AccessScope) (*domain.Customer, error) {
+	customer, err := uc.getInScope(ctx, id, scope)
 	if err != nil {
 		logger.Error("Failed to get customer", zap.Error(err), zap.Uint("id", id))
 		return nil, err
@@ -145,10 +168,10 @@ func (uc *useCase) GetCustomer(ctx context.Context, id uint) (*domain.Customer,
 	return customer, nil
 }
 
-// UpdateCustomer updates a customer
-func (uc *useCase) UpdateCustomer(ctx context.Context, id uint, req UpdateCustomerRequest) (*domain.Customer, error) {
+// UpdateCustomer updates a customer within the caller's scope
+func (uc *useCase) UpdateCustomer(ctx context.Context, id uint, req UpdateCustomerRequest, scope *domain.AccessScope) (*domain.Customer, error) {
 	// Get existing customer
-	customer, err := uc.customerRepo.GetByID(ctx, id)
+	customer, err := uc.getInScope(ctx, id, scope)
 	if err != nil {
 		logger.Error("Customer not found", zap.Error(err), zap.Uint("id", id))
 		return nil, err
@@ -228,10 +251,10 @@ func (uc *useCase) UpdateCustomer(ctx context.Context, id uint, req UpdateCustom
 	return customer, nil
 }
 
-// DeleteCustomer deletes a customer
-func (uc *useCase) DeleteCustomer(ctx context.Context, id uint) error {
+// DeleteCustomer deletes a customer within the caller's scope
+func (uc *useCase) DeleteCustomer(ctx context.Context, id uint, scope *domain.AccessScope) error {
 	// Check if customer exists
-	_, err := uc.customerRepo.GetByID(ctx, id)
+	_, err := uc.getInScope(ctx, id, scope)
 	if err != nil {
 		logger.Error("Customer not found", zap.Error(err), zap.Uint("id", id))
 		return err
@@ -247,14 +270,54 @@ func (uc *useCase) DeleteCustomer(ctx context.Context, id uint) error {
 	return nil
 }
 
-// ListCustomers lists customers with filters and pagination
+// ListCustomers lists customers with filters and pagination. When resource policies
+// apply to the scope's permission, every matching customer is evaluated before the
+// page is cut, so the total counts only customers the policies allow.
 func (uc *useCase) ListCustomers(ctx context.Context, filter repositories.CustomerFilter, page *pagination.OffsetPagination) ([]*domain.Customer, int64, error) {
-	customers, total, err := uc.customerRepo.List(ctx, filter, page)
+	if uc.policies == nil || filter.Scope == nil || filter.Scope.Permission == "" {
+		customers, total, err := uc.customerRepo.List(ctx, filter, page)
+		if err != nil {
+			logger.Error("Failed to list customers", zap.Error(err))
+			return nil, 0, err
+		}
+		return customers, total, nil
+	}
+
+	customers, _, err := uc.customerRepo.List(ctx, filter, nil)
 	if err != nil {
 		logger.Error("Failed to list customers", zap.Error(err))
 		return nil, 0, err
 	}
-	return customers, total, nil
+
+	allowed := make([]*domain.Customer, 0, len(customers))
+	for _, customer := range customers {
+		decision, err := uc.evaluatePolicies(ctx, customer, filter.Scope)
+		if err != nil {
+			logger.Error("Failed to evaluate customer policies", zap.Error(err))
+			return nil, 0, err
+		}
+		if decision.Allowed {
+			allowed = append(allowed, customer)
+		}
+	}
+
+	total := int64(len(allowed))
+	if page == nil {
+		return allowed, total, nil
+	}
+
+	start := page.GetOffset()
+	if start < 0 {
+		start = 0
+	}
+	if start > len(allowed) {
+		start = len(allowed)
+	}
+	end := len(allowed)
+	if page.PerPage > 0 && start+page.PerPage < end {
+		end = start + page.PerPage
+	}
+	return allowed[start:end], total, nil
 }
 
 // AssignToUser assigns a customer to a user
@@ -347,3 +410,63 @@ func (uc *useCase) SearchCustomers(ctx context.Context, query string) ([]*domain
 	}
 	return customers, nil
 }
+
+// getInScope gets a customer assigned within the scope. Customers outside it are
+// reported as not found.
+func (uc *useCase) getInScope(ctx context.Context, id uint, scope *domain.AccessScope) (*domain.Customer, error) {
+	var customer *domain.Customer
+	if scope.Unrestricted() {
+		found, err := uc.customerRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		customer = found
+	} else {
+		customers, _, err := uc.customerRepo.List(ctx, repositories.CustomerFilter{IDs: []uint{id}, Scope: scope}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(customers) == 0 {
+			return nil, errors.New(errors.ErrCodeNotFound, "customer not found", 404)
+		}
+		customer = customers[0]
+	}
+
+	if err := uc.enforcePolicies(ctx, customer, scope); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// enforcePolicies evaluates the resource policies on the permission the scope was
+// resolved for against the customer
+func (uc *useCase) enforcePolicies(ctx context.Context, customer *domain.Customer, scope *domain.AccessScope) error {
+	if uc.policies == nil || scope == nil || scope.Permission == "" {
+		return nil
+	}
+
+	decision, err := uc.evaluatePolicies(ctx, customer, scope)
+	if err != nil {
+		return err
+	}
+
+	if !decision.Allowed {
+		logger.Warn("Customer access denied by policy",
+			zap.Uint("customer_id", customer.ID),
+			zap.String("user_id", scope.UserID.String()),
+			zap.String("reason", decision.Reason),
+		)
+		return errors.ErrAccessDenied
+	}
+	return nil
+}
+
+// evaluatePolicies runs the resource policies on the scope's permission against the customer
+func (uc *useCase) evaluatePolicies(ctx context.Context, customer *domain.Customer, scope *domain.AccessScope) (*domain.PolicyDecision, error) {
+	return uc.policies.EvaluatePolicies(ctx, domain.PolicyInput{
+		UserID:     scope.UserID,
+		Permission: scope.Permission,
+		Resource:   customer,
+		Request:    scope.Request,
+	})
+}
@@ -0,0 +1,359 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/dto"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// mentionPattern matches "@jane.doe@example.com" style mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.])@([\w.%+\-]+@[\w\-]+(?:\.[\w\-]+)*\.[A-Za-z]{2,})`)
+
+const mentionExcerptLength = 140
+
+// Document comments methods
+
+// AddDocumentComment starts a thread or replies to one; commenting needs comment permission
+func (s *DocumentUsecase) AddDocumentComment(
+	ctx context.Context,
+	request dto.DocumentCommentRequest,
+	userID uuid.UUID,
+) (*domain.DocumentComment, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, request.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	// Check if user has comment permission
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionComment) {
+		return nil, errors.New("permission denied: you don't have permission to comment on this document")
+	}
+
+	comment := &domain.DocumentComment{
+		DocumentID: request.DocumentID,
+		UserID:     userID,
+		Comment:    request.Comment,
+	}
+
+	if request.ParentID != nil {
+		parent, err := s.documentRepo.GetDocumentCommentByID(ctx, *request.ParentID)
+		if err != nil || parent.DocumentID != request.DocumentID {
+			return nil, errors.New("parent comment not found")
+		}
+
+		if request.Anchor != nil {
+			return nil, errors.New("replies cannot have an anchor")
+		}
+
+		// Threads are one level deep; replies to replies join the same thread
+		threadID := parent.ID
+		if parent.ParentID != nil {
+			threadID = *parent.ParentID
+		}
+		comment.ParentID = &threadID
+	}
+
+	if request.Anchor != nil {
+		if err := applyCommentAnchor(comment, request.Anchor); err != nil {
+			return nil, err
+		}
+	}
+
+	mentioned, err := s.resolveMentions(ctx, document, request.Comment, request.MentionedUserIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range mentioned {
+		comment.Mentions = append(comment.Mentions, domain.DocumentCommentMention{UserID: user.ID})
+	}
+
+	if err := s.documentRepo.CreateDocumentComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	s.notifyMentions(ctx, document, comment, mentioned, userID)
+
+	return comment, nil
+}
+
+// UpdateDocumentComment edits a comment; only its author can edit it. Users newly
+// mentioned by the edit are notified.
+func (s *DocumentUsecase) UpdateDocumentComment(
+	ctx context.Context,
+	id uint,
+	request dto.DocumentCommentUpdateRequest,
+	userID uuid.UUID,
+) (*domain.DocumentComment, error) {
+	comment, err := s.documentRepo.GetDocumentCommentByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("comment not found")
+	}
+
+	// Check if user is the comment author
+	if comment.UserID != userID {
+		return nil, errors.New("permission denied: you can only edit your own comments")
+	}
+
+	document, err := s.documentRepo.GetDocumentByID(ctx, comment.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	// The author may have lost access since posting
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionComment) {
+		return nil, errors.New("permission denied: you don't have permission to comment on this document")
+	}
+
+	mentioned, err := s.resolveMentions(ctx, document, request.Comment, request.MentionedUserIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previouslyMentioned := make(map[uuid.UUID]bool, len(comment.Mentions))
+	for _, mention := range comment.Mentions {
+		previouslyMentioned[mention.UserID] = true
+	}
+
+	comment.Comment = request.Comment
+	comment.UpdatedAt = time.Now()
+	comment.Mentions = comment.Mentions[:0]
+
+	var newlyMentioned []domain.User
+	for _, user := range mentioned {
+		comment.Mentions = append(comment.Mentions, domain.DocumentCommentMention{UserID: user.ID})
+		if !previouslyMentioned[user.ID] {
+			newlyMentioned = append(newlyMentioned, user)
+		}
+	}
+
+	if err := s.documentRepo.UpdateDocumentComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	s.notifyMentions(ctx, document, comment, newlyMentioned, userID)
+
+	return comment, nil
+}
+
+// DeleteDocumentComment deletes a comment and, for a thread starter, its replies
+func (s *DocumentUsecase) DeleteDocumentComment(ctx context.Context, id uint, userID uuid.UUID) error {
+	comment, err := s.documentRepo.GetDocumentCommentByID(ctx, id)
+	if err != nil {
+		return errors.New("comment not found")
+	}
+
+	// Check if user is the comment author or document owner
+	if comment.UserID != userID {
+		document, err := s.documentRepo.GetDocumentByID(ctx, comment.DocumentID)
+		if err != nil {
+			return fmt.Errorf("document not found: %w", err)
+		}
+
+		if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionOwner) {
+			return errors.New("permission denied: you can only delete your own comments or comments on documents you own")
+		}
+	}
+
+	return s.documentRepo.DeleteDocumentComment(ctx, id)
+}
+
+// GetDocumentComments retrieves the comment threads of a document, optionally
+// filtered by resolved state
+func (s *DocumentUsecase) GetDocumentComments(
+	ctx context.Context,
+	documentID uint,
+	resolved *bool,
+	userID uuid.UUID,
+) ([]domain.DocumentComment, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	// Check if user has view permission
+	if s.getUserPermissionLevel(ctx, document, userID) == "" {
+		return nil, errors.New("permission denied: you don't have permission to view comments on this document")
+	}
+
+	return s.documentRepo.GetDocumentComments(ctx, documentID, resolved)
+}
+
+// SetCommentResolved resolves or reopens a thread. The thread author and anyone
+// with edit permission on the document can do so.
+func (s *DocumentUsecase) SetCommentResolved(
+	ctx context.Context,
+	id uint,
+	resolved bool,
+	userID uuid.UUID,
+) (*domain.DocumentComment, error) {
+	comment, err := s.documentRepo.GetDocumentCommentByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("comment not found")
+	}
+
+	if comment.ParentID != nil {
+		return nil, errors.New("only thread starters can be resolved")
+	}
+
+	document, err := s.documentRepo.GetDocumentByID(ctx, comment.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	permissionLevel := s.getUserPermissionLevel(ctx, document, userID)
+	if !hasPermissionLevel(permissionLevel, domain.PermissionEdit) &&
+		!(comment.UserID == userID && hasPermissionLevel(permissionLevel, domain.PermissionComment)) {
+		return nil, errors.New("permission denied: you cannot resolve this comment")
+	}
+
+	if comment.Resolved == resolved {
+		return comment, nil
+	}
+
+	comment.Resolved = resolved
+	if resolved {
+		now := time.Now()
+		comment.ResolvedBy = &userID
+		comment.ResolvedAt = &now
+	} else {
+		comment.ResolvedBy = nil
+		comment.ResolvedAt = nil
+	}
+
+	if err := s.documentRepo.UpdateDocumentComment(ctx, comment); err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// applyCommentAnchor validates an anchor and copies it onto the comment
+func applyCommentAnchor(comment *domain.DocumentComment, anchor *dto.DocumentCommentAnchor) error {
+	switch anchor.Type {
+	case domain.CommentAnchorPDFRegion:
+		if anchor.Page == nil || *anchor.Page < 1 {
+			return errors.New("pdf anchors need a page number starting at 1")
+		}
+		if anchor.X == nil || anchor.Y == nil || anchor.Width == nil || anchor.Height == nil {
+			return errors.New("pdf anchors need x, y, width and height")
+		}
+		if *anchor.X < 0 || *anchor.Y < 0 || *anchor.Width <= 0 || *anchor.Height <= 0 ||
+			*anchor.X+*anchor.Width > 1 || *anchor.Y+*anchor.Height > 1 {
+			return errors.New("pdf anchor rectangle must lie within the page (fractions between 0 and 1)")
+		}
+		comment.AnchorPage = anchor.Page
+		comment.AnchorX = anchor.X
+		comment.AnchorY = anchor.Y
+		comment.AnchorWidth = anchor.Width
+		comment.AnchorHeight = anchor.Height
+	case domain.CommentAnchorTextRange:
+		if anchor.Start == nil || anchor.End == nil || *anchor.Start < 0 || *anchor.End <= *anchor.Start {
+			return errors.New("text anchors need a start and an end after it")
+		}
+		comment.AnchorStart = anchor.Start
+		comment.AnchorEnd = anchor.End
+	default:
+		return errors.New("invalid anchor type")
+	}
+
+	comment.AnchorType = anchor.Type
+	return nil
+}
+
+// resolveMentions returns the users mentioned by "@email" in the text or by ID who can
+// view the document. The author and users without access are left out.
+func (s *DocumentUsecase) resolveMentions(
+	ctx context.Context,
+	document *domain.Document,
+	text string,
+	userIDs []uuid.UUID,
+	authorID uuid.UUID,
+) ([]domain.User, error) {
+	var emails []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		emails = append(emails, strings.ToLower(match[1]))
+	}
+
+	if len(emails) == 0 && len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.documentRepo.GetUsersByIDsOrEmails(ctx, userIDs, emails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mentions: %w", err)
+	}
+
+	mentioned := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		if user.ID == authorID || s.getUserPermissionLevel(ctx, document, user.ID) == "" {
+			continue
+		}
+		mentioned = append(mentioned, user)
+	}
+
+	return mentioned, nil
+}
+
+// notifyMentions sends a notification to each mentioned user. Delivery failures
+// are logged and do not fail the comment.
+func (s *DocumentUsecase) notifyMentions(
+	ctx context.Context,
+	document *domain.Document,
+	comment *domain.DocumentComment,
+	mentioned []domain.User,
+	authorID uuid.UUID,
+) {
+	if s.notifier == nil || len(mentioned) == 0 {
+		return
+	}
+
+	authorName := "Someone"
+	if authors, err := s.documentRepo.GetUsersByIDsOrEmails(ctx, []uuid.UUID{authorID}, nil); err == nil && len(authors) > 0 {
+		authorName = strings.TrimSpace(authors[0].FirstName + " " + authors[0].LastName)
+		if authorName == "" {
+			authorName = authors[0].Email
+		}
+	}
+
+	excerpt := []rune(comment.Comment)
+	if len(excerpt) > mentionExcerptLength {
+		excerpt = append(excerpt[:mentionExcerptLength], '…')
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"document_id": document.ID,
+		"comment_id":  comment.ID,
+	})
+	dataStr := string(data)
+	link := fmt.Sprintf("/documents/%d?comment=%d", document.ID, comment.ID)
+
+	for _, user := range mentioned {
+		recipient := user.ID
+		_, err := s.notifier.CreateNotification(ctx, &domain.CreateNotificationRequest{
+			UserID:  &recipient,
+			Type:    domain.NotificationTypeInfo,
+			Title:   fmt.Sprintf("%s mentioned you on %s", authorName, document.DocumentName),
+			Message: string(excerpt),
+			Data:    &dataStr,
+			Link:    &link,
+		})
+		if err != nil {
+			logger.Warn("Failed to send mention notification",
+				zap.Uint("comment_id", comment.ID),
+				zap.String("user_id", recipient.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
@@ -6,24 +6,78 @@ import (
 	"fmt"
 	"mime/multipart"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/dto"
 	repositories "github.com/owner/go-cms/internal/core/ports/repositories"
 	storage "github.com/owner/go-cms/internal/infrastructure/filestorage"
+	"github.com/owner/go-cms/internal/infrastructure/preview"
 )
 
+// AuditRecorder records audit log entries for document events
+type AuditRecorder interface {
+	Create(ctx context.Context, log *domain.AuditLog) error
+}
+
+// ScopeResolver returns the broadest scope at which a user holds a permission
+type ScopeResolver interface {
+	GetPermissionScope(ctx context.Context, userID uuid.UUID, permission string) (domain.ScopeLevel, error)
+}
+
+// PolicyEvaluator evaluates the attribute-based policies on a permission
+type PolicyEvaluator interface {
+	EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error)
+}
+
+// ReadPermission grants view access to documents uploaded by users within its scope
+const ReadPermission = "admin:system:documents:documents:read"
+
+// Notifier delivers in-app notifications, e.g. for comment mentions
+type Notifier interface {
+	CreateNotification(ctx context.Context, req *domain.CreateNotificationRequest) (*domain.Notification, error)
+}
+
 type DocumentUsecase struct {
-	documentRepo   repositories.DocumentRepository
-	storageUsecase storage.IStorage
+	documentRepo     repositories.DocumentRepository
+	storageUsecase   storage.IStorage
+	auditRecorder    AuditRecorder
+	notifier         Notifier
+	scopes           ScopeResolver
+	policies         PolicyEvaluator
+	previewGenerator *preview.Generator
+	config           config.DocumentConfig
+
+	// Background preview pipeline
+	previewQueue  chan uint
+	previewMu     sync.Mutex
+	previewQueued map[uint]struct{}
 }
 
-func NewDocumentUsecase(documentRepo repositories.DocumentRepository, storageUsecase storage.IStorage) *DocumentUsecase {
+func NewDocumentUsecase(
+	documentRepo repositories.DocumentRepository,
+	storageUsecase storage.IStorage,
+	auditRecorder AuditRecorder,
+	notifier Notifier,
+	scopes ScopeResolver,
+	policies PolicyEvaluator,
+	previewGenerator *preview.Generator,
+	cfg config.DocumentConfig,
+) *DocumentUsecase {
 	return &DocumentUsecase{
-		documentRepo:   documentRepo,
-		storageUsecase: storageUsecase,
+		documentRepo:     documentRepo,
+		storageUsecase:   storageUsecase,
+		auditRecorder:    auditRecorder,
+		notifier:         notifier,
+		scopes:           scopes,
+		policies:         policies,
+		previewGenerator: previewGenerator,
+		config:           cfg,
+		previewQueue:     make(chan uint, previewQueueSize),
+		previewQueued:    make(map[uint]struct{}),
 	}
 }
 
@@ -57,11 +111,59 @@ func (s *DocumentUsecase) getUserPermissionLevel(ctx context.Context, document *
 
 	// Check permissions
 	permission, err := s.documentRepo.GetUserDocumentPermission(ctx, document.ID, userID)
-	if err != nil {
-		return "" // No permission
+	if err == nil {
+		return permission.PermissionLevel
+	}
+
+	// Fall back to the read permission, limited to uploaders within its scope
+	if s.inReadScope(ctx, document, userID) {
+		return domain.PermissionView
+	}
+
+	return "" // No permission
+}
+
+// inReadScope reports whether the user's read permission scope covers the document's uploader
+func (s *DocumentUsecase) inReadScope(ctx context.Context, document *domain.Document, userID uuid.UUID) bool {
+	return s.scopeCovers(ctx, s.readScope(ctx, userID), document.UploadedBy) && s.policiesAllow(ctx, document, userID)
+}
+
+// readScope returns the scope of the user's read permission; its level is empty when they do not hold it
+func (s *DocumentUsecase) readScope(ctx context.Context, userID uuid.UUID) *domain.AccessScope {
+	scope := &domain.AccessScope{UserID: userID, Permission: ReadPermission}
+	if s.scopes != nil {
+		if level, err := s.scopes.GetPermissionScope(ctx, userID, ReadPermission); err == nil {
+			scope.Level = level
+		}
+	}
+	return scope
+}
+
+// scopeCovers reports whether documents uploaded by the user fall within a read scope
+func (s *DocumentUsecase) scopeCovers(ctx context.Context, scope *domain.AccessScope, uploaderID uuid.UUID) bool {
+	if scope.Level == "" {
+		return false
+	}
+	if scope.Unrestricted() {
+		return true
 	}
 
-	return permission.PermissionLevel
+	inScope, err := s.documentRepo.IsUserInScope(ctx, scope, uploaderID)
+	return err == nil && inScope
+}
+
+// policiesAllow evaluates the resource policies on the read permission against the document
+func (s *DocumentUsecase) policiesAllow(ctx context.Context, document *domain.Document, userID uuid.UUID) bool {
+	if s.policies == nil {
+		return true
+	}
+
+	decision, err := s.policies.EvaluatePolicies(ctx, domain.PolicyInput{
+		UserID:     userID,
+		Permission: ReadPermission,
+		Resource:   document,
+	})
+	return err == nil && decision.Allowed
 }
 
 // Document CRUD methods
@@ -78,6 +180,12 @@ func (s *DocumentUsecase) UploadDocument(
 		return nil, errors.New("file type not allowed")
 	}
 
+	// Resolve the folder the document is filed under
+	folderID, err := s.resolveUploadFolder(ctx, uploadRequest, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use storage Usecase to upload the file
 	storagePath, err := s.storageUsecase.UploadFile(ctx, file, uploadRequest.EntityType, uploadRequest.EntityID)
 	if err != nil {
@@ -89,9 +197,10 @@ func (s *DocumentUsecase) UploadDocument(
 		DocumentCode: s.generateDocumentCode(uploadRequest.EntityType),
 		EntityType:   uploadRequest.EntityType,
 		EntityID:     uploadRequest.EntityID,
+		FolderID:     folderID,
 		DocumentName: uploadRequest.DocumentName,
 		DocumentPath: storagePath,
-		DocumentType: file.Header.Get("Content-Type"),
+		DocumentType: storage.ContentTypeForFile(file.Filename),
 		FileSize:     file.Size,
 		UploadedBy:   userID,
 	}
@@ -108,12 +217,12 @@ func (s *DocumentUsecase) UploadDocument(
 
 	// Create initial version record
 	version := &domain.DocumentVersion{
-		DocumentID:    document.ID,
-		VersionNumber: 1,
-		DocumentPath:  storagePath,
-		FileSize:      file.Size,
-		ChangedBy:     userID,
-		ChangeNote:    "Initial upload",
+		DocumentID:   document.ID,
+		DocumentPath: storagePath,
+		DocumentType: document.DocumentType,
+		FileSize:     file.Size,
+		ChangedBy:    userID,
+		ChangeNote:   "Initial upload",
 	}
 
 	if err := s.documentRepo.CreateDocumentVersion(ctx, version); err != nil {
@@ -122,6 +231,9 @@ func (s *DocumentUsecase) UploadDocument(
 		// Just log the error in a real implementation
 	}
 
+	// Extract text and render the thumbnail in the background
+	s.schedulePreview(ctx, document)
+
 	return document, nil
 }
 
@@ -148,6 +260,11 @@ func (s *DocumentUsecase) UpdateDocument(
 		return nil, errors.New("permission denied: you don't have edit permission for this document")
 	}
 
+	// Only the holder can change a checked-out document
+	if err := s.ensureNotLockedByOther(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
 	// Update fields
 	document.DocumentName = updateRequest.DocumentName
 	document.UpdatedAt = time.Now()
@@ -177,7 +294,17 @@ func (s *DocumentUsecase) DeleteDocument(ctx context.Context, id uint, userID uu
 		return errors.New("permission denied: only the document owner can delete it")
 	}
 
-	// Soft delete the document
+	// Only the holder can delete a checked-out document
+	if err := s.ensureNotLockedByOther(ctx, id, userID); err != nil {
+		return err
+	}
+
+	// Legal holds and retention policies keep the document
+	if err := s.ensureDeletable(ctx, document); err != nil {
+		return err
+	}
+
+	// Soft delete the document; it is purged when its retention period ends
 	return s.documentRepo.DeleteDocumentByID(ctx, id)
 }
 
@@ -190,15 +317,12 @@ func (s *DocumentUsecase) GetDocumentByID(ctx context.Context, id uint, userID u
 	}
 
 	// Check permission (at least view)
-	hasPermission, err := s.CheckUserPermission(ctx, id, userID, domain.PermissionView)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check permission: %w", err)
-	}
-
-	if !hasPermission && document.UploadedBy != userID {
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionView) {
 		return nil, errors.New("permission denied: you don't have view permission for this document")
 	}
 
+	s.loadDocumentLock(ctx, document)
+
 	return document, nil
 }
 
@@ -211,15 +335,12 @@ func (s *DocumentUsecase) GetDocumentByCode(ctx context.Context, code string, us
 	}
 
 	// Check permission (at least view)
-	hasPermission, err := s.CheckUserPermission(ctx, document.ID, userID, domain.PermissionView)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check permission: %w", err)
-	}
-
-	if !hasPermission && document.UploadedBy != userID {
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionView) {
 		return nil, errors.New("permission denied: you don't have view permission for this document")
 	}
 
+	s.loadDocumentLock(ctx, document)
+
 	return document, nil
 }
 
@@ -233,53 +354,47 @@ func (s *DocumentUsecase) GetDocuments(ctx context.Context, filter dto.DocumentF
 		filter.PageSize = 10
 	}
 
-	documents, totalCount, totalPages, err := s.documentRepo.GetDocuments(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
-	}
-
-	// Map to response DTO with permissions
-	response := &dto.PaginatedDocumentsResponse{
-		Data:       make([]dto.DocumentResponse, 0, len(documents)),
-		TotalCount: totalCount,
-		Page:       filter.Page,
-		PageSize:   filter.PageSize,
-		TotalPages: totalPages,
-	}
-
-	for _, doc := range documents {
-		// Check if user has permission to see this document
-		permissionLevel := s.getUserPermissionLevel(ctx, &doc, userID)
-
-		// Skip documents the user doesn't have access to
-		if permissionLevel == "" && doc.UploadedBy != userID {
-			continue
+	viewer := s.readScope(ctx, userID)
+	if s.policies == nil {
+		documents, totalCount, totalPages, err := s.documentRepo.GetDocuments(ctx, filter, viewer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve documents: %w", err)
 		}
 
-		// If owner uploaded it, they have owner permission
-		if doc.UploadedBy == userID && permissionLevel == "" {
-			permissionLevel = domain.PermissionOwner
+		// Map to response DTO with permissions
+		return &dto.PaginatedDocumentsResponse{
+			Data:       s.visibleDocuments(ctx, documents, userID),
+			TotalCount: totalCount,
+			Page:       filter.Page,
+			PageSize:   filter.PageSize,
+			TotalPages: totalPages,
+		}, nil
+	}
+
+	// Policies can refuse documents the query returns, so they are applied to every match
+	// before the page is cut and counted
+	var visible []dto.DocumentResponse
+	batch := filter
+	batch.PageSize = policyFilterBatchSize
+	for batch.Page = 1; ; batch.Page++ {
+		documents, _, totalPages, err := s.documentRepo.GetDocuments(ctx, batch, viewer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve documents: %w", err)
 		}
-
-		docResponse := dto.DocumentResponse{
-			ID:             doc.ID,
-			DocumentCode:   doc.DocumentCode,
-			EntityType:     doc.EntityType,
-			EntityID:       doc.EntityID,
-			DocumentName:   doc.DocumentName,
-			DocumentType:   doc.DocumentType,
-			FileSize:       doc.FileSize,
-			UploadedBy:     doc.UploadedBy,
-			UploaderName:   doc.Uploader.LastName,
-			CreatedAt:      doc.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:      doc.UpdatedAt.Format(time.RFC3339),
-			UserPermission: permissionLevel,
+		visible = append(visible, s.visibleDocuments(ctx, documents, userID)...)
+		if batch.Page >= totalPages {
+			break
 		}
-
-		response.Data = append(response.Data, docResponse)
 	}
 
-	return response, nil
+	start, end := pageBounds(len(visible), filter.Page, filter.PageSize)
+	return &dto.PaginatedDocumentsResponse{
+		Data:       visible[start:end],
+		TotalCount: len(visible),
+		Page:       filter.Page,
+		PageSize:   filter.PageSize,
+		TotalPages: pageCount(len(visible), filter.PageSize),
+	}, nil
 }
 
 // GetDocumentsByEntityID retrieves documents for a specific entity
@@ -290,47 +405,12 @@ func (s *DocumentUsecase) GetDocumentsByEntityID(
 	userID uuid.UUID,
 ) ([]dto.DocumentResponse, error) {
 	// Get documents for entity
-	documents, err := s.documentRepo.GetDocumentsByEntityID(ctx, entityType, entityID)
+	documents, err := s.documentRepo.GetDocumentsByEntityID(ctx, entityType, entityID, s.readScope(ctx, userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
 	}
 
-	// Map to response DTO with permissions
-	response := make([]dto.DocumentResponse, 0, len(documents))
-
-	for _, doc := range documents {
-		// Check if user has permission to see this document
-		permissionLevel := s.getUserPermissionLevel(ctx, &doc, userID)
-
-		// Skip documents the user doesn't have access to
-		if permissionLevel == "" && doc.UploadedBy != userID {
-			continue
-		}
-
-		// If owner uploaded it, they have owner permission
-		if doc.UploadedBy == userID && permissionLevel == "" {
-			permissionLevel = domain.PermissionOwner
-		}
-
-		docResponse := dto.DocumentResponse{
-			ID:             doc.ID,
-			DocumentCode:   doc.DocumentCode,
-			EntityType:     doc.EntityType,
-			EntityID:       doc.EntityID,
-			DocumentName:   doc.DocumentName,
-			DocumentType:   doc.DocumentType,
-			FileSize:       doc.FileSize,
-			UploadedBy:     doc.UploadedBy,
-			UploaderName:   doc.Uploader.LastName,
-			CreatedAt:      doc.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:      doc.UpdatedAt.Format(time.RFC3339),
-			UserPermission: permissionLevel,
-		}
-
-		response = append(response, docResponse)
-	}
-
-	return response, nil
+	return s.visibleDocuments(ctx, documents, userID), nil
 }
 
 // DownloadDocument retrieves a document's content with permission check
@@ -378,29 +458,50 @@ func (s *DocumentUsecase) AddDocumentPermission(
 		return errors.New("permission denied: only the document owner can manage permissions")
 	}
 
-	// Validate request
-	if request.UserID == nil && request.JobTitle == "" {
-		return errors.New("either userId or roleId must be provided")
+	// Validate request and create permission record
+	permission, err := newGrantPermission(request.DocumentGrantRequest, userID)
+	if err != nil {
+		return err
 	}
+	permission.DocumentID = &request.DocumentID
 
-	// Create permission record
-	permission := &domain.DocumentPermission{
-		DocumentID:      request.DocumentID,
-		UserID:          uuid.Nil,
-		JobTitle:        "",
-		PermissionLevel: request.PermissionLevel,
-		CreatedBy:       userID,
-	}
+	return s.documentRepo.CreateDocumentPermission(ctx, permission)
+}
 
+// newGrantPermission validates a grant request and builds the permission record for it
+func newGrantPermission(request dto.DocumentGrantRequest, userID uuid.UUID) (*domain.DocumentPermission, error) {
+	subjects := 0
 	if request.UserID != nil {
-		permission.UserID = *request.UserID
+		subjects++
+	}
+	if request.RoleID != nil {
+		subjects++
+	}
+	if request.DepartmentID != nil {
+		subjects++
 	}
-
 	if request.JobTitle != "" {
-		permission.JobTitle = request.JobTitle
+		subjects++
 	}
 
-	return s.documentRepo.CreateDocumentPermission(ctx, permission)
+	if subjects != 1 {
+		return nil, errors.New("exactly one of userId, roleId, departmentId or jobTitle must be provided")
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry date must be in the future")
+	}
+
+	return &domain.DocumentPermission{
+		UserID:                request.UserID,
+		RoleID:                request.RoleID,
+		DepartmentID:          request.DepartmentID,
+		IncludeSubDepartments: request.DepartmentID != nil && request.IncludeSubDepartments,
+		JobTitle:              request.JobTitle,
+		PermissionLevel:       request.PermissionLevel,
+		ExpiresAt:             request.ExpiresAt,
+		CreatedBy:             userID,
+	}, nil
 }
 
 // UpdateDocumentPermission updates an existing permission
@@ -444,8 +545,13 @@ func (s *DocumentUsecase) UpdateDocumentPermission(
 		return errors.New("permission not found")
 	}
 
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return errors.New("expiry date must be in the future")
+	}
+
 	// Update permission
 	permissionToUpdate.PermissionLevel = request.PermissionLevel
+	permissionToUpdate.ExpiresAt = request.ExpiresAt
 	permissionToUpdate.UpdatedAt = time.Now()
 
 	return s.documentRepo.UpdateDocumentPermission(ctx, permissionToUpdate)
@@ -457,32 +563,38 @@ func (s *DocumentUsecase) RemoveDocumentPermission(
 	id uint,
 	userID uuid.UUID,
 ) error {
-	// Get the permission to find the document ID
-	permissions, err := s.documentRepo.GetDocumentPermissions(ctx, 0) // We don't know document ID yet
+	// Get the permission to find the document or folder it belongs to
+	permissionToDelete, err := s.documentRepo.GetDocumentPermissionByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve permissions: %w", err)
+		return errors.New("permission not found")
 	}
 
-	var permissionToDelete *domain.DocumentPermission
-	for i, p := range permissions {
-		if p.ID == id {
-			permissionToDelete = &permissions[i]
-			break
+	// Folder grants are managed by the folder owner
+	if permissionToDelete.FolderID != nil {
+		folder, err := s.documentRepo.GetFolderByID(ctx, *permissionToDelete.FolderID)
+		if err != nil {
+			return fmt.Errorf("folder not found: %w", err)
+		}
+
+		if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionOwner) {
+			return errors.New("permission denied: only the folder owner can manage permissions")
 		}
+
+		return s.documentRepo.DeleteDocumentPermission(ctx, id)
 	}
 
-	if permissionToDelete == nil {
+	if permissionToDelete.DocumentID == nil {
 		return errors.New("permission not found")
 	}
 
 	// Check if requester has owner permission
-	hasPermission, err := s.CheckUserPermission(ctx, permissionToDelete.DocumentID, userID, domain.PermissionOwner)
+	hasPermission, err := s.CheckUserPermission(ctx, *permissionToDelete.DocumentID, userID, domain.PermissionOwner)
 	if err != nil {
 		return fmt.Errorf("failed to check permission: %w", err)
 	}
 
 	// Get document to check if user is the uploader
-	document, err := s.documentRepo.GetDocumentByID(ctx, permissionToDelete.DocumentID)
+	document, err := s.documentRepo.GetDocumentByID(ctx, *permissionToDelete.DocumentID)
 	if err != nil {
 		return fmt.Errorf("document not found: %w", err)
 	}
@@ -526,175 +638,285 @@ func (s *DocumentUsecase) CheckUserPermission(
 	userID uuid.UUID,
 	requiredLevel string,
 ) (bool, error) {
-	return s.documentRepo.CheckUserPermission(ctx, documentID, userID, requiredLevel)
-}
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return false, err
+	}
 
-// Document comments methods
+	return hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), requiredLevel), nil
+}
 
-// AddDocumentComment adds a new comment to a document
-func (s *DocumentUsecase) AddDocumentComment(
+// GetEffectiveDocumentPermission explains which grants give a user their level on a document.
+// Users may inspect their own access; inspecting someone else's requires owner permission.
+func (s *DocumentUsecase) GetEffectiveDocumentPermission(
 	ctx context.Context,
-	request dto.DocumentCommentRequest,
+	documentID uint,
+	targetUserID uuid.UUID,
 	userID uuid.UUID,
-) (*domain.DocumentComment, error) {
-	// Check if user has comment permission
-	hasPermission, err := s.CheckUserPermission(ctx, request.DocumentID, userID, domain.PermissionComment)
+) (*dto.EffectiveDocumentPermissionResponse, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check permission: %w", err)
+		return nil, fmt.Errorf("document not found: %w", err)
 	}
 
-	// Get document to check if user is the uploader
-	document, err := s.documentRepo.GetDocumentByID(ctx, request.DocumentID)
+	if targetUserID != userID {
+		hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionOwner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check permission: %w", err)
+		}
+
+		if !hasPermission {
+			return nil, errors.New("permission denied: only the document owner can inspect other users' access")
+		}
+	}
+
+	response := &dto.EffectiveDocumentPermissionResponse{
+		DocumentID: documentID,
+		UserID:     targetUserID,
+		Sources:    make([]dto.DocumentPermissionSource, 0),
+	}
+
+	if document.UploadedBy == targetUserID {
+		response.PermissionLevel = domain.PermissionOwner
+		response.Sources = append(response.Sources, dto.DocumentPermissionSource{
+			PermissionLevel: domain.PermissionOwner,
+			Subject:         "uploader",
+			SubjectName:     "Uploaded the document",
+		})
+	}
+
+	permissions, err := s.documentRepo.GetApplicableDocumentPermissions(ctx, documentID, targetUserID)
 	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+		return nil, fmt.Errorf("failed to retrieve permissions: %w", err)
 	}
 
-	if !hasPermission && document.UploadedBy != userID {
-		return nil, errors.New("permission denied: you don't have permission to comment on this document")
+	for _, permission := range permissions {
+		source := dto.DocumentPermissionSource{
+			PermissionID:    permission.ID,
+			PermissionLevel: permission.PermissionLevel,
+			FolderID:        permission.FolderID,
+			ExpiresAt:       permission.ExpiresAt,
+		}
+
+		switch {
+		case permission.UserID != nil:
+			source.Subject = "user"
+			if permission.User != nil {
+				source.SubjectName = permission.User.Email
+			}
+		case permission.RoleID != nil:
+			source.Subject = "role"
+			if permission.Role != nil {
+				source.SubjectName = permission.Role.Name
+			}
+		case permission.DepartmentID != nil:
+			source.Subject = "department"
+			if permission.Department != nil {
+				source.SubjectName = permission.Department.Name
+				if permission.IncludeSubDepartments {
+					source.SubjectName += " (including sub-departments)"
+				}
+			}
+		default:
+			source.Subject = "job_title"
+			source.SubjectName = permission.JobTitle
+		}
+
+		if permission.Folder != nil {
+			source.FolderName = permission.Folder.Name
+		}
+
+		if permissionRanks[permission.PermissionLevel] > permissionRanks[response.PermissionLevel] {
+			response.PermissionLevel = permission.PermissionLevel
+		}
+
+		response.Sources = append(response.Sources, source)
 	}
 
-	// Create comment
-	comment := &domain.DocumentComment{
-		DocumentID: request.DocumentID,
-		UserID:     userID,
-		Comment:    request.Comment,
+	return response, nil
+}
+
+// Document versions methods
+
+// GetDocumentVersions retrieves all versions of a document
+func (s *DocumentUsecase) GetDocumentVersions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentVersion, error) {
+	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionView)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
 	}
 
-	if err := s.documentRepo.CreateDocumentComment(ctx, comment); err != nil {
-		return nil, fmt.Errorf("failed to create comment: %w", err)
+	// Get document to check if user is the uploader
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	if !hasPermission && document.UploadedBy != userID {
+		return nil, errors.New("permission denied: you don't have permission to view versions of this document")
 	}
 
-	return comment, nil
+	return s.documentRepo.GetDocumentVersions(ctx, documentID)
 }
 
-// UpdateDocumentComment updates an existing comment
-func (s *DocumentUsecase) UpdateDocumentComment(
+// UploadDocumentVersion uploads a new revision of a document and makes it current.
+// The previous object is kept in storage so older versions stay downloadable.
+func (s *DocumentUsecase) UploadDocumentVersion(
 	ctx context.Context,
-	id uint,
-	comment string,
+	documentID uint,
+	file *multipart.FileHeader,
+	request dto.DocumentVersionRequest,
 	userID uuid.UUID,
-) (*domain.DocumentComment, error) {
-	// Get document comments
-	comments, err := s.documentRepo.GetDocumentComments(ctx, 0) // We don't know document ID yet
+) (*domain.DocumentVersion, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve comments: %w", err)
+		return nil, fmt.Errorf("document not found: %w", err)
 	}
 
-	var commentToUpdate *domain.DocumentComment
-	for i, c := range comments {
-		if c.ID == id {
-			commentToUpdate = &comments[i]
-			break
-		}
+	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionEdit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	if !hasPermission {
+		return nil, errors.New("permission denied: you don't have edit permission for this document")
 	}
 
-	if commentToUpdate == nil {
-		return nil, errors.New("comment not found")
+	// Only the holder can change a checked-out document
+	if err := s.ensureNotLockedByOther(ctx, documentID, userID); err != nil {
+		return nil, err
 	}
 
-	// Check if user is the comment author
-	if commentToUpdate.UserID != userID {
-		return nil, errors.New("permission denied: you can only edit your own comments")
+	if !s.storageUsecase.IsAllowedFileType(file.Filename) {
+		return nil, errors.New("file type not allowed")
 	}
 
-	// Update comment
-	commentToUpdate.Comment = comment
-	commentToUpdate.UpdatedAt = time.Now()
+	storagePath, err := s.storageUsecase.UploadFile(ctx, file, document.EntityType, document.EntityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file to storage: %w", err)
+	}
+
+	version := &domain.DocumentVersion{
+		DocumentID:   documentID,
+		DocumentPath: storagePath,
+		DocumentType: storage.ContentTypeForFile(file.Filename),
+		FileSize:     file.Size,
+		ChangedBy:    userID,
+		ChangeNote:   request.ChangeNote,
+	}
 
-	if err := s.documentRepo.UpdateDocumentComment(ctx, commentToUpdate); err != nil {
-		return nil, fmt.Errorf("failed to update comment: %w", err)
+	if err := s.setCurrentVersion(ctx, document, version); err != nil {
+		// The new object is not referenced by anything, remove it again
+		_ = s.storageUsecase.DeleteFile(ctx, storagePath)
+		return nil, err
 	}
 
-	return commentToUpdate, nil
+	return version, nil
 }
 
-// DeleteDocumentComment deletes a comment
-func (s *DocumentUsecase) DeleteDocumentComment(ctx context.Context, id uint, userID uuid.UUID) error {
-	// Get document comments
-	comments, err := s.documentRepo.GetDocumentComments(ctx, 0) // We don't know document ID yet
+// DownloadDocumentVersion retrieves the content of a specific version of a document
+func (s *DocumentUsecase) DownloadDocumentVersion(
+	ctx context.Context,
+	documentID uint,
+	versionNumber int,
+	userID uuid.UUID,
+) ([]byte, string, string, error) {
+	// Get document with permission check
+	document, err := s.GetDocumentByID(ctx, documentID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve comments: %w", err)
+		return nil, "", "", err
 	}
 
-	var commentToDelete *domain.DocumentComment
-	for i, c := range comments {
-		if c.ID == id {
-			commentToDelete = &comments[i]
-			break
-		}
+	version, err := s.documentRepo.GetDocumentVersion(ctx, documentID, versionNumber)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("version not found: %w", err)
 	}
 
-	if commentToDelete == nil {
-		return errors.New("comment not found")
+	fileBytes, err := s.storageUsecase.DownloadFile(ctx, version.DocumentPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to download file: %w", err)
 	}
 
-	// Check if user is the comment author or document owner
-	if commentToDelete.UserID != userID {
-		// Check if user is document owner
-		document, err := s.documentRepo.GetDocumentByID(ctx, commentToDelete.DocumentID)
-		if err != nil {
-			return fmt.Errorf("document not found: %w", err)
-		}
-
-		if document.UploadedBy != userID {
-			// Check if user has owner permission
-			hasPermission, err := s.CheckUserPermission(ctx, commentToDelete.DocumentID, userID, domain.PermissionOwner)
-			if err != nil {
-				return fmt.Errorf("failed to check permission: %w", err)
-			}
-
-			if !hasPermission {
-				return errors.New("permission denied: you can only delete your own comments or comments on documents you own")
-			}
-		}
+	contentType := version.DocumentType
+	if contentType == "" {
+		contentType = document.DocumentType
 	}
 
-	return s.documentRepo.DeleteDocumentComment(ctx, id)
+	return fileBytes, contentType, fmt.Sprintf("v%d_%s", version.VersionNumber, document.DocumentName), nil
 }
 
-// GetDocumentComments retrieves all comments for a document
-func (s *DocumentUsecase) GetDocumentComments(
+// RestoreDocumentVersion makes an older version current again by recording it as a new version
+func (s *DocumentUsecase) RestoreDocumentVersion(
 	ctx context.Context,
 	documentID uint,
+	versionNumber int,
+	request dto.DocumentVersionRequest,
 	userID uuid.UUID,
-) ([]domain.DocumentComment, error) {
-	// Check if user has view permission
-	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionView)
+) (*domain.DocumentVersion, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionEdit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check permission: %w", err)
 	}
 
-	// Get document to check if user is the uploader
-	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if !hasPermission {
+		return nil, errors.New("permission denied: you don't have edit permission for this document")
+	}
+
+	// Only the holder can change a checked-out document
+	if err := s.ensureNotLockedByOther(ctx, documentID, userID); err != nil {
+		return nil, err
+	}
+
+	source, err := s.documentRepo.GetDocumentVersion(ctx, documentID, versionNumber)
 	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+		return nil, fmt.Errorf("version not found: %w", err)
 	}
 
-	if !hasPermission && document.UploadedBy != userID {
-		return nil, errors.New("permission denied: you don't have permission to view comments on this document")
+	changeNote := request.ChangeNote
+	if changeNote == "" {
+		changeNote = fmt.Sprintf("Restored from version %d", source.VersionNumber)
 	}
 
-	return s.documentRepo.GetDocumentComments(ctx, documentID)
-}
+	// The restored version shares the storage object of the source version;
+	// its number is assigned when it is saved
+	version := &domain.DocumentVersion{
+		DocumentID:   documentID,
+		DocumentPath: source.DocumentPath,
+		DocumentType: source.DocumentType,
+		FileSize:     source.FileSize,
+		ChangedBy:    userID,
+		ChangeNote:   changeNote,
+	}
 
-// Document versions methods
+	if version.DocumentType == "" {
+		version.DocumentType = document.DocumentType
+	}
 
-// GetDocumentVersions retrieves all versions of a document
-func (s *DocumentUsecase) GetDocumentVersions(ctx context.Context, documentID uint, userID uuid.UUID) ([]domain.DocumentVersion, error) {
-	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionView)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check permission: %w", err)
+	if err := s.setCurrentVersion(ctx, document, version); err != nil {
+		return nil, err
 	}
 
-	// Get document to check if user is the uploader
-	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
-	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+	return version, nil
+}
+
+// setCurrentVersion saves a version and points the document at its storage object
+func (s *DocumentUsecase) setCurrentVersion(ctx context.Context, document *domain.Document, version *domain.DocumentVersion) error {
+	if err := s.documentRepo.CreateCurrentDocumentVersion(ctx, version); err != nil {
+		return fmt.Errorf("failed to save document version: %w", err)
 	}
 
-	if !hasPermission && document.UploadedBy != userID {
-		return nil, errors.New("permission denied: you don't have permission to view versions of this document")
+	document.DocumentPath = version.DocumentPath
+	document.FileSize = version.FileSize
+	if version.DocumentType != "" {
+		document.DocumentType = version.DocumentType
 	}
+	document.UpdatedAt = time.Now()
 
-	return s.documentRepo.GetDocumentVersions(ctx, documentID)
+	s.schedulePreview(ctx, document)
+
+	return nil
 }
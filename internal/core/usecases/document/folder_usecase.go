@@ -0,0 +1,715 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/dto"
+	"gorm.io/gorm"
+)
+
+// permissionRanks orders document permission levels from the lowest to the highest
+var permissionRanks = map[string]int{
+	domain.PermissionView:    1,
+	domain.PermissionComment: 2,
+	domain.PermissionEdit:    3,
+	domain.PermissionOwner:   4,
+}
+
+// hasPermissionLevel reports whether level grants at least the required level
+func hasPermissionLevel(level, required string) bool {
+	return level != "" && permissionRanks[level] >= permissionRanks[required]
+}
+
+// toDocumentResponse maps a document to its response DTO
+func toDocumentResponse(doc *domain.Document, permissionLevel string) dto.DocumentResponse {
+	tags := make([]string, 0, len(doc.Tags))
+	for _, tag := range doc.Tags {
+		tags = append(tags, tag.Slug)
+	}
+
+	return dto.DocumentResponse{
+		ID:             doc.ID,
+		DocumentCode:   doc.DocumentCode,
+		EntityType:     doc.EntityType,
+		EntityID:       doc.EntityID,
+		DocumentName:   doc.DocumentName,
+		DocumentType:   doc.DocumentType,
+		FileSize:       doc.FileSize,
+		FolderID:       doc.FolderID,
+		Tags:           tags,
+		UploadedBy:     doc.UploadedBy,
+		UploaderName:   doc.Uploader.LastName,
+		CreatedAt:      doc.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      doc.UpdatedAt.Format(time.RFC3339),
+		UserPermission: permissionLevel,
+		PreviewStatus:  previewStatus(doc),
+		ThumbnailURL:   thumbnailURL(doc),
+	}
+}
+
+// toFolderResponse maps a folder to its response DTO without children
+func toFolderResponse(folder *domain.DocumentFolder, permissionLevel string) dto.DocumentFolderResponse {
+	return dto.DocumentFolderResponse{
+		ID:             folder.ID,
+		Name:           folder.Name,
+		ParentID:       folder.ParentID,
+		EntityType:     folder.EntityType,
+		EntityID:       folder.EntityID,
+		UserPermission: permissionLevel,
+		Children:       []dto.DocumentFolderResponse{},
+	}
+}
+
+// getFolderPermissionLevel returns the permission level a user holds on a folder, including grants on its ancestors
+func (s *DocumentUsecase) getFolderPermissionLevel(ctx context.Context, folder *domain.DocumentFolder, userID uuid.UUID) string {
+	permission, err := s.documentRepo.GetUserFolderPermission(ctx, folder, userID)
+	if err != nil {
+		return "" // No permission
+	}
+
+	return permission.PermissionLevel
+}
+
+// canViewFolder reports whether a user may see a folder. Entity workspaces are visible to
+// everyone; the documents inside them are still filtered by their own permissions.
+func canViewFolder(folder *domain.DocumentFolder, permissionLevel string) bool {
+	return permissionLevel != "" || folder.EntityType != ""
+}
+
+// canFileInFolder reports whether a user may add documents or subfolders to a folder.
+// Entity workspaces accept documents from anyone who can upload against the entity.
+func canFileInFolder(folder *domain.DocumentFolder, permissionLevel string) bool {
+	return hasPermissionLevel(permissionLevel, domain.PermissionEdit) || folder.EntityType != ""
+}
+
+// belongsToFolder reports whether a document linked to the given entity may be filed in a folder
+func belongsToFolder(folder *domain.DocumentFolder, entityType string, entityID uint) bool {
+	if folder.EntityType == "" {
+		return true
+	}
+	return folder.EntityType == entityType && folder.EntityID == entityID
+}
+
+// resolveUploadFolder picks the folder for a new upload: the requested folder, or the entity workspace root if one exists
+func (s *DocumentUsecase) resolveUploadFolder(ctx context.Context, uploadRequest dto.DocumentUploadRequest, userID uuid.UUID) (*uint, error) {
+	if uploadRequest.FolderID == nil {
+		root, err := s.documentRepo.GetEntityRootFolder(ctx, uploadRequest.EntityType, uploadRequest.EntityID)
+		if err != nil {
+			return nil, nil
+		}
+		return &root.ID, nil
+	}
+
+	folder, err := s.documentRepo.GetFolderByID(ctx, *uploadRequest.FolderID)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !canFileInFolder(folder, s.getFolderPermissionLevel(ctx, folder, userID)) {
+		return nil, errors.New("permission denied: you don't have edit permission for this folder")
+	}
+
+	if !belongsToFolder(folder, uploadRequest.EntityType, uploadRequest.EntityID) {
+		return nil, errors.New("folder belongs to another entity workspace")
+	}
+
+	return &folder.ID, nil
+}
+
+// Folder methods
+
+// CreateFolder creates a folder and makes the creator its owner
+func (s *DocumentUsecase) CreateFolder(
+	ctx context.Context,
+	request dto.DocumentFolderRequest,
+	userID uuid.UUID,
+) (*domain.DocumentFolder, error) {
+	folder := &domain.DocumentFolder{
+		Name:      strings.TrimSpace(request.Name),
+		ParentID:  request.ParentID,
+		CreatedBy: &userID,
+	}
+
+	if folder.Name == "" {
+		return nil, errors.New("folder name is required")
+	}
+
+	if request.ParentID != nil {
+		parent, err := s.documentRepo.GetFolderByID(ctx, *request.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("parent folder not found: %w", err)
+		}
+
+		if !canFileInFolder(parent, s.getFolderPermissionLevel(ctx, parent, userID)) {
+			return nil, errors.New("permission denied: you don't have edit permission for the parent folder")
+		}
+
+		// Subfolders stay part of the parent's entity workspace
+		folder.EntityType = parent.EntityType
+		folder.EntityID = parent.EntityID
+	}
+
+	if err := s.documentRepo.CreateFolder(ctx, folder); err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+
+	ownerPermission := &domain.DocumentPermission{
+		FolderID:        &folder.ID,
+		UserID:          &userID,
+		PermissionLevel: domain.PermissionOwner,
+		CreatedBy:       userID,
+	}
+
+	if err := s.documentRepo.CreateDocumentPermission(ctx, ownerPermission); err != nil {
+		return nil, fmt.Errorf("failed to grant folder ownership: %w", err)
+	}
+
+	return folder, nil
+}
+
+// RenameFolder changes the name of a folder
+func (s *DocumentUsecase) RenameFolder(
+	ctx context.Context,
+	id uint,
+	name string,
+	userID uuid.UUID,
+) (*domain.DocumentFolder, error) {
+	folder, err := s.documentRepo.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionEdit) {
+		return nil, errors.New("permission denied: you don't have edit permission for this folder")
+	}
+
+	folder.Name = strings.TrimSpace(name)
+	if folder.Name == "" {
+		return nil, errors.New("folder name is required")
+	}
+
+	if err := s.documentRepo.UpdateFolder(ctx, folder); err != nil {
+		return nil, fmt.Errorf("failed to update folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+// MoveFolder moves a folder and its subtree under a new parent
+func (s *DocumentUsecase) MoveFolder(
+	ctx context.Context,
+	id uint,
+	parentID *uint,
+	userID uuid.UUID,
+) (*domain.DocumentFolder, error) {
+	folder, err := s.documentRepo.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionOwner) {
+		return nil, errors.New("permission denied: only the folder owner can move it")
+	}
+
+	if folder.ParentID == nil && folder.EntityType != "" {
+		return nil, errors.New("an entity workspace root cannot be moved")
+	}
+
+	newPath := fmt.Sprintf("/%d/", folder.ID)
+	if parentID != nil {
+		parent, err := s.documentRepo.GetFolderByID(ctx, *parentID)
+		if err != nil {
+			return nil, fmt.Errorf("parent folder not found: %w", err)
+		}
+
+		if strings.HasPrefix(parent.Path, folder.Path) {
+			return nil, errors.New("a folder cannot be moved into itself or one of its subfolders")
+		}
+
+		if parent.EntityType != folder.EntityType || parent.EntityID != folder.EntityID {
+			return nil, errors.New("a folder cannot be moved to another entity workspace")
+		}
+
+		if !canFileInFolder(parent, s.getFolderPermissionLevel(ctx, parent, userID)) {
+			return nil, errors.New("permission denied: you don't have edit permission for the target folder")
+		}
+
+		newPath = fmt.Sprintf("%s%d/", parent.Path, folder.ID)
+	} else if folder.EntityType != "" {
+		return nil, errors.New("a workspace folder cannot be moved out of its workspace")
+	}
+
+	if err := s.documentRepo.MoveFolder(ctx, folder, parentID, newPath); err != nil {
+		return nil, fmt.Errorf("failed to move folder: %w", err)
+	}
+
+	folder.ParentID = parentID
+	folder.Path = newPath
+
+	return folder, nil
+}
+
+// DeleteFolder deletes an empty folder
+func (s *DocumentUsecase) DeleteFolder(ctx context.Context, id uint, userID uuid.UUID) error {
+	folder, err := s.documentRepo.GetFolderByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionOwner) {
+		return errors.New("permission denied: only the folder owner can delete it")
+	}
+
+	count, err := s.documentRepo.CountFolderContents(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check folder contents: %w", err)
+	}
+
+	if count > 0 {
+		return errors.New("folder is not empty")
+	}
+
+	hasRules, err := s.documentRepo.FolderHasRetentionRules(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check folder retention: %w", err)
+	}
+
+	if hasRules {
+		return errors.New("folder has a retention policy or legal hold")
+	}
+
+	return s.documentRepo.DeleteFolder(ctx, id)
+}
+
+// GetFolders retrieves the top-level folders the user has been granted access to
+func (s *DocumentUsecase) GetFolders(ctx context.Context, userID uuid.UUID) ([]dto.DocumentFolderResponse, error) {
+	folders, err := s.documentRepo.GetFolderChildren(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve folders: %w", err)
+	}
+
+	response := make([]dto.DocumentFolderResponse, 0, len(folders))
+	for i := range folders {
+		permissionLevel := s.getFolderPermissionLevel(ctx, &folders[i], userID)
+		if permissionLevel == "" {
+			continue
+		}
+		response = append(response, toFolderResponse(&folders[i], permissionLevel))
+	}
+
+	return response, nil
+}
+
+// GetFolderContents retrieves the subfolders and documents directly inside a folder
+func (s *DocumentUsecase) GetFolderContents(
+	ctx context.Context,
+	id uint,
+	userID uuid.UUID,
+) (*dto.DocumentFolderContentsResponse, error) {
+	folder, err := s.documentRepo.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	permissionLevel := s.getFolderPermissionLevel(ctx, folder, userID)
+	if !canViewFolder(folder, permissionLevel) {
+		return nil, errors.New("permission denied: you don't have view permission for this folder")
+	}
+
+	children, err := s.documentRepo.GetFolderChildren(ctx, &folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve folders: %w", err)
+	}
+
+	documents, err := s.documentRepo.GetDocumentsByFolderID(ctx, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+	}
+
+	response := &dto.DocumentFolderContentsResponse{
+		Folder:    toFolderResponse(folder, permissionLevel),
+		Folders:   make([]dto.DocumentFolderResponse, 0, len(children)),
+		Documents: s.visibleDocuments(ctx, documents, userID),
+	}
+
+	for i := range children {
+		childLevel := s.getFolderPermissionLevel(ctx, &children[i], userID)
+		if !canViewFolder(&children[i], childLevel) {
+			continue
+		}
+		response.Folders = append(response.Folders, toFolderResponse(&children[i], childLevel))
+	}
+
+	return response, nil
+}
+
+// MoveDocument files a document under another folder, or removes it from any folder when folderID is nil
+func (s *DocumentUsecase) MoveDocument(
+	ctx context.Context,
+	id uint,
+	folderID *uint,
+	userID uuid.UUID,
+) error {
+	document, err := s.documentRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("document not found: %w", err)
+	}
+
+	hasPermission, err := s.CheckUserPermission(ctx, id, userID, domain.PermissionEdit)
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	if !hasPermission {
+		return errors.New("permission denied: you don't have edit permission for this document")
+	}
+
+	// Only the holder can move a checked-out document
+	if err := s.ensureNotLockedByOther(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if folderID != nil {
+		folder, err := s.documentRepo.GetFolderByID(ctx, *folderID)
+		if err != nil {
+			return fmt.Errorf("folder not found: %w", err)
+		}
+
+		if !canFileInFolder(folder, s.getFolderPermissionLevel(ctx, folder, userID)) {
+			return errors.New("permission denied: you don't have edit permission for the target folder")
+		}
+
+		if !belongsToFolder(folder, document.EntityType, document.EntityID) {
+			return errors.New("folder belongs to another entity workspace")
+		}
+	}
+
+	return s.documentRepo.MoveDocument(ctx, id, folderID)
+}
+
+// visibleDocuments maps the documents the user can see to response DTOs. Grants are resolved in one query;
+// documents seen only through the read permission are checked against its scope and policies.
+func (s *DocumentUsecase) visibleDocuments(ctx context.Context, documents []domain.Document, userID uuid.UUID) []dto.DocumentResponse {
+	ids := make([]uint, 0, len(documents))
+	for i := range documents {
+		ids = append(ids, documents[i].ID)
+	}
+
+	levels, err := s.documentRepo.GetUserDocumentPermissionLevels(ctx, ids, userID)
+	if err != nil {
+		levels = map[uint]string{}
+	}
+
+	var scope *domain.AccessScope
+	covered := make(map[uuid.UUID]bool) // Whether the read scope covers each uploader
+
+	response := make([]dto.DocumentResponse, 0, len(documents))
+	for i := range documents {
+		document := &documents[i]
+		permissionLevel := levels[document.ID]
+
+		switch {
+		case document.UploadedBy == userID:
+			permissionLevel = domain.PermissionOwner
+		case permissionLevel == "":
+			if scope == nil {
+				scope = s.readScope(ctx, userID)
+			}
+			inScope, ok := covered[document.UploadedBy]
+			if !ok {
+				inScope = s.scopeCovers(ctx, scope, document.UploadedBy)
+				covered[document.UploadedBy] = inScope
+			}
+			if !inScope || !s.policiesAllow(ctx, document, userID) {
+				continue
+			}
+			permissionLevel = domain.PermissionView
+		}
+
+		response = append(response, toDocumentResponse(document, permissionLevel))
+	}
+	return s.withLocks(ctx, response)
+}
+
+// policyFilterBatchSize is how many documents are read at a time when policies have to be
+// applied to every match before a page is cut
+const policyFilterBatchSize = 500
+
+// pageBounds returns where a page starts and ends within total items
+func pageBounds(total, page, pageSize int) (int, int) {
+	start := min((page-1)*pageSize, total)
+	return start, min(start+pageSize, total)
+}
+
+// pageCount returns how many pages total items fill
+func pageCount(total, pageSize int) int {
+	return (total + pageSize - 1) / pageSize
+}
+
+// Folder permission methods
+
+// AddFolderPermission grants a user, role, department or job title access to a folder and everything below it
+func (s *DocumentUsecase) AddFolderPermission(
+	ctx context.Context,
+	folderID uint,
+	request dto.DocumentFolderPermissionRequest,
+	userID uuid.UUID,
+) error {
+	folder, err := s.documentRepo.GetFolderByID(ctx, folderID)
+	if err != nil {
+		return fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionOwner) {
+		return errors.New("permission denied: only the folder owner can manage permissions")
+	}
+
+	permission, err := newGrantPermission(request.DocumentGrantRequest, userID)
+	if err != nil {
+		return err
+	}
+	permission.FolderID = &folder.ID
+
+	return s.documentRepo.CreateDocumentPermission(ctx, permission)
+}
+
+// GetFolderPermissions retrieves the grants defined directly on a folder
+func (s *DocumentUsecase) GetFolderPermissions(
+	ctx context.Context,
+	folderID uint,
+	userID uuid.UUID,
+) ([]domain.DocumentPermission, error) {
+	folder, err := s.documentRepo.GetFolderByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getFolderPermissionLevel(ctx, folder, userID), domain.PermissionEdit) {
+		return nil, errors.New("permission denied: you don't have permission to view this folder's permissions")
+	}
+
+	return s.documentRepo.GetFolderPermissions(ctx, folderID)
+}
+
+// Tag methods
+
+// AddDocumentTags tags a document, creating tags that don't exist yet
+func (s *DocumentUsecase) AddDocumentTags(
+	ctx context.Context,
+	documentID uint,
+	names []string,
+	userID uuid.UUID,
+) ([]domain.Tag, error) {
+	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionEdit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	if !hasPermission {
+		return nil, errors.New("permission denied: you don't have edit permission for this document")
+	}
+
+	tags := make([]domain.Tag, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		tagSlug := slug.Make(name)
+		if tagSlug == "" {
+			continue
+		}
+
+		tag, err := s.documentRepo.FindOrCreateTag(ctx, name, tagSlug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save tag %q: %w", name, err)
+		}
+		tags = append(tags, *tag)
+	}
+
+	if len(tags) == 0 {
+		return nil, errors.New("no valid tags provided")
+	}
+
+	if err := s.documentRepo.AddDocumentTags(ctx, documentID, tags); err != nil {
+		return nil, fmt.Errorf("failed to tag document: %w", err)
+	}
+
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	return document.Tags, nil
+}
+
+// RemoveDocumentTag removes a tag from a document
+func (s *DocumentUsecase) RemoveDocumentTag(ctx context.Context, documentID uint, tagID uint, userID uuid.UUID) error {
+	hasPermission, err := s.CheckUserPermission(ctx, documentID, userID, domain.PermissionEdit)
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	if !hasPermission {
+		return errors.New("permission denied: you don't have edit permission for this document")
+	}
+
+	return s.documentRepo.RemoveDocumentTag(ctx, documentID, tagID)
+}
+
+// Entity workspace methods
+
+// InitializeEntityWorkspace creates the workspace root of an entity and the folders from its
+// entity type's templates. It is safe to call for an entity that already has a workspace.
+func (s *DocumentUsecase) InitializeEntityWorkspace(ctx context.Context, entityType string, entityID uint) (*domain.DocumentFolder, error) {
+	root, err := s.documentRepo.GetEntityRootFolder(ctx, entityType, entityID)
+	if err == nil {
+		return root, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to retrieve workspace: %w", err)
+	}
+
+	root = &domain.DocumentFolder{
+		Name:       fmt.Sprintf("%s #%d", entityType, entityID),
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	if err := s.documentRepo.CreateFolder(ctx, root); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	templates, err := s.documentRepo.GetFolderTemplates(ctx, entityType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve folder templates: %w", err)
+	}
+
+	// Templates may share path prefixes ("Contracts", "Contracts/Signed"), so reuse created folders
+	created := map[string]uint{"": root.ID}
+	for _, template := range templates {
+		path := ""
+		for _, name := range strings.Split(template.FolderPath, "/") {
+			parentID := created[path]
+			path = path + "/" + name
+
+			if _, exists := created[path]; exists {
+				continue
+			}
+
+			folder := &domain.DocumentFolder{
+				Name:       name,
+				ParentID:   &parentID,
+				EntityType: entityType,
+				EntityID:   entityID,
+			}
+
+			if err := s.documentRepo.CreateFolder(ctx, folder); err != nil {
+				return nil, fmt.Errorf("failed to create workspace folder %q: %w", path, err)
+			}
+			created[path] = folder.ID
+		}
+	}
+
+	return root, nil
+}
+
+// GetEntityWorkspace retrieves the folder tree and the visible documents of an entity
+func (s *DocumentUsecase) GetEntityWorkspace(
+	ctx context.Context,
+	entityType string,
+	entityID uint,
+	userID uuid.UUID,
+) (*dto.EntityWorkspaceResponse, error) {
+	root, err := s.InitializeEntityWorkspace(ctx, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	subtree, err := s.documentRepo.GetFolderSubtree(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve workspace folders: %w", err)
+	}
+
+	documents, err := s.documentRepo.GetDocumentsByEntityID(ctx, entityType, entityID, s.readScope(ctx, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+	}
+
+	children := make(map[uint][]domain.DocumentFolder)
+	for _, folder := range subtree {
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder)
+		}
+	}
+
+	return &dto.EntityWorkspaceResponse{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Root:       s.buildFolderTree(ctx, root, children, userID),
+		Documents:  s.visibleDocuments(ctx, documents, userID),
+	}, nil
+}
+
+// buildFolderTree recursively maps a folder and its children to response DTOs
+func (s *DocumentUsecase) buildFolderTree(
+	ctx context.Context,
+	folder *domain.DocumentFolder,
+	children map[uint][]domain.DocumentFolder,
+	userID uuid.UUID,
+) dto.DocumentFolderResponse {
+	response := toFolderResponse(folder, s.getFolderPermissionLevel(ctx, folder, userID))
+	for i := range children[folder.ID] {
+		response.Children = append(response.Children, s.buildFolderTree(ctx, &children[folder.ID][i], children, userID))
+	}
+	return response
+}
+
+// Folder template methods
+
+// CreateFolderTemplate adds a folder to the workspace template of an entity type
+func (s *DocumentUsecase) CreateFolderTemplate(
+	ctx context.Context,
+	request dto.DocumentFolderTemplateRequest,
+	userID uuid.UUID,
+) (*domain.DocumentFolderTemplate, error) {
+	// Normalize "/Contracts//Signed/" to "Contracts/Signed"
+	parts := make([]string, 0)
+	for _, name := range strings.Split(request.FolderPath, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			parts = append(parts, name)
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, errors.New("folder path is required")
+	}
+
+	template := &domain.DocumentFolderTemplate{
+		EntityType: request.EntityType,
+		FolderPath: strings.Join(parts, "/"),
+		SortOrder:  request.SortOrder,
+		CreatedBy:  userID,
+	}
+
+	if err := s.documentRepo.CreateFolderTemplate(ctx, template); err != nil {
+		return nil, fmt.Errorf("failed to create folder template: %w", err)
+	}
+
+	return template, nil
+}
+
+// GetFolderTemplates retrieves folder templates, optionally for a single entity type
+func (s *DocumentUsecase) GetFolderTemplates(ctx context.Context, entityType string) ([]domain.DocumentFolderTemplate, error) {
+	return s.documentRepo.GetFolderTemplates(ctx, entityType)
+}
+
+// DeleteFolderTemplate removes a folder template; existing workspaces are not changed
+func (s *DocumentUsecase) DeleteFolderTemplate(ctx context.Context, id uint) error {
+	return s.documentRepo.DeleteFolderTemplate(ctx, id)
+}
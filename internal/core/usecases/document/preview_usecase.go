@@ -0,0 +1,334 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/dto"
+	repositories "github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/preview"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	previewWorkers       = 2
+	previewQueueSize     = 256
+	previewSweepInterval = time.Minute
+	previewSweepBatch    = 100
+	previewTimeout       = 5 * time.Minute
+
+	thumbnailURLFormat = "/api/v1/documents/%d/thumbnail"
+
+	maxSearchPageSize = 100
+)
+
+// StartPreviewWorkers runs the background pipeline that extracts text and renders
+// thumbnails for new document files. It stops when ctx is cancelled.
+func (s *DocumentUsecase) StartPreviewWorkers(ctx context.Context) {
+	for i := 0; i < previewWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case documentID := <-s.previewQueue:
+					s.processPreview(ctx, documentID)
+				}
+			}
+		}()
+	}
+
+	// Periodically pick up documents whose preview was queued while the
+	// workers were busy or down, including documents uploaded before previews existed
+	go func() {
+		ticker := time.NewTicker(previewSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			s.sweepPendingPreviews(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SearchDocuments searches the extracted text of the documents the user can view
+func (s *DocumentUsecase) SearchDocuments(
+	ctx context.Context,
+	request dto.DocumentSearchRequest,
+	userID uuid.UUID,
+) (*dto.DocumentSearchResponse, error) {
+	if request.Page < 1 {
+		request.Page = 1
+	}
+
+	if request.PageSize < 1 {
+		request.PageSize = 10
+	} else if request.PageSize > maxSearchPageSize {
+		request.PageSize = maxSearchPageSize
+	}
+
+	viewer := s.readScope(ctx, userID)
+	if s.policies == nil {
+		hits, totalCount, totalPages, err := s.documentRepo.SearchDocuments(ctx, request.Query, request.Page, request.PageSize, viewer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search documents: %w", err)
+		}
+
+		return &dto.DocumentSearchResponse{
+			Data:       s.visibleSearchResults(ctx, hits, userID),
+			TotalCount: totalCount,
+			Page:       request.Page,
+			PageSize:   request.PageSize,
+			TotalPages: totalPages,
+		}, nil
+	}
+
+	// Policies can refuse documents the search returns, so they are applied to every match
+	// before the page is cut and counted
+	var visible []dto.DocumentSearchResult
+	for page := 1; ; page++ {
+		hits, _, totalPages, err := s.documentRepo.SearchDocuments(ctx, request.Query, page, policyFilterBatchSize, viewer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search documents: %w", err)
+		}
+		visible = append(visible, s.visibleSearchResults(ctx, hits, userID)...)
+		if page >= totalPages {
+			break
+		}
+	}
+
+	start, end := pageBounds(len(visible), request.Page, request.PageSize)
+	return &dto.DocumentSearchResponse{
+		Data:       visible[start:end],
+		TotalCount: len(visible),
+		Page:       request.Page,
+		PageSize:   request.PageSize,
+		TotalPages: pageCount(len(visible), request.PageSize),
+	}, nil
+}
+
+// visibleSearchResults maps the search hits the user can see to results, keeping their rank order
+func (s *DocumentUsecase) visibleSearchResults(ctx context.Context, hits []repositories.DocumentSearchHit, userID uuid.UUID) []dto.DocumentSearchResult {
+	documents := make([]domain.Document, 0, len(hits))
+	byID := make(map[uint]int, len(hits))
+	for i := range hits {
+		documents = append(documents, hits[i].Document)
+		byID[hits[i].Document.ID] = i
+	}
+
+	results := make([]dto.DocumentSearchResult, 0, len(hits))
+	for _, document := range s.visibleDocuments(ctx, documents, userID) {
+		hit := hits[byID[document.ID]]
+		results = append(results, dto.DocumentSearchResult{
+			DocumentResponse: document,
+			Snippet:          hit.Snippet,
+			Rank:             hit.Rank,
+		})
+	}
+	return results
+}
+
+// GetDocumentThumbnail returns the JPEG thumbnail of a document
+func (s *DocumentUsecase) GetDocumentThumbnail(ctx context.Context, id uint, userID uuid.UUID) ([]byte, error) {
+	// Get document with permission check
+	if _, err := s.GetDocumentByID(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	documentPreview, err := s.documentRepo.GetDocumentPreview(ctx, id)
+	if err != nil || documentPreview.ThumbnailPath == "" {
+		return nil, errors.New("thumbnail not available")
+	}
+
+	thumbnail, err := s.storageUsecase.DownloadFile(ctx, documentPreview.ThumbnailPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download thumbnail: %w", err)
+	}
+
+	return thumbnail, nil
+}
+
+// schedulePreview queues preview generation for the document's current file
+func (s *DocumentUsecase) schedulePreview(ctx context.Context, document *domain.Document) {
+	if err := s.documentRepo.MarkPreviewPending(ctx, document.ID, document.DocumentPath); err != nil {
+		logger.Warn("Failed to queue document preview", zap.Uint("document_id", document.ID), zap.Error(err))
+		return
+	}
+
+	s.enqueuePreview(document.ID)
+}
+
+// enqueuePreview hands a document to the workers unless it is already queued.
+// When the queue is full the pending row is picked up by the next sweep.
+func (s *DocumentUsecase) enqueuePreview(documentID uint) {
+	s.previewMu.Lock()
+	defer s.previewMu.Unlock()
+
+	if _, queued := s.previewQueued[documentID]; queued {
+		return
+	}
+
+	select {
+	case s.previewQueue <- documentID:
+		s.previewQueued[documentID] = struct{}{}
+	default:
+	}
+}
+
+func (s *DocumentUsecase) sweepPendingPreviews(ctx context.Context) {
+	documentIDs, err := s.documentRepo.GetPendingPreviews(ctx, previewSweepBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("Failed to load pending document previews", zap.Error(err))
+		}
+		return
+	}
+
+	for _, documentID := range documentIDs {
+		s.enqueuePreview(documentID)
+	}
+}
+
+// processPreview extracts the text and renders the thumbnail of a document's current file
+func (s *DocumentUsecase) processPreview(ctx context.Context, documentID uint) {
+	defer func() {
+		s.previewMu.Lock()
+		delete(s.previewQueued, documentID)
+		s.previewMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, previewTimeout)
+	defer cancel()
+
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	var previousThumbnail string
+	if existing, err := s.documentRepo.GetDocumentPreview(ctx, documentID); err == nil {
+		previousThumbnail = existing.ThumbnailPath
+	}
+
+	result := &domain.DocumentPreview{
+		DocumentID:    documentID,
+		SourcePath:    document.DocumentPath,
+		ThumbnailPath: previousThumbnail,
+	}
+
+	fileBytes, err := s.storageUsecase.DownloadFile(ctx, document.DocumentPath)
+	if err != nil {
+		s.savePreview(ctx, result, document, fmt.Errorf("failed to download file: %w", err))
+		return
+	}
+
+	text, textErr := s.previewGenerator.ExtractText(document.DocumentPath, fileBytes)
+	thumbnail, thumbnailErr := s.previewGenerator.Thumbnail(document.DocumentPath, fileBytes)
+
+	if errors.Is(textErr, preview.ErrUnsupported) && errors.Is(thumbnailErr, preview.ErrUnsupported) {
+		result.Status = domain.PreviewStatusUnsupported
+		result.ThumbnailPath = ""
+		if s.savePreview(ctx, result, document, nil) {
+			s.deleteThumbnail(ctx, previousThumbnail, "")
+		}
+		return
+	}
+
+	if textErr == nil {
+		result.ContentText = text
+	}
+
+	if thumbnailErr == nil {
+		thumbnailPath, err := s.storageUsecase.UploadFileFromBytes(ctx, thumbnail, fmt.Sprintf("thumbnail_%d.jpg", documentID), "document_thumbnails", documentID)
+		if err != nil {
+			thumbnailErr = err
+		} else {
+			result.ThumbnailPath = thumbnailPath
+		}
+	}
+
+	var processErr error
+	switch {
+	case textErr != nil && !errors.Is(textErr, preview.ErrUnsupported):
+		processErr = fmt.Errorf("text extraction failed: %w", textErr)
+	case thumbnailErr != nil && !errors.Is(thumbnailErr, preview.ErrUnsupported):
+		processErr = fmt.Errorf("thumbnail generation failed: %w", thumbnailErr)
+	}
+
+	if s.savePreview(ctx, result, document, processErr) {
+		s.deleteThumbnail(ctx, previousThumbnail, result.ThumbnailPath)
+	} else {
+		s.deleteThumbnail(ctx, result.ThumbnailPath, previousThumbnail)
+	}
+}
+
+// savePreview stores the outcome unless a newer file was uploaded while processing,
+// in which case the pending row is left for the next run. It reports whether it saved.
+func (s *DocumentUsecase) savePreview(ctx context.Context, result *domain.DocumentPreview, document *domain.Document, processErr error) bool {
+	current, err := s.documentRepo.GetDocumentByID(ctx, document.ID)
+	if err != nil || current.DocumentPath != document.DocumentPath {
+		return false
+	}
+
+	now := time.Now()
+	result.ProcessedAt = &now
+
+	if result.Status == "" {
+		result.Status = domain.PreviewStatusReady
+	}
+
+	if processErr != nil {
+		result.Error = processErr.Error()
+		if len(result.Error) > 500 {
+			result.Error = result.Error[:500]
+		}
+		// A document is still searchable if only its thumbnail failed
+		if result.ContentText == "" {
+			result.Status = domain.PreviewStatusFailed
+		}
+		logger.Warn("Document preview generation failed", zap.Uint("document_id", document.ID), zap.Error(processErr))
+	}
+
+	if err := s.documentRepo.SaveDocumentPreview(ctx, result); err != nil {
+		logger.Warn("Failed to save document preview", zap.Uint("document_id", document.ID), zap.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// deleteThumbnail removes a replaced thumbnail from storage
+func (s *DocumentUsecase) deleteThumbnail(ctx context.Context, path, replacement string) {
+	if path == "" || path == replacement {
+		return
+	}
+
+	if err := s.storageUsecase.DeleteFile(ctx, path); err != nil {
+		logger.Warn("Failed to delete document thumbnail", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// thumbnailURL returns the API path of a document's thumbnail, if it has one
+func thumbnailURL(document *domain.Document) string {
+	if document.Preview == nil || document.Preview.ThumbnailPath == "" {
+		return ""
+	}
+	return fmt.Sprintf(thumbnailURLFormat, document.ID)
+}
+
+func previewStatus(document *domain.Document) string {
+	if document.Preview == nil {
+		return ""
+	}
+	return document.Preview.Status
+}
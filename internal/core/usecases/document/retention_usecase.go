@@ -0,0 +1,499 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/dto"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrLegalHold is returned when deleting a document covered by an active legal hold
+	ErrLegalHold = errors.New("document is under legal hold")
+	// ErrRetentionPeriodActive is returned when deleting a document its retention policy still keeps
+	ErrRetentionPeriodActive = errors.New("document is within its retention period")
+)
+
+const (
+	defaultPurgeInterval = 24 * time.Hour
+	purgeBatchSize       = 100
+	purgeLockKey         = "document-purge"
+)
+
+// StartRetentionJob periodically purges documents past their retention period. Only one
+// instance purges per interval. It stops when ctx is cancelled.
+func (s *DocumentUsecase) StartRetentionJob(ctx context.Context) {
+	interval := s.config.PurgeInterval
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// The lock is left to expire so other instances skip this interval
+			if _, acquired, err := cache.AcquireLock(ctx, purgeLockKey, uuid.NewString(), interval*9/10); err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to acquire document purge lock", zap.Error(err))
+				}
+			} else if acquired {
+				result, err := s.PurgeExpiredDocuments(ctx)
+				if err != nil && ctx.Err() == nil {
+					logger.Warn("Document purge failed", zap.Error(err))
+				} else if result.Purged > 0 || result.Failed > 0 {
+					logger.Info("Purged expired documents", zap.Int("purged", result.Purged), zap.Int("failed", result.Failed))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CreateRetentionPolicy creates a retention policy for an entity type or a folder
+func (s *DocumentUsecase) CreateRetentionPolicy(
+	ctx context.Context,
+	request dto.DocumentRetentionPolicyRequest,
+	userID uuid.UUID,
+) (*domain.DocumentRetentionPolicy, error) {
+	policy := &domain.DocumentRetentionPolicy{CreatedBy: userID}
+	if err := s.applyRetentionPolicyRequest(ctx, policy, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.documentRepo.CreateRetentionPolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to create retention policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// UpdateRetentionPolicy changes the target or period of a retention policy
+func (s *DocumentUsecase) UpdateRetentionPolicy(
+	ctx context.Context,
+	id uint,
+	request dto.DocumentRetentionPolicyRequest,
+) (*domain.DocumentRetentionPolicy, error) {
+	policy, err := s.documentRepo.GetRetentionPolicyByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("retention policy not found")
+	}
+
+	if err := s.applyRetentionPolicyRequest(ctx, policy, request); err != nil {
+		return nil, err
+	}
+
+	if err := s.documentRepo.UpdateRetentionPolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to update retention policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// DeleteRetentionPolicy removes a retention policy
+func (s *DocumentUsecase) DeleteRetentionPolicy(ctx context.Context, id uint) error {
+	if _, err := s.documentRepo.GetRetentionPolicyByID(ctx, id); err != nil {
+		return errors.New("retention policy not found")
+	}
+
+	return s.documentRepo.DeleteRetentionPolicy(ctx, id)
+}
+
+// GetRetentionPolicies lists all retention policies
+func (s *DocumentUsecase) GetRetentionPolicies(ctx context.Context) ([]domain.DocumentRetentionPolicy, error) {
+	return s.documentRepo.GetRetentionPolicies(ctx)
+}
+
+// PlaceLegalHold places a legal hold on a document or on everything under a folder
+func (s *DocumentUsecase) PlaceLegalHold(
+	ctx context.Context,
+	request dto.DocumentLegalHoldRequest,
+	userID uuid.UUID,
+) (*domain.DocumentLegalHold, error) {
+	if (request.DocumentID == nil) == (request.FolderID == nil) {
+		return nil, errors.New("a legal hold applies to either a document or a folder")
+	}
+
+	if request.DocumentID != nil {
+		if _, err := s.documentRepo.GetDocumentByID(ctx, *request.DocumentID); err != nil {
+			return nil, fmt.Errorf("document not found: %w", err)
+		}
+	} else if _, err := s.documentRepo.GetFolderByID(ctx, *request.FolderID); err != nil {
+		return nil, fmt.Errorf("folder not found: %w", err)
+	}
+
+	hold := &domain.DocumentLegalHold{
+		DocumentID: request.DocumentID,
+		FolderID:   request.FolderID,
+		Reason:     strings.TrimSpace(request.Reason),
+		CreatedBy:  userID,
+	}
+
+	if err := s.documentRepo.CreateLegalHold(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+
+	s.recordLegalHoldAudit(ctx, hold, domain.AuditActionCreate, "placed", userID)
+
+	return hold, nil
+}
+
+// ReleaseLegalHold releases a legal hold; released holds are kept for the record
+func (s *DocumentUsecase) ReleaseLegalHold(ctx context.Context, id uint, userID uuid.UUID) (*domain.DocumentLegalHold, error) {
+	hold, err := s.documentRepo.GetLegalHoldByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("legal hold not found")
+	}
+
+	if !hold.IsActive() {
+		return nil, errors.New("legal hold is already released")
+	}
+
+	now := time.Now()
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = &userID
+
+	if err := s.documentRepo.UpdateLegalHold(ctx, hold); err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	s.recordLegalHoldAudit(ctx, hold, domain.AuditActionUpdate, "released", userID)
+
+	return hold, nil
+}
+
+// GetLegalHolds lists legal holds, optionally only those still in force
+func (s *DocumentUsecase) GetLegalHolds(ctx context.Context, activeOnly bool) ([]domain.DocumentLegalHold, error) {
+	return s.documentRepo.GetLegalHolds(ctx, activeOnly)
+}
+
+// GetDocumentRetention explains which policy keeps a document and which holds cover it
+func (s *DocumentUsecase) GetDocumentRetention(ctx context.Context, id uint, userID uuid.UUID) (*dto.DocumentRetentionResponse, error) {
+	// Get document with permission check
+	document, err := s.GetDocumentByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := s.documentRepo.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	holds, err := s.activeLegalHolds(ctx, document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+
+	response := &dto.DocumentRetentionResponse{
+		DocumentID: document.ID,
+		LegalHolds: make([]dto.DocumentLegalHoldRef, 0, len(holds)),
+		Deletable:  len(holds) == 0,
+	}
+
+	if policy := effectiveRetentionPolicy(document, policies); policy != nil {
+		retainUntil := policy.RetainUntil(document.CreatedAt)
+		response.PolicyID = &policy.ID
+		response.PolicyName = policy.Name
+		response.RetentionDays = policy.RetentionDays
+		response.RetainUntil = &retainUntil
+		response.Deletable = response.Deletable && !retainUntil.After(time.Now())
+	}
+
+	for _, hold := range holds {
+		response.LegalHolds = append(response.LegalHolds, dto.DocumentLegalHoldRef{
+			ID:        hold.ID,
+			FolderID:  hold.FolderID,
+			Reason:    hold.Reason,
+			CreatedAt: hold.CreatedAt,
+		})
+	}
+
+	return response, nil
+}
+
+// PurgeExpiredDocuments permanently deletes soft-deleted documents whose retention
+// period is over and that no legal hold covers, together with their stored files.
+// Documents that were never deleted are kept.
+// A document that fails to purge is retried on the next run.
+func (s *DocumentUsecase) PurgeExpiredDocuments(ctx context.Context) (*dto.DocumentPurgeResponse, error) {
+	result := &dto.DocumentPurgeResponse{}
+
+	policies, err := s.documentRepo.GetRetentionPolicies(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	now := time.Now()
+	for i := range policies {
+		policy := &policies[i]
+		cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+
+		var afterID uint
+		for {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			documents, err := s.documentRepo.GetRetentionCandidates(ctx, policy, cutoff, afterID, purgeBatchSize)
+			if err != nil {
+				return result, fmt.Errorf("failed to load documents for retention policy %d: %w", policy.ID, err)
+			}
+
+			for j := range documents {
+				document := &documents[j]
+				afterID = document.ID
+
+				// Documents also covered by a more specific policy are handled under that one
+				if effective := effectiveRetentionPolicy(document, policies); effective == nil || effective.ID != policy.ID {
+					continue
+				}
+
+				holds, err := s.activeLegalHolds(ctx, document)
+				if err != nil {
+					result.Failed++
+					logger.Warn("Failed to check legal holds before purge", zap.Uint("document_id", document.ID), zap.Error(err))
+					continue
+				}
+				if len(holds) > 0 {
+					continue
+				}
+
+				if err := s.purgeDocument(ctx, document, policy); err != nil {
+					result.Failed++
+					logger.Warn("Failed to purge document", zap.Uint("document_id", document.ID), zap.Error(err))
+					continue
+				}
+				result.Purged++
+			}
+
+			if len(documents) < purgeBatchSize {
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// ensureDeletable rejects deleting a document under legal hold or within its retention period
+func (s *DocumentUsecase) ensureDeletable(ctx context.Context, document *domain.Document) error {
+	holds, err := s.activeLegalHolds(ctx, document)
+	if err != nil {
+		return fmt.Errorf("failed to check legal holds: %w", err)
+	}
+
+	if len(holds) > 0 {
+		return fmt.Errorf("%w: %s", ErrLegalHold, holds[0].Reason)
+	}
+
+	policies, err := s.documentRepo.GetRetentionPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	if policy := effectiveRetentionPolicy(document, policies); policy != nil {
+		if retainUntil := policy.RetainUntil(document.CreatedAt); retainUntil.After(time.Now()) {
+			return fmt.Errorf("%w until %s (%s)", ErrRetentionPeriodActive, retainUntil.Format("2006-01-02"), policy.Name)
+		}
+	}
+
+	return nil
+}
+
+// activeLegalHolds returns the unreleased holds on the document or any folder above it
+func (s *DocumentUsecase) activeLegalHolds(ctx context.Context, document *domain.Document) ([]domain.DocumentLegalHold, error) {
+	var folderIDs []uint
+	if document.Folder != nil {
+		folderIDs = document.Folder.AncestorIDs()
+	}
+
+	return s.documentRepo.GetActiveLegalHolds(ctx, document.ID, folderIDs)
+}
+
+// purgeDocument removes the stored files of a document, then its records
+func (s *DocumentUsecase) purgeDocument(ctx context.Context, document *domain.Document, policy *domain.DocumentRetentionPolicy) error {
+	paths := []string{document.DocumentPath}
+
+	versions, err := s.documentRepo.GetDocumentVersions(ctx, document.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load versions: %w", err)
+	}
+	for _, version := range versions {
+		paths = append(paths, version.DocumentPath)
+	}
+
+	if documentPreview, err := s.documentRepo.GetDocumentPreview(ctx, document.ID); err == nil && documentPreview.ThumbnailPath != "" {
+		paths = append(paths, documentPreview.ThumbnailPath)
+	}
+
+	deleted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if path == "" || deleted[path] {
+			continue
+		}
+		if err := s.storageUsecase.DeleteFile(ctx, path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		deleted[path] = true
+	}
+
+	if err := s.documentRepo.PurgeDocument(ctx, document.ID); err != nil {
+		return fmt.Errorf("failed to delete document records: %w", err)
+	}
+
+	if _, err := cache.ReleaseLock(ctx, documentLockKey(document.ID), ""); err != nil {
+		logger.Warn("Failed to clear lock of purged document", zap.Uint("document_id", document.ID), zap.Error(err))
+	}
+
+	if s.auditRecorder != nil {
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"document_code":  document.DocumentCode,
+			"document_name":  document.DocumentName,
+			"entity_type":    document.EntityType,
+			"entity_id":      document.EntityID,
+			"folder_id":      document.FolderID,
+			"uploaded_by":    document.UploadedBy,
+			"uploaded_at":    document.CreatedAt,
+			"deleted":        document.DeletedAt.Valid,
+			"policy_id":      policy.ID,
+			"retention_days": policy.RetentionDays,
+			"objects":        len(deleted),
+		})
+		metadataStr := string(metadata)
+		documentID := document.ID
+
+		err := s.auditRecorder.Create(ctx, &domain.AuditLog{
+			Action:      domain.AuditActionPurge,
+			Resource:    "documents",
+			ResourceID:  &documentID,
+			Description: fmt.Sprintf("Purged document %s under retention policy %q", document.DocumentCode, policy.Name),
+			Metadata:    &metadataStr,
+		})
+		if err != nil {
+			// The document is already gone, so the log is the only record of the purge
+			logger.Error("Failed to record document purge",
+				zap.Uint("document_id", documentID),
+				zap.String("metadata", metadataStr),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// applyRetentionPolicyRequest validates a policy request and copies it onto the policy
+func (s *DocumentUsecase) applyRetentionPolicyRequest(
+	ctx context.Context,
+	policy *domain.DocumentRetentionPolicy,
+	request dto.DocumentRetentionPolicyRequest,
+) error {
+	entityType := strings.TrimSpace(request.EntityType)
+	if (entityType == "") == (request.FolderID == nil) {
+		return errors.New("a retention policy applies to either an entity type or a folder")
+	}
+
+	if request.FolderID != nil {
+		if _, err := s.documentRepo.GetFolderByID(ctx, *request.FolderID); err != nil {
+			return fmt.Errorf("folder not found: %w", err)
+		}
+	}
+
+	policies, err := s.documentRepo.GetRetentionPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	for _, existing := range policies {
+		if existing.ID == policy.ID {
+			continue
+		}
+		if request.FolderID != nil && existing.FolderID != nil && *existing.FolderID == *request.FolderID {
+			return errors.New("the folder already has a retention policy")
+		}
+		if request.FolderID == nil && existing.FolderID == nil && existing.EntityType == entityType {
+			return errors.New("the entity type already has a retention policy")
+		}
+	}
+
+	policy.Name = strings.TrimSpace(request.Name)
+	policy.EntityType = entityType
+	policy.FolderID = request.FolderID
+	policy.RetentionDays = request.RetentionDays
+	policy.Description = request.Description
+
+	return nil
+}
+
+func (s *DocumentUsecase) recordLegalHoldAudit(
+	ctx context.Context,
+	hold *domain.DocumentLegalHold,
+	action domain.AuditAction,
+	outcome string,
+	userID uuid.UUID,
+) {
+	if s.auditRecorder == nil {
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"legal_hold_id": hold.ID,
+		"document_id":   hold.DocumentID,
+		"folder_id":     hold.FolderID,
+		"reason":        hold.Reason,
+		"user_id":       userID,
+	})
+	metadataStr := string(metadata)
+	holdID := hold.ID
+
+	_ = s.auditRecorder.Create(ctx, &domain.AuditLog{
+		Action:      action,
+		Resource:    "document_legal_holds",
+		ResourceID:  &holdID,
+		Description: fmt.Sprintf("Legal hold %d %s", hold.ID, outcome),
+		Metadata:    &metadataStr,
+	})
+}
+
+// effectiveRetentionPolicy picks the policy of the nearest folder above the document,
+// falling back to the policy of its entity type
+func effectiveRetentionPolicy(document *domain.Document, policies []domain.DocumentRetentionPolicy) *domain.DocumentRetentionPolicy {
+	if document.Folder != nil {
+		ancestors := document.Folder.AncestorIDs()
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			for j := range policies {
+				if policies[j].FolderID != nil && *policies[j].FolderID == ancestors[i] {
+					return &policies[j]
+				}
+			}
+		}
+	}
+
+	if document.EntityType == "" {
+		return nil
+	}
+
+	for j := range policies {
+		if policies[j].FolderID == nil && policies[j].EntityType == document.EntityType {
+			return &policies[j]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package document
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/dto"
+	"github.com/owner/go-cms/pkg/utils"
+)
+
+// Share link errors, mapped to HTTP statuses by the public handlers
+var (
+	ErrShareLinkNotFound           = errors.New("share link not found")
+	ErrShareLinkExpired            = errors.New("share link has expired or been revoked")
+	ErrShareLinkPasswordRequired   = errors.New("share link password is required or incorrect")
+	ErrShareLinkDownloadNotAllowed = errors.New("share link does not allow downloads")
+	ErrShareLinkLimitReached       = errors.New("share link download limit reached")
+)
+
+const (
+	shareLinkTokenLength = 48
+	shareLinkBasePath    = "/api/v1/public/share/"
+	shareLinkResource    = "document_share_links"
+)
+
+// CreateShareLink creates a public link for a document; only owners can share publicly
+func (s *DocumentUsecase) CreateShareLink(
+	ctx context.Context,
+	documentID uint,
+	request dto.DocumentShareLinkRequest,
+	userID uuid.UUID,
+) (*dto.DocumentShareLinkResponse, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionOwner) {
+		return nil, errors.New("permission denied: only owners can create share links")
+	}
+
+	if !request.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	token, err := utils.GenerateRandomString(shareLinkTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+
+	link := &domain.DocumentShareLink{
+		DocumentID:   documentID,
+		Token:        token,
+		Mode:         request.Mode,
+		ExpiresAt:    request.ExpiresAt,
+		MaxDownloads: request.MaxDownloads,
+		CreatedBy:    userID,
+	}
+
+	if request.Password != "" {
+		hash, err := utils.HashPassword(request.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = hash
+	}
+
+	if err := s.documentRepo.CreateShareLink(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	link.Document = document
+	s.recordShareLinkAudit(ctx, link, domain.AuditActionShare, "created", dto.ShareLinkAccessRequest{})
+
+	response := toShareLinkResponse(link)
+	return &response, nil
+}
+
+// GetDocumentShareLinks lists the active share links of a document
+func (s *DocumentUsecase) GetDocumentShareLinks(
+	ctx context.Context,
+	documentID uint,
+	userID uuid.UUID,
+) ([]dto.DocumentShareLinkResponse, error) {
+	document, err := s.documentRepo.GetDocumentByID(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document not found: %w", err)
+	}
+
+	if !hasPermissionLevel(s.getUserPermissionLevel(ctx, document, userID), domain.PermissionOwner) {
+		return nil, errors.New("permission denied: only owners can view share links")
+	}
+
+	links, err := s.documentRepo.GetActiveShareLinks(ctx, &documentID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+
+	return toShareLinkResponses(links), nil
+}
+
+// GetMyShareLinks lists the active share links created by the user
+func (s *DocumentUsecase) GetMyShareLinks(ctx context.Context, userID uuid.UUID) ([]dto.DocumentShareLinkResponse, error) {
+	links, err := s.documentRepo.GetActiveShareLinks(ctx, nil, &userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+
+	return toShareLinkResponses(links), nil
+}
+
+// RevokeShareLink disables a share link; its creator or a document owner can revoke it
+func (s *DocumentUsecase) RevokeShareLink(ctx context.Context, id uint, userID uuid.UUID) error {
+	link, err := s.documentRepo.GetShareLinkByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("share link not found: %w", err)
+	}
+
+	if link.CreatedBy != userID {
+		if link.Document == nil || !hasPermissionLevel(s.getUserPermissionLevel(ctx, link.Document, userID), domain.PermissionOwner) {
+			return errors.New("permission denied: cannot revoke this share link")
+		}
+	}
+
+	if link.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.documentRepo.RevokeShareLink(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	s.recordShareLinkAudit(ctx, link, domain.AuditActionDelete, "revoked", dto.ShareLinkAccessRequest{})
+
+	return nil
+}
+
+// GetSharedDocument returns the public details of a shared document
+func (s *DocumentUsecase) GetSharedDocument(ctx context.Context, access dto.ShareLinkAccessRequest) (*dto.SharedDocumentResponse, error) {
+	link, err := s.resolveShareLink(ctx, access, domain.AuditActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordShareLinkAudit(ctx, link, domain.AuditActionRead, "details", access)
+
+	return &dto.SharedDocumentResponse{
+		DocumentName: link.Document.DocumentName,
+		DocumentType: link.Document.DocumentType,
+		FileSize:     link.Document.FileSize,
+		Mode:         link.Mode,
+		ExpiresAt:    link.ExpiresAt,
+	}, nil
+}
+
+// DownloadSharedDocument returns the shared file content. Views are unlimited until the
+// link expires; downloads need a download link and count against its limit.
+func (s *DocumentUsecase) DownloadSharedDocument(
+	ctx context.Context,
+	access dto.ShareLinkAccessRequest,
+	download bool,
+) ([]byte, string, string, error) {
+	action := domain.AuditActionRead
+	if download {
+		action = domain.AuditActionExport
+	}
+
+	link, err := s.resolveShareLink(ctx, access, action)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if download {
+		if link.Mode != domain.ShareLinkModeDownload {
+			s.recordShareLinkAudit(ctx, link, action, "denied: view only", access)
+			return nil, "", "", ErrShareLinkDownloadNotAllowed
+		}
+
+		counted, err := s.documentRepo.IncrementShareLinkDownloads(ctx, link.ID)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to record download: %w", err)
+		}
+		if !counted {
+			s.recordShareLinkAudit(ctx, link, action, "denied: download limit reached", access)
+			return nil, "", "", ErrShareLinkLimitReached
+		}
+	} else if err := s.documentRepo.IncrementShareLinkViews(ctx, link.ID); err != nil {
+		return nil, "", "", fmt.Errorf("failed to record view: %w", err)
+	}
+
+	fileBytes, err := s.storageUsecase.DownloadFile(ctx, link.Document.DocumentPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to download file: %w", err)
+	}
+
+	outcome := "viewed"
+	if download {
+		outcome = "downloaded"
+	}
+	s.recordShareLinkAudit(ctx, link, action, outcome, access)
+
+	return fileBytes, link.Document.DocumentType, link.Document.DocumentName, nil
+}
+
+// resolveShareLink looks up a link by token and checks that it is usable, auditing refusals
+func (s *DocumentUsecase) resolveShareLink(
+	ctx context.Context,
+	access dto.ShareLinkAccessRequest,
+	action domain.AuditAction,
+) (*domain.DocumentShareLink, error) {
+	link, err := s.documentRepo.GetShareLinkByToken(ctx, access.Token)
+	if err != nil || link.Document == nil {
+		return nil, ErrShareLinkNotFound
+	}
+
+	if link.RevokedAt != nil || !link.ExpiresAt.After(time.Now()) {
+		s.recordShareLinkAudit(ctx, link, action, "denied: expired or revoked", access)
+		return nil, ErrShareLinkExpired
+	}
+
+	if link.PasswordHash != "" && !utils.CheckPassword(link.PasswordHash, access.Password) {
+		s.recordShareLinkAudit(ctx, link, action, "denied: invalid password", access)
+		return nil, ErrShareLinkPasswordRequired
+	}
+
+	return link, nil
+}
+
+// recordShareLinkAudit writes an audit entry for a share link event. Failures are not
+// surfaced to the caller so that an audit outage does not break sharing.
+func (s *DocumentUsecase) recordShareLinkAudit(
+	ctx context.Context,
+	link *domain.DocumentShareLink,
+	action domain.AuditAction,
+	outcome string,
+	access dto.ShareLinkAccessRequest,
+) {
+	if s.auditRecorder == nil {
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"share_link_id": link.ID,
+		"mode":          link.Mode,
+		"outcome":       outcome,
+		"created_by":    link.CreatedBy,
+	})
+	metadataStr := string(metadata)
+	documentID := link.DocumentID
+
+	statusCode := http.StatusOK
+	if strings.HasPrefix(outcome, "denied:") {
+		statusCode = http.StatusForbidden
+	}
+
+	_ = s.auditRecorder.Create(ctx, &domain.AuditLog{
+		Action:      action,
+		Resource:    shareLinkResource,
+		ResourceID:  &documentID,
+		Description: fmt.Sprintf("Share link %d %s", link.ID, outcome),
+		IPAddress:   access.IPAddress,
+		UserAgent:   access.UserAgent,
+		StatusCode:  statusCode,
+		Metadata:    &metadataStr,
+	})
+}
+
+func toShareLinkResponse(link *domain.DocumentShareLink) dto.DocumentShareLinkResponse {
+	response := dto.DocumentShareLinkResponse{
+		ID:            link.ID,
+		DocumentID:    link.DocumentID,
+		Token:         link.Token,
+		URL:           shareLinkBasePath + link.Token,
+		Mode:          link.Mode,
+		HasPassword:   link.PasswordHash != "",
+		ExpiresAt:     link.ExpiresAt,
+		MaxDownloads:  link.MaxDownloads,
+		DownloadCount: link.DownloadCount,
+		ViewCount:     link.ViewCount,
+		RevokedAt:     link.RevokedAt,
+		CreatedAt:     link.CreatedAt,
+	}
+
+	if link.Document != nil {
+		response.DocumentName = link.Document.DocumentName
+	}
+
+	return response
+}
+
+func toShareLinkResponses(links []domain.DocumentShareLink) []dto.DocumentShareLinkResponse {
+	responses := make([]dto.DocumentShareLinkResponse, 0, len(links))
+	for i := range links {
+		responses = append(responses, toShareLinkResponse(&links[i]))
+	}
+	return responses
+}
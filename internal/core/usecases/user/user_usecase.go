@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
 	"github.com/owner/go-cms/pkg/logger"
 	"github.com/owner/go-cms/pkg/pagination"
@@ -177,6 +178,7 @@ func (uc *UserUseCase) AssignRole(ctx context.Context, userID uuid.UUID, roleID
 		logger.Error("Failed to assign role to user", zap.Error(err))
 		return err
 	}
+	_ = cache.InvalidatePermissions(ctx, userID)
 
 	logger.Info("Role assigned to user", zap.String("userID", userID.String()), zap.Uint("roleID", roleID))
 	return nil
@@ -188,6 +190,7 @@ func (uc *UserUseCase) RemoveRole(ctx context.Context, userID uuid.UUID, roleID
 		logger.Error("Failed to remove role from user", zap.Error(err))
 		return err
 	}
+	_ = cache.InvalidatePermissions(ctx, userID)
 
 	logger.Info("Role removed from user", zap.String("userID", userID.String()), zap.Uint("roleID", roleID))
 	return nil
@@ -201,3 +204,27 @@ func (uc *UserUseCase) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]*d
 	}
 	return roles, nil
 }
+
+// ListLockedUsers lists users currently locked out after failed logins
+func (uc *UserUseCase) ListLockedUsers(ctx context.Context, page *pagination.OffsetPagination) ([]*domain.User, int64, error) {
+	return uc.userRepo.List(ctx, repositories.UserFilter{Locked: true}, page)
+}
+
+// UnlockUser lifts a user's login lockout and forgets their failed attempts
+func (uc *UserUseCase) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	user, err := uc.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.UnlockAccount(ctx, id); err != nil {
+		return err
+	}
+
+	if err := cache.ClearFailedAttempts(ctx, cache.LoginAccountAttemptKey(user.Email), cache.TwoFactorAttemptKey(user.ID)); err != nil {
+		logger.Warn("Failed to clear failed attempts", zap.String("id", id.String()), zap.Error(err))
+	}
+
+	logger.Info("User unlocked", zap.String("id", id.String()))
+	return nil
+}
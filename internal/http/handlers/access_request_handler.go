@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/pkg/pagination"
+	"github.com/owner/go-cms/pkg/response"
+)
+
+// AccessRequestHandler handles HTTP requests for access requests
+type AccessRequestHandler struct {
+	useCase *authorization.AccessRequestUseCase
+}
+
+// NewAccessRequestHandler creates a new access request handler
+func NewAccessRequestHandler(useCase *authorization.AccessRequestUseCase) *AccessRequestHandler {
+	return &AccessRequestHandler{useCase: useCase}
+}
+
+// CreateAccessRequest godoc
+// @Summary Request access
+// @Description Ask for a role or a permission. The request goes to the role's owner or your nearest department manager if they hold the access themselves, otherwise to admins. System roles and admin permissions always go to admins.
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Param request body authorization.CreateAccessRequestRequest true "Access request"
+// @Success 201 {object} response.Response{data=domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests [post]
+func (h *AccessRequestHandler) CreateAccessRequest(c *gin.Context) {
+	var req authorization.CreateAccessRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	request, err := h.useCase.CreateRequest(c.Request.Context(), middleware.MustGetUserID(c), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, request)
+}
+
+// ListMyAccessRequests godoc
+// @Summary List my access requests
+// @Description List the access requests you have filed
+// @Tags access-requests
+// @Produce json
+// @Param status query string false "Status (pending, approved, denied, cancelled)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/mine [get]
+func (h *AccessRequestHandler) ListMyAccessRequests(c *gin.Context) {
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	requests, total, err := h.useCase.ListMyRequests(c.Request.Context(), middleware.MustGetUserID(c), domain.AccessRequestStatus(c.Query("status")), page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, requests, total, page)
+}
+
+// ListAssignedAccessRequests godoc
+// @Summary List access requests assigned to me
+// @Description List the access requests routed to you for a decision
+// @Tags access-requests
+// @Produce json
+// @Param status query string false "Status (pending, approved, denied, cancelled)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/assigned [get]
+func (h *AccessRequestHandler) ListAssignedAccessRequests(c *gin.Context) {
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	requests, total, err := h.useCase.ListAssignedRequests(c.Request.Context(), middleware.MustGetUserID(c), domain.AccessRequestStatus(c.Query("status")), page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, requests, total, page)
+}
+
+// ListAccessRequests godoc
+// @Summary List access requests
+// @Description List every access request
+// @Tags access-requests
+// @Produce json
+// @Param status query string false "Status (pending, approved, denied, cancelled)"
+// @Param requester_id query string false "Requester ID"
+// @Param approver_id query string false "Approver ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests [get]
+func (h *AccessRequestHandler) ListAccessRequests(c *gin.Context) {
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	filter := repositories.AccessRequestFilter{
+		Status: domain.AccessRequestStatus(c.Query("status")),
+	}
+
+	if requesterID := c.Query("requester_id"); requesterID != "" {
+		id, err := uuid.Parse(requesterID)
+		if err != nil {
+			response.BadRequest(c, "Invalid requester ID")
+			return
+		}
+		filter.RequesterID = &id
+	}
+	if approverID := c.Query("approver_id"); approverID != "" {
+		id, err := uuid.Parse(approverID)
+		if err != nil {
+			response.BadRequest(c, "Invalid approver ID")
+			return
+		}
+		filter.ApproverID = &id
+	}
+
+	requests, total, err := h.useCase.ListRequests(c.Request.Context(), filter, page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, requests, total, page)
+}
+
+// GetAccessRequest godoc
+// @Summary Get access request
+// @Description Get an access request you filed, one routed to you, or any request as an admin
+// @Tags access-requests
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Success 200 {object} response.Response{data=domain.AccessRequest}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/{id} [get]
+func (h *AccessRequestHandler) GetAccessRequest(c *gin.Context) {
+	id, ok := parseAccessRequestID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.useCase.GetRequest(c.Request.Context(), id, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, request)
+}
+
+// ApproveAccessRequest godoc
+// @Summary Approve access request
+// @Description Approve a pending access request routed to you and grant the access until expires_at, the requested expiry or the default duration
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body authorization.DecideAccessRequestRequest false "Decision"
+// @Success 200 {object} response.Response{data=domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/{id}/approve [post]
+func (h *AccessRequestHandler) ApproveAccessRequest(c *gin.Context) {
+	id, req, ok := bindAccessRequestDecision(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.useCase.ApproveRequest(c.Request.Context(), id, middleware.MustGetUserID(c), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, request)
+}
+
+// DenyAccessRequest godoc
+// @Summary Deny access request
+// @Description Deny a pending access request routed to you
+// @Tags access-requests
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body authorization.DecideAccessRequestRequest false "Decision"
+// @Success 200 {object} response.Response{data=domain.AccessRequest}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/{id}/deny [post]
+func (h *AccessRequestHandler) DenyAccessRequest(c *gin.Context) {
+	id, req, ok := bindAccessRequestDecision(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.useCase.DenyRequest(c.Request.Context(), id, middleware.MustGetUserID(c), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, request)
+}
+
+// CancelAccessRequest godoc
+// @Summary Cancel access request
+// @Description Withdraw a pending access request you filed
+// @Tags access-requests
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Success 200 {object} response.Response{data=domain.AccessRequest}
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Security BearerAuth
+// @Router /access-requests/{id}/cancel [post]
+func (h *AccessRequestHandler) CancelAccessRequest(c *gin.Context) {
+	id, ok := parseAccessRequestID(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.useCase.CancelRequest(c.Request.Context(), id, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, request)
+}
+
+func parseAccessRequestID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid access request ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindAccessRequestDecision reads the request ID and the optional decision body
+func bindAccessRequestDecision(c *gin.Context) (uint, authorization.DecideAccessRequestRequest, bool) {
+	var req authorization.DecideAccessRequestRequest
+
+	id, ok := parseAccessRequestID(c)
+	if !ok {
+		return 0, req, false
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ValidationError(c, err.Error())
+			return 0, req, false
+		}
+	}
+	return id, req, true
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/usecases/apitoken"
+	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/pkg/pagination"
+	"github.com/owner/go-cms/pkg/response"
+)
+
+// APITokenHandler handles HTTP requests for personal access tokens and service accounts
+type APITokenHandler struct {
+	useCase *apitoken.UseCase
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(useCase *apitoken.UseCase) *APITokenHandler {
+	return &APITokenHandler{useCase: useCase}
+}
+
+// CreatePersonalToken godoc
+// @Summary Create personal access token
+// @Description Create a token that acts as the current user, limited to the given permission codes. The token is only shown once.
+// @Tags api-tokens
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body apitoken.CreateTokenRequest true "Token details"
+// @Success 201 {object} response.Response{data=apitoken.CreatedToken}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /auth/tokens [post]
+func (h *APITokenHandler) CreatePersonalToken(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	var req apitoken.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	token, err := h.useCase.CreatePersonalToken(c.Request.Context(), userID, req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	middleware.OmitResponseFromAudit(c)
+	response.Created(c, token)
+}
+
+// ListPersonalTokens godoc
+// @Summary List personal access tokens
+// @Description List the current user's personal access tokens, including revoked and expired ones
+// @Tags api-tokens
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]domain.APIToken}
+// @Failure 401 {object} response.Response
+// @Router /auth/tokens [get]
+func (h *APITokenHandler) ListPersonalTokens(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	tokens, err := h.useCase.ListPersonalTokens(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, tokens)
+}
+
+// RevokePersonalToken godoc
+// @Summary Revoke personal access token
+// @Description Revoke one of the current user's personal access tokens
+// @Tags api-tokens
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /auth/tokens/{id} [delete]
+func (h *APITokenHandler) RevokePersonalToken(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid token ID")
+		return
+	}
+
+	if err := h.useCase.RevokePersonalToken(c.Request.Context(), userID, tokenID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "Token revoked successfully",
+	})
+}
+
+// CreateServiceAccount godoc
+// @Summary Create service account
+// @Description Create a non-human account that authenticates with API keys only. Grant it roles through /users/{id}/roles.
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body apitoken.CreateServiceAccountRequest true "Service account details"
+// @Success 201 {object} response.Response{data=domain.User}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /service-accounts [post]
+func (h *APITokenHandler) CreateServiceAccount(c *gin.Context) {
+	var req apitoken.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	account, err := h.useCase.CreateServiceAccount(c.Request.Context(), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, account)
+}
+
+// ListServiceAccounts godoc
+// @Summary List service accounts
+// @Description List service accounts
+// @Tags service-accounts
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Success 200 {object} response.Response{data=[]domain.User}
+// @Failure 403 {object} response.Response
+// @Router /service-accounts [get]
+func (h *APITokenHandler) ListServiceAccounts(c *gin.Context) {
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	accounts, total, err := h.useCase.ListServiceAccounts(c.Request.Context(), page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, accounts, total, page)
+}
+
+// CreateServiceAccountKey godoc
+// @Summary Create service account API key
+// @Description Create an API key for a service account, limited to the given permission codes. The key is only shown once.
+// @Tags service-accounts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Param request body apitoken.CreateTokenRequest true "Key details"
+// @Success 201 {object} response.Response{data=apitoken.CreatedToken}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys [post]
+func (h *APITokenHandler) CreateServiceAccountKey(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid service account ID")
+		return
+	}
+
+	var req apitoken.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	key, err := h.useCase.CreateServiceAccountKey(c.Request.Context(), accountID, req, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	middleware.OmitResponseFromAudit(c)
+	response.Created(c, key)
+}
+
+// ListServiceAccountKeys godoc
+// @Summary List service account API keys
+// @Description List a service account's API keys, including revoked and expired ones
+// @Tags service-accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Success 200 {object} response.Response{data=[]domain.APIToken}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys [get]
+func (h *APITokenHandler) ListServiceAccountKeys(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid service account ID")
+		return
+	}
+
+	keys, err := h.useCase.ListServiceAccountKeys(c.Request.Context(), accountID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, keys)
+}
+
+// RevokeServiceAccountKey godoc
+// @Summary Revoke service account API key
+// @Description Revoke one of a service account's API keys
+// @Tags service-accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Param keyId path string true "Key ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /service-accounts/{id}/keys/{keyId} [delete]
+func (h *APITokenHandler) RevokeServiceAccountKey(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid service account ID")
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid key ID")
+		return
+	}
+
+	if err := h.useCase.RevokeServiceAccountKey(c.Request.Context(), accountID, keyID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "API key revoked successfully",
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/internal/core/usecases/audit"
@@ -33,6 +34,8 @@ func NewAuditLogHandler(useCase *audit.UseCase) *AuditLogHandler {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(20)
 // @Param user_id query int false "Filter by user ID"
+// @Param api_token_id query string false "Filter by API token ID"
+// @Param impersonator_id query string false "Filter by impersonating admin ID"
 // @Param action query string false "Filter by action"
 // @Param resource query string false "Filter by resource"
 // @Param resource_id query int false "Filter by resource ID"
@@ -73,6 +76,18 @@ func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
 		}
 	}
 
+	if apiTokenID := c.Query("api_token_id"); apiTokenID != "" {
+		if id, err := uuid.Parse(apiTokenID); err == nil {
+			filter.APITokenID = &id
+		}
+	}
+
+	if impersonatorID := c.Query("impersonator_id"); impersonatorID != "" {
+		if id, err := uuid.Parse(impersonatorID); err == nil {
+			filter.ImpersonatorID = &id
+		}
+	}
+
 	if resourceID := c.Query("resource_id"); resourceID != "" {
 		if id, err := strconv.ParseUint(resourceID, 10, 32); err == nil {
 			rid := uint(id)
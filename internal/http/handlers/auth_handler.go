@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"encoding/json"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/usecases/auth"
 	"github.com/owner/go-cms/internal/http/middleware"
 	"github.com/owner/go-cms/pkg/response"
@@ -38,6 +41,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	req.Client = clientInfo(c)
+
 	result, err := h.authUseCase.Register(c.Request.Context(), req)
 	if err != nil {
 		response.Error(c, err)
@@ -66,12 +71,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.Client = clientInfo(c)
+
 	result, err := h.authUseCase.Login(c.Request.Context(), req)
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
+	// The response carries the session tokens
+	middleware.OmitResponseFromAudit(c)
 	response.Success(c, result)
 }
 
@@ -160,12 +169,14 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	result, err := h.authUseCase.RefreshToken(c.Request.Context(), req.RefreshToken)
+	result, err := h.authUseCase.RefreshToken(c.Request.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
+	// The response carries the rotated session tokens
+	middleware.OmitResponseFromAudit(c)
 	response.Success(c, result)
 }
 
@@ -235,12 +246,12 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 
 // Logout godoc
 // @Summary Logout
-// @Description Logout and revoke refresh token
+// @Description Logout, ending the current session and revoking its tokens
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body object{refresh_token=string} true "Logout request"
+// @Param request body object{refresh_token=string} false "Logout request"
 // @Success 200 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -249,15 +260,25 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := middleware.MustGetUserID(c)
 
 	var req struct {
-		RefreshToken string `json:"refresh_token" binding:"required"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.ValidationError(c, err.Error())
-		return
+	// The body is optional; the access token identifies the session
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ValidationError(c, err.Error())
+			return
+		}
+	}
+
+	sessionID, _ := middleware.GetSessionID(c)
+	accessToken := auth.AccessToken{
+		ID:        c.GetString("token_id"),
+		SessionID: sessionID,
+		ExpiresAt: c.GetTime("token_expires_at"),
 	}
 
-	if err := h.authUseCase.Logout(c.Request.Context(), userID, req.RefreshToken); err != nil {
+	if err := h.authUseCase.Logout(c.Request.Context(), userID, req.RefreshToken, accessToken); err != nil {
 		response.Error(c, err)
 		return
 	}
@@ -267,6 +288,36 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// Impersonate godoc
+// @Summary Impersonate user
+// @Description Get a short-lived access token to see the CMS exactly as a user does. The token carries both identities, cannot be refreshed, cannot change the user's password, 2FA, sessions or tokens or make any administrative change, such as managing users, roles, grants, permissions or departments, and every request made with it is audit-logged with both identities. Logging out with it ends the impersonation. Users holding access you lack cannot be impersonated.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response{data=auth.ImpersonationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /users/{id}/impersonate [post]
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	result, err := h.authUseCase.Impersonate(c.Request.Context(), middleware.MustGetUserID(c), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	// The token is a bearer credential for the impersonated user
+	middleware.OmitResponseFromAudit(c)
+	response.Success(c, result)
+}
+
 // ChangePassword godoc
 // @Summary Change password
 // @Description Change user password
@@ -377,12 +428,15 @@ func (h *AuthHandler) Enable2FA(c *gin.Context) {
 		return
 	}
 
+	// The TOTP secret must not be kept in the audit log
+	middleware.OmitResponseFromAudit(c)
+
 	response.Success(c, result)
 }
 
 // Verify2FA godoc
 // @Summary Verify 2FA
-// @Description Verify and activate two-factor authentication
+// @Description Verify and activate two-factor authentication. Recovery codes are returned if the user has none yet.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -405,13 +459,18 @@ func (h *AuthHandler) Verify2FA(c *gin.Context) {
 		return
 	}
 
-	if err := h.authUseCase.Verify2FA(c.Request.Context(), userID, req.Code); err != nil {
+	recoveryCodes, err := h.authUseCase.Verify2FA(c.Request.Context(), userID, req.Code)
+	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
+	// Recovery codes are shown once and must not be kept in the audit log
+	middleware.OmitResponseFromAudit(c)
+
 	response.Success(c, gin.H{
-		"message": "2FA enabled successfully",
+		"message":        "2FA enabled successfully",
+		"recovery_codes": recoveryCodes,
 	})
 }
 
@@ -449,3 +508,372 @@ func (h *AuthHandler) Disable2FA(c *gin.Context) {
 		"message": "2FA disabled successfully",
 	})
 }
+
+// CompleteTwoFactorLogin godoc
+// @Summary Complete 2FA login
+// @Description Finish a login that requires a second factor with a TOTP code, security key or recovery code
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body auth.TwoFactorLoginRequest true "2FA login request"
+// @Success 200 {object} response.Response{data=auth.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/2fa/login [post]
+func (h *AuthHandler) CompleteTwoFactorLogin(c *gin.Context) {
+	var req auth.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	req.Client = clientInfo(c)
+
+	result, err := h.authUseCase.CompleteTwoFactorLogin(c.Request.Context(), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	// The response carries the session tokens
+	middleware.OmitResponseFromAudit(c)
+	response.Success(c, result)
+}
+
+// GetRecoveryCodeStatus godoc
+// @Summary Get recovery code status
+// @Description Get how many unused 2FA recovery codes the current user has
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=auth.RecoveryCodeStatus}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/2fa/recovery-codes [get]
+func (h *AuthHandler) GetRecoveryCodeStatus(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	result, err := h.authUseCase.GetRecoveryCodeStatus(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all 2FA recovery codes of the current user with a new set
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body object{password=string} true "Regenerate recovery codes request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	recoveryCodes, err := h.authUseCase.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	// Recovery codes are shown once and must not be kept in the audit log
+	middleware.OmitResponseFromAudit(c)
+
+	response.Success(c, gin.H{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// BeginWebAuthnRegistration godoc
+// @Summary Begin security key registration
+// @Description Get the WebAuthn creation options for registering a new security key or passkey
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /auth/2fa/webauthn/register/begin [post]
+func (h *AuthHandler) BeginWebAuthnRegistration(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	result, err := h.authUseCase.BeginWebAuthnRegistration(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// FinishWebAuthnRegistration godoc
+// @Summary Finish security key registration
+// @Description Verify the authenticator's attestation and save the security key
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body object{name=string,credential=object} true "Security key registration request"
+// @Success 200 {object} response.Response{data=auth.WebAuthnRegistrationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/2fa/webauthn/register/finish [post]
+func (h *AuthHandler) FinishWebAuthnRegistration(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	var req struct {
+		Name       string          `json:"name" binding:"max=100"`
+		Credential json.RawMessage `json:"credential" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	result, err := h.authUseCase.FinishWebAuthnRegistration(c.Request.Context(), userID, req.Name, req.Credential)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	// The response carries the recovery codes of a first factor
+	middleware.OmitResponseFromAudit(c)
+
+	response.Success(c, result)
+}
+
+// GetWebAuthnCredentials godoc
+// @Summary List security keys
+// @Description List the security keys and passkeys registered by the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]domain.WebAuthnCredential}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/2fa/webauthn/credentials [get]
+func (h *AuthHandler) GetWebAuthnCredentials(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	credentials, err := h.authUseCase.ListWebAuthnCredentials(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, credentials)
+}
+
+// DeleteWebAuthnCredential godoc
+// @Summary Delete security key
+// @Description Remove one of the current user's security keys. The current password is required.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Credential ID"
+// @Param request body object{password=string} true "Delete security key request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /auth/2fa/webauthn/credentials/{id} [delete]
+func (h *AuthHandler) DeleteWebAuthnCredential(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	credentialID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid credential ID")
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	if err := h.authUseCase.DeleteWebAuthnCredential(c.Request.Context(), userID, credentialID, req.Password); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "Security key removed successfully",
+	})
+}
+
+// GetSSOProviders godoc
+// @Summary List SSO providers
+// @Description List the identity providers users can log in with
+// @Tags auth
+// @Produce json
+// @Success 200 {object} response.Response{data=[]string}
+// @Router /auth/sso/providers [get]
+func (h *AuthHandler) GetSSOProviders(c *gin.Context) {
+	response.Success(c, h.authUseCase.ListSSOProviders())
+}
+
+// BeginSSOLogin godoc
+// @Summary Begin SSO login
+// @Description Get the identity provider URL to send the user to. The state is returned to the callback.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} response.Response{data=auth.SSOLoginResponse}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/sso/{provider}/login [get]
+func (h *AuthHandler) BeginSSOLogin(c *gin.Context) {
+	result, err := h.authUseCase.BeginSSOLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// CompleteSSOLogin godoc
+// @Summary Complete SSO login
+// @Description Redeem the authorization code the identity provider redirected back with
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State returned by the login step"
+// @Success 200 {object} response.Response{data=auth.AuthResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /auth/sso/{provider}/callback [get]
+func (h *AuthHandler) CompleteSSOLogin(c *gin.Context) {
+	var req auth.SSOCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	req.Provider = c.Param("provider")
+	req.Client = clientInfo(c)
+
+	result, err := h.authUseCase.CompleteSSOLogin(c.Request.Context(), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	// The response carries the session tokens
+	middleware.OmitResponseFromAudit(c)
+	response.Success(c, result)
+}
+
+// GetSessions godoc
+// @Summary List sessions
+// @Description List the devices signed in to the current user's account
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]auth.SessionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/sessions [get]
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+	sessionID, _ := middleware.GetSessionID(c)
+
+	sessions, err := h.authUseCase.ListSessions(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Sign out one of the current user's devices
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.ValidationError(c, "Invalid session ID")
+		return
+	}
+
+	if err := h.authUseCase.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions godoc
+// @Summary Revoke all sessions
+// @Description Sign out all of the current user's devices, including this one
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /auth/sessions [delete]
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	userID := middleware.MustGetUserID(c)
+
+	if err := h.authUseCase.RevokeAllSessions(c.Request.Context(), userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "All sessions revoked successfully",
+	})
+}
+
+// clientInfo describes the device making the request
+func clientInfo(c *gin.Context) auth.ClientInfo {
+	return auth.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/http/middleware"
 	"github.com/owner/go-cms/pkg/pagination"
 	"github.com/owner/go-cms/pkg/response"
 )
@@ -31,6 +33,7 @@ func NewDepartmentHandler(departmentUseCase authorization.DepartmentUseCase) *De
 // @Param request body authorization.CreateDepartmentRequest true "Create department request"
 // @Success 201 {object} response.Response{data=domain.Department}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -176,6 +179,7 @@ func (h *DepartmentHandler) ListDepartmentsByModule(c *gin.Context) {
 // @Param request body authorization.UpdateDepartmentRequest true "Update department request"
 // @Success 200 {object} response.Response{data=domain.Department}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /departments/{id} [put]
@@ -211,6 +215,7 @@ func (h *DepartmentHandler) UpdateDepartment(c *gin.Context) {
 // @Param id path int true "Department ID"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /departments/{id} [delete]
@@ -250,3 +255,213 @@ func (h *DepartmentHandler) ListActiveDepartments(c *gin.Context) {
 
 	response.Success(c, departments)
 }
+
+// MoveDepartment godoc
+// @Summary Move department
+// @Description Move a department, with every department below it, under a new parent. Omit parent_id to make it a root.
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Department ID"
+// @Param request body authorization.MoveDepartmentRequest true "Move department request"
+// @Success 200 {object} response.Response{data=domain.Department}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /departments/{id}/move [post]
+func (h *DepartmentHandler) MoveDepartment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ValidationError(c, "Invalid department ID")
+		return
+	}
+
+	var req authorization.MoveDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	department, err := h.departmentUseCase.MoveDepartment(c.Request.Context(), uint(id), req.ParentID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, department)
+}
+
+// GetDepartmentSubtree godoc
+// @Summary Get department subtree
+// @Description Get a department and every department below it, ordered by path
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Department ID"
+// @Success 200 {object} response.Response{data=[]domain.Department}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /departments/{id}/subtree [get]
+func (h *DepartmentHandler) GetDepartmentSubtree(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ValidationError(c, "Invalid department ID")
+		return
+	}
+
+	departments, err := h.departmentUseCase.GetDepartmentSubtree(c.Request.Context(), uint(id))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, departments)
+}
+
+// ListMembers godoc
+// @Summary List department members
+// @Description List the users of a department, optionally with those of every department below it. Open to managers of the department.
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Department ID"
+// @Param include_sub_departments query bool false "Include members of departments below"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} response.Response{data=[]domain.User}
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /departments/{id}/members [get]
+func (h *DepartmentHandler) ListMembers(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ValidationError(c, "Invalid department ID")
+		return
+	}
+
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	includeSubDepartments := false
+	if value := c.Query("include_sub_departments"); value != "" {
+		includeSubDepartments, err = strconv.ParseBool(value)
+		if err != nil {
+			response.BadRequest(c, "Invalid include_sub_departments value")
+			return
+		}
+	}
+
+	users, total, err := h.departmentUseCase.ListMembers(c.Request.Context(), uint(id), includeSubDepartments, page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, users, total, page)
+}
+
+// AddMember godoc
+// @Summary Add department member
+// @Description Move a user into a department. Managers of the department can only take in users without a department or from departments they manage.
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Department ID"
+// @Param request body authorization.AddMemberRequest true "Add member request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /departments/{id}/members [post]
+func (h *DepartmentHandler) AddMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ValidationError(c, "Invalid department ID")
+		return
+	}
+
+	var req authorization.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	var managerID *uuid.UUID
+	if middleware.ActingAsDepartmentManager(c) {
+		userID := middleware.MustGetUserID(c)
+		managerID = &userID
+	}
+
+	if err := h.departmentUseCase.AddMember(c.Request.Context(), uint(id), req.UserID, managerID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "Member added successfully",
+	})
+}
+
+// RemoveMember godoc
+// @Summary Remove department member
+// @Description Take a user out of a department, leaving them without one
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Department ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /departments/{id}/members/{userId} [delete]
+func (h *DepartmentHandler) RemoveMember(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.ValidationError(c, "Invalid department ID")
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		response.ValidationError(c, "Invalid user ID")
+		return
+	}
+
+	if err := h.departmentUseCase.RemoveMember(c.Request.Context(), uint(id), userID); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+// ListManagedDepartments godoc
+// @Summary List my managed departments
+// @Description List the departments the current user manages directly. They also manage every department below these.
+// @Tags departments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]domain.Department}
+// @Failure 500 {object} response.Response
+// @Router /departments/managed [get]
+func (h *DepartmentHandler) ListManagedDepartments(c *gin.Context) {
+	departments, err := h.departmentUseCase.ListManagedDepartments(c.Request.Context(), middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, departments)
+}
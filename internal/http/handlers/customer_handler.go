@@ -7,6 +7,7 @@ import (
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/internal/core/usecases/customer"
+	"github.com/owner/go-cms/internal/http/middleware"
 	"github.com/owner/go-cms/pkg/pagination"
 	"github.com/owner/go-cms/pkg/response"
 )
@@ -50,6 +51,7 @@ func (h *CustomerHandler) ListCustomers(c *gin.Context) {
 	filter := repositories.CustomerFilter{
 		Search: c.Query("search"),
 		Source: c.Query("source"),
+		Scope:  middleware.GetAccessScope(c),
 	}
 
 	if status := c.Query("status"); status != "" {
@@ -114,7 +116,7 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		return
 	}
 
-	customer, err := h.customerUseCase.GetCustomer(c.Request.Context(), uint(id))
+	customer, err := h.customerUseCase.GetCustomer(c.Request.Context(), uint(id), middleware.GetAccessScope(c))
 	if err != nil {
 		response.Error(c, err)
 		return
@@ -151,7 +153,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		return
 	}
 
-	result, err := h.customerUseCase.UpdateCustomer(c.Request.Context(), uint(id), req)
+	result, err := h.customerUseCase.UpdateCustomer(c.Request.Context(), uint(id), req, middleware.GetAccessScope(c))
 	if err != nil {
 		response.Error(c, err)
 		return
@@ -180,7 +182,7 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
-	if err := h.customerUseCase.DeleteCustomer(c.Request.Context(), uint(id)); err != nil {
+	if err := h.customerUseCase.DeleteCustomer(c.Request.Context(), uint(id), middleware.GetAccessScope(c)); err != nil {
 		response.Error(c, err)
 		return
 	}
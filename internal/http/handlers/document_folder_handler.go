@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/dto"
+)
+
+// Folder handlers
+
+// CreateFolder handles creating a new folder
+func (h *DocumentHandler) CreateFolder(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse request data
+	var folderRequest dto.DocumentFolderRequest
+	if err := c.ShouldBindJSON(&folderRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Create folder
+	folder, err := h.documentusecase.CreateFolder(c.Request.Context(), folderRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create folder: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder created successfully",
+		"folder":  folder,
+	})
+}
+
+// GetFolders handles retrieving the top-level folders shared with the user
+func (h *DocumentHandler) GetFolders(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get folders
+	folders, err := h.documentusecase.GetFolders(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get folders: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// GetFolderContents handles retrieving the subfolders and documents of a folder
+func (h *DocumentHandler) GetFolderContents(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Get folder contents
+	contents, err := h.documentusecase.GetFolderContents(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get folder: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, contents)
+}
+
+// RenameFolder handles renaming a folder
+func (h *DocumentHandler) RenameFolder(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Parse request data
+	var folderRequest dto.DocumentFolderRequest
+	if err := c.ShouldBindJSON(&folderRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Rename folder
+	folder, err := h.documentusecase.RenameFolder(c.Request.Context(), uint(id), folderRequest.Name, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update folder: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder updated successfully",
+		"folder":  folder,
+	})
+}
+
+// MoveFolder handles moving a folder under a new parent
+func (h *DocumentHandler) MoveFolder(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Parse request data
+	var moveRequest dto.DocumentFolderMoveRequest
+	if err := c.ShouldBindJSON(&moveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Move folder
+	folder, err := h.documentusecase.MoveFolder(c.Request.Context(), uint(id), moveRequest.ParentID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to move folder: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Folder moved successfully",
+		"folder":  folder,
+	})
+}
+
+// DeleteFolder handles deleting an empty folder
+func (h *DocumentHandler) DeleteFolder(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Delete folder
+	if err := h.documentusecase.DeleteFolder(c.Request.Context(), uint(id), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete folder: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted successfully"})
+}
+
+// AddFolderPermission handles granting access to a folder and its contents
+func (h *DocumentHandler) AddFolderPermission(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Parse request data
+	var permissionRequest dto.DocumentFolderPermissionRequest
+	if err := c.ShouldBindJSON(&permissionRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Add permission
+	if err := h.documentusecase.AddFolderPermission(c.Request.Context(), uint(id), permissionRequest, userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add permission: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permission added successfully"})
+}
+
+// GetFolderPermissions handles retrieving the permissions defined on a folder
+func (h *DocumentHandler) GetFolderPermissions(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse folder ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	// Get permissions
+	permissions, err := h.documentusecase.GetFolderPermissions(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get permissions: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// MoveDocument handles filing a document under another folder
+func (h *DocumentHandler) MoveDocument(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Parse request data
+	var moveRequest dto.DocumentMoveRequest
+	if err := c.ShouldBindJSON(&moveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Move document
+	if err := h.documentusecase.MoveDocument(c.Request.Context(), uint(id), moveRequest.FolderID, userID.(uuid.UUID)); err != nil {
+		respondDocumentLockError(c, err, "move document")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document moved successfully"})
+}
+
+// Tag handlers
+
+// AddDocumentTags handles tagging a document
+func (h *DocumentHandler) AddDocumentTags(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Parse request data
+	var tagsRequest dto.DocumentTagsRequest
+	if err := c.ShouldBindJSON(&tagsRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Add tags
+	tags, err := h.documentusecase.AddDocumentTags(c.Request.Context(), uint(id), tagsRequest.Tags, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to add tags: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tags added successfully",
+		"tags":    tags,
+	})
+}
+
+// RemoveDocumentTag handles removing a tag from a document
+func (h *DocumentHandler) RemoveDocumentTag(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document and tag IDs
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	tagID, err := strconv.ParseUint(c.Param("tagId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	// Remove tag
+	if err := h.documentusecase.RemoveDocumentTag(c.Request.Context(), uint(id), uint(tagID), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to remove tag: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag removed successfully"})
+}
+
+// Workspace handlers
+
+// GetEntityWorkspace handles retrieving the document workspace of an entity
+func (h *DocumentHandler) GetEntityWorkspace(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse parameters
+	entityType := c.Param("type")
+	entityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity ID"})
+		return
+	}
+
+	// Get workspace
+	workspace, err := h.documentusecase.GetEntityWorkspace(c.Request.Context(), entityType, uint(entityID), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get workspace: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, workspace)
+}
+
+// CreateFolderTemplate handles adding a folder to an entity type's workspace template
+func (h *DocumentHandler) CreateFolderTemplate(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse request data
+	var templateRequest dto.DocumentFolderTemplateRequest
+	if err := c.ShouldBindJSON(&templateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Create template
+	template, err := h.documentusecase.CreateFolderTemplate(c.Request.Context(), templateRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create folder template: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Folder template created successfully",
+		"template": template,
+	})
+}
+
+// GetFolderTemplates handles retrieving workspace folder templates
+func (h *DocumentHandler) GetFolderTemplates(c *gin.Context) {
+	templates, err := h.documentusecase.GetFolderTemplates(c.Request.Context(), c.Query("entity_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get folder templates: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// DeleteFolderTemplate handles removing a workspace folder template
+func (h *DocumentHandler) DeleteFolderTemplate(c *gin.Context) {
+	// Parse template ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	// Delete template
+	if err := h.documentusecase.DeleteFolderTemplate(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete folder template: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder template deleted successfully"})
+}
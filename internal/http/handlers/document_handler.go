@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -55,6 +56,17 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	}
 	uploadRequest.EntityID = uint(entityID)
 
+	// Parse optional folder ID
+	if folderIDStr := c.PostForm("folder_id"); folderIDStr != "" {
+		folderID, err := strconv.ParseUint(folderIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+			return
+		}
+		id := uint(folderID)
+		uploadRequest.FolderID = &id
+	}
+
 	// Validate request data
 	if uploadRequest.EntityType == "" || uploadRequest.DocumentName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required fields"})
@@ -290,7 +302,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	// Update document
 	document, err := h.documentusecase.UpdateDocument(c.Request.Context(), uint(id), updateRequest, userID.(uuid.UUID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update document: %s", err.Error())})
+		respondDocumentLockError(c, err, "update document")
 		return
 	}
 
@@ -319,6 +331,10 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 
 	// Delete document
 	if err := h.documentusecase.DeleteDocument(c.Request.Context(), uint(id), userID.(uuid.UUID)); err != nil {
+		if errors.Is(err, document.ErrLegalHold) || errors.Is(err, document.ErrRetentionPeriodActive) || errors.Is(err, document.ErrDocumentLocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete document: %s", err.Error())})
 		return
 	}
@@ -409,6 +425,43 @@ func (h *DocumentHandler) GetDocumentPermissions(c *gin.Context) {
 	c.JSON(http.StatusOK, permissions)
 }
 
+// GetEffectivePermission handles explaining a user's access to a document
+func (h *DocumentHandler) GetEffectivePermission(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Defaults to the current user
+	targetUserID := userID.(uuid.UUID)
+	if targetStr := c.Query("user_id"); targetStr != "" {
+		targetUserID, err = uuid.Parse(targetStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+	}
+
+	// Get effective permission
+	effective, err := h.documentusecase.GetEffectiveDocumentPermission(c.Request.Context(), uint(id), targetUserID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get effective permission: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, effective)
+}
+
 // UpdateDocumentPermission handles updating an existing permission
 func (h *DocumentHandler) UpdateDocumentPermission(c *gin.Context) {
 	// Get user ID from context
@@ -516,8 +569,19 @@ func (h *DocumentHandler) GetDocumentComments(c *gin.Context) {
 		return
 	}
 
+	// Parse optional resolved filter
+	var resolved *bool
+	if resolvedStr := c.Query("resolved"); resolvedStr != "" {
+		value, err := strconv.ParseBool(resolvedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid resolved filter"})
+			return
+		}
+		resolved = &value
+	}
+
 	// Get comments
-	comments, err := h.documentusecase.GetDocumentComments(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	comments, err := h.documentusecase.GetDocumentComments(c.Request.Context(), uint(id), resolved, userID.(uuid.UUID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get comments: %s", err.Error())})
 		return
@@ -544,16 +608,14 @@ func (h *DocumentHandler) UpdateDocumentComment(c *gin.Context) {
 	}
 
 	// Parse request data
-	var requestData struct {
-		Comment string `json:"comment" binding:"required"`
-	}
+	var requestData dto.DocumentCommentUpdateRequest
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
 	// Update comment
-	comment, err := h.documentusecase.UpdateDocumentComment(c.Request.Context(), uint(id), requestData.Comment, userID.(uuid.UUID))
+	comment, err := h.documentusecase.UpdateDocumentComment(c.Request.Context(), uint(id), requestData, userID.(uuid.UUID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update comment: %s", err.Error())})
 		return
@@ -591,6 +653,44 @@ func (h *DocumentHandler) DeleteDocumentComment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
 }
 
+// ResolveDocumentComment handles marking a comment thread as resolved
+func (h *DocumentHandler) ResolveDocumentComment(c *gin.Context) {
+	h.setCommentResolved(c, true)
+}
+
+// ReopenDocumentComment handles marking a resolved comment thread as unresolved
+func (h *DocumentHandler) ReopenDocumentComment(c *gin.Context) {
+	h.setCommentResolved(c, false)
+}
+
+func (h *DocumentHandler) setCommentResolved(c *gin.Context, resolved bool) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse comment ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	// Update resolved state
+	comment, err := h.documentusecase.SetCommentResolved(c.Request.Context(), uint(id), resolved, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update comment: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment updated successfully",
+		"comment": comment,
+	})
+}
+
 // Version handlers
 
 // GetDocumentVersions handles retrieving versions of a document
@@ -619,3 +719,117 @@ func (h *DocumentHandler) GetDocumentVersions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, versions)
 }
+
+// UploadDocumentVersion handles uploading a new version of a document
+func (h *DocumentHandler) UploadDocumentVersion(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Parse file
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
+	}
+
+	versionRequest := dto.DocumentVersionRequest{
+		ChangeNote: c.PostForm("change_note"),
+	}
+
+	// Upload version
+	version, err := h.documentusecase.UploadDocumentVersion(c.Request.Context(), uint(id), file, versionRequest, userID.(uuid.UUID))
+	if err != nil {
+		respondDocumentLockError(c, err, "upload version")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Version uploaded successfully",
+		"version": version,
+	})
+}
+
+// DownloadDocumentVersion handles downloading a specific version of a document
+func (h *DocumentHandler) DownloadDocumentVersion(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID and version number
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version number"})
+		return
+	}
+
+	// Download version
+	fileBytes, contentType, fileName, err := h.documentusecase.DownloadDocumentVersion(c.Request.Context(), uint(id), versionNumber, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to download version: %s", err.Error())})
+		return
+	}
+
+	// Set response headers
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, contentType, fileBytes)
+}
+
+// RestoreDocumentVersion handles making an older version the current one
+func (h *DocumentHandler) RestoreDocumentVersion(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID and version number
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil || versionNumber < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version number"})
+		return
+	}
+
+	// The change note is optional
+	var versionRequest dto.DocumentVersionRequest
+	_ = c.ShouldBindJSON(&versionRequest)
+
+	// Restore version
+	version, err := h.documentusecase.RestoreDocumentVersion(c.Request.Context(), uint(id), versionNumber, versionRequest, userID.(uuid.UUID))
+	if err != nil {
+		respondDocumentLockError(c, err, "restore version")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Version restored successfully",
+		"version": version,
+	})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/usecases/document"
+)
+
+// Check-out handlers
+
+// CheckOutDocument handles locking a document for editing by the current user
+func (h *DocumentHandler) CheckOutDocument(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Check out document
+	lock, err := h.documentusecase.CheckOutDocument(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		respondDocumentLockError(c, err, "check out document")
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// CheckInDocument handles releasing the current user's check-out of a document
+func (h *DocumentHandler) CheckInDocument(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Check in document
+	if err := h.documentusecase.CheckInDocument(c.Request.Context(), uint(id), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Failed to check in document: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document checked in successfully"})
+}
+
+// GetDocumentLock handles retrieving the check-out state of a document
+func (h *DocumentHandler) GetDocumentLock(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get lock
+	lock, err := h.documentusecase.GetDocumentLock(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get document lock: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"locked": lock != nil, "lock": lock})
+}
+
+// ForceUnlockDocument handles an administrator releasing another user's check-out
+func (h *DocumentHandler) ForceUnlockDocument(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Force unlock document
+	if err := h.documentusecase.ForceUnlockDocument(c.Request.Context(), uint(id), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to unlock document: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document unlocked successfully"})
+}
+
+// respondDocumentLockError reports a check-out conflict as 409 and anything else as a failure
+func respondDocumentLockError(c *gin.Context, err error, action string) {
+	if errors.Is(err, document.ErrDocumentLocked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to %s: %s", action, err.Error())})
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/dto"
+)
+
+// Preview and search handlers
+
+// SearchDocuments handles full-text search over document contents
+func (h *DocumentHandler) SearchDocuments(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse query parameters
+	var searchRequest dto.DocumentSearchRequest
+	if err := c.ShouldBindQuery(&searchRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	// Search documents
+	results, err := h.documentusecase.SearchDocuments(c.Request.Context(), searchRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to search documents: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// GetDocumentThumbnail handles retrieving the thumbnail image of a document
+func (h *DocumentHandler) GetDocumentThumbnail(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get thumbnail
+	thumbnail, err := h.documentusecase.GetDocumentThumbnail(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Failed to get thumbnail: %s", err.Error())})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, "image/jpeg", thumbnail)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/dto"
+)
+
+// Retention and legal hold handlers
+
+// CreateRetentionPolicy handles creating a retention policy
+func (h *DocumentHandler) CreateRetentionPolicy(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse request data
+	var policyRequest dto.DocumentRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&policyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Create policy
+	policy, err := h.documentusecase.CreateRetentionPolicy(c.Request.Context(), policyRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create retention policy: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Retention policy created successfully",
+		"policy":  policy,
+	})
+}
+
+// GetRetentionPolicies handles listing retention policies
+func (h *DocumentHandler) GetRetentionPolicies(c *gin.Context) {
+	policies, err := h.documentusecase.GetRetentionPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get retention policies: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// UpdateRetentionPolicy handles updating a retention policy
+func (h *DocumentHandler) UpdateRetentionPolicy(c *gin.Context) {
+	// Parse policy ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention policy ID"})
+		return
+	}
+
+	// Parse request data
+	var policyRequest dto.DocumentRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&policyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Update policy
+	policy, err := h.documentusecase.UpdateRetentionPolicy(c.Request.Context(), uint(id), policyRequest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update retention policy: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Retention policy updated successfully",
+		"policy":  policy,
+	})
+}
+
+// DeleteRetentionPolicy handles deleting a retention policy
+func (h *DocumentHandler) DeleteRetentionPolicy(c *gin.Context) {
+	// Parse policy ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention policy ID"})
+		return
+	}
+
+	// Delete policy
+	if err := h.documentusecase.DeleteRetentionPolicy(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete retention policy: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted successfully"})
+}
+
+// PlaceLegalHold handles placing a legal hold on a document or folder
+func (h *DocumentHandler) PlaceLegalHold(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse request data
+	var holdRequest dto.DocumentLegalHoldRequest
+	if err := c.ShouldBindJSON(&holdRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Place hold
+	hold, err := h.documentusecase.PlaceLegalHold(c.Request.Context(), holdRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to place legal hold: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Legal hold placed successfully",
+		"legal_hold": hold,
+	})
+}
+
+// GetLegalHolds handles listing legal holds; pass active=true for holds still in force
+func (h *DocumentHandler) GetLegalHolds(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	holds, err := h.documentusecase.GetLegalHolds(c.Request.Context(), activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get legal holds: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// ReleaseLegalHold handles releasing a legal hold
+func (h *DocumentHandler) ReleaseLegalHold(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse hold ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid legal hold ID"})
+		return
+	}
+
+	// Release hold
+	hold, err := h.documentusecase.ReleaseLegalHold(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to release legal hold: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Legal hold released successfully",
+		"legal_hold": hold,
+	})
+}
+
+// GetDocumentRetention handles retrieving the retention state of a document
+func (h *DocumentHandler) GetDocumentRetention(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get retention
+	retention, err := h.documentusecase.GetDocumentRetention(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get document retention: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, retention)
+}
+
+// PurgeExpiredDocuments handles running the retention purge immediately
+func (h *DocumentHandler) PurgeExpiredDocuments(c *gin.Context) {
+	result, err := h.documentusecase.PurgeExpiredDocuments(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to purge documents: %s", err.Error()), "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/dto"
+	"github.com/owner/go-cms/internal/core/usecases/document"
+	"github.com/owner/go-cms/internal/http/middleware"
+)
+
+// Share link handlers
+
+// CreateShareLink handles creating a public share link for a document
+func (h *DocumentHandler) CreateShareLink(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Parse request data
+	var linkRequest dto.DocumentShareLinkRequest
+	if err := c.ShouldBindJSON(&linkRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	// Create share link
+	link, err := h.documentusecase.CreateShareLink(c.Request.Context(), uint(id), linkRequest, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create share link: %s", err.Error())})
+		return
+	}
+
+	// The link token grants access to the document without signing in
+	middleware.OmitResponseFromAudit(c)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Share link created successfully",
+		"share_link": link,
+	})
+}
+
+// GetDocumentShareLinks handles listing the active share links of a document
+func (h *DocumentHandler) GetDocumentShareLinks(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse document ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	// Get share links
+	links, err := h.documentusecase.GetDocumentShareLinks(c.Request.Context(), uint(id), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get share links: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// GetMyShareLinks handles listing the active share links created by the current user
+func (h *DocumentHandler) GetMyShareLinks(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get share links
+	links, err := h.documentusecase.GetMyShareLinks(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get share links: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RevokeShareLink handles revoking a share link
+func (h *DocumentHandler) RevokeShareLink(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Parse share link ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return
+	}
+
+	// Revoke share link
+	if err := h.documentusecase.RevokeShareLink(c.Request.Context(), uint(id), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to revoke share link: %s", err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked successfully"})
+}
+
+// Public share link handlers
+
+// GetSharedDocument handles retrieving the details of a document shared by link
+func (h *DocumentHandler) GetSharedDocument(c *gin.Context) {
+	shared, err := h.documentusecase.GetSharedDocument(c.Request.Context(), shareLinkAccess(c))
+	if err != nil {
+		respondShareLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shared)
+}
+
+// ViewSharedDocument handles displaying a document shared by link inline
+func (h *DocumentHandler) ViewSharedDocument(c *gin.Context) {
+	fileBytes, contentType, fileName, err := h.documentusecase.DownloadSharedDocument(c.Request.Context(), shareLinkAccess(c), false)
+	if err != nil {
+		respondShareLinkError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", fileName))
+	c.Data(http.StatusOK, contentType, fileBytes)
+}
+
+// DownloadSharedDocument handles downloading a document shared by link
+func (h *DocumentHandler) DownloadSharedDocument(c *gin.Context) {
+	fileBytes, contentType, fileName, err := h.documentusecase.DownloadSharedDocument(c.Request.Context(), shareLinkAccess(c), true)
+	if err != nil {
+		respondShareLinkError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, contentType, fileBytes)
+}
+
+// shareLinkAccess collects the token, password and client details of a public request.
+// The password is read from the X-Share-Password header, falling back to the query string.
+func shareLinkAccess(c *gin.Context) dto.ShareLinkAccessRequest {
+	password := c.GetHeader("X-Share-Password")
+	if password == "" {
+		password = c.Query("password")
+	}
+
+	return dto.ShareLinkAccessRequest{
+		Token:     c.Param("token"),
+		Password:  password,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func respondShareLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, document.ErrShareLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+	case errors.Is(err, document.ErrShareLinkExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Share link has expired"})
+	case errors.Is(err, document.ErrShareLinkPasswordRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required", "password_required": true})
+	case errors.Is(err, document.ErrShareLinkDownloadNotAllowed), errors.Is(err, document.ErrShareLinkLimitReached):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to access shared document: %s", err.Error())})
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/pkg/response"
+)
+
+// GrantHandler handles HTTP requests for permissions and roles granted directly to users
+type GrantHandler struct {
+	useCase *authorization.GrantUseCase
+}
+
+// NewGrantHandler creates a new grant handler
+func NewGrantHandler(useCase *authorization.GrantUseCase) *GrantHandler {
+	return &GrantHandler{useCase: useCase}
+}
+
+// ListUserGrants godoc
+// @Summary List user grants
+// @Description List the permissions granted directly to a user and the roles they hold, with who granted each and until when
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response{data=authorization.UserGrants}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/grants [get]
+func (h *GrantHandler) ListUserGrants(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	grants, err := h.useCase.ListUserGrants(c.Request.Context(), userID)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, grants)
+}
+
+// GrantPermission godoc
+// @Summary Grant permission to user
+// @Description Grant a permission directly to a user, optionally until expires_at. Granting a permission the user already holds replaces its expiry.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body authorization.GrantPermissionRequest true "Permission grant"
+// @Success 201 {object} response.Response{data=domain.UserEnhancedPermission}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/grants/permissions [post]
+func (h *GrantHandler) GrantPermission(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var req authorization.GrantPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	grant, err := h.useCase.GrantPermission(c.Request.Context(), userID, req, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, grant)
+}
+
+// RevokePermission godoc
+// @Summary Revoke permission from user
+// @Description Revoke a permission granted directly to a user
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID"
+// @Param permissionId path int true "Permission ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/grants/permissions/{permissionId} [delete]
+func (h *GrantHandler) RevokePermission(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	permissionID, err := strconv.ParseUint(c.Param("permissionId"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid permission ID")
+		return
+	}
+
+	if err := h.useCase.RevokePermission(c.Request.Context(), userID, uint(permissionID), middleware.MustGetUserID(c)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Permission revoked successfully"})
+}
+
+// GrantRole godoc
+// @Summary Grant role to user
+// @Description Grant a role to a user, optionally until expires_at. Granting a role the user already holds replaces its expiry.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body authorization.GrantRoleRequest true "Role grant"
+// @Success 201 {object} response.Response{data=authorization.RoleGrant}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/grants/roles [post]
+func (h *GrantHandler) GrantRole(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	var req authorization.GrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	grant, err := h.useCase.GrantRole(c.Request.Context(), userID, req, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, grant)
+}
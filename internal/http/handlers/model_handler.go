@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/pkg/response"
+)
+
+// ModelHandler handles HTTP requests for exporting and applying the authorization model
+type ModelHandler struct {
+	useCase *authorization.ModelUseCase
+}
+
+// NewModelHandler creates a new authorization model handler
+func NewModelHandler(useCase *authorization.ModelUseCase) *ModelHandler {
+	return &ModelHandler{useCase: useCase}
+}
+
+// ExportModel godoc
+// @Summary Export the authorization model
+// @Description Download every module, department, service, scope, permission, role and policy as one YAML or JSON document
+// @Tags authorization-model
+// @Produce json
+// @Produce application/yaml
+// @Param format query string false "Format (yaml, json)" default(yaml)
+// @Success 200 {object} domain.AuthorizationModel
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /authorization/model [get]
+func (h *ModelHandler) ExportModel(c *gin.Context) {
+	format := c.DefaultQuery("format", authorization.ModelFormatYAML)
+
+	model, err := h.useCase.ExportModel(c.Request.Context())
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	data, err := authorization.EncodeModel(model, format)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	contentType := "application/yaml"
+	if format == authorization.ModelFormatJSON {
+		contentType = "application/json"
+	}
+	c.Header("Content-Disposition", "attachment; filename=authorization-model."+format)
+	c.Data(200, contentType, data)
+}
+
+// PlanModel godoc
+// @Summary Plan an authorization model
+// @Description List what applying a model would create, update and delete, and any problems that stop it being applied, without changing anything
+// @Tags authorization-model
+// @Accept json
+// @Accept application/yaml
+// @Produce json
+// @Param format query string false "Body format (yaml, json); taken from Content-Type when omitted"
+// @Param model body domain.AuthorizationModel true "Desired authorization model"
+// @Success 200 {object} response.Response{data=authorization.ModelPlan}
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /authorization/model/plan [post]
+func (h *ModelHandler) PlanModel(c *gin.Context) {
+	model, ok := h.readModel(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.useCase.PlanModel(c.Request.Context(), model)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, plan)
+}
+
+// ApplyModel godoc
+// @Summary Apply an authorization model
+// @Description Make the authorization model match the given one: entries it lists are created or updated, entries it leaves out are deleted. System entries cannot be deleted; nothing is applied if the plan has errors.
+// @Tags authorization-model
+// @Accept json
+// @Accept application/yaml
+// @Produce json
+// @Param format query string false "Body format (yaml, json); taken from Content-Type when omitted"
+// @Param model body domain.AuthorizationModel true "Desired authorization model"
+// @Success 200 {object} response.Response{data=authorization.ModelPlan}
+// @Failure 400 {object} response.Response
+// @Security BearerAuth
+// @Router /authorization/model/apply [post]
+func (h *ModelHandler) ApplyModel(c *gin.Context) {
+	model, ok := h.readModel(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.useCase.ApplyModel(c.Request.Context(), model, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, plan)
+}
+
+// readModel decodes the request body, writing the error response if it cannot
+func (h *ModelHandler) readModel(c *gin.Context) (*domain.AuthorizationModel, bool) {
+	format := c.Query("format")
+	if format == "" {
+		format = authorization.ModelFormatYAML
+		if strings.Contains(c.ContentType(), "json") {
+			format = authorization.ModelFormatJSON
+		}
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, "Failed to read request body")
+		return nil, false
+	}
+
+	model, err := authorization.DecodeModel(data, format)
+	if err != nil {
+		response.Error(c, err)
+		return nil, false
+	}
+	return model, true
+}
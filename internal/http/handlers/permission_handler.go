@@ -4,7 +4,6 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/internal/core/usecases/authorization"
 	"github.com/owner/go-cms/pkg/response"
@@ -22,25 +21,27 @@ func NewPermissionHandler(useCase *authorization.PermissionUseCase) *PermissionH
 
 // CreatePermission godoc
 // @Summary Create a new permission
-// @Description Create a new permission in the system
+// @Description Create a permission for a service at a scope. Its code is module:department:service:scope:resource:action.
 // @Tags permissions
 // @Accept json
 // @Produce json
-// @Param permission body domain.Permission true "Permission object"
-// @Success 201 {object} response.Response{data=domain.Permission}
+// @Param permission body authorization.CreatePermissionRequest true "Permission object"
+// @Success 201 {object} response.Response{data=domain.EnhancedPermission}
 // @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
 // @Failure 409 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
 // @Router /permissions [post]
 func (h *PermissionHandler) CreatePermission(c *gin.Context) {
-	var permission domain.Permission
-	if err := c.ShouldBindJSON(&permission); err != nil {
+	var req authorization.CreatePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, "Invalid request body")
 		return
 	}
 
-	if err := h.useCase.CreatePermission(c.Request.Context(), &permission); err != nil {
+	permission, err := h.useCase.CreatePermission(c.Request.Context(), req)
+	if err != nil {
 		response.Error(c, err)
 		return
 	}
@@ -55,7 +56,7 @@ func (h *PermissionHandler) CreatePermission(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Permission ID"
-// @Success 200 {object} response.Response{data=domain.Permission}
+// @Success 200 {object} response.Response{data=domain.EnhancedPermission}
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
@@ -82,12 +83,13 @@ func (h *PermissionHandler) GetPermission(c *gin.Context) {
 // @Tags permissions
 // @Accept json
 // @Produce json
-// @Param module query string false "Filter by module"
-// @Param department query string false "Filter by department"
-// @Param service query string false "Filter by service"
+// @Param module query string false "Filter by module code"
+// @Param department query string false "Filter by department code"
+// @Param service query string false "Filter by service code"
+// @Param scope query string false "Filter by scope code or level"
 // @Param resource query string false "Filter by resource"
 // @Param action query string false "Filter by action"
-// @Success 200 {object} response.Response{data=[]domain.Permission}
+// @Success 200 {object} response.Response{data=[]domain.EnhancedPermission}
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
 // @Router /permissions [get]
@@ -96,6 +98,7 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 		Module:     c.Query("module"),
 		Department: c.Query("department"),
 		Service:    c.Query("service"),
+		Scope:      c.Query("scope"),
 		Resource:   c.Query("resource"),
 		Action:     c.Query("action"),
 	}
@@ -115,8 +118,8 @@ func (h *PermissionHandler) ListPermissions(c *gin.Context) {
 // @Tags permissions
 // @Accept json
 // @Produce json
-// @Param module path string true "Module name"
-// @Success 200 {object} response.Response{data=[]domain.Permission}
+// @Param module path string true "Module code"
+// @Success 200 {object} response.Response{data=[]domain.EnhancedPermission}
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
 // @Router /permissions/module/{module} [get]
@@ -143,8 +146,8 @@ func (h *PermissionHandler) GetPermissionsByModule(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Permission ID"
-// @Param permission body domain.Permission true "Permission update object"
-// @Success 200 {object} response.Response
+// @Param permission body authorization.UpdatePermissionRequest true "Permission update object"
+// @Success 200 {object} response.Response{data=domain.EnhancedPermission}
 // @Failure 400 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
@@ -157,28 +160,30 @@ func (h *PermissionHandler) UpdatePermission(c *gin.Context) {
 		return
 	}
 
-	var updates domain.Permission
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	var req authorization.UpdatePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, "Invalid request body")
 		return
 	}
 
-	if err := h.useCase.UpdatePermission(c.Request.Context(), uint(id), &updates); err != nil {
+	permission, err := h.useCase.UpdatePermission(c.Request.Context(), uint(id), req)
+	if err != nil {
 		response.Error(c, err)
 		return
 	}
 
-	response.Success(c, gin.H{"message": "Permission updated successfully"})
+	response.Success(c, permission)
 }
 
 // DeletePermission godoc
 // @Summary Delete a permission
-// @Description Delete a permission from the system
+// @Description Delete a permission and revoke it from every role. System permissions cannot be deleted.
 // @Tags permissions
 // @Accept json
 // @Produce json
 // @Param id path int true "Permission ID"
 // @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/core/usecases/authorization"
+	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/pkg/response"
+)
+
+// PolicyHandler handles HTTP requests for attribute-based access policies
+type PolicyHandler struct {
+	useCase *authorization.PolicyUseCase
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(useCase *authorization.PolicyUseCase) *PolicyHandler {
+	return &PolicyHandler{useCase: useCase}
+}
+
+// CreatePolicy godoc
+// @Summary Create a policy
+// @Description Create a condition on a permission over subject, resource and request attributes, e.g. resource.assigned_to == subject.id
+// @Tags policies
+// @Accept json
+// @Produce json
+// @Param policy body authorization.CreatePolicyRequest true "Policy object"
+// @Success 201 {object} response.Response{data=domain.Policy}
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Security BearerAuth
+// @Router /policies [post]
+func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
+	var req authorization.CreatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	policy, err := h.useCase.CreatePolicy(c.Request.Context(), req, middleware.MustGetUserID(c))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Created(c, policy)
+}
+
+// ListPolicies godoc
+// @Summary List policies
+// @Description Get a list of policies with optional filters
+// @Tags policies
+// @Produce json
+// @Param permission query string false "Filter by permission"
+// @Param effect query string false "Filter by effect (allow, deny)"
+// @Param is_active query bool false "Filter by active status"
+// @Success 200 {object} response.Response{data=[]domain.Policy}
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /policies [get]
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	filter := repositories.PolicyFilter{
+		Permission: c.Query("permission"),
+		Effect:     domain.PolicyEffect(c.Query("effect")),
+	}
+	if isActive := c.Query("is_active"); isActive != "" {
+		active, err := strconv.ParseBool(isActive)
+		if err != nil {
+			response.BadRequest(c, "Invalid is_active value")
+			return
+		}
+		filter.IsActive = &active
+	}
+
+	policies, err := h.useCase.ListPolicies(c.Request.Context(), filter)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, policies)
+}
+
+// GetPolicy godoc
+// @Summary Get policy by ID
+// @Description Get detailed information about a policy
+// @Tags policies
+// @Produce json
+// @Param id path int true "Policy ID"
+// @Success 200 {object} response.Response{data=domain.Policy}
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /policies/{id} [get]
+func (h *PolicyHandler) GetPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid policy ID")
+		return
+	}
+
+	policy, err := h.useCase.GetPolicy(c.Request.Context(), uint(id))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update a policy
+// @Description Update a policy's permission, effect, condition or active status
+// @Tags policies
+// @Accept json
+// @Produce json
+// @Param id path int true "Policy ID"
+// @Param policy body authorization.UpdatePolicyRequest true "Policy fields"
+// @Success 200 {object} response.Response{data=domain.Policy}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /policies/{id} [put]
+func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid policy ID")
+		return
+	}
+
+	var req authorization.UpdatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	policy, err := h.useCase.UpdatePolicy(c.Request.Context(), uint(id), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, policy)
+}
+
+// DeletePolicy godoc
+// @Summary Delete a policy
+// @Description Delete a policy
+// @Tags policies
+// @Produce json
+// @Param id path int true "Policy ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /policies/{id} [delete]
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid policy ID")
+		return
+	}
+
+	if err := h.useCase.DeletePolicy(c.Request.Context(), uint(id)); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Policy deleted successfully"})
+}
+
+// SimulatePolicy godoc
+// @Summary Simulate an access
+// @Description Evaluate whether a user would be allowed a permission on a resource, with each policy's outcome. Draft policies are evaluated alongside the stored ones without being saved.
+// @Tags policies
+// @Accept json
+// @Produce json
+// @Param request body authorization.SimulatePolicyRequest true "Access to simulate"
+// @Success 200 {object} response.Response{data=authorization.PolicySimulation}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Security BearerAuth
+// @Router /policies/simulate [post]
+func (h *PolicyHandler) SimulatePolicy(c *gin.Context) {
+	var req authorization.SimulatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err.Error())
+		return
+	}
+
+	simulation, err := h.useCase.Simulate(c.Request.Context(), req)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, simulation)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/ports/repositories"
 	"github.com/owner/go-cms/internal/core/usecases/authorization"
@@ -23,11 +24,12 @@ func NewRoleHandler(useCase *authorization.RoleUseCase) *RoleHandler {
 
 // CreateRoleRequest represents the request body for creating a role
 type CreateRoleRequest struct {
-	Name          string `json:"name" binding:"required"`
-	Description   string `json:"description"`
-	PermissionIDs []uint `json:"permission_ids" binding:"required,min=1"`
-	Level         string `json:"level"`
-	ParentID      *uint  `json:"parent_id"`
+	Name          string     `json:"name" binding:"required"`
+	Description   string     `json:"description"`
+	PermissionIDs []uint     `json:"permission_ids" binding:"required,min=1"`
+	Level         string     `json:"level"`
+	ParentID      *uint      `json:"parent_id"`
+	OwnerID       *uuid.UUID `json:"owner_id"` // Decides access requests for the role
 }
 
 // CreateRole godoc
@@ -54,6 +56,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		Name:        req.Name,
 		Description: req.Description,
 		ParentID:    req.ParentID,
+		OwnerID:     req.OwnerID,
 	}
 
 	if req.Level != "" {
@@ -182,11 +185,12 @@ func (h *RoleHandler) GetRoleHierarchy(c *gin.Context) {
 
 // UpdateRoleRequest represents the request body for updating a role
 type UpdateRoleRequest struct {
-	Name          *string `json:"name"`
-	Description   *string `json:"description"`
-	PermissionIDs []uint  `json:"permission_ids"`
-	Level         *string `json:"level"`
-	ParentID      *uint   `json:"parent_id"`
+	Name          *string    `json:"name"`
+	Description   *string    `json:"description"`
+	PermissionIDs []uint     `json:"permission_ids"`
+	Level         *string    `json:"level"`
+	ParentID      *uint      `json:"parent_id"`
+	OwnerID       *uuid.UUID `json:"owner_id"` // Decides access requests for the role
 }
 
 // UpdateRole godoc
@@ -230,6 +234,9 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	if req.ParentID != nil {
 		updates.ParentID = req.ParentID
 	}
+	if req.OwnerID != nil {
+		updates.OwnerID = req.OwnerID
+	}
 
 	if err := h.useCase.UpdateRole(c.Request.Context(), uint(id), &updates); err != nil {
 		response.Error(c, err)
@@ -311,7 +318,7 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Role ID"
-// @Success 200 {object} response.Response{data=[]domain.Permission}
+// @Success 200 {object} response.Response{data=[]domain.EnhancedPermission}
 // @Failure 404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Security BearerAuth
@@ -332,6 +339,64 @@ func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
 	response.Success(c, permissions)
 }
 
+// GetEffectivePermissions godoc
+// @Summary Get role effective permissions
+// @Description Get every permission a role holds, its own and those inherited from the roles below it, with where each comes from
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} response.Response{data=[]authorization.EffectivePermission}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /roles/{id}/effective-permissions [get]
+func (h *RoleHandler) GetEffectivePermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "Invalid role ID")
+		return
+	}
+
+	permissions, err := h.useCase.GetEffectivePermissions(c.Request.Context(), uint(id))
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, permissions)
+}
+
+// GetUserEffectivePermissions godoc
+// @Summary Get user effective permissions
+// @Description Get every permission a user holds through their roles, the roles below them and direct grants, with where each comes from
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response{data=[]authorization.EffectivePermission}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/effective-permissions [get]
+func (h *RoleHandler) GetUserEffectivePermissions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	permissions, err := h.useCase.GetUserEffectivePermissions(c.Request.Context(), id)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, permissions)
+}
+
 // AssignPermission godoc
 // @Summary Assign permission to role
 // @Description Assign a permission to a role
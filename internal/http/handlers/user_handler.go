@@ -326,3 +326,59 @@ func (h *UserHandler) RemoveRole(c *gin.Context) {
 
 	response.Success(c, gin.H{"message": "Role removed successfully"})
 }
+
+// ListLockedUsers godoc
+// @Summary List locked users
+// @Description List users currently locked out after too many failed logins
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size"
+// @Success 200 {object} response.Response{data=[]domain.User}
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /users/locked [get]
+func (h *UserHandler) ListLockedUsers(c *gin.Context) {
+	page, err := pagination.ParseOffsetRequest(c.Query("page"), c.Query("limit"), 10, 100)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	users, total, err := h.useCase.ListLockedUsers(c.Request.Context(), page)
+	if err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.SuccessWithPagination(c, users, total, page)
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Lift a user's lockout after failed logins
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Security BearerAuth
+// @Router /users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	if err := h.useCase.UnlockUser(c.Request.Context(), id); err != nil {
+		response.Error(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "User unlocked successfully"})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/core/usecases/audit"
 )
@@ -80,9 +81,30 @@ func AuditLogger(auditUseCase *audit.UseCase) gin.HandlerFunc {
 			}
 		}
 
+		// Record which API token made the request
+		var apiTokenID *uuid.UUID
+		if id, ok := GetAPITokenID(c); ok {
+			apiTokenID = &id
+		}
+
+		// Record both identities of an impersonated request; the impersonated user only
+		// fits in metadata as user IDs are UUIDs
+		var impersonatorID *uuid.UUID
+		var metadata *string
+		if id, ok := GetImpersonatorID(c); ok {
+			impersonatorID = &id
+			userUUID, _ := GetUserID(c)
+			data, _ := json.Marshal(map[string]interface{}{
+				"user_id":         userUUID,
+				"impersonator_id": id,
+			})
+			metadataStr := string(data)
+			metadata = &metadataStr
+		}
+
 		// Prepare response body (limit size to prevent huge logs)
 		var respBody *string
-		if blw.body.Len() > 0 && blw.body.Len() < 10000 { // Max 10KB
+		if blw.body.Len() > 0 && blw.body.Len() < 10000 && !c.GetBool(omitResponseKey) { // Max 10KB
 			respBodyStr := blw.body.String()
 			respBody = &respBodyStr
 		}
@@ -100,22 +122,25 @@ func AuditLogger(auditUseCase *audit.UseCase) gin.HandlerFunc {
 
 		// Create audit log entry
 		auditLog := &domain.AuditLog{
-			UserID:       userID,
-			Action:       action,
-			Resource:     resource,
-			ResourceID:   resourceID,
-			Description:  generateDescription(c.Request.Method, resource, c.Writer.Status()),
-			IPAddress:    c.ClientIP(),
-			UserAgent:    c.Request.UserAgent(),
-			Method:       c.Request.Method,
-			Path:         c.Request.URL.Path,
-			StatusCode:   c.Writer.Status(),
-			Duration:     duration,
-			RequestBody:  reqBody,
-			ResponseBody: respBody,
-			NewValues:    newValues,
-			CreatedAt:    startTime,
-			FinishedAt:   &finishedAt,
+			UserID:         userID,
+			APITokenID:     apiTokenID,
+			ImpersonatorID: impersonatorID,
+			Action:         action,
+			Resource:       resource,
+			ResourceID:     resourceID,
+			Description:    generateDescription(c.Request.Method, resource, c.Writer.Status()),
+			IPAddress:      c.ClientIP(),
+			UserAgent:      c.Request.UserAgent(),
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			StatusCode:     c.Writer.Status(),
+			Duration:       duration,
+			RequestBody:    reqBody,
+			ResponseBody:   respBody,
+			NewValues:      newValues,
+			Metadata:       metadata,
+			CreatedAt:      startTime,
+			FinishedAt:     &finishedAt,
 		}
 
 		// Save audit log asynchronously to not block the response
@@ -131,6 +156,15 @@ func AuditLogger(auditUseCase *audit.UseCase) gin.HandlerFunc {
 	}
 }
 
+// omitResponseKey marks responses that must not be stored in the audit log
+const omitResponseKey = "audit_omit_response"
+
+// OmitResponseFromAudit keeps the response body out of the audit log, for responses
+// carrying secrets that are only shown once
+func OmitResponseFromAudit(c *gin.Context) {
+	c.Set(omitResponseKey, true)
+}
+
 // shouldSkipAudit determines if a path should skip audit logging
 func shouldSkipAudit(path string) bool {
 	skipPaths := []string{
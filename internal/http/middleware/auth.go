@@ -1,26 +1,65 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
 	"github.com/owner/go-cms/pkg/response"
+	"go.uber.org/zap"
 )
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Email          string     `json:"email"`
+	TokenType      string     `json:"typ"`           // TokenTypeAccess or TokenTypeRefresh
+	SessionID      uuid.UUID  `json:"sid,omitempty"` // Device session the token was issued to
+	ImpersonatorID *uuid.UUID `json:"imp,omitempty"` // Admin acting as UserID; set on impersonation tokens only
 	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
+// Token types. Only access tokens authenticate requests; refresh tokens are exchanged
+// for new tokens at the refresh endpoint.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// TokenVerifier resolves the keys that verify access tokens
+type TokenVerifier interface {
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	Methods() []string
+}
+
+// APITokenAuthenticator resolves personal access tokens and service account API keys
+type APITokenAuthenticator interface {
+	AuthenticateAPIToken(ctx context.Context, token, ip string) (*domain.APIToken, error)
+}
+
+// AuthMiddleware requires a valid, unrevoked access token or an active API token.
+// API tokens are sent in the X-API-Key header or as a Bearer token.
+func AuthMiddleware(verifier TokenVerifier, apiTokens APITokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if value, ok := apiTokenFromRequest(c); ok {
+			token, err := apiTokens.AuthenticateAPIToken(c.Request.Context(), value, c.ClientIP())
+			if err != nil {
+				response.Error(c, errors.ErrInvalidToken)
+				c.Abort()
+				return
+			}
+
+			setAPIToken(c, token)
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -40,13 +79,7 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(cfg.Secret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verifier.Keyfunc, jwt.WithValidMethods(verifier.Methods()))
 
 		if err != nil {
 			response.Error(c, errors.ErrInvalidToken)
@@ -55,10 +88,16 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 		}
 
 		// Extract claims
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.TokenType == TokenTypeAccess {
+			// Reject tokens revoked by logout or session revocation
+			if isRevoked(c, claims) {
+				response.Error(c, errors.ErrInvalidToken)
+				c.Abort()
+				return
+			}
+
 			// Set user info in context
-			c.Set("user_id", claims.UserID)
-			c.Set("user_email", claims.Email)
+			setClaims(c, claims)
 			c.Next()
 		} else {
 			response.Error(c, errors.ErrInvalidToken)
@@ -68,9 +107,17 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
-// OptionalAuthMiddleware validates JWT tokens but doesn't require them
-func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
+// OptionalAuthMiddleware validates JWT and API tokens but doesn't require them
+func OptionalAuthMiddleware(verifier TokenVerifier, apiTokens APITokenAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if value, ok := apiTokenFromRequest(c); ok {
+			if token, err := apiTokens.AuthenticateAPIToken(c.Request.Context(), value, c.ClientIP()); err == nil {
+				setAPIToken(c, token)
+			}
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -88,17 +135,11 @@ func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(cfg.Secret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verifier.Keyfunc, jwt.WithValidMethods(verifier.Methods()))
 
 		if err == nil {
-			if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-				c.Set("user_id", claims.UserID)
-				c.Set("user_email", claims.Email)
+			if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.TokenType == TokenTypeAccess && !isRevoked(c, claims) {
+				setClaims(c, claims)
 			}
 		}
 
@@ -106,6 +147,82 @@ func OptionalAuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
+// setClaims stores the token's user and session in the context
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("session_id", claims.SessionID)
+	c.Set("token_id", claims.ID)
+	if claims.ExpiresAt != nil {
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+	}
+	if claims.ImpersonatorID != nil {
+		c.Set("impersonator_id", *claims.ImpersonatorID)
+	}
+}
+
+// DenyAPITokens rejects requests authenticated with an API token. It guards account
+// management, which tokens must not be able to use to widen their own access, and
+// every route that requires no permission, as there is no scope to limit a token to.
+func DenyAPITokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetAPITokenID(c); ok {
+			response.Error(c, errors.New(errors.ErrCodeForbidden, "This endpoint cannot be used with an API token", 403))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyImpersonation rejects requests made with an impersonation token. It guards
+// account security, such as the password and 2FA, and every administrative change,
+// which an impersonator must not make under the impersonated user's identity.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetImpersonatorID(c); ok {
+			response.Error(c, errors.New(errors.ErrCodeForbidden, "This endpoint cannot be used while impersonating a user", 403))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiTokenFromRequest returns an API token sent in the X-API-Key header, or as a
+// Bearer token recognized by its prefix
+func apiTokenFromRequest(c *gin.Context) (string, bool) {
+	if value := c.GetHeader("X-API-Key"); value != "" {
+		return value, true
+	}
+
+	value, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if found && strings.HasPrefix(value, domain.APITokenPrefix) {
+		return value, true
+	}
+
+	return "", false
+}
+
+// setAPIToken stores the token's owner and scopes in the context
+func setAPIToken(c *gin.Context, token *domain.APIToken) {
+	c.Set("user_id", token.UserID)
+	c.Set("user_email", token.User.Email)
+	c.Set("api_token_id", token.ID)
+	c.Set("api_token_scopes", token.Scopes)
+}
+
+// isRevoked checks the token blacklist. If Redis is unavailable the token is
+// accepted, as it still expires on its own shortly.
+func isRevoked(c *gin.Context, claims *Claims) bool {
+	revoked, err := cache.IsTokenRevoked(c.Request.Context(), claims.ID, claims.SessionID)
+	if err != nil {
+		logger.Warn("Failed to check token blacklist", zap.Error(err))
+		return false
+	}
+	return revoked
+}
+
 // GetUserID gets the user ID from context
 func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	userID, exists := c.Get("user_id")
@@ -126,6 +243,54 @@ func GetUserEmail(c *gin.Context) (string, bool) {
 	return e, ok
 }
 
+// GetSessionID gets the session ID of the access token from context
+func GetSessionID(c *gin.Context) (uuid.UUID, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	id, ok := sessionID.(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// GetAPITokenID gets the ID of the API token the request was authenticated with
+func GetAPITokenID(c *gin.Context) (uuid.UUID, bool) {
+	tokenID, exists := c.Get("api_token_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	id, ok := tokenID.(uuid.UUID)
+	return id, ok
+}
+
+// GetImpersonatorID gets the ID of the admin impersonating the user in user_id, if the
+// request was made with an impersonation token
+func GetImpersonatorID(c *gin.Context) (uuid.UUID, bool) {
+	impersonatorID, exists := c.Get("impersonator_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+	id, ok := impersonatorID.(uuid.UUID)
+	return id, ok
+}
+
+// tokenAllows checks the scopes of the request's API token. Requests authenticated
+// with a JWT are not limited by scopes. Only the Authorize middlewares call it, so
+// routes without one must use DenyAPITokens.
+func tokenAllows(c *gin.Context, permission string) bool {
+	scopes, exists := c.Get("api_token_scopes")
+	if !exists {
+		return true
+	}
+	granted, _ := scopes.([]string)
+	for _, scope := range granted {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // MustGetUserID gets the user ID from context or panics
 func MustGetUserID(c *gin.Context) uuid.UUID {
 	userID, ok := GetUserID(c)
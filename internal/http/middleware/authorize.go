@@ -3,11 +3,15 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/internal/infrastructure/cache"
 	"github.com/owner/go-cms/pkg/errors"
 	"github.com/owner/go-cms/pkg/logger"
@@ -20,84 +24,315 @@ import (
 type PermissionChecker interface {
 	HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error)
 	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetPermissionScope(ctx context.Context, userID uuid.UUID, permission string) (domain.ScopeLevel, error)
+	EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error)
 }
 
-// DefaultPermissionChecker implements PermissionChecker
+// PolicyEvaluator evaluates the attribute-based policies on a permission
+type PolicyEvaluator interface {
+	EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error)
+}
+
+// DefaultPermissionChecker implements PermissionChecker. Permission scopes are cached
+// in Redis and, briefly, in process memory.
 type DefaultPermissionChecker struct {
-	db *gorm.DB
+	db       *gorm.DB
+	policies PolicyEvaluator
+	local    *localPermissionCache
 }
 
 // NewPermissionChecker creates a new permission checker
 func NewPermissionChecker(db *gorm.DB) *DefaultPermissionChecker {
-	return &DefaultPermissionChecker{db: db}
+	return &DefaultPermissionChecker{
+		db:    db,
+		local: newLocalPermissionCache(),
+	}
+}
+
+// HandlePermissionEvent drops the in-process permissions of the users a permission
+// event names
+func (pc *DefaultPermissionChecker) HandlePermissionEvent(event cache.PermissionEvent) {
+	if event.All || len(event.UserIDs) > 0 {
+		pc.local.invalidate(event)
+	}
+}
+
+// SetPolicyEvaluator sets what evaluates policies on permissions. The evaluator is set
+// after construction because it looks up permission scopes through the checker.
+func (pc *DefaultPermissionChecker) SetPolicyEvaluator(policies PolicyEvaluator) {
+	pc.policies = policies
+}
+
+// EvaluatePolicies evaluates the policies on a permission. Without an evaluator every
+// access is allowed.
+func (pc *DefaultPermissionChecker) EvaluatePolicies(ctx context.Context, input domain.PolicyInput) (*domain.PolicyDecision, error) {
+	if pc.policies == nil {
+		return &domain.PolicyDecision{Allowed: true}, nil
+	}
+	return pc.policies.EvaluatePolicies(ctx, input)
 }
 
 // HasPermission checks if a user has a specific permission
 func (pc *DefaultPermissionChecker) HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error) {
-	// Try to get from cache first
-	cachedPerms, err := cache.GetPermissions(ctx, userID)
+	scopes, err := pc.permissionScopes(ctx, userID)
+	if err != nil {
+		return false, err
+	}
 
-	if err == nil && len(cachedPerms) > 0 {
-		// Check if permission exists in cache
-		if _, exists := cachedPerms[permission]; exists {
-			return true, nil
-		}
-		// If not found in cache, it means user doesn't have this permission
-		return false, nil
+	_, exists := scopes[permission]
+	return exists, nil
+}
+
+// GetUserPermissions gets all permissions for a user
+func (pc *DefaultPermissionChecker) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	scopes, err := pc.GetUserPermissionScopes(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Cache miss, query database
-	permissions, err := pc.GetUserPermissions(ctx, userID)
+	permissions := make([]string, 0, len(scopes))
+	for permission := range scopes {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions, nil
+}
+
+// GetPermissionScope returns the broadest scope the user holds a permission at, or an
+// empty level if they do not hold it
+func (pc *DefaultPermissionChecker) GetPermissionScope(ctx context.Context, userID uuid.UUID, permission string) (domain.ScopeLevel, error) {
+	scopes, err := pc.permissionScopes(ctx, userID)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 
-	// Cache the permissions
-	_ = cache.CachePermissions(ctx, userID, permissions, 15*time.Minute)
+	return scopes[permission], nil
+}
+
+// permissionScopes returns the user's permission scopes from process memory, then
+// Redis, then the database
+func (pc *DefaultPermissionChecker) permissionScopes(ctx context.Context, userID uuid.UUID) (map[string]domain.ScopeLevel, error) {
+	if scopes, ok := pc.local.get(userID); ok {
+		return scopes, nil
+	}
+	generation := pc.local.currentGeneration()
 
-	// Check if user has the permission
-	for _, perm := range permissions {
-		if perm == permission {
-			return true, nil
+	cachedPerms, ttl, err := cache.GetPermissionsWithTTL(ctx, userID)
+	if err == nil && len(cachedPerms) > 0 {
+		scopes := make(map[string]domain.ScopeLevel, len(cachedPerms))
+		for permission, value := range cachedPerms {
+			scopes[permission] = domain.ScopeLevel(value)
 		}
+		pc.local.set(userID, scopes, ttl, generation)
+		return scopes, nil
 	}
 
-	return false, nil
+	// Cache miss, query database
+	return pc.loadPermissionScopes(ctx, userID, generation)
 }
 
-// GetUserPermissions gets all permissions for a user
-func (pc *DefaultPermissionChecker) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
-	var permissions []string
-
-	// Get permissions from user roles
+// GetUserPermissionScopes gets every permission a user holds, from their roles and direct
+// grants, with the broadest scope it is held at. A role holds its own grants and those of
+// every role below it in the ParentID tree; UNION rather than UNION ALL stops the
+// recursion on cycles. Permissions are keyed without their scope. Expired role grants
+// and revoked or expired direct grants are ignored.
+func (pc *DefaultPermissionChecker) GetUserPermissionScopes(ctx context.Context, userID uuid.UUID) (map[string]domain.ScopeLevel, error) {
 	query := `
-		SELECT DISTINCT CONCAT(p.module, ':', p.department, ':', p.service, ':', p.resource, ':', p.action) as permission
-		FROM permissions p
-		INNER JOIN role_permissions rp ON p.id = rp.permission_id
-		INNER JOIN user_roles ur ON rp.role_id = ur.role_id
-		WHERE ur.user_id = ? AND ur.deleted_at IS NULL
-
-		UNION
-
-		SELECT DISTINCT CONCAT(p.module, ':', p.department, ':', p.service, ':', p.resource, ':', p.action) as permission
-		FROM permissions p
-		INNER JOIN user_permissions up ON p.id = up.permission_id
-		WHERE up.user_id = ?
+		WITH RECURSIVE role_tree AS (
+			SELECT ur.role_id FROM user_roles ur
+			WHERE ur.user_id = ? AND ur.deleted_at IS NULL
+				AND (ur.expires_at IS NULL OR ur.expires_at > NOW())
+			UNION
+			SELECT r.id FROM roles r
+			INNER JOIN role_tree rt ON r.parent_id = rt.role_id
+			WHERE r.deleted_at IS NULL
+		),
+		granted AS (
+			SELECT rep.permission_id FROM role_enhanced_permissions rep
+			INNER JOIN role_tree rt ON rep.role_id = rt.role_id
+			UNION
+			SELECT uep.permission_id FROM user_enhanced_permissions uep
+			WHERE uep.user_id = ? AND uep.is_revoked = false
+				AND (uep.expires_at IS NULL OR uep.expires_at > NOW())
+		)
+		SELECT DISTINCT CONCAT(m.code, ':', d.code, ':', sv.code, ':', ep.resource, ':', ep.action) as permission,
+			s.level as level
+		FROM enhanced_permissions ep
+		INNER JOIN modules m ON ep.module_id = m.id
+		INNER JOIN departments d ON ep.department_id = d.id
+		INNER JOIN services sv ON ep.service_id = sv.id
+		INNER JOIN scopes s ON ep.scope_id = s.id
+		INNER JOIN granted g ON ep.id = g.permission_id
+		WHERE ep.deleted_at IS NULL
 	`
 
 	var permissionRows []struct {
 		Permission string
+		Level      string
 	}
 
 	if err := pc.db.WithContext(ctx).Raw(query, userID, userID).Scan(&permissionRows).Error; err != nil {
 		return nil, err
 	}
 
+	scopes := make(map[string]domain.ScopeLevel, len(permissionRows))
 	for _, row := range permissionRows {
-		permissions = append(permissions, row.Permission)
+		level := domain.ScopeLevel(row.Level)
+		if level.Rank() > scopes[row.Permission].Rank() {
+			scopes[row.Permission] = level
+		}
 	}
 
-	return permissions, nil
+	return scopes, nil
+}
+
+// nextGrantExpiry returns when the user's earliest unexpired time-limited grant ends,
+// or nil if none of their grants expire
+func (pc *DefaultPermissionChecker) nextGrantExpiry(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `
+		SELECT MIN(expires_at) AS expires_at FROM (
+			SELECT expires_at FROM user_roles
+			WHERE user_id = ? AND deleted_at IS NULL AND expires_at > NOW()
+			UNION ALL
+			SELECT expires_at FROM user_enhanced_permissions
+			WHERE user_id = ? AND is_revoked = false AND expires_at > NOW()
+		) grants
+	`
+
+	var row struct {
+		ExpiresAt *time.Time
+	}
+	if err := pc.db.WithContext(ctx).Raw(query, userID, userID).Scan(&row).Error; err != nil {
+		return nil, err
+	}
+	return row.ExpiresAt, nil
+}
+
+// loadPermissionScopes queries the user's permission scopes and caches them. The cache
+// entry ends no later than the user's next grant expiry, so an expired grant is not
+// served from the cache. The generation is the in-process cache's when the lookup
+// started; the Redis write is likewise skipped if the user's permissions were
+// invalidated while they were loaded.
+func (pc *DefaultPermissionChecker) loadPermissionScopes(ctx context.Context, userID uuid.UUID, generation uint64) (map[string]domain.ScopeLevel, error) {
+	version, versionErr := cache.GetPermissionsVersion(ctx, userID)
+
+	scopes, err := pc.GetUserPermissionScopes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := 15 * time.Minute
+	expiresAt, err := pc.nextGrantExpiry(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to check grant expiry, skipping permission cache", zap.String("user_id", userID.String()), zap.Error(err))
+		return scopes, nil
+	}
+	if expiresAt != nil {
+		if untilExpiry := time.Until(*expiresAt); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+
+	if ttl > 0 {
+		cached := make(map[string]string, len(scopes))
+		for permission, level := range scopes {
+			cached[permission] = string(level)
+		}
+		if versionErr != nil {
+			logger.Warn("Failed to read permissions version, skipping Redis permission cache", zap.String("user_id", userID.String()), zap.Error(versionErr))
+		} else if _, err := cache.CachePermissionScopes(ctx, userID, cached, ttl, version); err != nil {
+			logger.Warn("Failed to cache permission scopes", zap.String("user_id", userID.String()), zap.Error(err))
+		}
+		pc.local.set(userID, scopes, ttl, generation)
+	}
+
+	return scopes, nil
+}
+
+// localPermissionTTL bounds how long permission scopes are served from process memory.
+// Permission events drop entries sooner; the TTL covers events missed while the
+// subscription reconnects.
+const localPermissionTTL = 30 * time.Second
+
+// Entries beyond this are swept, and if none have expired the cache starts over
+const maxLocalPermissionEntries = 10000
+
+// localPermissionCache holds permission scopes in process memory. Each event bumps the
+// generation, so a lookup that started before an event cannot store what it read.
+type localPermissionCache struct {
+	mu         sync.RWMutex
+	entries    map[uuid.UUID]localPermissions
+	generation uint64
+}
+
+type localPermissions struct {
+	scopes    map[string]domain.ScopeLevel
+	expiresAt time.Time
+}
+
+func newLocalPermissionCache() *localPermissionCache {
+	return &localPermissionCache{entries: make(map[uuid.UUID]localPermissions)}
+}
+
+func (c *localPermissionCache) get(userID uuid.UUID) (map[string]domain.ScopeLevel, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.scopes, true
+}
+
+func (c *localPermissionCache) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// set stores the scopes for at most ttl, unless an event arrived since the generation
+func (c *localPermissionCache) set(userID uuid.UUID, scopes map[string]domain.ScopeLevel, ttl time.Duration, generation uint64) {
+	if ttl <= 0 || ttl > localPermissionTTL {
+		// A key without an expiry reports a negative TTL
+		ttl = localPermissionTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+	if len(c.entries) >= maxLocalPermissionEntries {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxLocalPermissionEntries {
+			c.entries = make(map[uuid.UUID]localPermissions)
+		}
+	}
+
+	c.entries[userID] = localPermissions{scopes: scopes, expiresAt: now.Add(ttl)}
+}
+
+func (c *localPermissionCache) invalidate(event cache.PermissionEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if event.All {
+		c.entries = make(map[uuid.UUID]localPermissions)
+		return
+	}
+	for _, userID := range event.UserIDs {
+		delete(c.entries, userID)
+	}
 }
 
 // AuthorizeMiddleware checks if the user has the required permission
@@ -110,6 +345,17 @@ func AuthorizeMiddleware(checker PermissionChecker, requiredPermission string) g
 			return
 		}
 
+		// API tokens are limited to their scopes on top of their owner's permissions
+		if !tokenAllows(c, requiredPermission) {
+			logger.Warn("Permission outside API token scopes",
+				zap.String("user_id", userID.String()),
+				zap.String("permission", requiredPermission),
+			)
+			response.Error(c, errors.ErrInsufficientPermissions)
+			c.Abort()
+			return
+		}
+
 		// Check permission
 		hasPermission, err := checker.HasPermission(c.Request.Context(), userID, requiredPermission)
 		if err != nil {
@@ -133,6 +379,14 @@ func AuthorizeMiddleware(checker PermissionChecker, requiredPermission string) g
 			return
 		}
 
+		if !enforceRequestPolicies(c, checker, userID, requiredPermission) {
+			return
+		}
+
+		if !setAccessScope(c, checker, userID, requiredPermission) {
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -149,6 +403,10 @@ func AuthorizeAnyMiddleware(checker PermissionChecker, requiredPermissions ...st
 
 		// Check if user has any of the required permissions
 		for _, permission := range requiredPermissions {
+			if !tokenAllows(c, permission) {
+				continue
+			}
+
 			hasPermission, err := checker.HasPermission(c.Request.Context(), userID, permission)
 			if err != nil {
 				logger.Error("Failed to check permission",
@@ -159,10 +417,28 @@ func AuthorizeAnyMiddleware(checker PermissionChecker, requiredPermissions ...st
 				continue
 			}
 
-			if hasPermission {
+			if !hasPermission {
+				continue
+			}
+
+			// A permission its policies refuse counts as not held
+			allowed, err := requestPoliciesAllow(c, checker, userID, permission)
+			if err != nil {
+				logger.Error("Failed to evaluate policies",
+					zap.String("user_id", userID.String()),
+					zap.String("permission", permission),
+					zap.Error(err),
+				)
+				continue
+			}
+			if !allowed {
+				continue
+			}
+
+			if setAccessScope(c, checker, userID, permission) {
 				c.Next()
-				return
 			}
+			return
 		}
 
 		logger.Warn("Permission denied - none of required permissions found",
@@ -186,6 +462,16 @@ func AuthorizeAllMiddleware(checker PermissionChecker, requiredPermissions ...st
 
 		// Check if user has all required permissions
 		for _, permission := range requiredPermissions {
+			if !tokenAllows(c, permission) {
+				logger.Warn("Permission outside API token scopes",
+					zap.String("user_id", userID.String()),
+					zap.String("permission", permission),
+				)
+				response.Error(c, errors.ErrInsufficientPermissions)
+				c.Abort()
+				return
+			}
+
 			hasPermission, err := checker.HasPermission(c.Request.Context(), userID, permission)
 			if err != nil {
 				logger.Error("Failed to check permission",
@@ -207,12 +493,208 @@ func AuthorizeAllMiddleware(checker PermissionChecker, requiredPermissions ...st
 				c.Abort()
 				return
 			}
+
+			if !enforceRequestPolicies(c, checker, userID, permission) {
+				return
+			}
+		}
+
+		// Records are filtered by the scope of the first permission, the one the
+		// route reads with
+		if len(requiredPermissions) > 0 && !setAccessScope(c, checker, userID, requiredPermissions[0]) {
+			return
 		}
 
 		c.Next()
 	}
 }
 
+// DepartmentManagers tells whether a user manages a department
+type DepartmentManagers interface {
+	IsDepartmentManager(ctx context.Context, managerID uuid.UUID, departmentID uint) (bool, error)
+}
+
+// AuthorizeOrDepartmentManagerMiddleware lets through users with the required
+// permission, and managers of the department named by the :id path parameter or of a
+// department above it. Handlers tell the two apart with ActingAsDepartmentManager.
+func AuthorizeOrDepartmentManagerMiddleware(checker PermissionChecker, managers DepartmentManagers, requiredPermission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetUserID(c)
+		if !exists {
+			response.Error(c, errors.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		// API tokens are limited to their scopes, whichever way the user is allowed
+		if !tokenAllows(c, requiredPermission) {
+			logger.Warn("Permission outside API token scopes",
+				zap.String("user_id", userID.String()),
+				zap.String("permission", requiredPermission),
+			)
+			response.Error(c, errors.ErrInsufficientPermissions)
+			c.Abort()
+			return
+		}
+
+		hasPermission, err := checker.HasPermission(c.Request.Context(), userID, requiredPermission)
+		if err != nil {
+			logger.Error("Failed to check permission",
+				zap.String("user_id", userID.String()),
+				zap.String("permission", requiredPermission),
+				zap.Error(err),
+			)
+			response.Error(c, errors.ErrInternal)
+			c.Abort()
+			return
+		}
+
+		if hasPermission {
+			if !enforceRequestPolicies(c, checker, userID, requiredPermission) {
+				return
+			}
+			if !setAccessScope(c, checker, userID, requiredPermission) {
+				return
+			}
+			c.Next()
+			return
+		}
+
+		departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			response.Error(c, errors.ErrInsufficientPermissions)
+			c.Abort()
+			return
+		}
+
+		isManager, err := managers.IsDepartmentManager(c.Request.Context(), userID, uint(departmentID))
+		if err != nil {
+			logger.Error("Failed to check department manager",
+				zap.String("user_id", userID.String()),
+				zap.Uint64("department_id", departmentID),
+				zap.Error(err),
+			)
+			response.Error(c, errors.ErrInternal)
+			c.Abort()
+			return
+		}
+
+		if !isManager {
+			logger.Warn("Permission denied",
+				zap.String("user_id", userID.String()),
+				zap.String("permission", requiredPermission),
+				zap.Uint64("department_id", departmentID),
+			)
+			response.Error(c, errors.ErrInsufficientPermissions)
+			c.Abort()
+			return
+		}
+
+		c.Set("department_manager", true)
+		c.Next()
+	}
+}
+
+// ActingAsDepartmentManager reports whether the request was let through because the
+// user manages the department rather than because they hold the permission
+func ActingAsDepartmentManager(c *gin.Context) bool {
+	return c.GetBool("department_manager")
+}
+
+// setAccessScope stores the scope the user holds a permission at, for repositories to
+// filter records by. It aborts the request if the scope cannot be resolved.
+func setAccessScope(c *gin.Context, checker PermissionChecker, userID uuid.UUID, permission string) bool {
+	level, err := checker.GetPermissionScope(c.Request.Context(), userID, permission)
+	if err != nil {
+		logger.Error("Failed to resolve permission scope",
+			zap.String("user_id", userID.String()),
+			zap.String("permission", permission),
+			zap.Error(err),
+		)
+		response.Error(c, errors.ErrInternal)
+		c.Abort()
+		return false
+	}
+
+	if level != "" {
+		c.Set("access_scope", &domain.AccessScope{
+			UserID:     userID,
+			Level:      level,
+			Permission: permission,
+			Request:    policyRequest(c),
+		})
+	}
+	return true
+}
+
+// enforceRequestPolicies evaluates the policies on a permission that do not need the
+// resource, and aborts the request if they refuse access
+func enforceRequestPolicies(c *gin.Context, checker PermissionChecker, userID uuid.UUID, permission string) bool {
+	allowed, err := requestPoliciesAllow(c, checker, userID, permission)
+	if err != nil {
+		logger.Error("Failed to evaluate policies",
+			zap.String("user_id", userID.String()),
+			zap.String("permission", permission),
+			zap.Error(err),
+		)
+		response.Error(c, errors.ErrInternal)
+		c.Abort()
+		return false
+	}
+
+	if !allowed {
+		response.Error(c, errors.ErrAccessDenied)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// requestPoliciesAllow reports whether the policies on a permission that do not need
+// the resource allow the request
+func requestPoliciesAllow(c *gin.Context, checker PermissionChecker, userID uuid.UUID, permission string) (bool, error) {
+	decision, err := checker.EvaluatePolicies(c.Request.Context(), domain.PolicyInput{
+		UserID:     userID,
+		Permission: permission,
+		Request:    policyRequest(c),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !decision.Allowed {
+		logger.Warn("Access denied by policy",
+			zap.String("user_id", userID.String()),
+			zap.String("permission", permission),
+			zap.String("reason", decision.Reason),
+		)
+	}
+	return decision.Allowed, nil
+}
+
+// policyRequest describes the request for policy conditions
+func policyRequest(c *gin.Context) *domain.PolicyRequest {
+	return &domain.PolicyRequest{
+		IP:     c.ClientIP(),
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		Time:   time.Now(),
+	}
+}
+
+// GetAccessScope gets the scope resolved for the route's permission. Without one the
+// user holds no scope, which limits them to their own records.
+func GetAccessScope(c *gin.Context) *domain.AccessScope {
+	if scope, exists := c.Get("access_scope"); exists {
+		if s, ok := scope.(*domain.AccessScope); ok {
+			return s
+		}
+	}
+
+	userID, _ := GetUserID(c)
+	return &domain.AccessScope{UserID: userID, Level: domain.ScopeLevelPersonal}
+}
+
 // BuildPermission builds a permission string from components
 func BuildPermission(module, department, service, resource, action string) string {
 	return fmt.Sprintf("%s:%s:%s:%s:%s", module, department, service, resource, action)
@@ -248,10 +730,11 @@ const (
 	PermissionContentMediaDelete = "content:editorial:media:media:delete"
 
 	// Admin module permissions
-	PermissionAdminUsersCreate = "admin:system:users:users:create"
-	PermissionAdminUsersRead   = "admin:system:users:users:read"
-	PermissionAdminUsersUpdate = "admin:system:users:users:update"
-	PermissionAdminUsersDelete = "admin:system:users:users:delete"
+	PermissionAdminUsersCreate      = "admin:system:users:users:create"
+	PermissionAdminUsersRead        = "admin:system:users:users:read"
+	PermissionAdminUsersUpdate      = "admin:system:users:users:update"
+	PermissionAdminUsersDelete      = "admin:system:users:users:delete"
+	PermissionAdminUsersImpersonate = "admin:system:users:users:impersonate"
 
 	PermissionAdminRolesCreate = "admin:system:roles:roles:create"
 	PermissionAdminRolesRead   = "admin:system:roles:roles:read"
@@ -259,4 +742,6 @@ const (
 	PermissionAdminRolesDelete = "admin:system:roles:roles:delete"
 
 	PermissionAdminPermissionsManage = "admin:system:permissions:permissions:manage"
+
+	PermissionAdminDocumentsManage = "admin:system:documents:documents:manage"
 )
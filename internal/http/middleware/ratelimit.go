@@ -0,0 +1,333 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/errors"
+	"github.com/owner/go-cms/pkg/logger"
+	"github.com/owner/go-cms/pkg/response"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// RateLimitAlgorithm selects how requests are counted
+type RateLimitAlgorithm string
+
+const (
+	// RateLimitSlidingWindow allows Requests per Window, weighting the previous window
+	// by how much of it still overlaps the sliding window
+	RateLimitSlidingWindow RateLimitAlgorithm = "sliding_window"
+	// RateLimitTokenBucket allows bursts of up to Requests, refilled evenly over Window
+	RateLimitTokenBucket RateLimitAlgorithm = "token_bucket"
+)
+
+const prefixRateLimit = "ratelimit:"
+
+// RateLimitKeyFunc returns the identity a request is counted against
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitPolicy describes how often a group of routes may be called
+type RateLimitPolicy struct {
+	Name      string // Keeps the counters of different policies apart
+	Requests  int
+	Window    time.Duration
+	Algorithm RateLimitAlgorithm
+	Key       RateLimitKeyFunc // Defaults to RateLimitByIdentity
+}
+
+// RateLimiter enforces rate limit policies with counters kept in Redis, so limits
+// hold across all server instances
+type RateLimiter struct {
+	config            *config.RateLimitConfig
+	allowedNetworks   []*net.IPNet
+	allowedIdentities map[string]bool
+}
+
+// rateLimitResult is the outcome of counting one request
+type rateLimitResult struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration // Until the limit is fully restored
+	retryAfter time.Duration // Until the next request would be allowed
+}
+
+// NewRateLimiter creates a rate limiter. Allowlist entries are IP addresses, CIDR
+// ranges, or user IDs that are never limited.
+func NewRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
+	limiter := &RateLimiter{
+		config:            cfg,
+		allowedIdentities: make(map[string]bool),
+	}
+
+	for _, entry := range cfg.Allowlist {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			limiter.allowedNetworks = append(limiter.allowedNetworks, network)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			limiter.allowedNetworks = append(limiter.allowedNetworks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		limiter.allowedIdentities[entry] = true
+	}
+
+	return limiter
+}
+
+// Global limits every request to the configured number per duration
+func (l *RateLimiter) Global() gin.HandlerFunc {
+	return l.Limit(RateLimitPolicy{
+		Name:      "global",
+		Requests:  l.config.Requests,
+		Window:    l.config.Duration,
+		Algorithm: RateLimitAlgorithm(l.config.Algorithm),
+	})
+}
+
+// Limit enforces a policy on the routes it is attached to. Requests are allowed
+// when Redis is unavailable, so an outage does not take the API down.
+// Windows come from RateLimitConfig, which rejects any shorter than a millisecond.
+func (l *RateLimiter) Limit(policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Key == nil {
+		policy.Key = RateLimitByIdentity
+	}
+	if policy.Algorithm == "" {
+		policy.Algorithm = RateLimitSlidingWindow
+	}
+
+	return func(c *gin.Context) {
+		if policy.Requests <= 0 || policy.Window <= 0 || l.isAllowlisted(c) {
+			c.Next()
+			return
+		}
+
+		key := prefixRateLimit + policy.Name + ":" + policy.Key(c)
+
+		var (
+			result *rateLimitResult
+			err    error
+		)
+		switch policy.Algorithm {
+		case RateLimitTokenBucket:
+			result, err = takeToken(c, key, policy)
+		default:
+			result, err = slideWindow(c, key, policy)
+		}
+
+		if err != nil {
+			logger.Warn("Rate limiter unavailable, allowing request", zap.String("policy", policy.Name), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Requests, int(policy.Window.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.reset)))
+
+		if !result.allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.retryAfter)))
+			response.Error(c, errors.ErrRateLimitExceeded)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RateLimitByIP counts requests per client IP
+func RateLimitByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByIdentity counts requests per authenticated API token, then per
+// authenticated user, and per client IP otherwise. Credentials the request merely
+// carries are not trusted, so unauthenticated requests cannot pick their own bucket.
+func RateLimitByIdentity(c *gin.Context) string {
+	if tokenID, ok := GetAPITokenID(c); ok {
+		return "token:" + tokenID.String()
+	}
+
+	if userID, ok := GetUserID(c); ok {
+		return "user:" + userID.String()
+	}
+
+	return RateLimitByIP(c)
+}
+
+func (l *RateLimiter) isAllowlisted(c *gin.Context) bool {
+	if ip := net.ParseIP(c.ClientIP()); ip != nil {
+		for _, network := range l.allowedNetworks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	if userID, ok := GetUserID(c); ok && userID != uuid.Nil {
+		return l.allowedIdentities[userID.String()]
+	}
+
+	return false
+}
+
+// slidingWindowScript counts a request in the current fixed window unless the
+// weighted count of the current and previous windows has reached the limit.
+// It returns {allowed, previous count, current count}.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local weight = tonumber(ARGV[2])
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if previous * weight + current + 1 > limit then
+	return {0, previous, current}
+end
+current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {1, previous, current}
+`)
+
+func slideWindow(c *gin.Context, key string, policy RateLimitPolicy) (*rateLimitResult, error) {
+	window := policy.Window.Milliseconds()
+	now := time.Now().UnixMilli()
+	windowStart := now - now%window
+	elapsed := now - windowStart
+	weight := float64(window-elapsed) / float64(window)
+
+	keys := []string{
+		fmt.Sprintf("%s:%d", key, windowStart),
+		fmt.Sprintf("%s:%d", key, windowStart-window),
+	}
+
+	values, err := slidingWindowScript.Run(c.Request.Context(), cache.GetClient(), keys,
+		policy.Requests, strconv.FormatFloat(weight, 'f', 6, 64), 2*window).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", values)
+	}
+
+	allowed, previous, current := values[0] == 1, float64(values[1]), float64(values[2])
+	limit := float64(policy.Requests)
+	used := previous*weight + current
+
+	result := &rateLimitResult{
+		allowed:   allowed,
+		remaining: int(math.Max(0, math.Floor(limit-used))),
+		// The count is fully restored once this window has slid past
+		reset: time.Duration(2*window-elapsed) * time.Millisecond,
+	}
+
+	if !allowed {
+		result.retryAfter = slidingWindowRetryAfter(previous, current, limit, window, elapsed)
+	}
+
+	return result, nil
+}
+
+// slidingWindowRetryAfter returns when the weighted count will have room for one more request
+func slidingWindowRetryAfter(previous, current, limit float64, window, elapsed int64) time.Duration {
+	// Within this window only the previous window's share shrinks
+	if room := limit - current - 1; room >= 0 && previous > 0 {
+		wait := float64(window)*(1-room/previous) - float64(elapsed)
+		if wait > 0 {
+			return time.Duration(wait) * time.Millisecond
+		}
+	}
+
+	// Otherwise wait for the next window, where this window becomes the previous one
+	untilNext := window - elapsed
+	if current > 0 {
+		if share := (limit - 1) / current; share < 1 {
+			untilNext += int64(float64(window) * (1 - share))
+		}
+	}
+
+	return time.Duration(untilNext) * time.Millisecond
+}
+
+// tokenBucketScript refills the bucket for the time passed and takes a token if one
+// is available. It returns {allowed, tokens left}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+func takeToken(c *gin.Context, key string, policy RateLimitPolicy) (*rateLimitResult, error) {
+	capacity := float64(policy.Requests)
+	window := policy.Window.Milliseconds()
+	ratePerMs := capacity / float64(window)
+
+	reply, err := tokenBucketScript.Run(c.Request.Context(), cache.GetClient(), []string{key},
+		policy.Requests, strconv.FormatFloat(ratePerMs, 'g', -1, 64), time.Now().UnixMilli(), window).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensStr, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+
+	result := &rateLimitResult{
+		allowed:   allowed == 1,
+		remaining: int(math.Floor(tokens)),
+		reset:     time.Duration((capacity-tokens)/ratePerMs) * time.Millisecond,
+	}
+
+	if !result.allowed {
+		result.retryAfter = time.Duration((1-tokens)/ratePerMs) * time.Millisecond
+	}
+
+	return result, nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
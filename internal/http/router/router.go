@@ -1,37 +1,49 @@
 package router
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/usecases/apitoken"
 	"github.com/owner/go-cms/internal/core/usecases/audit"
 	"github.com/owner/go-cms/internal/http/handlers"
 	authHandlers "github.com/owner/go-cms/internal/http/handlers/authorization"
 	pageBuilderHandlers "github.com/owner/go-cms/internal/http/handlers/page_builder"
 	"github.com/owner/go-cms/internal/http/middleware"
+	"github.com/owner/go-cms/internal/infrastructure/jwtkeys"
 	"github.com/owner/go-cms/pkg/response"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type Router struct {
-	config              *config.Config
-	permissionChecker   *middleware.DefaultPermissionChecker
-	authHandler         *handlers.AuthHandler
-	userHandler         *handlers.UserHandler
-	moduleHandler       *authHandlers.ModuleHandler
-	departmentHandler   *authHandlers.DepartmentHandler
-	serviceHandler      *authHandlers.ServiceHandler
-	scopeHandler        *authHandlers.ScopeHandler
-	roleHandler         *handlers.RoleHandler
-	permissionHandler   *handlers.PermissionHandler
-	notificationHandler *handlers.NotificationHandler
-	websocketHandler    *handlers.WebSocketHandler
-	auditLogHandler     *handlers.AuditLogHandler
-	documentHandler     *handlers.DocumentHandler
-	auditLogUseCase     *audit.UseCase
-	categoryHandler     *handlers.CategoryHandler
+	config               *config.Config
+	keyring              *jwtkeys.Keyring
+	apiTokenUseCase      *apitoken.UseCase
+	permissionChecker    *middleware.DefaultPermissionChecker
+	departmentManagers   middleware.DepartmentManagers
+	authHandler          *handlers.AuthHandler
+	userHandler          *handlers.UserHandler
+	apiTokenHandler      *handlers.APITokenHandler
+	moduleHandler        *authHandlers.ModuleHandler
+	departmentHandler    *authHandlers.DepartmentHandler
+	serviceHandler       *authHandlers.ServiceHandler
+	scopeHandler         *authHandlers.ScopeHandler
+	roleHandler          *handlers.RoleHandler
+	permissionHandler    *handlers.PermissionHandler
+	grantHandler         *handlers.GrantHandler
+	policyHandler        *handlers.PolicyHandler
+	modelHandler         *handlers.ModelHandler
+	accessRequestHandler *handlers.AccessRequestHandler
+	notificationHandler  *handlers.NotificationHandler
+	websocketHandler     *handlers.WebSocketHandler
+	auditLogHandler      *handlers.AuditLogHandler
+	documentHandler      *handlers.DocumentHandler
+	customerHandler      *handlers.CustomerHandler
+	auditLogUseCase      *audit.UseCase
+	categoryHandler      *handlers.CategoryHandler
 	// Page Builder handlers
 	pageHandler         *pageBuilderHandlers.PageHandler
 	blockHandler        *pageBuilderHandlers.BlockHandler
@@ -42,19 +54,28 @@ type Router struct {
 
 func NewRouter(
 	cfg *config.Config,
+	keyring *jwtkeys.Keyring,
+	apiTokenUseCase *apitoken.UseCase,
 	permissionChecker *middleware.DefaultPermissionChecker,
+	departmentManagers middleware.DepartmentManagers,
 	authHandler *handlers.AuthHandler,
 	userHandler *handlers.UserHandler,
+	apiTokenHandler *handlers.APITokenHandler,
 	moduleHandler *authHandlers.ModuleHandler,
 	departmentHandler *authHandlers.DepartmentHandler,
 	serviceHandler *authHandlers.ServiceHandler,
 	scopeHandler *authHandlers.ScopeHandler,
 	roleHandler *handlers.RoleHandler,
 	permissionHandler *handlers.PermissionHandler,
+	grantHandler *handlers.GrantHandler,
+	policyHandler *handlers.PolicyHandler,
+	modelHandler *handlers.ModelHandler,
+	accessRequestHandler *handlers.AccessRequestHandler,
 	notificationHandler *handlers.NotificationHandler,
 	websocketHandler *handlers.WebSocketHandler,
 	auditLogHandler *handlers.AuditLogHandler,
 	documentHandler *handlers.DocumentHandler,
+	customerHandler *handlers.CustomerHandler,
 	auditLogUseCase *audit.UseCase,
 	categoryHandler *handlers.CategoryHandler,
 	// Page Builder handlers
@@ -65,22 +86,31 @@ func NewRouter(
 	themeSettingHandler *pageBuilderHandlers.ThemeSettingHandler,
 ) *Router {
 	return &Router{
-		config:              cfg,
-		permissionChecker:   permissionChecker,
-		authHandler:         authHandler,
-		userHandler:         userHandler,
-		moduleHandler:       moduleHandler,
-		departmentHandler:   departmentHandler,
-		serviceHandler:      serviceHandler,
-		scopeHandler:        scopeHandler,
-		roleHandler:         roleHandler,
-		permissionHandler:   permissionHandler,
-		notificationHandler: notificationHandler,
-		websocketHandler:    websocketHandler,
-		auditLogHandler:     auditLogHandler,
-		documentHandler:     documentHandler,
-		auditLogUseCase:     auditLogUseCase,
-		categoryHandler:     categoryHandler,
+		config:               cfg,
+		keyring:              keyring,
+		apiTokenUseCase:      apiTokenUseCase,
+		permissionChecker:    permissionChecker,
+		departmentManagers:   departmentManagers,
+		authHandler:          authHandler,
+		userHandler:          userHandler,
+		apiTokenHandler:      apiTokenHandler,
+		moduleHandler:        moduleHandler,
+		departmentHandler:    departmentHandler,
+		serviceHandler:       serviceHandler,
+		scopeHandler:         scopeHandler,
+		roleHandler:          roleHandler,
+		permissionHandler:    permissionHandler,
+		grantHandler:         grantHandler,
+		policyHandler:        policyHandler,
+		modelHandler:         modelHandler,
+		accessRequestHandler: accessRequestHandler,
+		notificationHandler:  notificationHandler,
+		websocketHandler:     websocketHandler,
+		auditLogHandler:      auditLogHandler,
+		documentHandler:      documentHandler,
+		customerHandler:      customerHandler,
+		auditLogUseCase:      auditLogUseCase,
+		categoryHandler:      categoryHandler,
 		// Page Builder handlers
 		pageHandler:         pageHandler,
 		blockHandler:        blockHandler,
@@ -109,78 +139,153 @@ func (r *Router) Setup() *gin.Engine {
 
 	engine.GET("/health", r.healthCheck)
 	engine.GET("/ping", r.ping)
+	engine.GET("/.well-known/jwks.json", r.jwks)
 
 	// Swagger documentation
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Rate limiting, shared across instances through Redis
+	rateLimiter := middleware.NewRateLimiter(&r.config.RateLimit)
+	globalRateLimit := rateLimiter.Global()
+	authRateLimit := rateLimiter.Limit(middleware.RateLimitPolicy{
+		Name:     "auth",
+		Requests: r.config.RateLimit.AuthRequests,
+		Window:   r.config.RateLimit.AuthDuration,
+		Key:      middleware.RateLimitByIP,
+	})
+
 	// API v1 routes
 	v1 := engine.Group("/api/v1")
 	{
 		// Public routes (no authentication required)
 		public := v1.Group("/public")
+		public.Use(globalRateLimit)
 		{
 			public.GET("/health", r.healthCheck)
+
+			// Document share links
+			public.GET("/share/:token", r.documentHandler.GetSharedDocument)
+			public.GET("/share/:token/view", r.documentHandler.ViewSharedDocument)
+			public.GET("/share/:token/download", r.documentHandler.DownloadSharedDocument)
 		}
 
 		// Auth routes (no authentication required for login/register)
 		auth := v1.Group("/auth")
+		auth.Use(globalRateLimit)
 		{
-			auth.POST("/register", r.authHandler.Register)
-			auth.POST("/login", r.authHandler.Login)
+			auth.POST("/register", authRateLimit, r.authHandler.Register)
+			auth.POST("/login", authRateLimit, r.authHandler.Login)
+			auth.POST("/2fa/login", authRateLimit, r.authHandler.CompleteTwoFactorLogin)
+			auth.GET("/sso/providers", r.authHandler.GetSSOProviders)
+			auth.GET("/sso/:provider/login", authRateLimit, r.authHandler.BeginSSOLogin)
+			auth.GET("/sso/:provider/callback", authRateLimit, r.authHandler.CompleteSSOLogin)
 			auth.POST("/refresh", r.authHandler.RefreshToken)
-			auth.POST("/forgot-password", r.authHandler.ForgotPassword)
-			auth.POST("/reset-password", r.authHandler.ResetPassword)
-			auth.POST("/verify-email", r.authHandler.VerifyEmail)
-			auth.POST("/resend-otp", r.authHandler.ResendOTP)
+			auth.POST("/forgot-password", authRateLimit, r.authHandler.ForgotPassword)
+			auth.POST("/reset-password", authRateLimit, r.authHandler.ResetPassword)
+			auth.POST("/verify-email", authRateLimit, r.authHandler.VerifyEmail)
+			auth.POST("/resend-otp", authRateLimit, r.authHandler.ResendOTP)
 		}
 
 		// Protected routes (authentication required)
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(&r.config.JWT))
+		protected.Use(middleware.AuthMiddleware(r.keyring, r.apiTokenUseCase))
+		// Limited after authentication so authenticated requests count per user
+		protected.Use(globalRateLimit)
+		// API tokens only reach what their scopes allow, which the Authorize middlewares
+		// check. Routes that require no permission must deny them with DenyAPITokens.
 		{
-			// Auth protected routes
+			// Auth protected routes, which API tokens cannot use
 			authProtected := protected.Group("/auth")
+			authProtected.Use(middleware.DenyAPITokens())
 			{
+				// Logging out with an impersonation token ends the impersonation
 				authProtected.POST("/logout", r.authHandler.Logout)
-				authProtected.POST("/change-password", r.authHandler.ChangePassword)
 				authProtected.GET("/me", r.authHandler.GetMe)
-				authProtected.PUT("/me", r.authHandler.UpdateProfile)
+			}
+
+			// Account security routes, which impersonation tokens cannot use either
+			account := authProtected.Group("")
+			account.Use(middleware.DenyImpersonation())
+			{
+				account.POST("/change-password", r.authHandler.ChangePassword)
+				account.PUT("/me", r.authHandler.UpdateProfile)
+
+				// Session routes
+				account.GET("/sessions", r.authHandler.GetSessions)
+				account.DELETE("/sessions", r.authHandler.RevokeAllSessions)
+				account.DELETE("/sessions/:id", r.authHandler.RevokeSession)
 
 				// 2FA routes
-				authProtected.POST("/2fa/enable", r.authHandler.Enable2FA)
-				authProtected.POST("/2fa/disable", r.authHandler.Disable2FA)
-				authProtected.POST("/2fa/verify", r.authHandler.Verify2FA)
+				account.POST("/2fa/enable", r.authHandler.Enable2FA)
+				account.POST("/2fa/disable", r.authHandler.Disable2FA)
+				account.POST("/2fa/verify", r.authHandler.Verify2FA)
+				account.GET("/2fa/recovery-codes", r.authHandler.GetRecoveryCodeStatus)
+				account.POST("/2fa/recovery-codes", r.authHandler.RegenerateRecoveryCodes)
+				account.POST("/2fa/webauthn/register/begin", r.authHandler.BeginWebAuthnRegistration)
+				account.POST("/2fa/webauthn/register/finish", r.authHandler.FinishWebAuthnRegistration)
+				account.GET("/2fa/webauthn/credentials", r.authHandler.GetWebAuthnCredentials)
+				account.DELETE("/2fa/webauthn/credentials/:id", r.authHandler.DeleteWebAuthnCredential)
+
+				// Personal access tokens
+				account.GET("/tokens", r.apiTokenHandler.ListPersonalTokens)
+				account.POST("/tokens", r.apiTokenHandler.CreatePersonalToken)
+				account.DELETE("/tokens/:id", r.apiTokenHandler.RevokePersonalToken)
+			}
+
+			// Service account routes
+			serviceAccounts := protected.Group("/service-accounts")
+			serviceAccounts.Use(middleware.DenyAPITokens(), middleware.DenyImpersonation())
+			{
+				serviceAccounts.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.apiTokenHandler.ListServiceAccounts)
+				serviceAccounts.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersCreate), r.apiTokenHandler.CreateServiceAccount)
+				serviceAccounts.GET("/:id/keys", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.apiTokenHandler.ListServiceAccountKeys)
+				serviceAccounts.POST("/:id/keys", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.apiTokenHandler.CreateServiceAccountKey)
+				serviceAccounts.DELETE("/:id/keys/:keyId", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.apiTokenHandler.RevokeServiceAccountKey)
 			}
 
 			// User management routes
 			users := protected.Group("/users")
 			{
 				users.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.userHandler.ListUsers)
-				users.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersCreate), r.userHandler.CreateUser)
+				users.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersCreate), r.userHandler.CreateUser)
 				users.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.userHandler.GetUser)
-				users.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.UpdateUser)
-				users.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersDelete), r.userHandler.DeleteUser)
+				users.PUT("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.UpdateUser)
+				users.DELETE("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersDelete), r.userHandler.DeleteUser)
+
+				// Login lockouts
+				users.GET("/locked", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.userHandler.ListLockedUsers)
+				users.POST("/:id/unlock", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.UnlockUser)
+
+				// Impersonation, which cannot be started with an API token or from another impersonation
+				users.POST("/:id/impersonate", middleware.DenyAPITokens(), middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersImpersonate), r.authHandler.Impersonate)
 
 				// User roles
 				users.GET("/:id/roles", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.userHandler.GetUserRoles)
-				users.POST("/:id/roles", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.AssignRole)
-				users.DELETE("/:id/roles/:roleId", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.RemoveRole)
+				users.POST("/:id/roles", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.AssignRole)
+				users.DELETE("/:id/roles/:roleId", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.userHandler.RemoveRole)
+				users.GET("/:id/effective-permissions", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.roleHandler.GetUserEffectivePermissions)
+
+				// Direct and time-limited grants
+				users.GET("/:id/grants", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.grantHandler.ListUserGrants)
+				users.POST("/:id/grants/permissions", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.grantHandler.GrantPermission)
+				users.DELETE("/:id/grants/permissions/:permissionId", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.grantHandler.RevokePermission)
+				users.POST("/:id/grants/roles", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersUpdate), r.grantHandler.GrantRole)
 			}
 
 			// Customer management routes
 			customers := protected.Group("/customers")
 			{
-				customers.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersRead), r.placeholder("List Customers"))
-				customers.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersCreate), r.placeholder("Create Customer"))
-				customers.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersRead), r.placeholder("Get Customer"))
-				customers.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersUpdate), r.placeholder("Update Customer"))
-				customers.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersDelete), r.placeholder("Delete Customer"))
+				customers.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersRead), r.customerHandler.ListCustomers)
+				customers.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersCreate), r.customerHandler.CreateCustomer)
+				customers.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersRead), r.customerHandler.GetCustomer)
+				customers.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersUpdate), r.customerHandler.UpdateCustomer)
+				customers.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionCRMCustomersDelete), r.customerHandler.DeleteCustomer)
 			}
 
 			// Post management routes
 			posts := protected.Group("/posts")
 			{
-				posts.GET("", r.placeholder("List Posts"))
+				posts.GET("", middleware.DenyAPITokens(), r.placeholder("List Posts"))
 				posts.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsCreate), r.placeholder("Create Post"))
 				posts.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsRead), r.placeholder("Get Post"))
 				posts.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsUpdate), r.placeholder("Update Post"))
@@ -202,11 +307,11 @@ func (r *Router) Setup() *gin.Engine {
 			// Category management routes
 			categories := protected.Group("/categories")
 			{
-				categories.GET("/tree", r.categoryHandler.GetCategoryTree)
-				categories.GET("/active", r.categoryHandler.GetActiveCategories)
-				categories.GET("", r.categoryHandler.ListCategories)
+				categories.GET("/tree", middleware.DenyAPITokens(), r.categoryHandler.GetCategoryTree)
+				categories.GET("/active", middleware.DenyAPITokens(), r.categoryHandler.GetActiveCategories)
+				categories.GET("", middleware.DenyAPITokens(), r.categoryHandler.ListCategories)
 				categories.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsCreate), r.categoryHandler.CreateCategory)
-				categories.GET("/:id", r.categoryHandler.GetCategory)
+				categories.GET("/:id", middleware.DenyAPITokens(), r.categoryHandler.GetCategory)
 				categories.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsUpdate), r.categoryHandler.UpdateCategory)
 				categories.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsDelete), r.categoryHandler.DeleteCategory)
 				categories.PUT("/:id/reorder", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionContentPostsUpdate), r.categoryHandler.ReorderCategory)
@@ -216,88 +321,174 @@ func (r *Router) Setup() *gin.Engine {
 			roles := protected.Group("/roles")
 			{
 				roles.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesRead), r.roleHandler.ListRoles)
-				roles.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesCreate), r.roleHandler.CreateRole)
+				roles.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesCreate), r.roleHandler.CreateRole)
 				roles.GET("/hierarchy", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesRead), r.roleHandler.GetRoleHierarchy)
 				roles.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesRead), r.roleHandler.GetRole)
-				roles.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesUpdate), r.roleHandler.UpdateRole)
-				roles.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesDelete), r.roleHandler.DeleteRole)
+				roles.PUT("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesUpdate), r.roleHandler.UpdateRole)
+				roles.DELETE("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesDelete), r.roleHandler.DeleteRole)
 
 				// Role permissions
 				roles.GET("/:id/permissions", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesRead), r.roleHandler.GetRolePermissions)
-				roles.POST("/:id/permissions", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.roleHandler.AssignPermission)
-				roles.DELETE("/:id/permissions/:permissionId", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.roleHandler.RemovePermission)
+				roles.GET("/:id/effective-permissions", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminRolesRead), r.roleHandler.GetEffectivePermissions)
+				roles.POST("/:id/permissions", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.roleHandler.AssignPermission)
+				roles.DELETE("/:id/permissions/:permissionId", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.roleHandler.RemovePermission)
 			}
 
 			// Permission management routes
 			permissions := protected.Group("/permissions")
 			{
 				permissions.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.ListPermissions)
-				permissions.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.CreatePermission)
+				permissions.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.CreatePermission)
 				permissions.GET("/module/:module", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.GetPermissionsByModule)
 				permissions.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.GetPermission)
-				permissions.PUT("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.UpdatePermission)
-				permissions.DELETE("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.DeletePermission)
+				permissions.PUT("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.UpdatePermission)
+				permissions.DELETE("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.permissionHandler.DeletePermission)
+			}
+
+			// Access policy routes
+			policies := protected.Group("/policies")
+			{
+				policies.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.ListPolicies)
+				policies.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.CreatePolicy)
+				policies.POST("/simulate", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.SimulatePolicy)
+				policies.GET("/:id", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.GetPolicy)
+				policies.PUT("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.UpdatePolicy)
+				policies.DELETE("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.policyHandler.DeletePolicy)
+			}
+
+			// Authorization model export and declarative apply
+			model := protected.Group("/authorization/model")
+			{
+				model.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.modelHandler.ExportModel)
+				model.POST("/plan", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.modelHandler.PlanModel)
+				model.POST("/apply", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.modelHandler.ApplyModel)
+			}
+
+			// Access request routes; approvers are checked per request, and an impersonator
+			// cannot decide as the user they impersonate
+			accessRequests := protected.Group("/access-requests")
+			{
+				accessRequests.POST("", middleware.DenyAPITokens(), r.accessRequestHandler.CreateAccessRequest)
+				accessRequests.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.accessRequestHandler.ListAccessRequests)
+				accessRequests.GET("/mine", middleware.DenyAPITokens(), r.accessRequestHandler.ListMyAccessRequests)
+				accessRequests.GET("/assigned", middleware.DenyAPITokens(), r.accessRequestHandler.ListAssignedAccessRequests)
+				accessRequests.GET("/:id", middleware.DenyAPITokens(), r.accessRequestHandler.GetAccessRequest)
+				accessRequests.POST("/:id/approve", middleware.DenyAPITokens(), middleware.DenyImpersonation(), r.accessRequestHandler.ApproveAccessRequest)
+				accessRequests.POST("/:id/deny", middleware.DenyAPITokens(), middleware.DenyImpersonation(), r.accessRequestHandler.DenyAccessRequest)
+				accessRequests.POST("/:id/cancel", middleware.DenyAPITokens(), r.accessRequestHandler.CancelAccessRequest)
 			}
 
 			// Notification routes
 			notifications := protected.Group("/notifications")
 			{
 				// User notification routes
-				notifications.GET("/me", r.notificationHandler.GetMyNotifications)
-				notifications.GET("/unread-count", r.notificationHandler.GetUnreadCount)
-				notifications.GET("/stats", r.notificationHandler.GetStats)
-				notifications.POST("/mark-all-read", r.notificationHandler.MarkAllAsRead)
-				notifications.DELETE("/me", r.notificationHandler.DeleteAllMyNotifications)
+				notifications.GET("/me", middleware.DenyAPITokens(), r.notificationHandler.GetMyNotifications)
+				notifications.GET("/unread-count", middleware.DenyAPITokens(), r.notificationHandler.GetUnreadCount)
+				notifications.GET("/stats", middleware.DenyAPITokens(), r.notificationHandler.GetStats)
+				notifications.POST("/mark-all-read", middleware.DenyAPITokens(), r.notificationHandler.MarkAllAsRead)
+				notifications.DELETE("/me", middleware.DenyAPITokens(), r.notificationHandler.DeleteAllMyNotifications)
 
 				// Individual notification routes
-				notifications.GET("/:id", r.notificationHandler.GetNotification)
-				notifications.PUT("/:id", r.notificationHandler.UpdateNotification)
-				notifications.DELETE("/:id", r.notificationHandler.DeleteNotification)
-				notifications.POST("/:id/read", r.notificationHandler.MarkAsRead)
-				notifications.POST("/:id/unread", r.notificationHandler.MarkAsUnread)
+				notifications.GET("/:id", middleware.DenyAPITokens(), r.notificationHandler.GetNotification)
+				notifications.PUT("/:id", middleware.DenyAPITokens(), r.notificationHandler.UpdateNotification)
+				notifications.DELETE("/:id", middleware.DenyAPITokens(), r.notificationHandler.DeleteNotification)
+				notifications.POST("/:id/read", middleware.DenyAPITokens(), r.notificationHandler.MarkAsRead)
+				notifications.POST("/:id/unread", middleware.DenyAPITokens(), r.notificationHandler.MarkAsUnread)
 
 				// Admin routes
 				notifications.GET("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.notificationHandler.GetAllNotifications)
-				notifications.POST("", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.notificationHandler.CreateNotification)
-				notifications.POST("/broadcast", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.notificationHandler.BroadcastNotification)
+				notifications.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.notificationHandler.CreateNotification)
+				notifications.POST("/broadcast", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.notificationHandler.BroadcastNotification)
 			}
 			document := protected.Group("/documents")
 			{
 				// Document CRUD
-				document.POST("/upload", r.documentHandler.UploadDocument)
-				document.GET("/list", r.documentHandler.GetDocuments)
-				document.GET("/entity/:type/:id", r.documentHandler.GetDocumentsByEntity)
-				document.GET("/:id", r.documentHandler.GetDocumentByID)
-				document.GET("/code/:code", r.documentHandler.GetDocumentByCode)
-				document.GET("/view/:id", r.documentHandler.ViewDocument)
-				document.GET("/view-url/:id", r.documentHandler.GetDocumentViewURL)
-				document.PUT("/:id", r.documentHandler.UpdateDocument)
-				document.DELETE("/:id", r.documentHandler.DeleteDocument)
-				document.GET("/download/:id", r.documentHandler.DownloadDocument)
+				document.POST("/upload", middleware.DenyAPITokens(), r.documentHandler.UploadDocument)
+				document.GET("/list", middleware.DenyAPITokens(), r.documentHandler.GetDocuments)
+				document.GET("/search", middleware.DenyAPITokens(), r.documentHandler.SearchDocuments)
+				document.GET("/entity/:type/:id", middleware.DenyAPITokens(), r.documentHandler.GetDocumentsByEntity)
+				document.GET("/:id", middleware.DenyAPITokens(), r.documentHandler.GetDocumentByID)
+				document.GET("/code/:code", middleware.DenyAPITokens(), r.documentHandler.GetDocumentByCode)
+				document.GET("/view/:id", middleware.DenyAPITokens(), r.documentHandler.ViewDocument)
+				document.GET("/view-url/:id", middleware.DenyAPITokens(), r.documentHandler.GetDocumentViewURL)
+				document.GET("/:id/thumbnail", middleware.DenyAPITokens(), r.documentHandler.GetDocumentThumbnail)
+				document.PUT("/:id", middleware.DenyAPITokens(), r.documentHandler.UpdateDocument)
+				document.DELETE("/:id", middleware.DenyAPITokens(), r.documentHandler.DeleteDocument)
+				document.GET("/download/:id", middleware.DenyAPITokens(), r.documentHandler.DownloadDocument)
 
 				// Permissions
-				document.POST("/permissions", r.documentHandler.AddDocumentPermission)
-				document.GET("/:id/permissions", r.documentHandler.GetDocumentPermissions)
-				document.PUT("/permissions/:id", r.documentHandler.UpdateDocumentPermission)
-				document.DELETE("/permissions/:id", r.documentHandler.DeleteDocumentPermission)
+				document.POST("/permissions", middleware.DenyAPITokens(), r.documentHandler.AddDocumentPermission)
+				document.GET("/:id/permissions", middleware.DenyAPITokens(), r.documentHandler.GetDocumentPermissions)
+				document.GET("/:id/effective-permissions", middleware.DenyAPITokens(), r.documentHandler.GetEffectivePermission)
+				document.PUT("/permissions/:id", middleware.DenyAPITokens(), r.documentHandler.UpdateDocumentPermission)
+				document.DELETE("/permissions/:id", middleware.DenyAPITokens(), r.documentHandler.DeleteDocumentPermission)
 
 				// Comments
-				document.POST("/comments", r.documentHandler.AddDocumentComment)
-				document.GET("/:id/comments", r.documentHandler.GetDocumentComments)
-				document.PUT("/comments/:id", r.documentHandler.UpdateDocumentComment)
-				document.DELETE("/comments/:id", r.documentHandler.DeleteDocumentComment)
+				document.POST("/comments", middleware.DenyAPITokens(), r.documentHandler.AddDocumentComment)
+				document.GET("/:id/comments", middleware.DenyAPITokens(), r.documentHandler.GetDocumentComments)
+				document.PUT("/comments/:id", middleware.DenyAPITokens(), r.documentHandler.UpdateDocumentComment)
+				document.DELETE("/comments/:id", middleware.DenyAPITokens(), r.documentHandler.DeleteDocumentComment)
+				document.POST("/comments/:id/resolve", middleware.DenyAPITokens(), r.documentHandler.ResolveDocumentComment)
+				document.DELETE("/comments/:id/resolve", middleware.DenyAPITokens(), r.documentHandler.ReopenDocumentComment)
 
 				// Versions
-				document.GET("/:id/versions", r.documentHandler.GetDocumentVersions)
+				document.GET("/:id/versions", middleware.DenyAPITokens(), r.documentHandler.GetDocumentVersions)
+				document.POST("/:id/versions", middleware.DenyAPITokens(), r.documentHandler.UploadDocumentVersion)
+				document.GET("/:id/versions/:version/download", middleware.DenyAPITokens(), r.documentHandler.DownloadDocumentVersion)
+				document.POST("/:id/versions/:version/restore", middleware.DenyAPITokens(), r.documentHandler.RestoreDocumentVersion)
+
+				// Check-out locking
+				document.POST("/:id/checkout", middleware.DenyAPITokens(), r.documentHandler.CheckOutDocument)
+				document.POST("/:id/checkin", middleware.DenyAPITokens(), r.documentHandler.CheckInDocument)
+				document.GET("/:id/lock", middleware.DenyAPITokens(), r.documentHandler.GetDocumentLock)
+				document.DELETE("/:id/lock", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.ForceUnlockDocument)
+
+				// Folders
+				document.POST("/folders", middleware.DenyAPITokens(), r.documentHandler.CreateFolder)
+				document.GET("/folders", middleware.DenyAPITokens(), r.documentHandler.GetFolders)
+				document.GET("/folders/:id", middleware.DenyAPITokens(), r.documentHandler.GetFolderContents)
+				document.PUT("/folders/:id", middleware.DenyAPITokens(), r.documentHandler.RenameFolder)
+				document.PUT("/folders/:id/move", middleware.DenyAPITokens(), r.documentHandler.MoveFolder)
+				document.DELETE("/folders/:id", middleware.DenyAPITokens(), r.documentHandler.DeleteFolder)
+				document.POST("/folders/:id/permissions", middleware.DenyAPITokens(), r.documentHandler.AddFolderPermission)
+				document.GET("/folders/:id/permissions", middleware.DenyAPITokens(), r.documentHandler.GetFolderPermissions)
+				document.PUT("/:id/move", middleware.DenyAPITokens(), r.documentHandler.MoveDocument)
+
+				// Tags
+				document.POST("/:id/tags", middleware.DenyAPITokens(), r.documentHandler.AddDocumentTags)
+				document.DELETE("/:id/tags/:tagId", middleware.DenyAPITokens(), r.documentHandler.RemoveDocumentTag)
+
+				// Share links
+				document.POST("/:id/share-links", middleware.DenyAPITokens(), r.documentHandler.CreateShareLink)
+				document.GET("/:id/share-links", middleware.DenyAPITokens(), r.documentHandler.GetDocumentShareLinks)
+				document.GET("/share-links", middleware.DenyAPITokens(), r.documentHandler.GetMyShareLinks)
+				document.DELETE("/share-links/:id", middleware.DenyAPITokens(), r.documentHandler.RevokeShareLink)
+
+				// Retention and legal hold
+				document.GET("/:id/retention", middleware.DenyAPITokens(), r.documentHandler.GetDocumentRetention)
+				document.GET("/retention-policies", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.GetRetentionPolicies)
+				document.POST("/retention-policies", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.CreateRetentionPolicy)
+				document.PUT("/retention-policies/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.UpdateRetentionPolicy)
+				document.DELETE("/retention-policies/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.DeleteRetentionPolicy)
+				document.POST("/retention/purge", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.PurgeExpiredDocuments)
+				document.GET("/legal-holds", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.GetLegalHolds)
+				document.POST("/legal-holds", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.PlaceLegalHold)
+				document.DELETE("/legal-holds/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.ReleaseLegalHold)
+
+				// Entity workspaces
+				document.GET("/workspaces/:type/:id", middleware.DenyAPITokens(), r.documentHandler.GetEntityWorkspace)
+				document.GET("/folder-templates", middleware.DenyAPITokens(), r.documentHandler.GetFolderTemplates)
+				document.POST("/folder-templates", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.CreateFolderTemplate)
+				document.DELETE("/folder-templates/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminDocumentsManage), r.documentHandler.DeleteFolderTemplate)
 			}
 
 			// WebSocket routes
 			ws := protected.Group("/ws")
 			{
-				ws.GET("", r.websocketHandler.HandleWebSocket)
-				ws.GET("/online-users", r.websocketHandler.GetOnlineUsers)
-				ws.GET("/stats", r.websocketHandler.GetConnectionStats)
-				ws.POST("/broadcast", middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.websocketHandler.BroadcastMessage)
+				ws.GET("", middleware.DenyAPITokens(), r.websocketHandler.HandleWebSocket)
+				ws.GET("/online-users", middleware.DenyAPITokens(), r.websocketHandler.GetOnlineUsers)
+				ws.GET("/stats", middleware.DenyAPITokens(), r.websocketHandler.GetConnectionStats)
+				ws.POST("/broadcast", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminUsersRead), r.websocketHandler.BroadcastMessage)
 			}
 
 			// Audit log routes (admin only)
@@ -308,65 +499,79 @@ func (r *Router) Setup() *gin.Engine {
 				auditLogs.GET("/:id", r.auditLogHandler.GetAuditLog)
 				auditLogs.GET("/user/:user_id", r.auditLogHandler.GetUserAuditLogs)
 				auditLogs.GET("/resource", r.auditLogHandler.GetResourceAuditLogs)
-				auditLogs.DELETE("/cleanup", r.auditLogHandler.CleanupOldLogs)
+				auditLogs.DELETE("/cleanup", middleware.DenyImpersonation(), r.auditLogHandler.CleanupOldLogs)
 			}
 
 			// Module routes
 			modules := protected.Group("/modules")
+			modules.Use(middleware.DenyAPITokens())
 			{
-				modules.POST("", r.moduleHandler.CreateModule)
+				modules.POST("", middleware.DenyImpersonation(), r.moduleHandler.CreateModule)
 				modules.GET("", r.moduleHandler.ListModules)
 				modules.GET("/active", r.moduleHandler.ListActiveModules)
 				modules.GET("/:id", r.moduleHandler.GetModule)
 				modules.GET("/code/:code", r.moduleHandler.GetModuleByCode)
-				modules.PUT("/:id", r.moduleHandler.UpdateModule)
-				modules.DELETE("/:id", r.moduleHandler.DeleteModule)
+				modules.PUT("/:id", middleware.DenyImpersonation(), r.moduleHandler.UpdateModule)
+				modules.DELETE("/:id", middleware.DenyImpersonation(), r.moduleHandler.DeleteModule)
 			}
 
 			// Department routes
 			departments := protected.Group("/departments")
 			{
-				departments.POST("", r.departmentHandler.CreateDepartment)
-				departments.GET("", r.departmentHandler.ListDepartments)
-				departments.GET("/active", r.departmentHandler.ListActiveDepartments)
-				departments.GET("/:id", r.departmentHandler.GetDepartment)
-				departments.GET("/code/:code", r.departmentHandler.GetDepartmentByCode)
-				departments.PUT("/:id", r.departmentHandler.UpdateDepartment)
-				departments.DELETE("/:id", r.departmentHandler.DeleteDepartment)
+				departments.POST("", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.departmentHandler.CreateDepartment)
+				departments.GET("", middleware.DenyAPITokens(), r.departmentHandler.ListDepartments)
+				departments.GET("/active", middleware.DenyAPITokens(), r.departmentHandler.ListActiveDepartments)
+				departments.GET("/managed", middleware.DenyAPITokens(), r.departmentHandler.ListManagedDepartments)
+				departments.GET("/:id", middleware.DenyAPITokens(), r.departmentHandler.GetDepartment)
+				departments.GET("/code/:code", middleware.DenyAPITokens(), r.departmentHandler.GetDepartmentByCode)
+				departments.PUT("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.departmentHandler.UpdateDepartment)
+				departments.DELETE("/:id", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.departmentHandler.DeleteDepartment)
+
+				// Tree operations
+				departments.GET("/:id/subtree", middleware.DenyAPITokens(), r.departmentHandler.GetDepartmentSubtree)
+				departments.POST("/:id/move", middleware.DenyImpersonation(), middleware.AuthorizeMiddleware(r.permissionChecker, middleware.PermissionAdminPermissionsManage), r.departmentHandler.MoveDepartment)
+
+				// Members, also managed by the department's managers
+				departments.GET("/:id/members", middleware.AuthorizeOrDepartmentManagerMiddleware(r.permissionChecker, r.departmentManagers, middleware.PermissionAdminUsersRead), r.departmentHandler.ListMembers)
+				departments.POST("/:id/members", middleware.DenyImpersonation(), middleware.AuthorizeOrDepartmentManagerMiddleware(r.permissionChecker, r.departmentManagers, middleware.PermissionAdminUsersUpdate), r.departmentHandler.AddMember)
+				departments.DELETE("/:id/members/:userId", middleware.DenyImpersonation(), middleware.AuthorizeOrDepartmentManagerMiddleware(r.permissionChecker, r.departmentManagers, middleware.PermissionAdminUsersUpdate), r.departmentHandler.RemoveMember)
 			}
 
 			// Module departments route
-			protected.GET("/modules/:id/departments", r.departmentHandler.ListDepartmentsByModule)
+			protected.GET("/modules/:id/departments", middleware.DenyAPITokens(), r.departmentHandler.ListDepartmentsByModule)
 
 			// Service routes
 			services := protected.Group("/services")
+			services.Use(middleware.DenyAPITokens())
 			{
-				services.POST("", r.serviceHandler.CreateService)
+				services.POST("", middleware.DenyImpersonation(), r.serviceHandler.CreateService)
 				services.GET("", r.serviceHandler.ListServices)
 				services.GET("/active", r.serviceHandler.ListActiveServices)
 				services.GET("/:id", r.serviceHandler.GetService)
 				services.GET("/code/:code", r.serviceHandler.GetServiceByCode)
-				services.PUT("/:id", r.serviceHandler.UpdateService)
-				services.DELETE("/:id", r.serviceHandler.DeleteService)
+				services.PUT("/:id", middleware.DenyImpersonation(), r.serviceHandler.UpdateService)
+				services.DELETE("/:id", middleware.DenyImpersonation(), r.serviceHandler.DeleteService)
 			}
 
 			// Department services route
-			protected.GET("/departments/:id/services", r.serviceHandler.ListServicesByDepartment)
+			protected.GET("/departments/:id/services", middleware.DenyAPITokens(), r.serviceHandler.ListServicesByDepartment)
 
 			// Scope routes
 			scopes := protected.Group("/scopes")
+			scopes.Use(middleware.DenyAPITokens())
 			{
-				scopes.POST("", r.scopeHandler.CreateScope)
+				scopes.POST("", middleware.DenyImpersonation(), r.scopeHandler.CreateScope)
 				scopes.GET("", r.scopeHandler.ListScopes)
 				scopes.GET("/all", r.scopeHandler.ListAllScopes)
 				scopes.GET("/:id", r.scopeHandler.GetScope)
 				scopes.GET("/code/:code", r.scopeHandler.GetScopeByCode)
-				scopes.PUT("/:id", r.scopeHandler.UpdateScope)
-				scopes.DELETE("/:id", r.scopeHandler.DeleteScope)
+				scopes.PUT("/:id", middleware.DenyImpersonation(), r.scopeHandler.UpdateScope)
+				scopes.DELETE("/:id", middleware.DenyImpersonation(), r.scopeHandler.DeleteScope)
 			}
 
 			// Page Builder routes
 			pages := protected.Group("/pages")
+			pages.Use(middleware.DenyAPITokens())
 			{
 				pages.GET("", r.pageHandler.ListPages)
 				pages.POST("", r.pageHandler.CreatePage)
@@ -389,6 +594,7 @@ func (r *Router) Setup() *gin.Engine {
 
 			// Block routes
 			blocks := protected.Group("/blocks")
+			blocks.Use(middleware.DenyAPITokens())
 			{
 				blocks.GET("", r.blockHandler.ListBlocks)
 				blocks.POST("", r.blockHandler.CreateBlock)
@@ -399,6 +605,7 @@ func (r *Router) Setup() *gin.Engine {
 
 			// Theme settings routes
 			themeSettings := protected.Group("/theme-settings")
+			themeSettings.Use(middleware.DenyAPITokens())
 			{
 				themeSettings.GET("", r.themeSettingHandler.GetAllThemes)
 				themeSettings.GET("/active", r.themeSettingHandler.GetActiveTheme)
@@ -441,6 +648,19 @@ func (r *Router) ping(c *gin.Context) {
 	})
 }
 
+// jwks publishes the public keys that verify access tokens
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying tokens signed with RS256 or ES256, identified by kid. Empty with HS256.
+// @Tags health
+// @Produce json
+// @Success 200 {object} object
+// @Router /.well-known/jwks.json [get]
+func (r *Router) jwks(c *gin.Context) {
+	// Short enough for verifiers to see a new key before it starts signing
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, r.keyring.JWKS())
+}
+
 // placeholder is a temporary handler for routes that are not yet implemented
 func (r *Router) placeholder(name string) gin.HandlerFunc {
 	return func(c *gin.Context) {
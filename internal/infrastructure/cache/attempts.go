@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Key prefixes for failed attempt tracking
+const (
+	PrefixFailedAttempts = "failed_attempts:"
+	PrefixAttemptDelay   = "attempt_delay:"
+)
+
+// LoginAccountAttemptKey identifies failed logins against an account
+func LoginAccountAttemptKey(email string) string {
+	return "login:account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// LoginIPAttemptKey identifies failed logins from a client IP
+func LoginIPAttemptKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// TwoFactorAttemptKey identifies failed 2FA codes entered for an account
+func TwoFactorAttemptKey(userID uuid.UUID) string {
+	return "2fa:" + userID.String()
+}
+
+// RecordFailedAttempt counts a failed attempt for key and returns the number of
+// failures within the window. The window starts at the first failure.
+func RecordFailedAttempt(ctx context.Context, key string, window time.Duration) (int64, error) {
+	attemptsKey := BuildKey(PrefixFailedAttempts, key)
+
+	pipe := Client.TxPipeline()
+	count := pipe.Incr(ctx, attemptsKey)
+	pipe.ExpireNX(ctx, attemptsKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+// DelayAttempts refuses further attempts for key for the given duration. An existing
+// longer delay is kept.
+func DelayAttempts(ctx context.Context, key string, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	remaining, err := AttemptDelay(ctx, key)
+	if err != nil {
+		return err
+	}
+	if remaining >= delay {
+		return nil
+	}
+
+	return Set(ctx, BuildKey(PrefixAttemptDelay, key), "1", delay)
+}
+
+// AttemptDelay returns how long attempts for key are still refused
+func AttemptDelay(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := Client.PTTL(ctx, BuildKey(PrefixAttemptDelay, key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Missing keys report a negative TTL
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// ClearFailedAttempts forgets the failures and delays recorded for the keys
+func ClearFailedAttempts(ctx context.Context, keys ...string) error {
+	redisKeys := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		redisKeys = append(redisKeys, BuildKey(PrefixFailedAttempts, key), BuildKey(PrefixAttemptDelay, key))
+	}
+
+	if len(redisKeys) == 0 {
+		return nil
+	}
+
+	return Delete(ctx, redisKeys...)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PrefixLock is the key prefix for exclusive locks
+const PrefixLock = "lock:"
+
+// Lock describes an exclusive lock held in Redis
+type Lock struct {
+	Owner      string
+	AcquiredAt time.Time
+	ExpiresAt  time.Time
+}
+
+// Lock values are stored as "owner|acquiredAtUnixMilli"
+var acquireLockScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+	return {1, ARGV[2], tonumber(ARGV[3])}
+end
+if string.match(current, '^([^|]*)|') == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	return {1, current, tonumber(ARGV[3])}
+end
+return {0, current, redis.call('PTTL', KEYS[1])}
+`)
+
+var releaseLockScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return 0
+end
+if ARGV[1] == '' or string.match(current, '^([^|]*)|') == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+return 0
+`)
+
+// AcquireLock takes the lock for owner, or extends it if owner already holds it.
+// It returns the lock as it stands afterwards and whether owner holds it.
+func AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (*Lock, bool, error) {
+	now := time.Now()
+	value := fmt.Sprintf("%s|%d", owner, now.UnixMilli())
+
+	result, err := acquireLockScript.Run(ctx, Client, []string{BuildKey(PrefixLock, key)}, owner, value, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(result) != 3 {
+		return nil, false, fmt.Errorf("unexpected lock script result")
+	}
+
+	acquired, _ := result[0].(int64)
+	current, _ := result[1].(string)
+	pttl, _ := result[2].(int64)
+
+	return parseLock(current, now, pttl), acquired == 1, nil
+}
+
+// ReleaseLock releases the lock if owner holds it. An empty owner releases it
+// whoever holds it. It reports whether a lock was released.
+func ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	released, err := releaseLockScript.Run(ctx, Client, []string{BuildKey(PrefixLock, key)}, owner).Int()
+	if err != nil {
+		return false, err
+	}
+	return released == 1, nil
+}
+
+// GetLocks returns the current holders of the given locks; free locks are nil
+func GetLocks(ctx context.Context, keys ...string) ([]*Lock, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := Client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, BuildKey(PrefixLock, key))
+		ttls[i] = pipe.PTTL(ctx, BuildKey(PrefixLock, key))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	locks := make([]*Lock, len(keys))
+	for i := range keys {
+		value, err := gets[i].Result()
+		if err != nil {
+			continue
+		}
+		locks[i] = parseLock(value, now, ttls[i].Val().Milliseconds())
+	}
+
+	return locks, nil
+}
+
+func parseLock(value string, now time.Time, pttl int64) *Lock {
+	owner, acquired, _ := strings.Cut(value, "|")
+	lock := &Lock{
+		Owner:     owner,
+		ExpiresAt: now.Add(time.Duration(pttl) * time.Millisecond),
+	}
+
+	if millis, err := strconv.ParseInt(acquired, 10, 64); err == nil {
+		lock.AcquiredAt = time.UnixMilli(millis)
+	}
+
+	return lock
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PermissionEventsChannel is the pub/sub channel permission changes are announced on
+const PermissionEventsChannel = "events:permissions"
+
+// Invalidating more users than this at once announces that every user changed, so
+// the message stays small
+const maxPermissionEventUsers = 1000
+
+// Keys are deleted in batches of this size
+const permissionDeleteBatch = 500
+
+// PermissionEvent announces a permission change to every instance, so each can drop
+// what it caches in process
+type PermissionEvent struct {
+	UserIDs  []uuid.UUID `json:"user_ids,omitempty"` // Users whose permissions changed
+	All      bool        `json:"all,omitempty"`      // Every user's permissions may have changed
+	Policies bool        `json:"policies,omitempty"` // Access policies changed
+}
+
+// InvalidatePermissions invalidates the permissions cache of the users and announces
+// the change to every instance. Each user's permissions version is bumped before their
+// cache is deleted, so a load that started earlier cannot cache what it read.
+func InvalidatePermissions(ctx context.Context, userIDs ...uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	for start := 0; start < len(userIDs); start += permissionDeleteBatch {
+		end := start + permissionDeleteBatch
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+
+		pipe := Client.Pipeline()
+		keys := make([]string, 0, end-start)
+		for _, userID := range userIDs[start:end] {
+			pipe.Incr(ctx, BuildKey(PrefixPermissionVersion, userID.String()))
+			keys = append(keys, BuildKey(PrefixPermission, userID.String()))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		if err := Delete(ctx, keys...); err != nil {
+			return err
+		}
+	}
+
+	if len(userIDs) > maxPermissionEventUsers {
+		return PublishPermissionEvent(ctx, PermissionEvent{All: true})
+	}
+	return PublishPermissionEvent(ctx, PermissionEvent{UserIDs: userIDs})
+}
+
+// InvalidateAllPermissions invalidates the permissions cache of every user and
+// announces the change to every instance. Bumping the shared version first stops loads
+// already running from caching what they read.
+func InvalidateAllPermissions(ctx context.Context) error {
+	if err := Client.Incr(ctx, allPermissionsVersionKey).Err(); err != nil {
+		return err
+	}
+
+	iter := Client.Scan(ctx, 0, PrefixPermission+"*", permissionDeleteBatch).Iterator()
+
+	keys := make([]string, 0, permissionDeleteBatch)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == permissionDeleteBatch {
+			if err := Delete(ctx, keys...); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		if err := Delete(ctx, keys...); err != nil {
+			return err
+		}
+	}
+
+	return PublishPermissionEvent(ctx, PermissionEvent{All: true})
+}
+
+// PublishPermissionEvent announces a permission change to every instance
+func PublishPermissionEvent(ctx context.Context, event PermissionEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return Client.Publish(ctx, PermissionEventsChannel, payload).Err()
+}
+
+// SubscribePermissionEvents passes every permission event, including those this
+// instance publishes, to the handlers until the context is done. The subscription
+// reconnects on its own after a connection loss.
+func SubscribePermissionEvents(ctx context.Context, handlers ...func(PermissionEvent)) {
+	pubsub := Client.Subscribe(ctx, PermissionEventsChannel)
+
+	go func() {
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event PermissionEvent
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					logger.Warn("Ignoring malformed permission event", zap.Error(err))
+					continue
+				}
+				for _, handle := range handlers {
+					handle(event)
+				}
+			}
+		}
+	}()
+}
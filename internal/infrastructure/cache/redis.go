@@ -136,6 +136,12 @@ const (
 	PrefixPermission = "permission:"
 	PrefixRateLimit  = "ratelimit:"
 	PrefixCache      = "cache:"
+
+	PrefixTokenBlacklist = "blacklist:"
+	PrefixRevokedSession = "revoked_session:"
+
+	// Kept apart from PrefixPermission so clearing every permission cache keeps the versions
+	PrefixPermissionVersion = "permission_version:"
 )
 
 // BuildKey builds a cache key with prefix
@@ -193,16 +199,94 @@ func CachePermissions(ctx context.Context, userID uuid.UUID, permissions []strin
 	return Expire(ctx, key, expiration)
 }
 
+// allPermissionsVersionKey is bumped when every user's permissions are invalidated
+var allPermissionsVersionKey = BuildKey(PrefixPermissionVersion, "all")
+
+// PermissionsVersion identifies a user's permissions as of when it was read. Invalidating
+// the user's permissions, or everyone's, moves them to a new version.
+type PermissionsVersion struct {
+	user string
+	all  string
+}
+
+// GetPermissionsVersion reads the version of a user's permissions. Read it before
+// loading the permissions, then cache them with CachePermissionScopes.
+func GetPermissionsVersion(ctx context.Context, userID uuid.UUID) (PermissionsVersion, error) {
+	values, err := Client.MGet(ctx, BuildKey(PrefixPermissionVersion, userID.String()), allPermissionsVersionKey).Result()
+	if err != nil {
+		return PermissionsVersion{}, err
+	}
+
+	version := PermissionsVersion{}
+	if user, ok := values[0].(string); ok {
+		version.user = user
+	}
+	if all, ok := values[1].(string); ok {
+		version.all = all
+	}
+	return version, nil
+}
+
+// cachePermissionScopesScript replaces the cached permissions only while both
+// versions are still the ones read before the permissions were loaded.
+// It returns 1 when it wrote and 0 when the permissions were invalidated meanwhile.
+var cachePermissionScopesScript = redis.NewScript(`
+local user = redis.call('GET', KEYS[2]) or ''
+local all = redis.call('GET', KEYS[3]) or ''
+if user ~= ARGV[1] or all ~= ARGV[2] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+for i = 4, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// CachePermissionScopes caches user permissions with the scope level each is held at,
+// unless they were invalidated since version was read, so a slow load cannot put back
+// permissions that changed while it ran. It reports whether the permissions were cached.
+func CachePermissionScopes(ctx context.Context, userID uuid.UUID, scopes map[string]string, expiration time.Duration, version PermissionsVersion) (bool, error) {
+	if len(scopes) == 0 {
+		return false, nil
+	}
+
+	keys := []string{
+		BuildKey(PrefixPermission, userID.String()),
+		BuildKey(PrefixPermissionVersion, userID.String()),
+		allPermissionsVersionKey,
+	}
+	args := make([]interface{}, 0, 3+len(scopes)*2)
+	args = append(args, version.user, version.all, expiration.Milliseconds())
+	for perm, level := range scopes {
+		args = append(args, perm, level)
+	}
+
+	written, err := cachePermissionScopesScript.Run(ctx, Client, keys, args...).Int()
+	if err != nil {
+		return false, err
+	}
+	return written == 1, nil
+}
+
 // GetPermissions gets cached user permissions
 func GetPermissions(ctx context.Context, userID uuid.UUID) (map[string]string, error) {
 	key := BuildKey(PrefixPermission, userID.String())
 	return HGetAll(ctx, key)
 }
 
-// InvalidatePermissions invalidates user permissions cache
-func InvalidatePermissions(ctx context.Context, userID uuid.UUID) error {
+// GetPermissionsWithTTL gets cached user permissions and how much longer they are cached
+func GetPermissionsWithTTL(ctx context.Context, userID uuid.UUID) (map[string]string, time.Duration, error) {
 	key := BuildKey(PrefixPermission, userID.String())
-	return Delete(ctx, key)
+
+	pipe := Client.Pipeline()
+	permissions := pipe.HGetAll(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, err
+	}
+	return permissions.Val(), ttl.Val(), nil
 }
 
 // CheckRateLimit checks rate limit for a key
@@ -223,3 +307,40 @@ func CheckRateLimit(ctx context.Context, key string, limit int64, window time.Du
 
 	return count <= limit, nil
 }
+
+// BlacklistToken rejects an access token by its ID until it would have expired anyway
+func BlacklistToken(ctx context.Context, tokenID string, expiration time.Duration) error {
+	if expiration <= 0 {
+		return nil
+	}
+	key := BuildKey(PrefixTokenBlacklist, tokenID)
+	return Set(ctx, key, "1", expiration)
+}
+
+// RevokeSessionTokens rejects every token of a session issued so far. The expiration
+// should cover the longest lifetime of a token issued to the session, that of its
+// refresh tokens.
+func RevokeSessionTokens(ctx context.Context, sessionID uuid.UUID, expiration time.Duration) error {
+	key := BuildKey(PrefixRevokedSession, sessionID.String())
+	return Set(ctx, key, "1", expiration)
+}
+
+// IsTokenRevoked checks whether an access token or its session has been revoked
+func IsTokenRevoked(ctx context.Context, tokenID string, sessionID uuid.UUID) (bool, error) {
+	keys := make([]string, 0, 2)
+	if tokenID != "" {
+		keys = append(keys, BuildKey(PrefixTokenBlacklist, tokenID))
+	}
+	if sessionID != uuid.Nil {
+		keys = append(keys, BuildKey(PrefixRevokedSession, sessionID.String()))
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	count, err := Exists(ctx, keys...)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
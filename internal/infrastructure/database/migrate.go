@@ -1,6 +1,8 @@
 package database
 
 import (
+	"strings"
+
 	"github.com/owner/go-cms/internal/core/domain"
 	"github.com/owner/go-cms/pkg/logger"
 	"github.com/owner/go-cms/pkg/utils"
@@ -23,11 +25,32 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.UserPermission{},
 		&domain.OTP{},
 		&domain.RefreshToken{},
+		&domain.UserSession{},
+		&domain.RecoveryCode{},
+		&domain.WebAuthnCredential{},
+		&domain.ExternalIdentity{},
+		&domain.SigningKey{},
+		&domain.APIToken{},
 	); err != nil {
 		logger.Error("Failed to migrate user tables", zap.Error(err))
 		return err
 	}
 
+	// Role grants used to be inserted with a zero deleted_at, which never matched the
+	// "deleted_at IS NULL" filter the permission checker applies
+	if err := db.Exec("UPDATE user_roles SET deleted_at = NULL WHERE deleted_at < '1900-01-01'").Error; err != nil {
+		logger.Error("Failed to clear zero user role deletion times", zap.Error(err))
+		return err
+	}
+
+	if err := resetUserEnhancedPermissions(db); err != nil {
+		return err
+	}
+
+	if err := resetDepartmentManagers(db); err != nil {
+		return err
+	}
+
 	// Authorization related tables (new enhanced permission system)
 	if err := db.AutoMigrate(
 		&domain.Module{},
@@ -37,6 +60,8 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.EnhancedPermission{},
 		&domain.RoleEnhancedPermission{},
 		&domain.UserEnhancedPermission{},
+		&domain.Policy{},
+		&domain.AccessRequest{},
 	); err != nil {
 		logger.Error("Failed to migrate authorization tables", zap.Error(err))
 		return err
@@ -75,16 +100,128 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.ActivityLog{},
 		&domain.EmailTemplate{},
 		&domain.EmailLog{},
-		&domain.Document{},
 	); err != nil {
 		logger.Error("Failed to migrate system tables", zap.Error(err))
 		return err
 	}
 
+	if err := renumberDocumentVersions(db); err != nil {
+		return err
+	}
+
+	// Document management tables
+	if err := db.AutoMigrate(
+		&domain.DocumentFolder{},
+		&domain.Document{},
+		&domain.DocumentFolderTemplate{},
+		&domain.DocumentPermission{},
+		&domain.DocumentComment{},
+		&domain.DocumentCommentMention{},
+		&domain.DocumentVersion{},
+		&domain.DocumentShareLink{},
+		&domain.DocumentPreview{},
+		&domain.DocumentRetentionPolicy{},
+		&domain.DocumentLegalHold{},
+	); err != nil {
+		logger.Error("Failed to migrate document tables", zap.Error(err))
+		return err
+	}
+
+	// Full-text index over extracted document text
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_document_previews_content_fts
+		ON document_previews USING GIN (to_tsvector('simple', COALESCE(content_text, '')))`).Error; err != nil {
+		logger.Error("Failed to create document search index", zap.Error(err))
+		return err
+	}
+
 	logger.Info("Database migrations completed successfully")
 	return nil
 }
 
+// resetUserEnhancedPermissions drops user_enhanced_permissions when it still keys users
+// by integer. Users are keyed by UUID, so such rows cannot name a user and were never
+// read; AutoMigrate recreates the table with the UUID schema.
+func resetUserEnhancedPermissions(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&domain.UserEnhancedPermission{}) {
+		return nil
+	}
+
+	columns, err := migrator.ColumnTypes(&domain.UserEnhancedPermission{})
+	if err != nil {
+		logger.Error("Failed to inspect user enhanced permissions", zap.Error(err))
+		return err
+	}
+
+	for _, column := range columns {
+		if column.Name() != "user_id" || strings.EqualFold(column.DatabaseTypeName(), "uuid") {
+			continue
+		}
+		logger.Warn("Dropping user_enhanced_permissions with integer user IDs", zap.String("type", column.DatabaseTypeName()))
+		if err := migrator.DropTable(&domain.UserEnhancedPermission{}); err != nil {
+			logger.Error("Failed to drop user enhanced permissions", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// resetDepartmentManagers drops departments.manager_id when it still holds integers.
+// Users are keyed by UUID, so no such value named a user; AutoMigrate recreates the
+// column as a UUID.
+func resetDepartmentManagers(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&domain.Department{}, "manager_id") {
+		return nil
+	}
+
+	columns, err := migrator.ColumnTypes(&domain.Department{})
+	if err != nil {
+		logger.Error("Failed to inspect departments", zap.Error(err))
+		return err
+	}
+
+	for _, column := range columns {
+		if column.Name() != "manager_id" || strings.EqualFold(column.DatabaseTypeName(), "uuid") {
+			continue
+		}
+		logger.Warn("Dropping integer department manager IDs", zap.String("type", column.DatabaseTypeName()))
+		if err := migrator.DropColumn(&domain.Department{}, "manager_id"); err != nil {
+			logger.Error("Failed to drop department manager IDs", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// renumberDocumentVersions renumbers the versions of documents where concurrent uploads
+// saved two versions under the same number, so the unique index on
+// (document_id, version_number) can be created. Versions keep their upload order.
+func renumberDocumentVersions(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&domain.DocumentVersion{}) {
+		return nil
+	}
+
+	result := db.Exec(`UPDATE document_versions SET version_number = numbered.position
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY document_id ORDER BY version_number, id) AS position
+			FROM document_versions
+			WHERE document_id IN (
+				SELECT document_id FROM document_versions
+				GROUP BY document_id, version_number HAVING COUNT(*) > 1
+			)
+		) numbered
+		WHERE document_versions.id = numbered.id AND document_versions.version_number <> numbered.position`)
+	if result.Error != nil {
+		logger.Error("Failed to renumber duplicate document versions", zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		logger.Warn("Renumbered duplicate document versions", zap.Int64("rows", result.RowsAffected))
+	}
+	return nil
+}
+
 // SeedData seeds initial data into the database
 func SeedData(db *gorm.DB) error {
 	logger.Info("Seeding initial data...")
@@ -114,13 +251,13 @@ func SeedData(db *gorm.DB) error {
 		return err
 	}
 
-	// Seed Permissions (legacy)
-	if err := seedPermissions(db); err != nil {
+	// Seed Enhanced Permissions
+	if err := seedEnhancedPermissions(db); err != nil {
 		return err
 	}
 
-	// Seed Enhanced Permissions
-	if err := seedEnhancedPermissions(db); err != nil {
+	// Move role grants of legacy permissions onto enhanced permissions
+	if err := migrateLegacyPermissions(db); err != nil {
 		return err
 	}
 
@@ -283,6 +420,16 @@ func seedServices(db *gorm.DB) error {
 			IsActive:     true,
 			IsSystem:     true,
 		},
+		{
+			DepartmentID: systemDept.ID,
+			Code:         "documents",
+			Name:         "Document Management",
+			DisplayName:  "Document Management Service",
+			Description:  "Manage documents and folders",
+			Endpoint:     "/api/v1/documents",
+			IsActive:     true,
+			IsSystem:     true,
+		},
 		// CRM services
 		{
 			DepartmentID: salesDept.ID,
@@ -424,57 +571,6 @@ func seedRoles(db *gorm.DB) error {
 	return nil
 }
 
-// seedPermissions seeds legacy permissions
-func seedPermissions(db *gorm.DB) error {
-	permissions := []domain.Permission{
-		// User management
-		{Resource: "users", Action: "create", Module: "admin", Department: "system", Service: "users", Description: "Create users"},
-		{Resource: "users", Action: "read", Module: "admin", Department: "system", Service: "users", Description: "Read users"},
-		{Resource: "users", Action: "update", Module: "admin", Department: "system", Service: "users", Description: "Update users"},
-		{Resource: "users", Action: "delete", Module: "admin", Department: "system", Service: "users", Description: "Delete users"},
-
-		// Customer management
-		{Resource: "customers", Action: "create", Module: "crm", Department: "sales", Service: "customers", Description: "Create customers"},
-		{Resource: "customers", Action: "read", Module: "crm", Department: "sales", Service: "customers", Description: "Read customers"},
-		{Resource: "customers", Action: "update", Module: "crm", Department: "sales", Service: "customers", Description: "Update customers"},
-		{Resource: "customers", Action: "delete", Module: "crm", Department: "sales", Service: "customers", Description: "Delete customers"},
-
-		// Post management
-		{Resource: "posts", Action: "create", Module: "content", Department: "editorial", Service: "posts", Description: "Create posts"},
-		{Resource: "posts", Action: "read", Module: "content", Department: "editorial", Service: "posts", Description: "Read posts"},
-		{Resource: "posts", Action: "update", Module: "content", Department: "editorial", Service: "posts", Description: "Update posts"},
-		{Resource: "posts", Action: "delete", Module: "content", Department: "editorial", Service: "posts", Description: "Delete posts"},
-		{Resource: "posts", Action: "publish", Module: "content", Department: "editorial", Service: "posts", Description: "Publish posts"},
-
-		// Media management
-		{Resource: "media", Action: "upload", Module: "content", Department: "editorial", Service: "media", Description: "Upload media"},
-		{Resource: "media", Action: "read", Module: "content", Department: "editorial", Service: "media", Description: "Read media"},
-		{Resource: "media", Action: "delete", Module: "content", Department: "editorial", Service: "media", Description: "Delete media"},
-
-		// Role management
-		{Resource: "roles", Action: "create", Module: "admin", Department: "system", Service: "roles", Description: "Create roles"},
-		{Resource: "roles", Action: "read", Module: "admin", Department: "system", Service: "roles", Description: "Read roles"},
-		{Resource: "roles", Action: "update", Module: "admin", Department: "system", Service: "roles", Description: "Update roles"},
-		{Resource: "roles", Action: "delete", Module: "admin", Department: "system", Service: "roles", Description: "Delete roles"},
-
-		// Permission management
-		{Resource: "permissions", Action: "manage", Module: "admin", Department: "system", Service: "permissions", Description: "Manage permissions"},
-	}
-
-	for _, permission := range permissions {
-		key := permission.Module + ":" + permission.Department + ":" + permission.Service + ":" + permission.Resource + ":" + permission.Action
-		if err := db.Where("module = ? AND department = ? AND service = ? AND resource = ? AND action = ?",
-			permission.Module, permission.Department, permission.Service, permission.Resource, permission.Action).
-			FirstOrCreate(&permission).Error; err != nil {
-			logger.Error("Failed to create permission", zap.String("permission", key), zap.Error(err))
-			return err
-		}
-	}
-
-	logger.Info("Permissions seeded successfully")
-	return nil
-}
-
 // seedEnhancedPermissions seeds enhanced permissions with full hierarchy
 func seedEnhancedPermissions(db *gorm.DB) error {
 	// Get all modules, departments, services, scopes
@@ -529,11 +625,20 @@ func seedEnhancedPermissions(db *gorm.DB) error {
 		{"admin", "system", "users", "org", "users", domain.ActionUpdate, "Update Users (Org)", "Update any user"},
 		{"admin", "system", "users", "personal", "users", domain.ActionUpdate, "Update Own Profile", "Update own user profile"},
 		{"admin", "system", "users", "org", "users", domain.ActionDelete, "Delete Users (Org)", "Delete any user"},
+		{"admin", "system", "users", "org", "users", domain.ActionImpersonate, "Impersonate Users", "See the CMS as another user sees it"},
+
+		// Role management
+		{"admin", "system", "roles", "org", "roles", domain.ActionCreate, "Create Roles", "Create roles"},
+		{"admin", "system", "roles", "org", "roles", domain.ActionRead, "Read Roles", "View roles and their permissions"},
+		{"admin", "system", "roles", "org", "roles", domain.ActionUpdate, "Update Roles", "Update roles"},
+		{"admin", "system", "roles", "org", "roles", domain.ActionDelete, "Delete Roles", "Delete roles"},
+		{"admin", "system", "roles", "org", "roles", domain.ActionManage, "Manage Roles", "Full role management"},
 
 		// Customer management
 		{"crm", "sales", "customers", "org", "customers", domain.ActionCreate, "Create Customers (Org)", "Create customers"},
 		{"crm", "sales", "customers", "org", "customers", domain.ActionRead, "Read Customers (Org)", "View all customers"},
 		{"crm", "sales", "customers", "dept", "customers", domain.ActionRead, "Read Customers (Dept)", "View department customers"},
+		{"crm", "sales", "customers", "team", "customers", domain.ActionRead, "Read Customers (Team)", "View team customers"},
 		{"crm", "sales", "customers", "personal", "customers", domain.ActionRead, "Read Own Customers", "View assigned customers"},
 		{"crm", "sales", "customers", "org", "customers", domain.ActionUpdate, "Update Customers (Org)", "Update any customer"},
 		{"crm", "sales", "customers", "personal", "customers", domain.ActionUpdate, "Update Own Customers", "Update assigned customers"},
@@ -551,13 +656,17 @@ func seedEnhancedPermissions(db *gorm.DB) error {
 
 		// Media management
 		{"content", "editorial", "media", "org", "media", domain.ActionCreate, "Upload Media (Org)", "Upload media files"},
+		{"content", "editorial", "media", "org", "media", domain.ActionUpload, "Upload Media Files (Org)", "Upload media files"},
 		{"content", "editorial", "media", "org", "media", domain.ActionRead, "Read Media (Org)", "View all media"},
 		{"content", "editorial", "media", "personal", "media", domain.ActionRead, "Read Own Media", "View own media"},
 		{"content", "editorial", "media", "org", "media", domain.ActionDelete, "Delete Media (Org)", "Delete any media"},
 		{"content", "editorial", "media", "personal", "media", domain.ActionDelete, "Delete Own Media", "Delete own media"},
 
-		// Role management
-		{"admin", "system", "roles", "org", "roles", domain.ActionManage, "Manage Roles", "Full role management"},
+		// Document management
+		{"admin", "system", "documents", "org", "documents", domain.ActionRead, "Read Documents (Org)", "View all documents"},
+		{"admin", "system", "documents", "dept", "documents", domain.ActionRead, "Read Documents (Dept)", "View documents uploaded in department"},
+		{"admin", "system", "documents", "team", "documents", domain.ActionRead, "Read Documents (Team)", "View documents uploaded by team"},
+		{"admin", "system", "documents", "org", "documents", domain.ActionManage, "Manage Documents", "Manage document folder templates"},
 
 		// Permission management
 		{"admin", "system", "permissions", "org", "permissions", domain.ActionManage, "Manage Permissions", "Full permission management"},
@@ -590,28 +699,113 @@ func seedEnhancedPermissions(db *gorm.DB) error {
 	return nil
 }
 
-// assignPermissionsToSuperAdmin assigns all permissions to super_admin role
-func assignPermissionsToSuperAdmin(db *gorm.DB) error {
-	var superAdminRole domain.Role
-	if err := db.Where("name = ?", "super_admin").First(&superAdminRole).Error; err != nil {
-		logger.Error("Failed to find super_admin role", zap.Error(err))
+// migrateLegacyPermissions maps legacy permissions granted to roles or users onto
+// enhanced permissions at organization scope, which is as broad as the unscoped legacy
+// grant, and moves the grants to role_enhanced_permissions and user_enhanced_permissions.
+// Moved grants are deleted from role_permissions and user_permissions so a grant revoked
+// later is not restored on the next start.
+func migrateLegacyPermissions(db *gorm.DB) error {
+	var legacy []domain.Permission
+	if err := db.Where("id IN (SELECT permission_id FROM role_permissions UNION SELECT permission_id FROM user_permissions)").
+		Find(&legacy).Error; err != nil {
+		logger.Error("Failed to fetch legacy permissions", zap.Error(err))
 		return err
 	}
 
-	// Assign legacy permissions
-	var allPermissions []domain.Permission
-	if err := db.Find(&allPermissions).Error; err != nil {
-		logger.Error("Failed to fetch permissions", zap.Error(err))
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	var orgScope domain.Scope
+	if err := db.Where("level = ?", domain.ScopeLevelOrganization).Order("priority DESC").First(&orgScope).Error; err != nil {
+		logger.Error("Failed to find organization scope", zap.Error(err))
 		return err
 	}
 
-	for _, permission := range allPermissions {
-		rolePermission := domain.RolePermission{
-			RoleID:       superAdminRole.ID,
-			PermissionID: permission.ID,
+	var moved int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, permission := range legacy {
+			enhanced, err := enhancedFromLegacy(tx, permission, orgScope)
+			if err != nil {
+				return err
+			}
+
+			result := tx.Exec(`INSERT INTO role_enhanced_permissions (role_id, permission_id, created_at)
+				SELECT role_id, ?, NOW() FROM role_permissions WHERE permission_id = ?
+				ON CONFLICT DO NOTHING`, enhanced.ID, permission.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved += result.RowsAffected
+
+			if err := tx.Where("permission_id = ?", permission.ID).Delete(&domain.RolePermission{}).Error; err != nil {
+				return err
+			}
+
+			result = tx.Exec(`INSERT INTO user_enhanced_permissions (user_id, permission_id, granted_at, is_revoked)
+				SELECT user_id, ?, COALESCE(created_at, NOW()), false FROM user_permissions WHERE permission_id = ?
+				ON CONFLICT DO NOTHING`, enhanced.ID, permission.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved += result.RowsAffected
+
+			if err := tx.Where("permission_id = ?", permission.ID).Delete(&domain.UserPermission{}).Error; err != nil {
+				return err
+			}
 		}
-		db.Where("role_id = ? AND permission_id = ?", rolePermission.RoleID, rolePermission.PermissionID).
-			FirstOrCreate(&rolePermission)
+		return nil
+	})
+	if err != nil {
+		logger.Error("Failed to migrate legacy permissions", zap.Error(err))
+		return err
+	}
+
+	logger.Info("Legacy permission grants migrated", zap.Int("permissions", len(legacy)), zap.Int64("grants", moved))
+	return nil
+}
+
+// enhancedFromLegacy finds or creates the enhanced permission matching a legacy one,
+// creating any module, department or service it names that does not exist yet
+func enhancedFromLegacy(tx *gorm.DB, permission domain.Permission, scope domain.Scope) (*domain.EnhancedPermission, error) {
+	module := domain.Module{Code: permission.Module, Name: permission.Module}
+	if err := tx.Where("code = ?", permission.Module).FirstOrCreate(&module).Error; err != nil {
+		return nil, err
+	}
+
+	department := domain.Department{ModuleID: module.ID, Code: permission.Department, Name: permission.Department}
+	if err := tx.Where("code = ?", permission.Department).FirstOrCreate(&department).Error; err != nil {
+		return nil, err
+	}
+
+	service := domain.Service{DepartmentID: department.ID, Code: permission.Service, Name: permission.Service}
+	if err := tx.Where("code = ?", permission.Service).FirstOrCreate(&service).Error; err != nil {
+		return nil, err
+	}
+
+	enhanced := domain.EnhancedPermission{
+		ModuleID:     module.ID,
+		DepartmentID: department.ID,
+		ServiceID:    service.ID,
+		ScopeID:      scope.ID,
+		Resource:     permission.Resource,
+		Action:       domain.PermissionAction(permission.Action),
+		Code:         module.Code + ":" + department.Code + ":" + service.Code + ":" + scope.Code + ":" + permission.Resource + ":" + permission.Action,
+		Description:  permission.Description,
+	}
+	if err := tx.Where("code = ?", enhanced.Code).FirstOrCreate(&enhanced).Error; err != nil {
+		return nil, err
+	}
+
+	return &enhanced, nil
+}
+
+// assignPermissionsToSuperAdmin assigns all permissions to super_admin role
+func assignPermissionsToSuperAdmin(db *gorm.DB) error {
+	var superAdminRole domain.Role
+	if err := db.Where("name = ?", "super_admin").First(&superAdminRole).Error; err != nil {
+		logger.Error("Failed to find super_admin role", zap.Error(err))
+		return err
 	}
 
 	// Assign enhanced permissions
@@ -158,6 +158,9 @@ func CreateIndexes() error {
 		"CREATE INDEX IF NOT EXISTS idx_media_uploaded_by ON media(uploaded_by)",
 		"CREATE INDEX IF NOT EXISTS idx_media_created_at ON media(created_at DESC)",
 
+		// Departments table indexes (prefix matches on the materialized path)
+		"CREATE INDEX IF NOT EXISTS idx_departments_path ON departments(path text_pattern_ops)",
+
 		// Roles table indexes
 		"CREATE INDEX IF NOT EXISTS idx_roles_name ON roles(name)",
 		"CREATE INDEX IF NOT EXISTS idx_roles_level ON roles(level)",
@@ -395,12 +395,18 @@ func (s *MinioStorage) getContentType(file *multipart.FileHeader) string {
 		return contentType
 	}
 
-	// Determine by file extension
-	ext := strings.ToLower(filepath.Ext(file.Filename))
+	return ContentTypeForFile(file.Filename)
+}
+
+// ContentTypeForFile returns the content type of a file by its extension, the same
+// extension IsAllowedFileType checks, rather than what the client claimed
+func ContentTypeForFile(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
 	contentTypes := map[string]string{
 		".pdf":  "application/pdf",
 		".doc":  "application/msword",
 		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".rtf":  "application/rtf",
 		".xls":  "application/vnd.ms-excel",
 		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
 		".txt":  "text/plain",
@@ -0,0 +1,479 @@
+package jwtkeys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/owner/go-cms/internal/config"
+	"github.com/owner/go-cms/internal/core/domain"
+	"github.com/owner/go-cms/internal/core/ports/repositories"
+	"github.com/owner/go-cms/internal/infrastructure/cache"
+	"github.com/owner/go-cms/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+const (
+	rotationLockKey      = "jwt_key_rotation"
+	refreshInterval      = time.Minute
+	minReloadInterval    = 10 * time.Second
+	defaultRotationEvery = 30 * 24 * time.Hour
+	rsaKeyBits           = 2048
+)
+
+// Keyring signs tokens with the current key and verifies them with any key that has
+// not expired. With HS256 it falls back to the shared secret.
+//
+// Key pairs are kept in the database so all instances share them. A new key is
+// published in the JWKS for KeyPublishLead before it starts signing, and the key
+// it replaces keeps verifying until the longest lived token it signed has expired.
+type Keyring struct {
+	config *config.JWTConfig
+	repo   repositories.SigningKeyRepository
+
+	mu       sync.RWMutex
+	keys     []*signingKey // Newest first
+	byID     map[string]*signingKey
+	loadedAt time.Time
+}
+
+// signingKey is a parsed domain.SigningKey
+type signingKey struct {
+	id          string
+	algorithm   string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	public      crypto.PublicKey
+	activatesAt time.Time
+	retiresAt   *time.Time
+	expiresAt   *time.Time
+}
+
+// NewKeyring creates a keyring for the configured algorithm
+func NewKeyring(cfg *config.JWTConfig, repo repositories.SigningKeyRepository) *Keyring {
+	return &Keyring{
+		config: cfg,
+		repo:   repo,
+		byID:   make(map[string]*signingKey),
+	}
+}
+
+// Init loads the key pairs and creates the first one if none can sign yet
+func (k *Keyring) Init(ctx context.Context) error {
+	if k.symmetric() {
+		return nil
+	}
+
+	switch k.config.Algorithm {
+	case AlgorithmRS256, AlgorithmES256:
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", k.config.Algorithm)
+	}
+
+	if err := k.reload(ctx); err != nil {
+		return err
+	}
+	if k.currentKey() != nil {
+		return nil
+	}
+
+	// Only one instance creates the first key; the others pick it up
+	owner := uuid.NewString()
+	_, acquired, err := cache.AcquireLock(ctx, rotationLockKey, owner, time.Minute)
+	if err != nil {
+		logger.Warn("Failed to acquire signing key lock, creating key anyway", zap.Error(err))
+	} else if !acquired {
+		time.Sleep(2 * time.Second)
+		if err := k.reload(ctx); err != nil {
+			return err
+		}
+		if k.currentKey() != nil {
+			return nil
+		}
+	} else {
+		defer func() { _, _ = cache.ReleaseLock(ctx, rotationLockKey, owner) }()
+		if err := k.reload(ctx); err != nil {
+			return err
+		}
+		if k.currentKey() != nil {
+			return nil
+		}
+	}
+
+	return k.rotate(ctx, true)
+}
+
+// StartRotationJob keeps the keys in sync with the database and rotates the signing
+// key when it is due. Only one instance rotates. It stops when ctx is cancelled.
+func (k *Keyring) StartRotationJob(ctx context.Context) {
+	if k.symmetric() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := k.reload(ctx); err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to reload signing keys", zap.Error(err))
+				}
+				continue
+			}
+
+			if !k.rotationDue() {
+				continue
+			}
+
+			// The lock is left to expire so other instances skip this round
+			if _, acquired, err := cache.AcquireLock(ctx, rotationLockKey, uuid.NewString(), refreshInterval*9/10); err != nil {
+				if ctx.Err() == nil {
+					logger.Warn("Failed to acquire signing key lock", zap.Error(err))
+				}
+				continue
+			} else if !acquired {
+				continue
+			}
+
+			// Another instance may have rotated since the last reload
+			if err := k.reload(ctx); err != nil || !k.rotationDue() {
+				continue
+			}
+
+			if err := k.rotate(ctx, false); err != nil {
+				logger.Error("Failed to rotate signing key", zap.Error(err))
+				continue
+			}
+
+			if deleted, err := k.repo.DeleteExpired(ctx); err != nil {
+				logger.Warn("Failed to delete expired signing keys", zap.Error(err))
+			} else if deleted > 0 {
+				logger.Info("Deleted expired signing keys", zap.Int64("count", deleted))
+			}
+		}
+	}()
+}
+
+// Sign signs claims with the current key, naming it in the kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.symmetric() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(k.config.Secret))
+	}
+
+	key := k.currentKey()
+	if key == nil {
+		// The cached keys may predate a rotation by another instance
+		if err := k.reloadSoon(); err == nil {
+			key = k.currentKey()
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// Keyfunc returns the key that verifies a token. Tokens must name an unexpired
+// key in their kid header and use that key's algorithm.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if k.symmetric() {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(k.config.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no kid")
+	}
+
+	key := k.lookup(kid)
+	if key == nil {
+		// The key may have been created by another instance
+		if err := k.reloadSoon(); err == nil {
+			key = k.lookup(kid)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if key.expiresAt != nil && time.Now().After(*key.expiresAt) {
+		return nil, fmt.Errorf("signing key %q has expired", kid)
+	}
+
+	return key.public, nil
+}
+
+// Methods lists the algorithms tokens may be signed with
+func (k *Keyring) Methods() []string {
+	if k.symmetric() {
+		return []string{AlgorithmHS256}
+	}
+	return []string{AlgorithmRS256, AlgorithmES256}
+}
+
+// JWKS returns the public keys that verify tokens, including keys about to sign
+func (k *Keyring) JWKS() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	if k.symmetric() {
+		return set
+	}
+
+	now := time.Now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.expiresAt != nil && now.After(*key.expiresAt) {
+			continue
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.public,
+			KeyID:     key.id,
+			Algorithm: key.algorithm,
+			Use:       "sig",
+		})
+	}
+
+	return set
+}
+
+func (k *Keyring) symmetric() bool {
+	return k.config.Algorithm == "" || k.config.Algorithm == AlgorithmHS256
+}
+
+// currentKey returns the newest key that has activated and not retired
+func (k *Keyring) currentKey() *signingKey {
+	now := time.Now()
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.activatesAt.After(now) {
+			continue
+		}
+		if key.retiresAt != nil && !now.Before(*key.retiresAt) {
+			continue
+		}
+		return key
+	}
+
+	return nil
+}
+
+func (k *Keyring) lookup(kid string) *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.byID[kid]
+}
+
+// rotationDue reports whether the newest key has signed for the rotation interval
+func (k *Keyring) rotationDue() bool {
+	interval := k.config.KeyRotationInterval
+	if interval <= 0 {
+		interval = defaultRotationEvery
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if len(k.keys) == 0 {
+		return true
+	}
+	return !time.Now().Before(k.keys[0].activatesAt.Add(interval))
+}
+
+// rotate creates a key of the configured algorithm. Unless immediate, it starts
+// signing after the publish lead, when the keys it replaces retire.
+func (k *Keyring) rotate(ctx context.Context, immediate bool) error {
+	record, err := generateKey(k.config.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	record.ActivatesAt = time.Now()
+	if !immediate && k.config.KeyPublishLead > 0 {
+		record.ActivatesAt = record.ActivatesAt.Add(k.config.KeyPublishLead)
+	}
+
+	// Replaced keys verify until the longest lived token they signed has expired
+	lifetime := k.config.AccessTokenExpire
+	if k.config.RefreshTokenExpire > lifetime {
+		lifetime = k.config.RefreshTokenExpire
+	}
+
+	if err := k.repo.Rotate(ctx, record, record.ActivatesAt, record.ActivatesAt.Add(lifetime)); err != nil {
+		return err
+	}
+
+	logger.Info("Created signing key",
+		zap.String("kid", record.KeyID),
+		zap.String("alg", record.Algorithm),
+		zap.Time("activates_at", record.ActivatesAt),
+	)
+
+	return k.reload(ctx)
+}
+
+// reloadSoon reloads the keys unless they were loaded very recently, so unknown kids
+// cannot be used to flood the database
+func (k *Keyring) reloadSoon() error {
+	k.mu.RLock()
+	recent := time.Since(k.loadedAt) < minReloadInterval
+	k.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return k.reload(ctx)
+}
+
+// reload replaces the cached keys with the unexpired keys in the database
+func (k *Keyring) reload(ctx context.Context) error {
+	records, err := k.repo.ListUnexpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(records))
+	byID := make(map[string]*signingKey, len(records))
+	for _, record := range records {
+		key, err := parseKey(record)
+		if err != nil {
+			logger.Error("Skipping invalid signing key", zap.String("kid", record.KeyID), zap.Error(err))
+			continue
+		}
+		keys = append(keys, key)
+		byID[key.id] = key
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.byID = byID
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+
+	return nil
+}
+
+// generateKey creates a key pair identified by its RFC 7638 thumbprint
+func generateKey(algorithm string) (*domain.SigningKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	jwk := jose.JSONWebKey{Key: private.Public()}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key ID: %w", err)
+	}
+
+	return &domain.SigningKey{
+		KeyID:      base64.RawURLEncoding.EncodeToString(thumbprint),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
+
+func parseKey(record *domain.SigningKey) (*signingKey, error) {
+	method := jwt.GetSigningMethod(record.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported algorithm %q", record.Algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(record.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("private key cannot sign")
+	}
+
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		if record.Algorithm != AlgorithmRS256 {
+			return nil, fmt.Errorf("RSA key used with %s", record.Algorithm)
+		}
+	case *ecdsa.PrivateKey:
+		if record.Algorithm != AlgorithmES256 {
+			return nil, fmt.Errorf("ECDSA key used with %s", record.Algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", private)
+	}
+
+	return &signingKey{
+		id:          record.KeyID,
+		algorithm:   record.Algorithm,
+		method:      method,
+		private:     private,
+		public:      private.Public(),
+		activatesAt: record.ActivatesAt,
+		retiresAt:   record.RetiresAt,
+		expiresAt:   record.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,252 @@
+package preview
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxXMLPartSize guards against zip bombs in office documents
+const maxXMLPartSize = 64 << 20
+
+// extractDOCXText reads the paragraphs of word/document.xml
+func extractDOCXText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid docx file: %w", err)
+	}
+
+	part, err := readZipPart(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	decoder := xml.NewDecoder(bytes.NewReader(part))
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid docx file: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+
+	return b.String(), nil
+}
+
+// extractXLSXText reads every worksheet as tab separated rows
+func extractXLSXText(data []byte) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid xlsx file: %w", err)
+	}
+
+	sharedStrings, err := readSharedStrings(archive)
+	if err != nil {
+		return "", err
+	}
+
+	var sheets []*zip.File
+	for _, file := range archive.File {
+		if strings.HasPrefix(file.Name, "xl/worksheets/sheet") && strings.HasSuffix(file.Name, ".xml") {
+			sheets = append(sheets, file)
+		}
+	}
+	sort.Slice(sheets, func(i, j int) bool {
+		return sheetNumber(sheets[i].Name) < sheetNumber(sheets[j].Name)
+	})
+
+	var b strings.Builder
+	for _, sheet := range sheets {
+		part, err := readZipFile(sheet)
+		if err != nil {
+			return "", err
+		}
+
+		if err := writeSheetText(&b, part, sharedStrings); err != nil {
+			return "", err
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String(), nil
+}
+
+func readSharedStrings(archive *zip.Reader) ([]string, error) {
+	part, err := readZipPart(archive, "xl/sharedStrings.xml")
+	if err != nil {
+		// Workbooks without text cells have no shared string table
+		return nil, nil
+	}
+
+	var (
+		values  []string
+		current strings.Builder
+		inText  bool
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx shared strings: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				values = append(values, current.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func writeSheetText(b *strings.Builder, part []byte, sharedStrings []string) error {
+	var (
+		cellType  string
+		value     strings.Builder
+		inValue   bool
+		firstCell = true
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid xlsx worksheet: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				firstCell = true
+			case "c":
+				cellType = ""
+				value.Reset()
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "t" {
+						cellType = attr.Value
+					}
+				}
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				text := value.String()
+				if cellType == "s" {
+					index, err := strconv.Atoi(text)
+					if err != nil || index < 0 || index >= len(sharedStrings) {
+						text = ""
+					} else {
+						text = sharedStrings[index]
+					}
+				}
+				if text == "" {
+					continue
+				}
+				if !firstCell {
+					b.WriteByte('\t')
+				}
+				b.WriteString(text)
+				firstCell = false
+			case "row":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+func readZipPart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return readZipFile(file)
+		}
+	}
+	return nil, fmt.Errorf("missing %s", name)
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	part, err := io.ReadAll(io.LimitReader(rc, maxXMLPartSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return part, nil
+}
+
+func sheetNumber(name string) int {
+	number := strings.TrimSuffix(strings.TrimPrefix(name, "xl/worksheets/sheet"), ".xml")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,359 @@
+package preview
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFStreamSize guards against decompression bombs in PDF streams
+const maxPDFStreamSize = 64 << 20
+
+// pdfStream is a raw stream object together with its dictionary source
+type pdfStream struct {
+	dict string
+	data []byte
+}
+
+// extractPDFText pulls the text shown by Tj/TJ operators out of the page content
+// streams. It handles the common single-byte and UTF-16 encodings; text drawn with
+// embedded CID fonts has no recoverable mapping without the font and is skipped.
+func extractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		return "", errors.New("invalid pdf file")
+	}
+
+	var b strings.Builder
+	for _, stream := range pdfStreams(data) {
+		if !isPDFContentStream(stream.dict) {
+			continue
+		}
+
+		content, ok := decodePDFStream(stream)
+		if !ok || !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+
+		b.WriteString(pdfContentText(content))
+		b.WriteByte('\n')
+
+		if b.Len() > MaxTextLength {
+			break
+		}
+	}
+
+	return b.String(), nil
+}
+
+// firstPDFImage returns the first JPEG image embedded in the PDF
+func firstPDFImage(data []byte) ([]byte, bool) {
+	for _, stream := range pdfStreams(data) {
+		dict := strings.ReplaceAll(stream.dict, " ", "")
+		if strings.Contains(dict, "/Subtype/Image") && strings.Contains(dict, "/DCTDecode") &&
+			!strings.Contains(dict, "/FlateDecode") {
+			return stream.data, true
+		}
+	}
+	return nil, false
+}
+
+// pdfStreams scans the file for "stream ... endstream" objects
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	keyword := []byte("stream")
+	pos := 0
+
+	for {
+		i := bytes.Index(data[pos:], keyword)
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		pos = start + len(keyword)
+
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+
+		// The stream keyword is followed by CRLF or LF
+		dataStart := pos
+		switch {
+		case bytes.HasPrefix(data[pos:], []byte("\r\n")):
+			dataStart += 2
+		case bytes.HasPrefix(data[pos:], []byte("\n")):
+			dataStart++
+		default:
+			continue
+		}
+
+		end := bytes.Index(data[dataStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+
+		dictStart := bytes.LastIndex(data[:start], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+
+		streams = append(streams, pdfStream{
+			dict: string(data[dictStart:start]),
+			data: bytes.TrimRight(data[dataStart:dataStart+end], "\r\n"),
+		})
+		pos = dataStart + end + len("endstream")
+	}
+
+	return streams
+}
+
+// isPDFContentStream filters out images, fonts, metadata and cross-reference streams
+func isPDFContentStream(dict string) bool {
+	for _, marker := range []string{"/Subtype", "/Length1", "/Length2", "/XRef", "/ObjStm", "/Metadata"} {
+		if strings.Contains(dict, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+func decodePDFStream(stream pdfStream) ([]byte, bool) {
+	if !strings.Contains(stream.dict, "/Filter") {
+		return stream.data, true
+	}
+
+	// Only Flate is used for content streams in practice
+	filter := strings.ReplaceAll(stream.dict, " ", "")
+	if !strings.Contains(filter, "/FlateDecode") || strings.Count(filter, "Decode") > 1 {
+		return nil, false
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	// Truncated streams still yield their readable prefix
+	content, _ := io.ReadAll(io.LimitReader(reader, maxPDFStreamSize))
+	return content, len(content) > 0
+}
+
+// pdfContentText interprets the text operators of a content stream
+func pdfContentText(content []byte) string {
+	var (
+		b       strings.Builder
+		strs    []string
+		numbers []float64
+		inArray bool
+		newLine = func() {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteByte('\n')
+			}
+		}
+	)
+
+	for i := 0; i < len(content); {
+		c := content[i]
+
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := readPDFLiteral(content[i:])
+			strs = append(strs, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, n := readPDFHex(content[i:])
+			strs = append(strs, s)
+			i += n
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '/':
+			// Names are operands we never need
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			if value, err := strconv.ParseFloat(string(content[i:j]), 64); err == nil {
+				// Large negative kerning inside a TJ array separates words
+				if inArray && value < -200 {
+					strs = append(strs, " ")
+				}
+				numbers = append(numbers, value)
+			}
+			i = j
+		default:
+			j := i
+			for j < len(content) && !isPDFSpace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+
+			switch string(content[i:j]) {
+			case "Tj", "TJ":
+				b.WriteString(strings.Join(strs, ""))
+			case "'", "\"":
+				newLine()
+				b.WriteString(strings.Join(strs, ""))
+			case "T*", "ET":
+				newLine()
+			case "Td", "TD":
+				if len(numbers) >= 2 && numbers[len(numbers)-1] != 0 {
+					newLine()
+				} else if len(numbers) >= 2 && numbers[len(numbers)-2] > 0 {
+					b.WriteByte(' ')
+				}
+			case "Tm":
+				newLine()
+			}
+
+			strs = strs[:0]
+			numbers = numbers[:0]
+			i = j
+		}
+	}
+
+	return b.String()
+}
+
+// readPDFLiteral decodes a (literal string) and returns it with the number of bytes consumed
+func readPDFLiteral(content []byte) (string, int) {
+	var out []byte
+	depth := 0
+	i := 0
+
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r':
+				if i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					n := 0
+					for n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7' {
+						value = value*8 + int(content[i]-'0')
+						i++
+						n++
+					}
+					out = append(out, byte(value))
+					continue
+				}
+				out = append(out, e)
+			}
+			i++
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+			if depth == 0 {
+				return decodePDFString(out), i
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+
+	return decodePDFString(out), i
+}
+
+// readPDFHex decodes a <hex string>; glyph IDs from CID fonts are dropped
+func readPDFHex(content []byte) (string, int) {
+	end := bytes.IndexByte(content, '>')
+	if end < 0 {
+		return "", len(content)
+	}
+
+	digits := make([]byte, 0, end)
+	for _, c := range content[1:end] {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	raw := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(raw, digits); err != nil {
+		return "", end + 1
+	}
+
+	text := decodePDFString(raw)
+	for _, r := range text {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return "", end + 1
+		}
+	}
+
+	return text, end + 1
+}
+
+// decodePDFString converts UTF-16BE strings (with BOM) and single-byte strings to UTF-8
+func decodePDFString(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, (len(raw)-2)/2)
+		for i := 2; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(raw))
+	for i, c := range raw {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,119 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/disintegration/imaging"
+	imageProcessor "github.com/owner/go-cms/internal/infrastructure/image"
+)
+
+// ErrUnsupported is returned when a file type has no text extractor or thumbnail renderer
+var ErrUnsupported = errors.New("unsupported file type")
+
+const (
+	// ThumbnailSize is the maximum width and height of generated thumbnails
+	ThumbnailSize = 320
+	// MaxTextLength caps the amount of extracted text stored per document
+	MaxTextLength = 1 << 20
+
+	thumbnailQuality = 80
+)
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// Generator extracts searchable text and renders thumbnails from document files
+type Generator struct {
+	images *imageProcessor.Processor
+}
+
+// NewGenerator creates a new preview generator
+func NewGenerator() *Generator {
+	return &Generator{
+		images: imageProcessor.NewProcessor(),
+	}
+}
+
+// ExtractText returns the plain text of a PDF, DOCX, XLSX or text file
+func (g *Generator) ExtractText(filename string, data []byte) (string, error) {
+	var (
+		text string
+		err  error
+	)
+
+	switch fileExt(filename) {
+	case ".pdf":
+		text, err = extractPDFText(data)
+	case ".docx":
+		text, err = extractDOCXText(data)
+	case ".xlsx":
+		text, err = extractXLSXText(data)
+	case ".txt", ".csv":
+		text = string(data)
+	default:
+		return "", ErrUnsupported
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return cleanText(text), nil
+}
+
+// Thumbnail renders a JPEG thumbnail of an image or of the first embedded image of a PDF
+func (g *Generator) Thumbnail(filename string, data []byte) ([]byte, error) {
+	source := data
+
+	switch fileExt(filename) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp":
+	case ".pdf":
+		// Rendering PDF vector content needs a full PDF engine; scanned and
+		// image-based PDFs carry their page as a JPEG, which is what we use here.
+		image, ok := firstPDFImage(data)
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		source = image
+	default:
+		return nil, ErrUnsupported
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(source), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := g.images.OptimizeImage(img, ThumbnailSize, ThumbnailSize, thumbnailQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func fileExt(filename string) string {
+	return strings.ToLower(filepath.Ext(filename))
+}
+
+// cleanText makes extracted text safe to store in a postgres text column
+func cleanText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	text = strings.TrimSpace(text)
+
+	if len(text) > MaxTextLength {
+		text = text[:MaxTextLength]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+
+	return text
+}
@@ -56,6 +56,7 @@ const (
 	ErrCodeConflict     = "CONFLICT"
 	ErrCodeValidation   = "VALIDATION_ERROR"
 	ErrCodeTimeout      = "TIMEOUT"
+	ErrCodeRateLimited  = "RATE_LIMIT_EXCEEDED"
 
 	// Authentication errors
 	ErrCodeInvalidCredentials = "INVALID_CREDENTIALS"
@@ -64,6 +65,8 @@ const (
 	ErrCodeInvalidOTP         = "INVALID_OTP"
 	ErrCodeExpiredOTP         = "EXPIRED_OTP"
 	ErrCodeInvalid2FA         = "INVALID_2FA"
+	ErrCodeTooManyAttempts    = "TOO_MANY_ATTEMPTS"
+	ErrCodeAccountLocked      = "ACCOUNT_LOCKED"
 
 	// Authorization errors
 	ErrCodeInsufficientPermissions = "INSUFFICIENT_PERMISSIONS"
@@ -99,6 +102,8 @@ var (
 	ErrValidation   = New(ErrCodeValidation, "Validation error", http.StatusBadRequest)
 	ErrTimeout      = New(ErrCodeTimeout, "Request timeout", http.StatusRequestTimeout)
 
+	ErrRateLimitExceeded = New(ErrCodeRateLimited, "Too many requests", http.StatusTooManyRequests)
+
 	// Authentication errors
 	ErrInvalidCredentials = New(ErrCodeInvalidCredentials, "Invalid email or password", http.StatusUnauthorized)
 	ErrInvalidToken       = New(ErrCodeInvalidToken, "Invalid token", http.StatusUnauthorized)
@@ -106,6 +111,8 @@ var (
 	ErrInvalidOTP         = New(ErrCodeInvalidOTP, "Invalid OTP", http.StatusUnauthorized)
 	ErrExpiredOTP         = New(ErrCodeExpiredOTP, "OTP has expired", http.StatusUnauthorized)
 	ErrInvalid2FA         = New(ErrCodeInvalid2FA, "Invalid 2FA code", http.StatusUnauthorized)
+	ErrTooManyAttempts    = New(ErrCodeTooManyAttempts, "Too many failed attempts", http.StatusTooManyRequests)
+	ErrAccountLocked      = New(ErrCodeAccountLocked, "Account is temporarily locked", http.StatusLocked)
 
 	// Authorization errors
 	ErrInsufficientPermissions = New(ErrCodeInsufficientPermissions, "Insufficient permissions", http.StatusForbidden)
@@ -0,0 +1,391 @@
+// Package expression implements the small condition language used by access policies.
+//
+// An expression is evaluated against nested attributes, e.g.
+//
+//	resource.assigned_to == subject.id && request.hour >= 8 && request.hour < 18
+//	"manager" in subject.roles || !(resource.status in ["archived", "locked"])
+//	in_cidr(request.ip, "10.0.0.0/8")
+//
+// Operands are attributes (dotted paths, null when missing), numbers, strings in
+// single or double quotes, true, false, null and lists. Operators are ||, &&, !,
+// ==, !=, <, <=, >, >= and in, which tests list membership or substrings.
+// Functions are contains, starts_with, ends_with and in_cidr.
+package expression
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Expression is a compiled condition
+type Expression struct {
+	source string
+	root   node
+	refs   []string
+}
+
+// Compile parses an expression
+func Compile(source string) (*Expression, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, refs: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+	}
+
+	refs := make([]string, 0, len(p.refs))
+	for ref := range p.refs {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	return &Expression{source: source, root: root, refs: refs}, nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// References returns the top-level attribute names the expression reads, e.g. "subject"
+// for subject.id
+func (e *Expression) References() []string {
+	return e.refs
+}
+
+// Reads reports whether the expression reads the named top-level attribute
+func (e *Expression) Reads(name string) bool {
+	for _, ref := range e.refs {
+		if ref == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate evaluates the expression against the attributes. Attribute values should be
+// JSON-like: nil, bool, float64 or other numbers, string, []interface{} and
+// map[string]interface{}. The result must be a boolean.
+func (e *Expression) Evaluate(attributes map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(attributes)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluates to %s, not a boolean", typeName(value))
+	}
+	return result, nil
+}
+
+type node interface {
+	eval(attributes map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type attributeNode struct {
+	path []string
+}
+
+func (n *attributeNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	var current interface{} = attributes
+	for _, part := range n.path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		current = object[part]
+	}
+	return normalize(current), nil
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! needs a boolean, got %s", typeName(value))
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs booleans, got %s", n.op, typeName(left))
+	}
+
+	// Short-circuit
+	if n.op == "&&" && !l {
+		return false, nil
+	}
+	if n.op == "||" && l {
+		return true, nil
+	}
+
+	right, err := n.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs booleans, got %s", n.op, typeName(right))
+	}
+	return r, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	}
+
+	// Ordering compares numbers with numbers and strings with strings; a missing
+	// attribute never satisfies it
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot order %s", typeName(left))
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"contains": {arity: 2, call: func(args []interface{}) (interface{}, error) {
+		return contains(args[0], args[1])
+	}},
+	"starts_with": {arity: 2, call: func(args []interface{}) (interface{}, error) {
+		s, prefix, ok := twoStrings(args)
+		return ok && strings.HasPrefix(s, prefix), nil
+	}},
+	"ends_with": {arity: 2, call: func(args []interface{}) (interface{}, error) {
+		s, suffix, ok := twoStrings(args)
+		return ok && strings.HasSuffix(s, suffix), nil
+	}},
+	"in_cidr": {arity: 2, call: func(args []interface{}) (interface{}, error) {
+		ip, cidr, ok := twoStrings(args)
+		if !ok {
+			return false, nil
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		parsed := net.ParseIP(ip)
+		return parsed != nil && network.Contains(parsed), nil
+	}},
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(attributes map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(attributes)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	result, err := n.fn.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return result, nil
+}
+
+// contains reports whether a list holds an item, or a string holds a substring. A
+// missing collection holds nothing.
+func contains(collection, item interface{}) (interface{}, error) {
+	switch c := collection.(type) {
+	case nil:
+		return false, nil
+	case []interface{}:
+		for _, element := range c {
+			if equal(normalize(element), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s), nil
+	default:
+		return nil, fmt.Errorf("cannot search in %s", typeName(collection))
+	}
+}
+
+func twoStrings(args []interface{}) (string, string, bool) {
+	a, ok := args[0].(string)
+	if !ok {
+		return "", "", false
+	}
+	b, ok := args[1].(string)
+	return a, b, ok
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// normalize converts Go numbers to float64 and typed slices to []interface{}, so
+// attributes built without a JSON round trip compare like literals
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, string, float64, map[string]interface{}:
+		return v
+	case []interface{}:
+		return v
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.Slice:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = normalize(rv.Index(i).Interface())
+		}
+		return items
+	}
+	return value
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,211 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var testAttributes = map[string]interface{}{
+	"subject": map[string]interface{}{
+		"id":    "u-1",
+		"roles": []string{"manager", "editor"},
+		"level": 3,
+	},
+	"resource": map[string]interface{}{
+		"assigned_to": "u-1",
+		"status":      "active",
+		"amount":      1500.5,
+		"owner":       uuid.MustParse("6f1c2a3b-4d5e-4f60-8a7b-9c0d1e2f3a4b"),
+		"flags":       []interface{}{"vip", 2},
+	},
+	"request": map[string]interface{}{
+		"ip":   "10.1.2.3",
+		"hour": 9,
+	},
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"equal attributes", "resource.assigned_to == subject.id", true},
+		{"not equal", "resource.status != 'active'", false},
+		{"integer attribute compares with number literal", "request.hour >= 8 && request.hour < 18", true},
+		{"float ordering", "resource.amount > 1000", true},
+		{"string ordering", `"apple" < "banana"`, true},
+		{"stringer attribute", `resource.owner == "6f1c2a3b-4d5e-4f60-8a7b-9c0d1e2f3a4b"`, true},
+		{"in typed list", `"manager" in subject.roles`, true},
+		{"in list literal", `resource.status in ["archived", "locked"]`, false},
+		{"number in mixed list", "2 in resource.flags", true},
+		{"substring in", `"act" in resource.status`, true},
+		{"empty list", "1 in []", false},
+		{"functions", `starts_with(resource.status, "act") && ends_with(resource.status, "ive") && contains(subject.roles, "editor")`, true},
+		{"in_cidr", `in_cidr(request.ip, "10.0.0.0/8")`, true},
+		{"in_cidr outside", `in_cidr(request.ip, "192.168.0.0/16")`, false},
+		{"escaped quote", `'it\'s' == "it's"`, true},
+
+		// Precedence: ! binds tighter than &&, which binds tighter than ||
+		{"and before or", "true || false && false", true},
+		{"parentheses override", "(true || false) && false", false},
+		{"not before and", "!false && false", false},
+		{"not applies to comparison", "!resource.status == 'active'", false},
+		{"double negation", "!!true", true},
+		{"or is left to right", "false || false || true", true},
+
+		// Missing attributes are null
+		{"missing equals null", "resource.missing == null", true},
+		{"missing path through scalar", "resource.status.length == null", true},
+		{"missing is not equal to value", "resource.missing == 'x'", false},
+		{"missing never ordered", "resource.missing < 5", false},
+		{"missing never ordered reversed", "5 >= resource.missing", false},
+		{"nothing in missing collection", "'a' in resource.missing", false},
+		{"function on missing", "starts_with(resource.missing, 'a')", false},
+
+		// Mismatched types are unequal rather than errors
+		{"number is not string", `1 == "1"`, false},
+		{"bool is not string", `true != "true"`, true},
+		{"number not in string", `1 in "123"`, false},
+
+		// Short-circuit skips the side that would fail
+		{"and short-circuits", "false && resource.status < 1", false},
+		{"or short-circuits", "true || resource.status < 1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.expr, err)
+			}
+			got, err := expr.Evaluate(testAttributes)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"number ordered with string", "resource.amount < 'x'", "cannot compare number with string"},
+		{"string ordered with number", "resource.status >= 1", "cannot compare string with number"},
+		{"booleans are not ordered", "true < false", "cannot order boolean"},
+		{"lists are not ordered", "[1] < [2]", "cannot order list"},
+		{"and needs booleans", "resource.status && true", "&& needs booleans, got string"},
+		{"or needs booleans on the right", "false || request.hour", "|| needs booleans, got number"},
+		{"not needs a boolean", "!resource.status", "! needs a boolean, got string"},
+		{"not of missing", "!resource.missing", "! needs a boolean, got null"},
+		{"in needs a collection", "1 in request.hour", "cannot search in number"},
+		{"result must be boolean", "subject.id", "evaluates to string"},
+		{"missing result is not false", "resource.missing", "evaluates to null"},
+		{"invalid cidr", `in_cidr(request.ip, "10.0.0.0/33")`, "in_cidr: invalid CIDR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.expr, err)
+			}
+			got, err := expr.Evaluate(testAttributes)
+			if err == nil {
+				t.Fatalf("Evaluate(%q) = %v, want error containing %q", tt.expr, got, tt.want)
+			}
+			if got {
+				t.Errorf("Evaluate(%q) returned true alongside error %v", tt.expr, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Evaluate(%q) error = %q, want it to contain %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"empty", "", "unexpected end of expression"},
+		{"only whitespace", "   ", "unexpected end of expression"},
+		{"dangling operator", "a ==", "unexpected end of expression"},
+		{"dangling and", "a == 1 &&", "unexpected end of expression"},
+		{"leading operator", "&& a", `unexpected "&&"`},
+		{"lone not", "!", "unexpected end of expression"},
+		{"unclosed parenthesis", "(a == 1", `expected ")"`},
+		{"extra closing parenthesis", "a == 1)", `unexpected ")"`},
+		{"unclosed list", "a in [1, 2", `expected ","`},
+		{"list without separator", "a in [1 2]", `expected ","`},
+		{"trailing comma in list", "a in [1,]", `unexpected "]"`},
+		{"chained comparison", "a == b == c", `unexpected "=="`},
+		{"juxtaposed operands", "a b", `unexpected "b"`},
+		{"unterminated string", `a == "abc`, "unterminated string"},
+		{"escaped final quote", `a == "abc\"`, "unterminated string"},
+		{"single equals", "a = 1", `unexpected character '='`},
+		{"single ampersand", "a & b", `unexpected character '&'`},
+		{"unknown character", "a == 1 ; b", `unexpected character ';'`},
+		{"bad number", "a == 1.2.3", `invalid number "1.2.3"`},
+		{"empty attribute segment", "resource..id == 1", "invalid attribute"},
+		{"trailing dot", "resource. == 1", "invalid attribute"},
+		{"unknown function", "lower(a) == 'x'", `unknown function "lower"`},
+		{"too few arguments", "contains(a)", "takes 2 arguments, got 1"},
+		{"too many arguments", "in_cidr(a, b, c)", "takes 2 arguments, got 3"},
+		{"unclosed call", "contains(a, b", `expected ","`},
+		{"deep parentheses", strings.Repeat("(", 10000) + "true" + strings.Repeat(")", 10000), "nested deeper than"},
+		{"deep negation", strings.Repeat("!", 10000) + "true", "nested deeper than"},
+		{"deep lists", "1 in " + strings.Repeat("[", 10000), "nested deeper than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err == nil {
+				t.Fatalf("Compile(%q) = %v, want error containing %q", tt.expr, expr, tt.want)
+			}
+			if expr != nil {
+				t.Errorf("Compile(%q) returned an expression alongside error %v", tt.expr, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile(%q) error = %q, want it to contain %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileAllowsModerateNesting(t *testing.T) {
+	source := strings.Repeat("(", 20) + "!!true" + strings.Repeat(")", 20)
+	expr, err := Compile(source)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if got, err := expr.Evaluate(nil); err != nil || !got {
+		t.Errorf("Evaluate = %v, %v; want true", got, err)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	expr, err := Compile(`resource.owner == subject.id && in_cidr(request.ip, "10.0.0.0/8") || subject.admin == true`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	got := strings.Join(expr.References(), ",")
+	if got != "request,resource,subject" {
+		t.Errorf("References() = %q, want %q", got, "request,resource,subject")
+	}
+	if !expr.Reads("request") || expr.Reads("environment") {
+		t.Errorf("Reads reports %v for request and %v for environment", expr.Reads("request"), expr.Reads("environment"))
+	}
+}
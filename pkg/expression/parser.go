@@ -0,0 +1,324 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// lex splits an expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[start:i]), pos: start})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[start:i]), pos: start})
+
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: sb.String(), pos: start})
+
+		default:
+			start := i
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{kind: tokenOperator, value: two, pos: start})
+				i += 2
+				continue
+			}
+			switch r {
+			case '<', '>', '!', '(', ')', '[', ']', ',':
+				tokens = append(tokens, token{kind: tokenOperator, value: string(r), pos: start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, start)
+			}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// maxDepth bounds the nesting of parentheses, lists, calls and negations, so
+// hostile input fails to parse instead of exhausting the stack
+const maxDepth = 64
+
+// parser is a recursive descent parser. From lowest to highest precedence:
+// ||, &&, unary !, comparisons (== != < <= > >= in), then operands.
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	refs   map[string]bool
+}
+
+// enter records one more level of nesting; callers undo it with leave
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxDepth {
+		return fmt.Errorf("expression nested deeper than %d levels at position %d", maxDepth, p.peek().pos)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind tokenKind, value string) bool {
+	if t := p.peek(); t.kind == kind && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.accept(kind, value) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at position %d", value, t.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOperator, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOperator, "&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept(tokenOperator, "!") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	isComparison := t.kind == tokenOperator && (t.value == "==" || t.value == "!=" ||
+		t.value == "<" || t.value == "<=" || t.value == ">" || t.value == ">=")
+	if t.kind == tokenIdent && t.value == "in" {
+		isComparison = true
+	}
+	if !isComparison {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: t.value, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.value, t.pos)
+		}
+		return &literalNode{value: n}, nil
+
+	case tokenString:
+		return &literalNode{value: t.value}, nil
+
+	case tokenIdent:
+		switch t.value {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if p.accept(tokenOperator, "(") {
+			return p.parseCall(t)
+		}
+
+		path := strings.Split(t.value, ".")
+		for _, part := range path {
+			if part == "" {
+				return nil, fmt.Errorf("invalid attribute %q at position %d", t.value, t.pos)
+			}
+		}
+		p.refs[path[0]] = true
+		return &attributeNode{path: path}, nil
+
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenOperator, ")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+
+		case "[":
+			list := &listNode{}
+			if p.accept(tokenOperator, "]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept(tokenOperator, "]") {
+					return list, nil
+				}
+				if err := p.expect(tokenOperator, ","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.value, t.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.value, name.pos)
+	}
+
+	call := &callNode{name: name.value, fn: fn}
+	if !p.accept(tokenOperator, ")") {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.accept(tokenOperator, ")") {
+				break
+			}
+			if err := p.expect(tokenOperator, ","); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(call.args) != fn.arity {
+		return nil, fmt.Errorf("function %s takes %d arguments, got %d", name.value, fn.arity, len(call.args))
+	}
+	return call, nil
+}
@@ -26,36 +26,45 @@ func CheckPassword(hashedPassword, password string) bool {
 	return err == nil
 }
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(userID uuid.UUID, email string, cfg *config.JWTConfig) (string, error) {
-	claims := &middleware.Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.AccessTokenExpire)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
-	}
+// TokenSigner signs JWT claims with the current signing key
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// GenerateJWT generates a JWT access token for a user's session
+func GenerateJWT(userID uuid.UUID, email string, sessionID uuid.UUID, cfg *config.JWTConfig, signer TokenSigner) (string, error) {
+	return generateToken(middleware.TokenTypeAccess, userID, email, sessionID, nil, cfg.AccessTokenExpire, signer)
+}
+
+// GenerateImpersonationJWT generates a short-lived access token that lets an admin act
+// as a user. It belongs to no session and comes without a refresh token.
+func GenerateImpersonationJWT(userID uuid.UUID, email string, impersonatorID uuid.UUID, cfg *config.JWTConfig, signer TokenSigner) (string, error) {
+	return generateToken(middleware.TokenTypeAccess, userID, email, uuid.Nil, &impersonatorID, cfg.ImpersonationExpire, signer)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+// GenerateRefreshToken generates a refresh token for a user's session
+func GenerateRefreshToken(userID uuid.UUID, email string, sessionID uuid.UUID, cfg *config.JWTConfig, signer TokenSigner) (string, error) {
+	return generateToken(middleware.TokenTypeRefresh, userID, email, sessionID, nil, cfg.RefreshTokenExpire, signer)
 }
 
-// GenerateRefreshToken generates a refresh token
-func GenerateRefreshToken(userID uuid.UUID, email string, cfg *config.JWTConfig) (string, error) {
+// generateToken signs a token of the given type with a unique ID, so tokens issued in
+// the same second differ
+func generateToken(tokenType string, userID uuid.UUID, email string, sessionID uuid.UUID, impersonatorID *uuid.UUID, expire time.Duration, signer TokenSigner) (string, error) {
 	claims := &middleware.Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:         userID,
+		Email:          email,
+		TokenType:      tokenType,
+		SessionID:      sessionID,
+		ImpersonatorID: impersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.RefreshTokenExpire)),
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expire)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	return signer.Sign(claims)
 }
 
 // GenerateOTP generates a random OTP code